@@ -0,0 +1,267 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// An ApplyFunc is invoked by Apply for each node n, even if n is nil,
+// before and/or after the node's children, using a Cursor describing
+// the current node and providing operations on it.
+//
+// The return value of ApplyFunc controls the syntax tree traversal.
+// See Apply for details.
+type ApplyFunc func(*Cursor) bool
+
+// Apply traverses a syntax tree recursively, starting with root, and
+// calling pre and post for each node as described below. Apply returns
+// the syntax tree, possibly modified.
+//
+// If pre is not nil, it is called for each node before the node's
+// children are traversed (pre-order). If pre returns false, no children
+// are traversed, and post is not called for that node.
+//
+// If post is not nil, and a prior call of pre didn't return false, post
+// is called for each node after its children are traversed (post-order).
+// If post returns false, traversal is terminated and Apply returns
+// immediately.
+//
+// Only fields that refer to nodes, or to lists of nodes, are traversed,
+// in the same order as Walk. Replaced nodes are not traversed; the
+// children of the original node are.
+func Apply(root Node, pre, post ApplyFunc) (result Node) {
+	parent := &struct{ Node }{root}
+	defer func() {
+		if r := recover(); r != nil && r != abort {
+			panic(r)
+		}
+		result = parent.Node
+	}()
+	a := &application{pre: pre, post: post}
+	a.apply(parent, "Node", reflect.ValueOf(parent).Elem().Field(0), nil, root)
+	return
+}
+
+var abort = new(int) // singleton, to signal termination of Apply
+
+// A Cursor describes a node encountered during Apply. Information about
+// the node and its parent is available from the Node, Parent, Name, and
+// Index methods.
+//
+// The methods Replace, Delete, InsertBefore, and InsertAfter can be used
+// to change the tree. They must only be called from the ApplyFunc given
+// the cursor.
+type Cursor struct {
+	parent Node
+	name   string
+	// field is the parent's field holding the node, or the list of nodes
+	// it belongs to.
+	field reflect.Value
+	iter  *iterator // valid if field is a list
+	node  Node
+}
+
+// Node returns the current Node.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the parent of the current Node.
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Name returns the name of the parent Node field that contains the
+// current Node. If the parent is a *Block and the current Node is a
+// declaration, Name returns "Declarations", the field of the block's
+// DeclList holding it.
+func (c *Cursor) Name() string { return c.name }
+
+// Index reports the index >= 0 of the current Node in the list of Nodes
+// that contains it, or a value < 0 if the current Node is not part of a
+// list.
+func (c *Cursor) Index() int {
+	if c.iter != nil {
+		return c.iter.index
+	}
+	return -1
+}
+
+// Replace replaces the current Node with n. The replacement node is not
+// walked by Apply.
+func (c *Cursor) Replace(n Node) {
+	v := c.field
+	if i := c.Index(); i >= 0 {
+		v = v.Index(i)
+	}
+	v.Set(nodeValue(n, v.Type()))
+}
+
+// Delete deletes the current Node from its containing list. If the
+// current Node is not part of a list, Delete panics.
+func (c *Cursor) Delete() {
+	i := c.Index()
+	if i < 0 {
+		panic("Delete node not contained in list")
+	}
+	v := c.field
+	l := v.Len()
+	reflect.Copy(v.Slice(i, l), v.Slice(i+1, l))
+	v.Index(l - 1).Set(reflect.Zero(v.Type().Elem()))
+	v.SetLen(l - 1)
+	c.iter.step--
+}
+
+// InsertAfter inserts n after the current Node in its containing list.
+// If the current Node is not part of a list, InsertAfter panics. Apply
+// does not walk n.
+func (c *Cursor) InsertAfter(n Node) {
+	i := c.Index()
+	if i < 0 {
+		panic("InsertAfter node not contained in list")
+	}
+	v := c.field
+	v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+	l := v.Len()
+	reflect.Copy(v.Slice(i+2, l), v.Slice(i+1, l))
+	v.Index(i + 1).Set(nodeValue(n, v.Type().Elem()))
+	c.iter.step++
+}
+
+// InsertBefore inserts n before the current Node in its containing list.
+// If the current Node is not part of a list, InsertBefore panics. Apply
+// will not walk n.
+func (c *Cursor) InsertBefore(n Node) {
+	i := c.Index()
+	if i < 0 {
+		panic("InsertBefore node not contained in list")
+	}
+	v := c.field
+	v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+	l := v.Len()
+	reflect.Copy(v.Slice(i+1, l), v.Slice(i, l))
+	v.Index(i).Set(nodeValue(n, v.Type().Elem()))
+	c.iter.index++
+}
+
+// nodeValue returns n as a value to store in a field of type typ.
+func nodeValue(n Node, typ reflect.Type) reflect.Value {
+	if n == nil {
+		return reflect.Zero(typ)
+	}
+	return reflect.ValueOf(n)
+}
+
+// application carries all the shared data so we can pass it around cheaply.
+type application struct {
+	pre, post ApplyFunc
+	cursor    Cursor
+	iter      iterator
+}
+
+// An iterator controls iteration over a list of nodes.
+type iterator struct {
+	index, step int
+}
+
+func (a *application) apply(parent Node, name string, field reflect.Value, iter *iterator, n Node) {
+	// avoid heap-allocating a new cursor for each apply call; reuse a.cursor instead
+	saved := a.cursor
+	a.cursor = Cursor{parent: parent, name: name, field: field, iter: iter, node: n}
+
+	if a.pre != nil && !a.pre(&a.cursor) {
+		a.cursor = saved
+		return
+	}
+
+	switch n := n.(type) {
+	case nil, *Comment, *ComponentValue, *ImportRule:
+		// nothing to do
+
+	case *Stylesheet:
+		a.applyList(n, "Children", reflect.ValueOf(&n.Children).Elem())
+
+	case *AtRule:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *FontFaceRule:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *KeyframesRule:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *Keyframe:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *PageRule:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *MarginRule:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *CounterStyleRule:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *PropertyRule:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *FontFeatureValuesRule:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *FeatureValuesBlock:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *LayerRule:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *ScopeRule:
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *QualifiedRule:
+		a.applyList(n, "Components", reflect.ValueOf(&n.Components).Elem())
+		a.applyField(n, "Block", reflect.ValueOf(&n.Block).Elem())
+
+	case *Block:
+		if l := n.DeclList; l != nil {
+			a.applyList(n, "Declarations", reflect.ValueOf(&l.Declarations).Elem())
+			a.applyList(n, "Trailing", reflect.ValueOf(&l.Trailing).Elem())
+		}
+		a.applyList(n, "Rules", reflect.ValueOf(&n.Rules).Elem())
+
+	case *Declaration:
+		a.applyList(n, "Leading", reflect.ValueOf(&n.Leading).Elem())
+		a.applyList(n, "Trailing", reflect.ValueOf(&n.Trailing).Elem())
+
+	default:
+		panic(fmt.Sprintf("ast.Apply: unexpected node type %T", n))
+	}
+
+	if a.post != nil && !a.post(&a.cursor) {
+		panic(abort)
+	}
+
+	a.cursor = saved
+}
+
+// applyField applies to the node held by field, a pointer field of
+// parent.
+func (a *application) applyField(parent Node, name string, field reflect.Value) {
+	a.apply(parent, name, field, nil, node(field))
+}
+
+// applyList applies to the nodes of list, a slice field of parent.
+func (a *application) applyList(parent Node, name string, list reflect.Value) {
+	// avoid heap-allocating a new iterator for each applyList call; reuse a.iter instead
+	saved := a.iter
+	a.iter.index = 0
+	for a.iter.index < list.Len() {
+		a.iter.step = 1
+		a.apply(parent, name, list, &a.iter, node(list.Index(a.iter.index)))
+		a.iter.index += a.iter.step
+	}
+	a.iter = saved
+}
+
+// node returns the node held by v, or nil.
+func node(v reflect.Value) Node {
+	if v.IsNil() {
+		return nil
+	}
+	return v.Interface().(Node)
+}
@@ -1,7 +1,15 @@
 package ast
 
+// Node is implemented by all nodes of the tree.
+type Node interface {
+	Pos() Position // position of the first character of the node
+	End() Position // position of the character immediately after the node
+}
+
 type Stylesheet struct {
+	Span
 	Children []Rule
+	Raw      *Raw
 }
 
 // TODO(ttacon): don't think we need RuleList type?
@@ -10,38 +18,67 @@ type RuleList struct {
 }
 
 type Rule interface {
+	Node
 }
 
 // TODO(ttacon): CDO/CDC?
 
+// Comment is a /* ... */ comment. Comments between rules are kept as
+// Comment entries in the enclosing rule list; comments inside a
+// declaration block are attached to the neighboring declarations.
+type Comment struct {
+	Span
+	// Text is the comment text, including the /* and */ markers.
+	Text string
+	Raw  *Raw
+}
+
 type AtRule struct {
+	Span
 	// TODO(ttacon): atkeyword and any should be nodes...
-	AtKeyword     string
-	Any           string
-	QualifiedRule *QualifiedRule
-	Block         *Block
-	JustSemi      bool
+	AtKeyword string
+	// Any is the prelude, with comments removed and whitespace collapsed.
+	Any      string
+	Block    *Block
+	JustSemi bool
+	Raw      *Raw
 }
 
 type QualifiedRule struct {
+	Span
 	Components []*ComponentValue
 	Block      *Block
+	Raw        *Raw
 }
 
 type ComponentValue struct {
+	Span
 	Name string
 }
 
 type DeclarationList struct {
 	Declarations []*Declaration
+	// Trailing holds the comments following the last declaration that do
+	// not belong to it.
+	Trailing []*Comment
 }
 
 type Declaration struct {
-	Ident      string
+	Span
+	Ident string
+	// Components holds the component values of the value, without
+	// !important. The value of a custom property, like --x, is kept as
+	// written instead: Components holds its text, possibly empty, as a
+	// single component.
 	Components []string
-}
-
-type Important struct {
+	// Important is set for a declaration ending with !important.
+	Important bool
+	// Leading holds the comments on the lines before the declaration.
+	Leading []*Comment
+	// Trailing holds the comments inside the declaration and those
+	// following it on the same line.
+	Trailing []*Comment
+	Raw      *Raw
 }
 
 type CurlyBlock struct {
@@ -56,7 +93,12 @@ type SquareBlock struct {
 type FunctionBlock struct {
 }
 
+// Block is a {}-block. The block of a style rule, or of an at-rule like
+// @font-face, holds declarations in DeclList and any nested at-rules in
+// Rules; the block of a group rule like @media holds a list of rules.
 type Block struct {
-	// TODO(ttacon): this needs to be updated
+	Span
 	DeclList *DeclarationList
+	Rules    []Rule
+	Raw      *Raw
 }
@@ -0,0 +1,153 @@
+package ast
+
+// The at-rules below have their own node types; other at-rules are
+// parsed as AtRule. Their descriptors are the declarations of their
+// block.
+
+// FontFaceRule is a @font-face rule.
+type FontFaceRule struct {
+	Span
+	Block *Block
+	Raw   *Raw
+}
+
+// FontSource is an entry of the src descriptor of @font-face.
+type FontSource struct {
+	// URL is the URL of the font, or empty for a local() source.
+	URL string
+	// Local is the name of the font of a local() source.
+	Local string
+	// Format is the argument of format(), if any.
+	Format string
+	// Tech holds the arguments of tech(), if any.
+	Tech []string
+}
+
+// KeyframesRule is a @keyframes rule, or one of its vendor-prefixed
+// variants. The rules of its block are Keyframes and Comments.
+type KeyframesRule struct {
+	Span
+	// AtKeyword is @keyframes or a prefixed variant like
+	// @-webkit-keyframes.
+	AtKeyword string
+	// Name is the name of the animation, an ident or a string as written.
+	Name  string
+	Block *Block
+	Raw   *Raw
+}
+
+// Keyframe is a rule of a @keyframes block.
+type Keyframe struct {
+	Span
+	// Selectors hold the keyframe selectors, like "from", "50%" or
+	// "entry 10%".
+	Selectors []string
+	Block     *Block
+	Raw       *Raw
+}
+
+// PageRule is a @page rule. The rules of its block are MarginRules and
+// Comments.
+type PageRule struct {
+	Span
+	// Selectors hold the page selectors, like "toc:first"; there are none
+	// for a rule applying to every page.
+	Selectors []string
+	Block     *Block
+	Raw       *Raw
+}
+
+// MarginRule is a margin box rule like @top-left in a @page block.
+type MarginRule struct {
+	Span
+	AtKeyword string
+	Block     *Block
+	Raw       *Raw
+}
+
+// CounterStyleRule is a @counter-style rule.
+type CounterStyleRule struct {
+	Span
+	Name  string
+	Block *Block
+	Raw   *Raw
+}
+
+// PropertyRule is a @property rule.
+type PropertyRule struct {
+	Span
+	// Name is the custom property name, like "--x".
+	Name  string
+	Block *Block
+	Raw   *Raw
+}
+
+// FontFeatureValuesRule is a @font-feature-values rule. The rules of its
+// block are FeatureValuesBlocks and Comments.
+type FontFeatureValuesRule struct {
+	Span
+	// FontFamilies hold the family names, strings or idents as written.
+	FontFamilies []string
+	Block        *Block
+	Raw          *Raw
+}
+
+// FeatureValuesBlock is a block of feature values like @swash in a
+// @font-feature-values block. Its declarations map names to feature
+// indices.
+type FeatureValuesBlock struct {
+	Span
+	AtKeyword string
+	Block     *Block
+	Raw       *Raw
+}
+
+// LayerRule is a @layer rule: a statement declaring layers, like
+// "@layer a, b.c;", or a block holding the rules of a layer.
+type LayerRule struct {
+	Span
+	// Names holds the layer names, like "a" or "b.c". A statement has one
+	// or more; a block has one, or none for an anonymous layer.
+	Names []string
+	// Block is the block of the layer, or nil for a statement.
+	Block *Block
+	Raw   *Raw
+}
+
+// ImportRule is an @import rule:
+//
+//	@import URL [ layer | layer(Layer) ]? [ supports(Supports) ]? Media;
+type ImportRule struct {
+	Span
+	// URL is the URL of the imported stylesheet, unquoted.
+	URL string
+	// Layered is set if the import has a layer, which is anonymous if
+	// Layer is empty.
+	Layered bool
+	Layer   string
+	// Supports is the condition of the supports() function, if any.
+	Supports string
+	// Media is the media query list, if any.
+	Media string
+	Raw   *Raw
+}
+
+// ScopeRule is a @scope rule:
+//
+//	@scope [ ( Roots ) ]? [ to ( Limits ) ]? { ... }
+//
+// Its block holds style rules, which are scoped to the subtrees rooted at
+// the elements matching Roots and ending before those matching Limits, and
+// declarations, which apply to the scoping roots themselves.
+type ScopeRule struct {
+	Span
+	// Roots holds the selectors of the scoping roots. There are none for
+	// an implicit scope, rooted at the parent element of the owner node
+	// of the stylesheet, or at the elements matched by the style rule the
+	// @scope rule is nested in.
+	Roots []string
+	// Limits holds the selectors of the scoping limits, if any.
+	Limits []string
+	Block  *Block
+	Raw    *Raw
+}
@@ -0,0 +1,67 @@
+package ast
+
+// BlockOf returns the block of the rule n, or nil if it has none.
+func BlockOf(n Node) *Block {
+	switch n := n.(type) {
+	case *QualifiedRule:
+		return n.Block
+	case *AtRule:
+		return n.Block
+	case *FontFaceRule:
+		return n.Block
+	case *KeyframesRule:
+		return n.Block
+	case *Keyframe:
+		return n.Block
+	case *PageRule:
+		return n.Block
+	case *MarginRule:
+		return n.Block
+	case *CounterStyleRule:
+		return n.Block
+	case *PropertyRule:
+		return n.Block
+	case *FontFeatureValuesRule:
+		return n.Block
+	case *FeatureValuesBlock:
+		return n.Block
+	case *LayerRule:
+		return n.Block
+	case *ScopeRule:
+		return n.Block
+	}
+	return nil
+}
+
+// Children returns the declarations and rules of b in source order.
+// Nodes without a position follow their previous sibling in their own
+// list.
+func (b *Block) Children() []Node {
+	var decls []*Declaration
+	if b.DeclList != nil {
+		decls = b.DeclList.Declarations
+	}
+	var (
+		children []Node
+		i, j     int
+	)
+	for i < len(decls) || j < len(b.Rules) {
+		if j == len(b.Rules) || (i < len(decls) && precedes(decls[i], b.Rules[j])) {
+			children = append(children, decls[i])
+			i++
+		} else {
+			children = append(children, b.Rules[j])
+			j++
+		}
+	}
+	return children
+}
+
+// precedes reports whether declaration d comes before rule r.
+func precedes(d *Declaration, r Rule) bool {
+	dpos, rpos := d.Pos(), r.Pos()
+	if !dpos.IsValid() || !rpos.IsValid() {
+		return !dpos.IsValid()
+	}
+	return dpos.Offset < rpos.Offset
+}
@@ -0,0 +1,62 @@
+package ast
+
+import "reflect"
+
+// Clone returns a deep copy of the node n: its children, positions and
+// Raw are copied too, so that the copy prints like n and can be modified
+// without affecting it.
+func Clone(n Node) Node {
+	if n == nil {
+		return nil
+	}
+	return clone(reflect.ValueOf(n)).Interface().(Node)
+}
+
+func clone(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(clone(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(clone(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(clone(v.Index(i)))
+		}
+		return c
+	case reflect.Struct:
+		// Copy the unexported fields, like the key of a Raw, as they are.
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := c.Field(i); f.CanSet() {
+				f.Set(clone(v.Field(i)))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+// CopyDeclaration returns a copy of d, as Clone does, without the comments
+// of d, to be modified into a variant of d added next to it: a prefixed
+// name or a fallback value. The comments are left out so that they are
+// not repeated.
+func CopyDeclaration(d *Declaration) *Declaration {
+	c := Clone(d).(*Declaration)
+	c.Leading, c.Trailing = nil, nil
+	return c
+}
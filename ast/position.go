@@ -0,0 +1,42 @@
+package ast
+
+import "fmt"
+
+// Position is a location in the source. Line and Column start at 1, and
+// Column counts UTF-16 code units, as source maps do; Offset is in bytes
+// and starts at 0.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as "line:column", or "-" if it is not
+// known.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the source range of a node parsed from text. The zero Span
+// belongs to nodes built by hand.
+type Span struct {
+	// From is the position of the node's first character and To that of
+	// the character immediately following the node.
+	From Position
+	To   Position
+}
+
+// Pos returns the position of the first character of the node.
+func (s Span) Pos() Position { return s.From }
+
+// End returns the position of the character immediately following the
+// node.
+func (s Span) End() Position { return s.To }
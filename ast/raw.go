@@ -0,0 +1,148 @@
+package ast
+
+import "strings"
+
+// Raw holds the original source of a node parsed in lossless mode.
+//
+// The source of a node is split in three parts: Before, the text between
+// the previous node and this one; Text, the node's own tokens up to its
+// first child; and After, the text following its last child. Printing
+// Before, Text, the children and After of every node, in order, reproduces
+// the input exactly.
+type Raw struct {
+	Before string
+	Text   string
+	After  string
+
+	// key is a digest of the node's own fields at parse time.
+	key string
+}
+
+// NewRaw returns the Raw for node n. It must be called once n's fields
+// are set, so that later edits can be detected by Modified.
+func NewRaw(n interface{}, before, text, after string) *Raw {
+	return &Raw{
+		Before: before,
+		Text:   text,
+		After:  after,
+		key:    rawKey(n),
+	}
+}
+
+// Modified reports whether n's own fields have changed since r was
+// created. Changes to children are not taken into account: each child
+// carries its own Raw.
+func (r *Raw) Modified(n interface{}) bool {
+	return r.key != rawKey(n)
+}
+
+// RawOf returns the Raw of n, or nil if n has none.
+func RawOf(n interface{}) *Raw {
+	switch n := n.(type) {
+	case *Stylesheet:
+		return n.Raw
+	case *Comment:
+		return n.Raw
+	case *AtRule:
+		return n.Raw
+	case *QualifiedRule:
+		return n.Raw
+	case *Block:
+		return n.Raw
+	case *Declaration:
+		return n.Raw
+	case *FontFaceRule:
+		return n.Raw
+	case *KeyframesRule:
+		return n.Raw
+	case *Keyframe:
+		return n.Raw
+	case *PageRule:
+		return n.Raw
+	case *MarginRule:
+		return n.Raw
+	case *CounterStyleRule:
+		return n.Raw
+	case *PropertyRule:
+		return n.Raw
+	case *FontFeatureValuesRule:
+		return n.Raw
+	case *FeatureValuesBlock:
+		return n.Raw
+	case *LayerRule:
+		return n.Raw
+	case *ImportRule:
+		return n.Raw
+	case *ScopeRule:
+		return n.Raw
+	}
+	return nil
+}
+
+// rawKey returns a digest of the fields of n that are printed as part of
+// its Raw Text and After.
+func rawKey(n interface{}) string {
+	var parts []string
+	switch n := n.(type) {
+	case *Comment:
+		parts = append(parts, n.Text)
+	case *AtRule:
+		parts = append(parts, n.AtKeyword, n.Any)
+		if n.JustSemi {
+			parts = append(parts, ";")
+		}
+	case *QualifiedRule:
+		for _, c := range n.Components {
+			parts = append(parts, c.Name)
+		}
+	case *Block:
+		if n.DeclList != nil {
+			parts = appendComments(parts, n.DeclList.Trailing)
+		}
+	case *Declaration:
+		parts = append(parts, n.Ident)
+		parts = append(parts, n.Components...)
+		if n.Important {
+			parts = append(parts, "!important")
+		}
+		parts = appendComments(parts, n.Leading)
+		parts = appendComments(parts, n.Trailing)
+	case *KeyframesRule:
+		parts = append(parts, n.AtKeyword, n.Name)
+	case *Keyframe:
+		parts = append(parts, n.Selectors...)
+	case *PageRule:
+		parts = append(parts, n.Selectors...)
+	case *MarginRule:
+		parts = append(parts, n.AtKeyword)
+	case *CounterStyleRule:
+		parts = append(parts, n.Name)
+	case *PropertyRule:
+		parts = append(parts, n.Name)
+	case *FontFeatureValuesRule:
+		parts = append(parts, n.FontFamilies...)
+	case *FeatureValuesBlock:
+		parts = append(parts, n.AtKeyword)
+	case *LayerRule:
+		parts = append(parts, n.Names...)
+	case *ImportRule:
+		parts = append(parts, n.URL)
+		if n.Layered {
+			parts = append(parts, "layer", n.Layer)
+		}
+		parts = append(parts, n.Supports, n.Media)
+	case *ScopeRule:
+		parts = append(parts, n.Roots...)
+		parts = append(parts, "to")
+		parts = append(parts, n.Limits...)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func appendComments(parts []string, comments []*Comment) []string {
+	parts = append(parts, "/*")
+	for _, c := range comments {
+		parts = append(parts, c.Text)
+	}
+	return parts
+}
@@ -0,0 +1,124 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth-first order: it starts
+// by calling v.Visit(node); node must not be nil. If the visitor w
+// returned by v.Visit(node) is not nil, Walk is invoked recursively with
+// visitor w for each of the non-nil children of node, followed by a call
+// of w.Visit(nil).
+//
+// The children of a node are visited in the order of its fields: the
+// declarations of a Block, for instance, come before its nested rules.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Stylesheet:
+		for _, r := range n.Children {
+			Walk(v, r)
+		}
+
+	case *Comment, *ComponentValue, *ImportRule:
+		// nothing to do
+
+	case *AtRule:
+		walkBlock(v, n.Block)
+
+	case *FontFaceRule:
+		walkBlock(v, n.Block)
+
+	case *KeyframesRule:
+		walkBlock(v, n.Block)
+
+	case *Keyframe:
+		walkBlock(v, n.Block)
+
+	case *PageRule:
+		walkBlock(v, n.Block)
+
+	case *MarginRule:
+		walkBlock(v, n.Block)
+
+	case *CounterStyleRule:
+		walkBlock(v, n.Block)
+
+	case *PropertyRule:
+		walkBlock(v, n.Block)
+
+	case *FontFeatureValuesRule:
+		walkBlock(v, n.Block)
+
+	case *FeatureValuesBlock:
+		walkBlock(v, n.Block)
+
+	case *LayerRule:
+		walkBlock(v, n.Block)
+
+	case *ScopeRule:
+		walkBlock(v, n.Block)
+	case *QualifiedRule:
+		for _, c := range n.Components {
+			Walk(v, c)
+		}
+		walkBlock(v, n.Block)
+
+	case *Block:
+		if n.DeclList != nil {
+			for _, d := range n.DeclList.Declarations {
+				Walk(v, d)
+			}
+			walkComments(v, n.DeclList.Trailing)
+		}
+		for _, r := range n.Rules {
+			Walk(v, r)
+		}
+
+	case *Declaration:
+		walkComments(v, n.Leading)
+		walkComments(v, n.Trailing)
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkBlock(v Visitor, b *Block) {
+	if b != nil {
+		Walk(v, b)
+	}
+}
+
+func walkComments(v Visitor, comments []*Comment) {
+	for _, c := range comments {
+		Walk(v, c)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first order: it
+// starts by calling f(node); node must not be nil. If f returns true,
+// Inspect invokes f recursively for each of the non-nil children of node,
+// followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
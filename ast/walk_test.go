@@ -0,0 +1,136 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testSheet() *Stylesheet {
+	return &Stylesheet{
+		Children: []Rule{
+			&Comment{Text: "/* a */"},
+			&QualifiedRule{
+				Components: []*ComponentValue{{Name: ".b"}},
+				Block: &Block{
+					DeclList: &DeclarationList{
+						Declarations: []*Declaration{
+							{Ident: "color", Components: []string{"red"}},
+							{Ident: "top", Components: []string{"0"}, Trailing: []*Comment{{Text: "/* c */"}}},
+						},
+					},
+					Rules: []Rule{
+						&AtRule{AtKeyword: "@media", Any: "print", Block: &Block{}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// describe returns a short description of n.
+func describe(n Node) string {
+	switch n := n.(type) {
+	case nil:
+		return "nil"
+	case *Comment:
+		return n.Text
+	case *ComponentValue:
+		return n.Name
+	case *AtRule:
+		return n.AtKeyword
+	case *Declaration:
+		return n.Ident
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")
+}
+
+func TestInspect(t *testing.T) {
+	var got []string
+	Inspect(testSheet(), func(n Node) bool {
+		if n != nil {
+			got = append(got, describe(n))
+		}
+		return true
+	})
+	want := []string{
+		"Stylesheet", "/* a */", "QualifiedRule", ".b", "Block",
+		"color", "top", "/* c */", "@media", "Block",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	ss := testSheet()
+	var visited []string
+	Apply(ss, func(c *Cursor) bool {
+		switch n := c.Node().(type) {
+		case *Comment:
+			if c.Name() == "Children" {
+				c.Delete()
+			}
+		case *Declaration:
+			visited = append(visited, fmt.Sprintf("%s/%s[%d]", describe(c.Parent()), c.Name(), c.Index()))
+			if n.Ident == "color" {
+				c.InsertBefore(&Declaration{Ident: "display"})
+				c.InsertAfter(&Declaration{Ident: "margin"})
+			}
+		case *AtRule:
+			c.Replace(&AtRule{AtKeyword: "@supports"})
+			return false
+		}
+		return true
+	}, nil)
+
+	want := []string{"Block/Declarations[0]", "Block/Declarations[3]"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("expected visits %v, got %v", want, visited)
+	}
+
+	var got []string
+	Inspect(ss, func(n Node) bool {
+		if n != nil {
+			got = append(got, describe(n))
+		}
+		return true
+	})
+	want = []string{
+		"Stylesheet", "QualifiedRule", ".b", "Block",
+		"display", "color", "margin", "top", "/* c */", "@supports",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestApplyAbort(t *testing.T) {
+	var n int
+	root := Apply(testSheet(), nil, func(c *Cursor) bool {
+		n++
+		return describe(c.Node()) != "color"
+	})
+	if n != 3 {
+		t.Errorf("expected traversal to stop after 3 nodes, got %d", n)
+	}
+	if _, ok := root.(*Stylesheet); !ok {
+		t.Errorf("expected the stylesheet back, got %T", root)
+	}
+}
+
+func TestClone(t *testing.T) {
+	ss := testSheet()
+	c := Clone(ss).(*Stylesheet)
+	if !reflect.DeepEqual(c, ss) {
+		t.Fatal("expected the clone to equal the original")
+	}
+	rule := c.Children[1].(*QualifiedRule)
+	rule.Components[0].Name = ".x"
+	rule.Block.DeclList.Declarations[0].Components[0] = "blue"
+	rule.Block.Rules = nil
+	if !reflect.DeepEqual(ss, testSheet()) {
+		t.Error("expected the original to be unchanged by edits of the clone")
+	}
+}
@@ -0,0 +1,390 @@
+// Package browsers holds the vendor prefixes browsers need for CSS
+// features and the versions supporting newer features, and parses the
+// lists of browser versions to target.
+//
+// The support data is embedded, so that it is available offline; an
+// updated copy in the same JSON format can be loaded with Load or
+// LoadFile.
+package browsers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed data.json
+var data []byte
+
+// Kind is the kind of a CSS feature.
+type Kind int
+
+const (
+	Property Kind = iota // a property, like user-select
+	Value                // a keyword or a function of a value, like sticky or image-set(
+	Selector             // a pseudo-class or pseudo-element, like ::placeholder
+	AtRule               // an at-rule, like @keyframes
+)
+
+// Data holds the vendor prefixes browsers need for CSS features, and the
+// versions supporting newer features.
+type Data struct {
+	// Browsers maps the browsers to their vendor prefix, like
+	// "firefox": "-moz-".
+	Browsers map[string]string `json:"browsers"`
+
+	Properties map[string]*Feature `json:"properties"`
+	Values     map[string]*Feature `json:"values"`
+	Selectors  map[string]*Feature `json:"selectors"`
+	AtRules    map[string]*Feature `json:"atrules"`
+
+	// Features maps newer features, like "nesting", to the browsers
+	// supporting them and the first version doing so.
+	Features map[string]map[string]string `json:"features"`
+
+	once       sync.Once
+	unprefixed [4]map[string]string
+}
+
+// Feature is a CSS feature some browsers only support with a prefix.
+type Feature struct {
+	// Properties lists the properties a value feature is prefixed for, or
+	// is empty if it is prefixed in every property.
+	Properties []string `json:"properties,omitempty"`
+	// Browsers maps the browsers needing a prefix to their support.
+	Browsers map[string]SupportList `json:"browsers"`
+}
+
+// SupportList lists the prefixed names of a feature a browser supports, like
+// -webkit-box and -webkit-flex for display: flex in older Safari versions.
+//
+// In JSON, a single support is written without the enclosing list.
+type SupportList []*Support
+
+func (ss *SupportList) UnmarshalJSON(b []byte) error {
+	var list []*Support
+	if err := json.Unmarshal(b, &list); err == nil {
+		*ss = list
+		return nil
+	}
+	s := &Support{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return err
+	}
+	*ss = SupportList{s}
+	return nil
+}
+
+// Support describes the prefixed support of a feature by a browser.
+//
+// In JSON, a support with the default name is written as its Until
+// version, or "*" if the browser always needs the prefix.
+type Support struct {
+	// Until is the first version supporting the feature without prefix,
+	// or "" if the browser always needs the prefix.
+	Until string `json:"until,omitempty"`
+	// Name is the prefixed name of the feature, or "" for the name with
+	// the vendor prefix of the browser added.
+	Name string `json:"name,omitempty"`
+}
+
+func (s *Support) UnmarshalJSON(b []byte) error {
+	var until string
+	if err := json.Unmarshal(b, &until); err == nil {
+		if until == "*" {
+			until = ""
+		}
+		*s = Support{Until: until}
+		return nil
+	}
+	type support Support
+	return json.Unmarshal(b, (*support)(s))
+}
+
+var (
+	defaultOnce sync.Once
+	defaultData *Data
+)
+
+// Default returns the embedded support data.
+func Default() *Data {
+	defaultOnce.Do(func() {
+		d, err := parse(data)
+		if err != nil {
+			panic("browsers: invalid embedded data: " + err.Error())
+		}
+		defaultData = d
+	})
+	return defaultData
+}
+
+// Load reads support data in the JSON format of the embedded data from r.
+func Load(r io.Reader) (*Data, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parse(b)
+}
+
+// LoadFile reads support data from the named file, as Load does.
+func LoadFile(name string) (*Data, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+func parse(b []byte) (*Data, error) {
+	d := &Data{}
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, fmt.Errorf("browsers: %v", err)
+	}
+	for name, versions := range d.Features {
+		for browser := range versions {
+			if _, ok := d.Browsers[browser]; !ok {
+				return nil, fmt.Errorf("browsers: unknown browser %q for %s", browser, name)
+			}
+		}
+	}
+	for _, features := range []map[string]*Feature{d.Properties, d.Values, d.Selectors, d.AtRules} {
+		for name, f := range features {
+			for browser := range f.Browsers {
+				if _, ok := d.Browsers[browser]; !ok {
+					return nil, fmt.Errorf("browsers: unknown browser %q for %s", browser, name)
+				}
+			}
+		}
+	}
+	return d, nil
+}
+
+func (d *Data) features(k Kind) map[string]*Feature {
+	switch k {
+	case Property:
+		return d.Properties
+	case Value:
+		return d.Values
+	case Selector:
+		return d.Selectors
+	case AtRule:
+		return d.AtRules
+	}
+	return nil
+}
+
+// Feature returns the feature of kind k with the given unprefixed name, or
+// nil if no browser needs a prefix for it.
+func (d *Data) Feature(k Kind, name string) *Feature {
+	return d.features(k)[strings.ToLower(name)]
+}
+
+// Prefixed returns the prefixed names of the feature of kind k the
+// targets need, sorted. It returns nil if the targets support the
+// unprefixed name, or if the feature is unknown.
+func (d *Data) Prefixed(k Kind, name string, targets []Target) []string {
+	name = strings.ToLower(name)
+	f := d.features(k)[name]
+	if f == nil {
+		return nil
+	}
+	var names []string
+	for _, t := range targets {
+		for _, s := range f.Browsers[t.Browser] {
+			if s.Until != "" && t.Version != "" && Compare(t.Version, s.Until) >= 0 {
+				continue
+			}
+			if p := d.name(k, name, t.Browser, s); !contains(names, p) {
+				names = append(names, p)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Unprefixed returns the unprefixed name of the feature of kind k the
+// prefixed name is a variant of, or "" if name is not a known prefixed
+// name.
+func (d *Data) Unprefixed(k Kind, name string) string {
+	d.once.Do(func() {
+		for k := Property; k <= AtRule; k++ {
+			m := map[string]string{}
+			for name, f := range d.features(k) {
+				for browser, ss := range f.Browsers {
+					for _, s := range ss {
+						m[d.name(k, name, browser, s)] = name
+					}
+				}
+			}
+			d.unprefixed[k] = m
+		}
+	})
+	if k < Property || k > AtRule {
+		return ""
+	}
+	return d.unprefixed[k][strings.ToLower(name)]
+}
+
+// Supports reports whether all targets support the feature, one of the
+// keys of d.Features. Browsers missing from the data, and targets without
+// version, don't support it.
+func (d *Data) Supports(feature string, targets []Target) bool {
+	versions := d.Features[feature]
+	for _, t := range targets {
+		v, ok := versions[t.Browser]
+		if !ok || t.Version == "" || Compare(t.Version, v) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// name returns the name of the feature of kind k prefixed for browser.
+func (d *Data) name(k Kind, name, browser string, s *Support) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	prefix := d.Browsers[browser]
+	switch k {
+	case Selector:
+		i := len(name) - len(strings.TrimLeft(name, ":"))
+		return name[:i] + prefix + name[i:]
+	case AtRule:
+		return "@" + prefix + strings.TrimPrefix(name, "@")
+	}
+	return prefix + name
+}
+
+// Prefix returns the vendor prefix of name, like "-webkit-" for
+// "-webkit-user-select", "@-moz-keyframes" or "::-webkit-input-placeholder",
+// or "" if it has none.
+func Prefix(name string) string {
+	name = strings.TrimLeft(name, "@:")
+	if len(name) < 3 || name[0] != '-' || name[1] == '-' {
+		return ""
+	}
+	if i := strings.IndexByte(name[1:], '-'); i > 0 {
+		return strings.ToLower(name[:i+2])
+	}
+	return ""
+}
+
+// Target is a browser version to support.
+type Target struct {
+	// Browser is the name of the browser, like "chrome" or "ios_saf".
+	Browser string
+	// Version is the oldest version to support, or "" for all versions.
+	Version string
+}
+
+func (t Target) String() string {
+	if t.Version == "" {
+		return t.Browser
+	}
+	return t.Browser + " >= " + t.Version
+}
+
+// Defaults is a query for the browser versions targeted by default.
+const Defaults = "chrome >= 109, edge >= 109, firefox >= 115, safari >= 15.6, ios_saf >= 15.6, opera >= 95, samsung >= 21"
+
+// aliases maps alternative browser names to the names used in the data.
+var aliases = map[string]string{
+	"ios":             "ios_saf",
+	"android_chrome":  "and_chr",
+	"chromeandroid":   "and_chr",
+	"android_firefox": "and_ff",
+	"firefoxandroid":  "and_ff",
+	"explorer":        "ie",
+	"samsunginternet": "samsung",
+}
+
+// ParseTargets parses a comma-separated list of browser versions, like
+// "chrome >= 100, safari 14, ie 11, firefox", of the browsers of the
+// embedded data. See Data.ParseTargets.
+func ParseTargets(s string) ([]Target, error) {
+	return Default().ParseTargets(s)
+}
+
+// ParseTargets parses a comma-separated list of browser versions, like
+// "chrome >= 100, safari 14, ie 11, firefox". A version without operator
+// is the oldest version to support, like with >=, and a browser without
+// version stands for all its versions. Browsers missing from d.Browsers
+// are an error, so that a misspelled name doesn't go unnoticed.
+func (d *Data) ParseTargets(s string) ([]Target, error) {
+	var targets []Target
+	for _, q := range strings.Split(s, ",") {
+		fields := strings.Fields(strings.ToLower(q))
+		if len(fields) == 3 && fields[1] == ">=" {
+			fields = []string{fields[0], fields[2]}
+		} else if len(fields) == 2 && strings.HasPrefix(fields[1], ">=") {
+			fields[1] = fields[1][2:]
+		}
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("browsers: invalid target %q", strings.TrimSpace(q))
+		}
+		t := Target{Browser: fields[0]}
+		if a, ok := aliases[t.Browser]; ok {
+			t.Browser = a
+		}
+		if _, ok := d.Browsers[t.Browser]; !ok {
+			return nil, fmt.Errorf("browsers: unknown browser %q in target %q", fields[0], strings.TrimSpace(q))
+		}
+		if len(fields) == 2 {
+			t.Version = fields[1]
+			if !validVersion(t.Version) {
+				return nil, fmt.Errorf("browsers: invalid version %q in target %q", t.Version, strings.TrimSpace(q))
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+func validVersion(v string) bool {
+	for _, n := range strings.Split(v, ".") {
+		if _, err := strconv.Atoi(n); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare compares the dotted versions a and b, like "15.4" and "15.10",
+// and returns -1, 0 or +1. Missing components count as 0.
+func Compare(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return +1
+		}
+	}
+	return 0
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
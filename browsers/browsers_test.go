@@ -0,0 +1,171 @@
+package browsers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTargets(t *testing.T) {
+	got, err := ParseTargets("Chrome >= 100, safari 14.1,ie 11, firefox, iOS >=15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Target{
+		{Browser: "chrome", Version: "100"},
+		{Browser: "safari", Version: "14.1"},
+		{Browser: "ie", Version: "11"},
+		{Browser: "firefox"},
+		{Browser: "ios_saf", Version: "15"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, s := range []string{"", "chrome 1 2", "chrome x", "chorme 60", "chrome 100, netscape"} {
+		if _, err := ParseTargets(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+	if _, err := ParseTargets(Defaults); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"15.4", "15.10", -1},
+		{"16", "15.4", +1},
+		{"15", "15.0", 0},
+		{"9", "10", -1},
+	}
+	for _, test := range tests {
+		if got := Compare(test.a, test.b); got != test.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestPrefixed(t *testing.T) {
+	d := Default()
+	targets := func(s string) []Target {
+		ts, err := ParseTargets(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	tests := []struct {
+		kind    Kind
+		name    string
+		targets string
+		want    []string
+	}{
+		{Property, "user-select", "chrome 100, safari 16", []string{"-webkit-user-select"}},
+		{Property, "User-Select", "chrome 50, firefox 60, ie 11", []string{"-moz-user-select", "-ms-user-select", "-webkit-user-select"}},
+		{Property, "user-select", "chrome 120, firefox 120", nil},
+		{Property, "color", "ie 6", nil},
+		{Property, "appearance", "safari", []string{"-webkit-appearance"}},
+		{Value, "sticky", "safari 12", []string{"-webkit-sticky"}},
+		{Value, "flex", "safari 6, ie 10", []string{"-ms-flexbox", "-webkit-box", "-webkit-flex"}},
+		{Value, "flex", "safari 8, ie 11", []string{"-webkit-flex"}},
+		{Value, "linear-gradient(", "firefox 15", []string{"-moz-linear-gradient("}},
+		{Value, "stretch", "chrome 120, firefox 120", []string{"-moz-available", "-webkit-fill-available"}},
+		{Selector, "::placeholder", "firefox 50, ie 11, edge 18", []string{":-ms-input-placeholder", "::-moz-placeholder", "::-ms-input-placeholder"}},
+		{Selector, "::selection", "firefox 60", []string{"::-moz-selection"}},
+		{AtRule, "@keyframes", "safari 8, firefox 100", []string{"@-webkit-keyframes"}},
+	}
+	for _, test := range tests {
+		got := d.Prefixed(test.kind, test.name, targets(test.targets))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s for %q: got %q, want %q", test.name, test.targets, got, test.want)
+		}
+	}
+}
+
+func TestUnprefixed(t *testing.T) {
+	d := Default()
+	tests := []struct {
+		kind       Kind
+		name, want string
+	}{
+		{Property, "-webkit-user-select", "user-select"},
+		{Property, "-MS-User-Select", "user-select"},
+		{Property, "user-select", ""},
+		{Property, "-webkit-box-flex", ""},
+		{Value, "-webkit-image-set(", "image-set("},
+		{Value, "-moz-available", "stretch"},
+		{Value, "-webkit-box", "flex"},
+		{Value, "-webkit-flex", "flex"},
+		{Property, "-ms-flex-order", "order"},
+		{Selector, "::-webkit-input-placeholder", "::placeholder"},
+		{Selector, ":-ms-input-placeholder", "::placeholder"},
+		{AtRule, "@-moz-keyframes", "@keyframes"},
+	}
+	for _, test := range tests {
+		if got := d.Unprefixed(test.kind, test.name); got != test.want {
+			t.Errorf("Unprefixed(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSupports(t *testing.T) {
+	d := Default()
+	tests := []struct {
+		feature, targets string
+		want             bool
+	}{
+		{"nesting", "chrome 120, safari 17.2, firefox >= 120", true},
+		{"nesting", "chrome 120, safari 16", false},
+		{"nesting", "chrome", false},
+		{"is-selector", "chrome 100, ie 11", false},
+		{"unknown", "chrome 200", false},
+	}
+	for _, test := range tests {
+		targets, err := ParseTargets(test.targets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.Supports(test.feature, targets); got != test.want {
+			t.Errorf("Supports(%q, %q) = %v, want %v", test.feature, test.targets, got, test.want)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	tests := map[string]string{
+		"-webkit-user-select":         "-webkit-",
+		"@-moz-keyframes":             "-moz-",
+		"::-webkit-input-placeholder": "-webkit-",
+		":-ms-fullscreen":             "-ms-",
+		"--custom-prop":               "",
+		"user-select":                 "",
+		"-x":                          "",
+	}
+	for name, want := range tests {
+		if got := Prefix(name); got != want {
+			t.Errorf("Prefix(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	d, err := Load(strings.NewReader(`{
+		"browsers": {"chrome": "-webkit-"},
+		"properties": {"zoom": {"browsers": {"chrome": "*"}}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := d.Prefixed(Property, "zoom", []Target{{Browser: "chrome", Version: "200"}})
+	if want := []string{"-webkit-zoom"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := Load(strings.NewReader(`{"properties": {"zoom": {"browsers": {"netscape": "4"}}}}`)); err == nil {
+		t.Error("expected an error for an unknown browser")
+	}
+}
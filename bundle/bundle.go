@@ -0,0 +1,187 @@
+// Package bundle inlines the stylesheets a stylesheet imports with
+// @import into a single stylesheet.
+package bundle
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/scanner"
+	"github.com/ttacon/css/urls"
+)
+
+// Bundler inlines the @import rules of stylesheets read from a file
+// system.
+type Bundler struct {
+	FS fs.FS
+	// Resolve returns the name in FS of the stylesheet imported with url
+	// by the stylesheet named from. It returns "" for imports that are to
+	// be kept as @import rules. If Resolve is nil, relative URLs are
+	// resolved against from, paths starting with "/" against the root of
+	// FS, and any other URL is kept.
+	Resolve func(from, url string) (string, error)
+}
+
+// Bundle returns the stylesheet named name in fsys, with its imports
+// inlined.
+func Bundle(fsys fs.FS, name string) (*ast.Stylesheet, error) {
+	return (&Bundler{FS: fsys}).Bundle(name)
+}
+
+// Bundle returns the stylesheet named name with its imports inlined.
+//
+// Every @import at the start of a stylesheet is replaced with the rules
+// of the imported stylesheet, wrapped in @media, @supports and @layer
+// rules applying the conditions and layer of the import. Relative url()
+// paths in imported stylesheets are rewritten to stay correct from the
+// directory of name. The imports that are kept are moved to the start of
+// the result, as @import rules must precede all other rules; keeping an
+// import within a conditional import, or after inlined rules or @layer
+// statements it would move ahead of, is an error. Nodes keep the
+// positions they have in the stylesheet they were read from.
+//
+// Syntax errors in the stylesheets do not stop the bundling: the
+// stylesheet is returned with everything that could be parsed, and the
+// error is a parser.ErrorList whose errors have the name of their
+// stylesheet as Source.
+func (b *Bundler) Bundle(name string) (*ast.Stylesheet, error) {
+	bb := &bundler{Bundler: b, root: name}
+	rules, err := bb.load(name, false)
+	if err != nil {
+		return nil, err
+	}
+	children := append(bb.imports, rules...)
+	if bb.charset != nil {
+		children = append([]ast.Rule{bb.charset}, children...)
+	}
+	return &ast.Stylesheet{Children: children}, bb.errors.Err()
+}
+
+type bundler struct {
+	*Bundler
+	root string
+	// stack holds the names of the stylesheets being loaded.
+	stack   []string
+	charset ast.Rule
+	// imports holds the @import rules that are kept.
+	imports []ast.Rule
+	// inlined is set once the result holds rules a kept @import would
+	// have to move ahead of: inlined rules or @layer statements.
+	inlined bool
+	errors  parser.ErrorList
+}
+
+// load returns the rules of the stylesheet name, with its imports
+// inlined. conditional is set if the stylesheet is imported with
+// conditions or in a layer.
+func (b *bundler) load(name string, conditional bool) ([]ast.Rule, error) {
+	for i, s := range b.stack {
+		if s == name {
+			cycle := append(b.stack[i:len(b.stack):len(b.stack)], name)
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	b.stack = append(b.stack, name)
+	defer func() { b.stack = b.stack[:len(b.stack)-1] }()
+
+	data, err := fs.ReadFile(b.FS, name)
+	if err != nil {
+		return nil, err
+	}
+	ss, err := parser.New(scanner.New(string(data))).Parse()
+	if errs, ok := err.(parser.ErrorList); ok {
+		for _, e := range errs {
+			e.Source = name
+		}
+		b.errors = append(b.errors, errs...)
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if name != b.root {
+		urls.Rewrite(ss, rebase(name, b.root))
+	}
+
+	var (
+		rules []ast.Rule
+		// @import rules are only valid before any other rule but @charset
+		// and @layer statements.
+		preamble = true
+	)
+	for _, r := range ss.Children {
+		if !preamble {
+			rules = append(rules, r)
+			continue
+		}
+		switch r := r.(type) {
+		case *ast.Comment:
+		case *ast.AtRule:
+			if strings.EqualFold(r.AtKeyword, "@charset") {
+				if name == b.root {
+					b.charset = r
+				}
+				continue
+			}
+			preamble = false
+		case *ast.LayerRule:
+			preamble = r.Block == nil
+			b.inlined = true
+		case *ast.ImportRule:
+			imported, err := b.inline(name, r, conditional)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range imported {
+				if _, ok := r.(*ast.Comment); !ok {
+					b.inlined = true
+				}
+			}
+			rules = append(rules, imported...)
+			continue
+		default:
+			preamble = false
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// inline returns the rules imported by imp, an @import rule of the
+// stylesheet from.
+func (b *bundler) inline(from string, imp *ast.ImportRule, conditional bool) ([]ast.Rule, error) {
+	name, err := b.resolve(from, imp.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", from, err)
+	}
+	if name == "" {
+		if conditional {
+			return nil, fmt.Errorf("%s: cannot keep @import of %q in a conditional import", from, imp.URL)
+		}
+		if b.inlined {
+			return nil, fmt.Errorf("%s: cannot keep @import of %q after inlined rules or @layer statements", from, imp.URL)
+		}
+		b.imports = append(b.imports, imp)
+		return nil, nil
+	}
+	rules, err := b.load(name, conditional || isConditional(imp))
+	if err != nil {
+		return nil, err
+	}
+	return wrap(imp, rules), nil
+}
+
+func (b *bundler) resolve(from, url string) (string, error) {
+	if b.Resolve != nil {
+		return b.Resolve(from, url)
+	}
+	switch {
+	case urls.IsRelative(url):
+		return path.Join(path.Dir(from), url), nil
+	case strings.HasPrefix(url, "/") && !strings.HasPrefix(url, "//"):
+		return path.Clean(url[1:]), nil
+	}
+	return "", nil
+}
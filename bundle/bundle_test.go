@@ -0,0 +1,161 @@
+package bundle
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/printer"
+)
+
+func TestBundle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/main.css": {Data: []byte(`@charset "utf-8";
+@import "https://fonts.example.com/a.css";
+@layer base, theme;
+@import "lib/reset.css" layer(base);
+@import url(theme.css) supports(display: grid) screen;
+.main { background: url(img/bg.png); }
+@import "ignored.css";
+`)},
+		"css/lib/reset.css": {Data: []byte(`@charset "utf-8";
+@import "/css/lib/fonts.css" print;
+* { margin: 0; }
+`)},
+		"css/lib/fonts.css": {Data: []byte(`@font-face { font-family: a; src: url("../fonts/a.woff2") format("woff2"), url('/abs.woff'); }
+.x { background: url(data:image/png;base64,AAAA); --bg: url(../img/x.png) no-repeat, url("y.png") }
+`)},
+		"css/theme.css": {Data: []byte(`.theme { color: red; }`)},
+	}
+	ss, err := Bundle(fsys, "css/main.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, ss); err != nil {
+		t.Fatal(err)
+	}
+	want := `@charset "utf-8";
+@import "https://fonts.example.com/a.css";
+@layer base, theme;
+@layer base {
+  @media print {
+    @font-face {
+      font-family: a;
+      src: url("fonts/a.woff2") format("woff2"), url('/abs.woff');
+    }
+    .x {
+      background: url(data:image/png;base64,AAAA);
+      --bg: url(img/x.png) no-repeat, url("lib/y.png");
+    }
+  }
+  * {
+    margin: 0;
+  }
+}
+@media screen {
+  @supports (display: grid) {
+    .theme {
+      color: red;
+    }
+  }
+}
+.main {
+  background: url(img/bg.png);
+}
+@import "ignored.css";
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBundleErrors(t *testing.T) {
+	var tests = []struct {
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			fstest.MapFS{
+				"a.css":     {Data: []byte(`@import "b.css";`)},
+				"b.css":     {Data: []byte(`@import "sub/c.css";`)},
+				"sub/c.css": {Data: []byte(`@import "../b.css";`)},
+			},
+			"import cycle: b.css -> sub/c.css -> b.css",
+		},
+		{
+			fstest.MapFS{
+				"a.css": {Data: []byte(`@import "b.css" print;`)},
+				"b.css": {Data: []byte(`@import "http://example.com/c.css";`)},
+			},
+			`b.css: cannot keep @import of "http://example.com/c.css" in a conditional import`,
+		},
+		{
+			fstest.MapFS{
+				"a.css": {Data: []byte(`@import "b.css"; @import "http://example.com/c.css";`)},
+				"b.css": {Data: []byte(`.b { color: red }`)},
+			},
+			`a.css: cannot keep @import of "http://example.com/c.css" after inlined rules or @layer statements`,
+		},
+		{
+			fstest.MapFS{
+				"a.css": {Data: []byte(`@layer a, b; @import "b.css";`)},
+				"b.css": {Data: []byte(`@import "http://example.com/c.css";`)},
+			},
+			`b.css: cannot keep @import of "http://example.com/c.css" after inlined rules or @layer statements`,
+		},
+		{
+			fstest.MapFS{
+				"a.css": {Data: []byte(`@import "b.css";`)},
+			},
+			"open b.css: file does not exist",
+		},
+	}
+	for _, test := range tests {
+		_, err := Bundle(test.fsys, "a.css")
+		if err == nil || err.Error() != test.want {
+			t.Errorf("got error %v, want %q", err, test.want)
+		}
+	}
+}
+
+func TestBundleSyntaxErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.css": {Data: []byte(`@import "b.css"; .a { color: red }`)},
+		"b.css": {Data: []byte(`.b { color }`)},
+	}
+	ss, err := Bundle(fsys, "a.css")
+	errs, ok := err.(parser.ErrorList)
+	if !ok || err.Error() != `b.css:1:12: unexpected "}", expected ":"` {
+		t.Fatalf("got error %v, want a parser.ErrorList", err)
+	}
+	if len(errs) != 1 || errs[0].Source != "b.css" {
+		t.Errorf("got errors %v", errs)
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, ss); err != nil {
+		t.Fatal(err)
+	}
+	want := ".b {\n}\n.a {\n  color: red;\n}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	var tests = []struct {
+		dir, target, want string
+	}{
+		{".", "a.png", "a.png"},
+		{"css", "css/img/a.png", "img/a.png"},
+		{"css", "fonts/a.woff", "../fonts/a.woff"},
+		{"css/a", "css/b/c.png", "../b/c.png"},
+		{"css", "../a.png", "../../a.png"},
+	}
+	for _, test := range tests {
+		if got := relPath(test.dir, test.target); got != test.want {
+			t.Errorf("relPath(%q, %q) = %q, want %q", test.dir, test.target, got, test.want)
+		}
+	}
+}
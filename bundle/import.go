@@ -0,0 +1,57 @@
+package bundle
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/scanner"
+)
+
+// isConditional reports whether imp has conditions or a layer.
+func isConditional(imp *ast.ImportRule) bool {
+	return imp.Layered || imp.Supports != "" || imp.Media != ""
+}
+
+// wrap returns rules wrapped in the at-rules applying the conditions and
+// layer of imp.
+func wrap(imp *ast.ImportRule, rules []ast.Rule) []ast.Rule {
+	if imp.Layered {
+		layer := &ast.LayerRule{Block: &ast.Block{Rules: rules}}
+		if imp.Layer != "" {
+			layer.Names = []string{imp.Layer}
+		}
+		rules = []ast.Rule{layer}
+	}
+	if imp.Supports != "" {
+		cond := imp.Supports
+		if toks := tokens(cond); len(toks) > 1 && toks[0].Type == scanner.TokenIdent && toks[1].Value == ":" {
+			// A declaration.
+			cond = "(" + cond + ")"
+		}
+		rules = []ast.Rule{group("@supports", cond, rules)}
+	}
+	if imp.Media != "" && !strings.EqualFold(imp.Media, "all") {
+		rules = []ast.Rule{group("@media", imp.Media, rules)}
+	}
+	return rules
+}
+
+func group(keyword, prelude string, rules []ast.Rule) *ast.AtRule {
+	return &ast.AtRule{
+		AtKeyword: keyword,
+		Any:       prelude,
+		Block:     &ast.Block{Rules: rules},
+	}
+}
+
+// tokens returns the tokens of s, without whitespace and comments.
+func tokens(s string) []*scanner.Token {
+	var toks []*scanner.Token
+	sc := scanner.New(s)
+	for t := sc.Next(); t.Type != scanner.TokenEOF && t.Type != scanner.TokenError; t = sc.Next() {
+		if t.Type != scanner.TokenS && t.Type != scanner.TokenComment {
+			toks = append(toks, t)
+		}
+	}
+	return toks
+}
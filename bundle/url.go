@@ -0,0 +1,45 @@
+package bundle
+
+import (
+	"path"
+	"strings"
+
+	"github.com/ttacon/css/urls"
+)
+
+// rebase returns a function rewriting the URLs of a stylesheet at path
+// from, so that they stay correct in a stylesheet at path to. The URLs of
+// @import rules are left alone: they are resolved against from.
+func rebase(from, to string) urls.RewriteFunc {
+	return func(a *urls.Asset) (string, error) {
+		if a.Property == "@import" || !urls.IsRelative(a.URL) {
+			return a.URL, nil
+		}
+		return relPath(path.Dir(to), path.Join(path.Dir(from), a.URL)), nil
+	}
+}
+
+// relPath returns the slash-separated path of target relative to the
+// directory dir.
+func relPath(dir, target string) string {
+	var (
+		from = split(path.Clean(dir))
+		to   = split(path.Clean(target))
+		i    int
+	)
+	for i < len(from) && i < len(to) && from[i] == to[i] && from[i] != ".." {
+		i++
+	}
+	var parts []string
+	for range from[i:] {
+		parts = append(parts, "..")
+	}
+	return path.Join(append(parts, to[i:]...)...)
+}
+
+func split(p string) []string {
+	if p == "." {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
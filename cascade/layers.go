@@ -0,0 +1,164 @@
+// Package cascade computes how the rules of a stylesheet rank in the
+// cascade.
+package cascade
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+)
+
+// Layers is the order of the cascade layers of a stylesheet, following
+// CSS Cascading and Inheritance Level 5
+// (https://www.w3.org/TR/css-cascade-5/#layering).
+type Layers struct {
+	// Names holds the full names of the layers, like "base" or "base.ui",
+	// in order of increasing precedence for normal declarations. Layers
+	// are ordered by their first declaration, and sublayers precede their
+	// parent layer. Anonymous layers are given unique names like
+	// "<anonymous-1>", which no named layer can have.
+	Names []string
+
+	rank map[string]int
+	// of maps every node to the full name of its layer.
+	of map[ast.Node]string
+}
+
+// LayerOrder returns the layers declared in ss, by @layer rules and by
+// @import rules with a layer. Layers declared in conditional rules like
+// @media are taken into account whatever the condition.
+func LayerOrder(ss *ast.Stylesheet) *Layers {
+	var (
+		l    = &Layers{rank: map[string]int{}, of: map[ast.Node]string{}}
+		root = &layer{}
+		anon int
+	)
+	v := &visitor{
+		layers: l,
+		layer:  root,
+		anonymous: func() string {
+			anon++
+			return fmt.Sprintf("<anonymous-%d>", anon)
+		},
+	}
+	ast.Walk(v, ss)
+	root.order(&l.Names)
+	for i, name := range l.Names {
+		l.rank[name] = i
+	}
+	return l
+}
+
+// Layer returns the full name of the layer the node n belongs to, or ""
+// if n is not layered. The layer of an @import rule with a layer is the
+// layer of the imported rules.
+func (l *Layers) Layer(n ast.Node) string {
+	return l.of[n]
+}
+
+// Compare compares the precedence of the layers a and b for normal
+// declarations: it returns a negative number if a loses to b, a positive
+// number if a wins, and 0 if they are the same layer. Unlayered styles,
+// named "", win over every layer. The order is reversed for !important
+// declarations.
+func (l *Layers) Compare(a, b string) int {
+	return l.Rank(a) - l.Rank(b)
+}
+
+// Rank returns the index of the layer name in Names, len(Names) for
+// unlayered styles, or -1 for an unknown layer.
+func (l *Layers) Rank(name string) int {
+	if name == "" {
+		return len(l.Names)
+	}
+	if i, ok := l.rank[name]; ok {
+		return i
+	}
+	return -1
+}
+
+// layer is a node of the tree of layers.
+type layer struct {
+	// name is the full name of the layer, or "" for the root.
+	name     string
+	children []*layer
+	byName   map[string]*layer
+}
+
+// child returns the sublayer of l named name, declaring it if needed.
+func (l *layer) child(name string) *layer {
+	if c, ok := l.byName[name]; ok {
+		return c
+	}
+	c := &layer{name: name}
+	if l.name != "" {
+		c.name = l.name + "." + name
+	}
+	if l.byName == nil {
+		l.byName = map[string]*layer{}
+	}
+	l.byName[name] = c
+	l.children = append(l.children, c)
+	return c
+}
+
+// declare returns the sublayer of l with the dotted name, declaring it and
+// its ancestors if needed.
+func (l *layer) declare(name string) *layer {
+	for _, part := range strings.Split(name, ".") {
+		l = l.child(strings.TrimSpace(part))
+	}
+	return l
+}
+
+// order appends the names of the sublayers of l to names, in order of
+// increasing precedence.
+func (l *layer) order(names *[]string) {
+	for _, c := range l.children {
+		c.order(names)
+		*names = append(*names, c.name)
+	}
+}
+
+type visitor struct {
+	layers    *Layers
+	layer     *layer
+	anonymous func() string
+}
+
+func (v *visitor) Visit(n ast.Node) ast.Visitor {
+	switch n := n.(type) {
+	case nil:
+		return nil
+
+	case *ast.LayerRule:
+		v.layers.of[n] = v.layer.name
+		if n.Block == nil {
+			for _, name := range n.Names {
+				v.layer.declare(name)
+			}
+			return nil
+		}
+		w := *v
+		if len(n.Names) == 0 {
+			w.layer = v.layer.child(v.anonymous())
+		} else {
+			w.layer = v.layer.declare(n.Names[0])
+		}
+		return &w
+
+	case *ast.ImportRule:
+		switch {
+		case !n.Layered:
+			v.layers.of[n] = v.layer.name
+		case n.Layer == "":
+			v.layers.of[n] = v.layer.child(v.anonymous()).name
+		default:
+			v.layers.of[n] = v.layer.declare(n.Layer).name
+		}
+		return nil
+	}
+	v.layers.of[n] = v.layer.name
+	return v
+}
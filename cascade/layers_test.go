@@ -0,0 +1,98 @@
+package cascade
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/scanner"
+)
+
+const src = `@import "vendor.css" layer(vendor);
+@import "anon.css" layer;
+@import "plain.css";
+@layer reset, base;
+@layer base.ui { .a { color: red } }
+@layer base {
+  .b { color: red }
+  @layer ui { .c { color: red } }
+  @layer forms { .d { color: red } }
+}
+@layer { .e { color: red } }
+@media print {
+  @layer reset { .f { color: red } }
+  @layer print { .g { color: red } }
+}
+.h { color: red }
+`
+
+func TestLayerOrder(t *testing.T) {
+	ss, err := parser.New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := LayerOrder(ss)
+	want := []string{"vendor", "<anonymous-1>", "reset", "base.ui", "base.forms", "base", "<anonymous-2>", "print"}
+	if !reflect.DeepEqual(l.Names, want) {
+		t.Errorf("got %q, want %q", l.Names, want)
+	}
+
+	layers := map[string]string{}
+	ast.Inspect(ss, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.QualifiedRule:
+			layers[n.Components[0].Name] = l.Layer(n)
+		case *ast.ImportRule:
+			layers[n.URL] = l.Layer(n)
+		}
+		return true
+	})
+	wantLayers := map[string]string{
+		"vendor.css": "vendor",
+		"anon.css":   "<anonymous-1>",
+		"plain.css":  "",
+		".a":         "base.ui",
+		".b":         "base",
+		".c":         "base.ui",
+		".d":         "base.forms",
+		".e":         "<anonymous-2>",
+		".f":         "reset",
+		".g":         "print",
+		".h":         "",
+	}
+	if !reflect.DeepEqual(layers, wantLayers) {
+		t.Errorf("got layers %q, want %q", layers, wantLayers)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ss, err := parser.New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := LayerOrder(ss)
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "print", 1},
+		{"vendor", "", -1},
+		{"base", "base.ui", 1},
+		{"base.ui", "base.forms", -1},
+		{"reset", "base", -1},
+		{"base", "base", 0},
+		{"unknown", "vendor", -1},
+	}
+	for _, test := range tests {
+		got := l.Compare(test.a, test.b)
+		if got > 0 {
+			got = 1
+		} else if got < 0 {
+			got = -1
+		}
+		if got != test.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
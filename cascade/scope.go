@@ -0,0 +1,238 @@
+package cascade
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/scanner"
+)
+
+// Scope is the scope of the rules in the block of a @scope rule,
+// following CSS Cascading and Inheritance Level 6
+// (https://www.w3.org/TR/css-cascade-6/#scoped-styles).
+type Scope struct {
+	Rule *ast.ScopeRule
+	// Parent is the scope the @scope rule is nested in, or nil.
+	Parent *Scope
+	// Roots holds the selectors of the scoping roots, which are matched
+	// within Parent. For a @scope rule nested in a style rule, & stands
+	// for the elements matched by the style rule, which are also the
+	// roots of an implicit scope. Roots is empty for other implicit
+	// scopes, rooted at the root element.
+	Roots []string
+	// Limits holds the selectors of the scoping limits, in which :scope
+	// stands for the scoping root.
+	Limits []string
+}
+
+// Scopes maps the rules of a stylesheet to their scope.
+type Scopes struct {
+	of map[ast.Node]*Scope
+}
+
+// Scoping returns the scopes of the rules of ss.
+func Scoping(ss *ast.Stylesheet) *Scopes {
+	s := &Scopes{of: map[ast.Node]*Scope{}}
+	ast.Walk(&scopeVisitor{scopes: s}, ss)
+	return s
+}
+
+// Scope returns the innermost scope of the node n, or nil if n is not
+// scoped. The scope of a @scope rule is the one it is nested in.
+func (s *Scopes) Scope(n ast.Node) *Scope {
+	return s.of[n]
+}
+
+// Selector returns the selector sel of a style rule directly in the block
+// of s, with its implicit scoping made explicit: & is replaced with
+// :where(:scope), and a selector with neither & nor :scope is made
+// relative to :where(:scope). The result has the specificity of sel.
+//
+//	img      :where(:scope) img
+//	> img    :where(:scope) > img
+//	:scope   :scope
+func (s *Scope) Selector(sel string) string {
+	sel = strings.TrimSpace(sel)
+	if r, ok := replaceNesting(sel, ":where(:scope)"); ok {
+		return r
+	}
+	return ":where(:scope) " + sel
+}
+
+// Element is an element of the document the cascade is computed for.
+// Elements are compared with ==, so implementations must be comparable,
+// like pointers.
+type Element interface {
+	// Parent returns the parent element, or nil for the root element.
+	Parent() Element
+	// Matches reports whether the element matches the selector, in which
+	// :scope stands for the element scope, or for the root element if
+	// scope is nil.
+	Matches(selector string, scope Element) bool
+}
+
+// Root returns the scoping root of s the element e is in scope of: the
+// nearest of e and its ancestors that is a scoping root of s and that is
+// not separated from e by a scoping limit. It returns nil if e is not in
+// scope.
+func (s *Scope) Root(e Element) Element {
+	if roots := s.roots(e); len(roots) > 0 {
+		return roots[0]
+	}
+	return nil
+}
+
+// roots returns the scoping roots of s the element e is in scope of,
+// nearest first.
+func (s *Scope) roots(e Element) []Element {
+	var roots []Element
+	for root := e; root != nil; root = root.Parent() {
+		if s.limited(e, root) {
+			continue
+		}
+		if s.Parent == nil {
+			if s.isRoot(root, nil) {
+				roots = append(roots, root)
+			}
+			continue
+		}
+		for _, parent := range s.Parent.roots(root) {
+			if s.isRoot(root, parent) {
+				roots = append(roots, root)
+				break
+			}
+		}
+	}
+	return roots
+}
+
+// Proximity returns the scoping proximity of the element e: the number of
+// generations between e and its scoping root, 0 if e is the root. It
+// reports false if e is not in scope. When the declarations of two rules
+// conflict, the rule with the smaller proximity wins; this is compared
+// after the cascade layers and before specificity, and unscoped rules
+// have an infinite proximity.
+func (s *Scope) Proximity(e Element) (int, bool) {
+	root := s.Root(e)
+	if root == nil {
+		return 0, false
+	}
+	n := 0
+	for ; e != root; e = e.Parent() {
+		n++
+	}
+	return n, true
+}
+
+// isRoot reports whether e is a scoping root of s, given the scoping root
+// parent of the parent scope.
+func (s *Scope) isRoot(e, parent Element) bool {
+	if len(s.Roots) == 0 {
+		if s.Parent != nil {
+			return e == parent
+		}
+		return e.Parent() == nil
+	}
+	for _, sel := range s.Roots {
+		sel, explicit := replaceNesting(sel, ":where(:scope)")
+		// Like the selectors of a scoped style rule, the selectors of a
+		// nested scope only match the parent root through & or :scope.
+		if s.Parent != nil && e == parent && !explicit {
+			continue
+		}
+		if e.Matches(sel, parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// limited reports whether a scoping limit of s lies between the element e
+// and its scoping root, e included.
+func (s *Scope) limited(e, root Element) bool {
+	for ; e != nil && e != root; e = e.Parent() {
+		for _, sel := range s.Limits {
+			if e.Matches(sel, root) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type scopeVisitor struct {
+	scopes *Scopes
+	scope  *Scope
+	// rule holds the selectors of the style rule being visited, if any.
+	rule []string
+}
+
+func (v *scopeVisitor) Visit(n ast.Node) ast.Visitor {
+	switch n := n.(type) {
+	case nil:
+		return nil
+
+	case *ast.ScopeRule:
+		v.scopes.of[n] = v.scope
+		s := &Scope{
+			Rule:   n,
+			Parent: v.scope,
+			Roots:  n.Roots,
+			Limits: n.Limits,
+		}
+		if v.rule != nil {
+			parent := ":is(" + strings.Join(v.rule, ", ") + ")"
+			s.Roots = nil
+			for _, sel := range n.Roots {
+				if r, ok := replaceNesting(sel, parent); ok {
+					s.Roots = append(s.Roots, r)
+				} else {
+					s.Roots = append(s.Roots, parent+" "+sel)
+				}
+			}
+			if len(s.Roots) == 0 {
+				s.Roots = []string{parent}
+			}
+		}
+		w := *v
+		w.scope, w.rule = s, nil
+		return &w
+
+	case *ast.QualifiedRule:
+		v.scopes.of[n] = v.scope
+		w := *v
+		w.rule = nil
+		for _, c := range n.Components {
+			w.rule = append(w.rule, c.Name)
+		}
+		return &w
+	}
+	v.scopes.of[n] = v.scope
+	return v
+}
+
+// replaceNesting returns sel with its nesting selectors & replaced with
+// repl, and reports whether sel holds & or :scope.
+func replaceNesting(sel, repl string) (string, bool) {
+	var (
+		b     strings.Builder
+		found bool
+		prev  *scanner.Token
+		sc    = scanner.New(sel)
+	)
+	for t := sc.Next(); t.Type != scanner.TokenEOF && t.Type != scanner.TokenError; t = sc.Next() {
+		switch {
+		case t.Type == scanner.TokenChar && t.Value == "&":
+			b.WriteString(repl)
+			found = true
+			prev = t
+			continue
+		case t.Type == scanner.TokenIdent && strings.EqualFold(t.Value, "scope") &&
+			prev != nil && prev.Type == scanner.TokenChar && prev.Value == ":":
+			found = true
+		}
+		b.WriteString(t.Value)
+		prev = t
+	}
+	return b.String(), found
+}
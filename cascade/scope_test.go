@@ -0,0 +1,129 @@
+package cascade
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/scanner"
+)
+
+func TestScoping(t *testing.T) {
+	src := `@scope (.card) to (.content) {
+  img { color: red }
+  :scope > p { color: red }
+  & .title { color: red }
+  @scope (.media) { .b { color: red } }
+}
+.list {
+  @scope (> .item) to (:scope .nested) { .c { color: red } }
+  @scope { .d { color: red } }
+}
+.e { color: red }
+`
+	ss, err := parser.New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Scoping(ss)
+
+	var got []string
+	ast.Inspect(ss, func(n ast.Node) bool {
+		r, ok := n.(*ast.QualifiedRule)
+		if !ok {
+			return true
+		}
+		sel := r.Components[0].Name
+		sc := s.Scope(r)
+		if sc == nil {
+			got = append(got, sel+" unscoped")
+			return true
+		}
+		line := sc.Selector(sel) + " in " + strings.Join(sc.Roots, ", ")
+		if len(sc.Limits) > 0 {
+			line += " to " + strings.Join(sc.Limits, ", ")
+		}
+		if sc.Parent != nil {
+			line += " in " + strings.Join(sc.Parent.Roots, ", ")
+		}
+		got = append(got, line)
+		return true
+	})
+	want := []string{
+		":where(:scope) img in .card to .content",
+		":scope > p in .card to .content",
+		":where(:scope) .title in .card to .content",
+		":where(:scope) .b in .media in .card",
+		".list unscoped",
+		":where(:scope) .c in :is(.list) > .item to :scope .nested",
+		":where(:scope) .d in :is(.list)",
+		".e unscoped",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// element is an element with classes, matching selectors made of a single
+// class or :scope.
+type element struct {
+	parent  *element
+	classes string
+}
+
+func (e *element) Parent() Element {
+	if e.parent == nil {
+		return nil
+	}
+	return e.parent
+}
+
+func (e *element) Matches(sel string, scope Element) bool {
+	if sel == ":scope" {
+		return scope == Element(e)
+	}
+	for _, c := range strings.Fields(e.classes) {
+		if "."+c == sel {
+			return true
+		}
+	}
+	return false
+}
+
+func TestProximity(t *testing.T) {
+	var (
+		html    = &element{}
+		outer   = &element{parent: html, classes: "card"}
+		content = &element{parent: outer, classes: "content"}
+		inner   = &element{parent: outer, classes: "card"}
+		img     = &element{parent: inner}
+		deep    = &element{parent: content}
+	)
+	scope := &Scope{Roots: []string{".card"}, Limits: []string{".content"}}
+	nested := &Scope{Parent: scope, Roots: []string{".card"}}
+	implicit := &Scope{}
+	tests := []struct {
+		scope *Scope
+		e     *element
+		want  int
+		ok    bool
+	}{
+		{scope, outer, 0, true},
+		{scope, inner, 0, true},
+		{scope, img, 1, true},
+		{scope, content, 0, false},
+		{scope, deep, 0, false},
+		{scope, html, 0, false},
+		{nested, img, 1, true},
+		{nested, outer, 0, false},
+		{implicit, img, 3, true},
+	}
+	for i, test := range tests {
+		got, ok := test.scope.Proximity(test.e)
+		if got != test.want || ok != test.ok {
+			t.Errorf("%d: got %d, %v, want %d, %v", i, got, ok, test.want, test.ok)
+		}
+	}
+}
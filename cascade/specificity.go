@@ -0,0 +1,161 @@
+package cascade
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/scanner"
+)
+
+// CompareSpecificity compares the specificities a and b, and returns a
+// negative number, zero or a positive number if a is lower than, equal to
+// or higher than b.
+func CompareSpecificity(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+// Specificity computes the specificity of a single complex selector as
+// defined by Selectors Level 4: the numbers of ID selectors, of class
+// selectors, attribute selectors and pseudo-classes, and of type
+// selectors and pseudo-elements. A namespace prefix, like svg| in svg|a,
+// does not count.
+func Specificity(sel string) [3]int {
+	s := scanner.New(sel)
+	return specificityUntil(s, "")
+}
+
+// specificityUntil consumes tokens from s up to the closing token (or
+// EOF), returning the specificity of the consumed selector. A ',' at the
+// top level starts a new selector; the largest specificity wins.
+func specificityUntil(s *scanner.Scanner, closing string) [3]int {
+	var (
+		best, cur [3]int
+		prev      *scanner.Token
+		// prefix is set if prev is a '|' taken for the namespace
+		// separator after the ident before it.
+		prefix bool
+	)
+	for {
+		t := s.Next()
+		switch {
+		case isEnd(t) || (t.Type == scanner.TokenChar && t.Value == closing):
+			if CompareSpecificity(cur, best) > 0 {
+				best = cur
+			}
+			return best
+		case t.Type == scanner.TokenChar && t.Value == ",":
+			if CompareSpecificity(cur, best) > 0 {
+				best = cur
+			}
+			cur = [3]int{}
+		case t.Type == scanner.TokenHash:
+			cur[0]++
+		case t.Type == scanner.TokenIdent:
+			cur[2]++
+		case isChar(t, "|"):
+			switch {
+			case isChar(prev, "|"):
+				// A column combinator: the ident before the first '|'
+				// was a type selector after all.
+				if prefix {
+					cur[2]++
+				}
+			case prev != nil && prev.Type == scanner.TokenIdent:
+				// The ident is a namespace prefix, not a type selector.
+				cur[2]--
+				prefix = true
+				prev = t
+				continue
+			}
+		case t.Type == scanner.TokenChar && t.Value == ".":
+			cur[1]++
+			s.Next()
+		case t.Type == scanner.TokenChar && t.Value == "[":
+			cur[1]++
+			skipUntil(s, "]")
+		case t.Type == scanner.TokenChar && t.Value == ":":
+			t = s.Next()
+			element := false
+			if t.Type == scanner.TokenChar && t.Value == ":" {
+				element = true
+				t = s.Next()
+			}
+			name := strings.ToLower(strings.TrimSuffix(t.Value, "("))
+			if legacyPseudoElements[name] {
+				element = true
+			}
+			var args [3]int
+			switch {
+			case t.Type != scanner.TokenFunction:
+			case name == "nth-child" || name == "nth-last-child":
+				args = ofSelector(s)
+			default:
+				args = specificityUntil(s, ")")
+			}
+			switch {
+			case element:
+				cur[2]++
+				if name == "slotted" {
+					add(&cur, args)
+				}
+			case name == "where":
+			case name == "is" || name == "not" || name == "has" || name == "matches":
+				add(&cur, args)
+			case name == "nth-child" || name == "nth-last-child" || name == "host" || name == "host-context":
+				cur[1]++
+				add(&cur, args)
+			default:
+				cur[1]++
+			}
+		}
+		prev, prefix = t, false
+	}
+}
+
+// ofSelector consumes the arguments of :nth-child() from s through the
+// closing ')', returning the specificity of the selector list following
+// "of", if any.
+func ofSelector(s *scanner.Scanner) [3]int {
+	for t := s.Next(); !isEnd(t); t = s.Next() {
+		switch {
+		case isChar(t, ")"):
+			return [3]int{}
+		case t.Type == scanner.TokenIdent && strings.EqualFold(t.Value, "of"):
+			return specificityUntil(s, ")")
+		}
+	}
+	return [3]int{}
+}
+
+func add(spec *[3]int, b [3]int) {
+	for i := range spec {
+		spec[i] += b[i]
+	}
+}
+
+func isChar(t *scanner.Token, c string) bool {
+	return t != nil && t.Type == scanner.TokenChar && t.Value == c
+}
+
+func skipUntil(s *scanner.Scanner, closing string) {
+	for t := s.Next(); !isEnd(t); t = s.Next() {
+		if t.Type == scanner.TokenChar && t.Value == closing {
+			return
+		}
+	}
+}
+
+func isEnd(t *scanner.Token) bool {
+	return t.Type == scanner.TokenEOF || t.Type == scanner.TokenError
+}
+
+var legacyPseudoElements = map[string]bool{
+	"before":       true,
+	"after":        true,
+	"first-line":   true,
+	"first-letter": true,
+}
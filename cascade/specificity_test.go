@@ -0,0 +1,39 @@
+package cascade
+
+import "testing"
+
+func TestSpecificity(t *testing.T) {
+	var tests = []struct {
+		sel  string
+		spec [3]int
+	}{
+		{"*", [3]int{0, 0, 0}},
+		{"li", [3]int{0, 0, 1}},
+		{"ul li", [3]int{0, 0, 2}},
+		{"ul ol+li", [3]int{0, 0, 3}},
+		{"h1 + *[rel=up]", [3]int{0, 1, 1}},
+		{"ul ol li.red", [3]int{0, 1, 3}},
+		{"li.red.level", [3]int{0, 2, 1}},
+		{"#x34y", [3]int{1, 0, 0}},
+		{"#s12:not(FOO)", [3]int{1, 0, 1}},
+		{".foo :is(.bar, #baz)", [3]int{1, 1, 0}},
+		{":where(#a) p", [3]int{0, 0, 1}},
+		{"a::before", [3]int{0, 0, 2}},
+		{"a:hover", [3]int{0, 1, 1}},
+		{"p:nth-child(2)", [3]int{0, 1, 1}},
+		{":nth-child(2n of #a)", [3]int{1, 1, 0}},
+		{":nth-last-child(odd of li.x, p)", [3]int{0, 2, 1}},
+		{"svg|a", [3]int{0, 0, 1}},
+		{"*|a", [3]int{0, 0, 1}},
+		{"col || td", [3]int{0, 0, 2}},
+		{"col||td", [3]int{0, 0, 2}},
+		{"::slotted(#x)", [3]int{1, 0, 1}},
+		{":host(.a)", [3]int{0, 2, 0}},
+		{":host-context(main .a)", [3]int{0, 2, 1}},
+	}
+	for _, test := range tests {
+		if got := Specificity(test.sel); got != test.spec {
+			t.Errorf("Specificity(%q): expected %v, got %v", test.sel, test.spec, got)
+		}
+	}
+}
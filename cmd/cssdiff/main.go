@@ -0,0 +1,97 @@
+// Command cssdiff compares two CSS files semantically.
+//
+// Usage:
+//
+//	cssdiff [-format text|json] old.css new.css
+//
+// cssdiff reports the rules added, removed and moved, to another @media
+// rule or @layer for instance, and the declarations changed per rule,
+// ignoring the differences of whitespace, comments, quotes and color or
+// number syntax. Either file may be "-" for standard input.
+//
+// Syntax errors are reported on standard error, and the parts of the
+// files that could be parsed are compared. As for diff, the exit status is
+// 0 if the files are equivalent, 1 if they differ and 2 if a file could
+// not be read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/diff"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/scanner"
+)
+
+var format = flag.String("format", "text", "output format: text or json")
+
+var writers = map[string]func(io.Writer, []*diff.Change) error{
+	"text": diff.WriteText,
+	"json": diff.WriteJSON,
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: cssdiff [flags] old.css new.css\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cssdiff: unknown format %q\n", *format)
+		os.Exit(2)
+	}
+
+	old, err := parse(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cssdiff: %v\n", err)
+		os.Exit(2)
+	}
+	new, err := parse(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cssdiff: %v\n", err)
+		os.Exit(2)
+	}
+
+	changes := diff.Diff(old, new)
+	if err := write(os.Stdout, changes); err != nil {
+		fmt.Fprintf(os.Stderr, "cssdiff: %v\n", err)
+		os.Exit(2)
+	}
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+// parse parses the file name, reporting its syntax errors.
+func parse(name string) (*ast.Stylesheet, error) {
+	src, err := readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	ss, err := parser.New(scanner.New(string(src))).Parse()
+	if errs, ok := err.(parser.ErrorList); ok {
+		for _, e := range errs {
+			fmt.Fprintf(os.Stderr, "%s:%v\n", name, e)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return ss, nil
+}
+
+func readFile(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
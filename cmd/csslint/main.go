@@ -0,0 +1,146 @@
+// Command csslint checks CSS files for common problems.
+//
+// Usage:
+//
+//	csslint [-config file] [-format text|json|sarif] [-sourcemap] [file ...]
+//
+// With no files, csslint reads standard input. If -config is not given,
+// .csslintrc.json in the current directory is used when present. With
+// -sourcemap, diagnostics in files with a source map comment are reported
+// at their positions in the original sources, like a .scss file.
+//
+// Syntax errors are reported as error-level diagnostics of the "syntax"
+// rule. The exit status is 1 if any error-level diagnostic was reported
+// and 2 if a file could not be read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ttacon/css/lint"
+	"github.com/ttacon/css/sourcemap"
+)
+
+const defaultConfig = ".csslintrc.json"
+
+var (
+	configPath = flag.String("config", "", "configuration `file`")
+	format     = flag.String("format", "text", "output format: text, json or sarif")
+	listRules  = flag.Bool("rules", false, "list the available rules and exit")
+	sourceMaps = flag.Bool("sourcemap", false, "report positions in the original sources named by the files' source maps")
+)
+
+var writers = map[string]func(io.Writer, []lint.FileResult) error{
+	"text":  lint.WriteText,
+	"json":  lint.WriteJSON,
+	"sarif": lint.WriteSARIF,
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: csslint [flags] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *listRules {
+		for _, r := range lint.Rules() {
+			fmt.Printf("%-22s %s\n", r.Name(), r.Severity())
+		}
+		return
+	}
+
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "csslint: unknown format %q\n", *format)
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "csslint: %v\n", err)
+		os.Exit(2)
+	}
+	l, err := lint.New(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "csslint: %v\n", err)
+		os.Exit(2)
+	}
+
+	var (
+		results []lint.FileResult
+		status  int
+	)
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		src, err := readFile(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "csslint: %v\n", err)
+			status = 2
+			continue
+		}
+		diags := l.Lint(string(src))
+		if *sourceMaps {
+			m, err := loadSourceMap(name, string(src))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "csslint: %s: %v\n", name, err)
+				status = 2
+			} else if m != nil {
+				lint.Remap(diags, m)
+			}
+		}
+		for _, d := range diags {
+			if d.Severity == lint.Error && status == 0 {
+				status = 1
+			}
+		}
+		results = append(results, lint.FileResult{Filename: name, Diagnostics: diags})
+	}
+
+	if err := write(os.Stdout, results); err != nil {
+		fmt.Fprintf(os.Stderr, "csslint: %v\n", err)
+		os.Exit(2)
+	}
+	os.Exit(status)
+}
+
+func loadConfig() (*lint.Config, error) {
+	if *configPath != "" {
+		return lint.LoadConfig(*configPath)
+	}
+	if _, err := os.Stat(defaultConfig); err == nil {
+		return lint.LoadConfig(defaultConfig)
+	}
+	return nil, nil
+}
+
+// loadSourceMap returns the source map of the file name with contents
+// src, if it has one. Map URLs are relative to the file.
+func loadSourceMap(name, src string) (*sourcemap.Map, error) {
+	dir := "."
+	if name != "-" {
+		dir = filepath.Dir(name)
+	}
+	return sourcemap.Load(src, func(u string) ([]byte, error) {
+		u = strings.TrimPrefix(u, "file://")
+		if !filepath.IsAbs(u) {
+			u = filepath.Join(dir, filepath.FromSlash(u))
+		}
+		return os.ReadFile(u)
+	})
+}
+
+func readFile(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
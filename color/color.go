@@ -0,0 +1,116 @@
+// Package color parses CSS colors, converts them between the color spaces
+// of CSS Color Level 4 (https://www.w3.org/TR/css-color-4/) and evaluates
+// color-mix() following CSS Color Level 5
+// (https://www.w3.org/TR/css-color-5/#color-mix).
+package color
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Color is a color in the sRGB color space. Its components are
+// gamma-encoded and range from 0 to 1 within the sRGB gamut; colors
+// converted from wider color spaces, like oklch(), may lie outside of it.
+type Color struct {
+	R, G, B float64
+	// Alpha is the opacity, from 0 to 1.
+	Alpha float64
+}
+
+// InGamut reports whether c lies within the sRGB gamut.
+func (c Color) InGamut() bool {
+	const eps = 1e-6
+	for _, v := range []float64{c.R, c.G, c.B} {
+		if v < -eps || v > 1+eps {
+			return false
+		}
+	}
+	return true
+}
+
+// ToGamut returns c mapped into the sRGB gamut with the gamut mapping
+// algorithm of CSS Color Level 4
+// (https://www.w3.org/TR/css-color-4/#binsearch): the chroma of c in
+// oklch is reduced until clipping the color changes it imperceptibly.
+func (c Color) ToGamut() Color {
+	if c.InGamut() {
+		return c
+	}
+	oklch := spaces["oklch"]
+	v := c.in(oklch)
+	switch {
+	case v[0] >= 1:
+		return Color{R: 1, G: 1, B: 1, Alpha: c.Alpha}
+	case v[0] <= 0:
+		return Color{Alpha: c.Alpha}
+	case math.IsNaN(v[2]):
+		return c.clip()
+	}
+	const (
+		jnd = 0.02
+		eps = 0.0001
+	)
+	var (
+		current    = c
+		clipped    = c.clip()
+		min, max   = 0.0, v[1]
+		minInGamut = true
+	)
+	if deltaEOK(clipped, current) < jnd {
+		return clipped
+	}
+	for max-min > eps {
+		v[1] = (min + max) / 2
+		current = fromSpace(oklch, v, c.Alpha)
+		if minInGamut && current.InGamut() {
+			min = v[1]
+			continue
+		}
+		clipped = current.clip()
+		if e := deltaEOK(clipped, current); e < jnd {
+			if jnd-e < eps {
+				return clipped
+			}
+			minInGamut = false
+			min = v[1]
+		} else {
+			max = v[1]
+		}
+	}
+	return clipped
+}
+
+func (c Color) clip() Color {
+	return Color{R: clip(c.R), G: clip(c.G), B: clip(c.B), Alpha: c.Alpha}
+}
+
+// deltaEOK returns the color difference of a and b: their distance in
+// oklab.
+func deltaEOK(a, b Color) float64 {
+	oklab := spaces["oklab"]
+	u, v := a.in(oklab), b.in(oklab)
+	return math.Sqrt((u[0]-v[0])*(u[0]-v[0]) + (u[1]-v[1])*(u[1]-v[1]) + (u[2]-v[2])*(u[2]-v[2]))
+}
+
+// String returns c in the syntax all browsers support: #rrggbb for opaque
+// colors and rgba() for the others. Components outside the sRGB gamut are
+// clipped.
+func (c Color) String() string {
+	r, g, b := channel(c.R), channel(c.G), channel(c.B)
+	a := math.Round(clip(c.Alpha)*1000) / 1000
+	if a == 1 {
+		return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	}
+	return fmt.Sprintf("rgba(%d, %d, %d, %s)", r, g, b, strconv.FormatFloat(a, 'f', -1, 64))
+}
+
+// channel returns the 8-bit value of the sRGB component v.
+func channel(v float64) int {
+	return int(math.Round(clip(v) * 255))
+}
+
+func clip(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
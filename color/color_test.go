@@ -0,0 +1,101 @@
+package color
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"RED", "#ff0000"},
+		{"#f00a", "rgba(255, 0, 0, 0.667)"},
+		{"#336699cc", "rgba(51, 102, 153, 0.8)"},
+		{"transparent", "rgba(0, 0, 0, 0)"},
+		{"rgb(255 0 0 / .5)", "rgba(255, 0, 0, 0.5)"},
+		{"rgba(255, 0, 0, 50%)", "rgba(255, 0, 0, 0.5)"},
+		{"rgb(100% 50% 0% / 1)", "#ff8000"},
+		{"rgb(none 0 300)", "#0000ff"},
+		{"hsl(120, 100%, 25%)", "#008000"},
+		{"hsl(0.5turn 100% 50% / 50%)", "rgba(0, 255, 255, 0.5)"},
+		{"hsl(-240deg 100% 50%)", "#00ff00"},
+		{"lab(50% -20 +30)", "#618041"},
+		{"hwb(0 60% 60%)", "#808080"},
+		{"lab(54.29% 80.82 69.88)", "#ff0000"},
+		{"lch(54.29 106.84 40.85)", "#ff0000"},
+		{"oklab(0.628 0.2249 0.1258)", "#ff0000"},
+		{"oklch(62.8% 0.2577 29.23)", "#ff0000"},
+		{"oklch(70% 0.1 200)", "#40b1b7"},
+		{"oklch(0.7 0.1 200deg / 0.25)", "rgba(64, 177, 183, 0.25)"},
+		{"color(srgb-linear 0.5 0.5 0.5)", "#bcbcbc"},
+		{"color(xyz-d50 0.9642 1 0.8251)", "#ffffff"},
+		{"color-mix(in srgb, red 40%, blue)", "#660099"},
+		{"color-mix(in srgb, 40% red, blue)", "#660099"},
+		{"color-mix(in srgb, red, blue)", "#800080"},
+		{"color-mix(in srgb, red 30%, blue 30%)", "rgba(128, 0, 128, 0.6)"},
+		{"color-mix(in srgb, red, transparent)", "rgba(255, 0, 0, 0.5)"},
+		{"color-mix(in oklab, white, black)", "#636363"},
+		{"color-mix(in oklch, red, blue)", "#b700be"},
+		{"color-mix(in hsl, red, blue)", "#ff00ff"},
+		{"color-mix(in hsl longer hue, red, blue)", "#00ff00"},
+		{"color-mix(in hsl, white, blue)", "#9f9fdf"},
+		{"color-mix(in srgb, color-mix(in srgb, red, blue), white)", "#bf7fbf"},
+	}
+	for _, test := range tests {
+		c, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if got := c.ToGamut().String(); got != test.want {
+			t.Errorf("%q: got %s, want %s", test.in, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in, err string
+	}{
+		{"", `color: invalid color "": unexpected end of color`},
+		{"currentColor", `color: cannot resolve currentColor in "currentColor"`},
+		{"var(--x)", `color: cannot resolve var() in "var(--x)"`},
+		{"rgb(1 2)", `color: invalid color "rgb(1 2)": unexpected ")"`},
+		{"rgb(1, 2 3)", `color: invalid color "rgb(1, 2 3)": unexpected "3"`},
+		{"rgb(1, none, 3)", `color: invalid color "rgb(1, none, 3)": none in legacy syntax`},
+		{"hsl(1px 2% 3%)", `color: invalid color "hsl(1px 2% 3%)": invalid hue`},
+		{"red blue", `color: invalid color "red blue": unexpected "blue"`},
+		{"#12345", `color: invalid color "#12345": unexpected "#12345"`},
+		{"oklch(from red l c h)", `color: cannot resolve relative color "oklch(from red l c h)"`},
+		{"color(lab 1 2 3)", `color: unsupported color space "lab" in "color(lab 1 2 3)"`},
+		{"color-mix(in foo, red, blue)", `color: unsupported color space "foo" in "color-mix(in foo, red, blue)"`},
+		{"color-mix(in srgb, red 0%, blue 0%)", `color: invalid color "color-mix(in srgb, red 0%, blue 0%)": percentages sum to zero`},
+		{"color-mix(in srgb, red 120%, blue)", `color: invalid color "color-mix(in srgb, red 120%, blue)": unexpected "120%"`},
+		{"color-mix(in srgb longer hue, red, blue)", `color: invalid color "color-mix(in srgb longer hue, red, blue)": unexpected "longer"`},
+	}
+	for _, test := range tests {
+		if _, err := Parse(test.in); err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v, want %s", test.in, err, test.err)
+		}
+	}
+}
+
+func TestToGamut(t *testing.T) {
+	c, err := Parse("oklch(70% 0.3 150)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.InGamut() {
+		t.Fatal("expected oklch(70% 0.3 150) to be outside of the sRGB gamut")
+	}
+	mapped := c.ToGamut()
+	if !mapped.InGamut() {
+		t.Errorf("expected %v to be within the sRGB gamut", mapped)
+	}
+	// Reducing the chroma preserves the lightness and the hue.
+	v, w := c.in(spaces["oklch"]), mapped.in(spaces["oklch"])
+	if d := v[0] - w[0]; d > 0.02 || d < -0.02 {
+		t.Errorf("lightness changed from %v to %v", v[0], w[0])
+	}
+	if d := v[2] - w[2]; d > 5 || d < -5 {
+		t.Errorf("hue changed from %v to %v", v[2], w[2])
+	}
+}
@@ -0,0 +1,185 @@
+package color
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ttacon/css/scanner"
+)
+
+// hueMethods are the hue interpolation methods of polar color spaces.
+var hueMethods = map[string]bool{
+	"shorter":    true,
+	"longer":     true,
+	"increasing": true,
+	"decreasing": true,
+}
+
+// parseMix parses the arguments of color-mix():
+//
+//	in <space> [ <hue-method> hue ]? , <color> <percentage>? , <color> <percentage>?
+func (p *parser) parseMix() (Color, error) {
+	if !p.ident("in") {
+		return Color{}, p.errorf()
+	}
+	t := p.tok()
+	if t == nil || t.Type != scanner.TokenIdent {
+		return Color{}, p.errorf()
+	}
+	s := lookupSpace(t.Value)
+	if s == nil {
+		return Color{}, fmt.Errorf("color: unsupported color space %q in %q", t.Value, p.src)
+	}
+	p.pos++
+	method := "shorter"
+	if t := p.tok(); s.hue >= 0 && t != nil && t.Type == scanner.TokenIdent && hueMethods[strings.ToLower(t.Value)] {
+		p.pos++
+		if !p.ident("hue") {
+			return Color{}, p.errorf()
+		}
+		method = strings.ToLower(t.Value)
+	}
+	if !p.char(",") {
+		return Color{}, p.errorf()
+	}
+	c1, w1, err := p.parseMixArg()
+	if err != nil {
+		return Color{}, err
+	}
+	if !p.char(",") {
+		return Color{}, p.errorf()
+	}
+	c2, w2, err := p.parseMixArg()
+	if err != nil {
+		return Color{}, err
+	}
+	if !p.char(")") {
+		return Color{}, p.errorf()
+	}
+	c, ok := mix(s, method, c1, w1, c2, w2)
+	if !ok {
+		return Color{}, fmt.Errorf("color: invalid color %q: percentages sum to zero", p.src)
+	}
+	return c, nil
+}
+
+// parseMixArg parses a color of color-mix() and its optional percentage,
+// which is NaN if it is missing.
+func (p *parser) parseMixArg() (Color, float64, error) {
+	pct := math.NaN()
+	percentage := func() error {
+		start := p.pos
+		if v, ok := p.parseValue(); ok {
+			if v.unit != "%" || v.num < 0 || v.num > 100 {
+				p.pos = start
+				return p.errorf()
+			}
+			pct = v.num
+		}
+		return nil
+	}
+	if err := percentage(); err != nil {
+		return Color{}, 0, err
+	}
+	c, err := p.parseColor()
+	if err != nil {
+		return Color{}, 0, err
+	}
+	if math.IsNaN(pct) {
+		if err := percentage(); err != nil {
+			return Color{}, 0, err
+		}
+	}
+	return c, pct, nil
+}
+
+// Mix mixes the colors c1 and c2 in the color space named space, as
+// color-mix() does, with their percentages p1 and p2, which are NaN if
+// omitted. method is the hue interpolation method of polar spaces:
+// "shorter", "longer", "increasing" or "decreasing". It reports false if
+// space is unknown or the percentages sum to zero.
+func Mix(space, method string, c1 Color, p1 float64, c2 Color, p2 float64) (Color, bool) {
+	s := lookupSpace(space)
+	if s == nil {
+		return Color{}, false
+	}
+	return mix(s, method, c1, p1, c2, p2)
+}
+
+func mix(s *space, method string, c1 Color, p1 float64, c2 Color, p2 float64) (Color, bool) {
+	switch {
+	case math.IsNaN(p1) && math.IsNaN(p2):
+		p1, p2 = 50, 50
+	case math.IsNaN(p1):
+		p1 = 100 - p2
+	case math.IsNaN(p2):
+		p2 = 100 - p1
+	}
+	sum := p1 + p2
+	if sum == 0 {
+		return Color{}, false
+	}
+	// Percentages summing to less than 100% make the result translucent.
+	scale := math.Min(sum, 100) / 100
+	p1, p2 = p1/sum, p2/sum
+
+	a, b := c1.in(s), c2.in(s)
+	if h := s.hue; h >= 0 {
+		// A missing hue takes the value of the other color.
+		switch {
+		case math.IsNaN(a[h]) && math.IsNaN(b[h]):
+			a[h], b[h] = 0, 0
+		case math.IsNaN(a[h]):
+			a[h] = b[h]
+		case math.IsNaN(b[h]):
+			b[h] = a[h]
+		}
+		a[h], b[h] = fixupHues(a[h], b[h], method)
+	}
+
+	// Interpolate with premultiplied alpha.
+	alpha := c1.Alpha*p1 + c2.Alpha*p2
+	var v [3]float64
+	for i := range v {
+		if i == s.hue {
+			v[i] = a[i]*p1 + b[i]*p2
+			continue
+		}
+		v[i] = a[i]*c1.Alpha*p1 + b[i]*c2.Alpha*p2
+		if alpha != 0 {
+			v[i] /= alpha
+		}
+	}
+	return fromSpace(s, v, alpha*scale), true
+}
+
+// fixupHues adjusts the hues a and b, in degrees, so that interpolating
+// between them follows the hue interpolation method.
+func fixupHues(a, b float64, method string) (float64, float64) {
+	a, b = normalizeHue(a), normalizeHue(b)
+	d := b - a
+	switch method {
+	case "longer":
+		if 0 < d && d < 180 {
+			a += 360
+		} else if -180 < d && d <= 0 {
+			b += 360
+		}
+	case "increasing":
+		if d < 0 {
+			b += 360
+		}
+	case "decreasing":
+		if d > 0 {
+			a += 360
+		}
+	default:
+		if d > 180 {
+			a += 360
+		} else if d < -180 {
+			b += 360
+		}
+	}
+	return a, b
+}
@@ -0,0 +1,408 @@
+package color
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ttacon/css/scanner"
+)
+
+// Parse parses a color: a hex color, a named color, transparent, or one
+// of the rgb(), rgba(), hsl(), hsla(), hwb(), lab(), lch(), oklab(),
+// oklch(), color() and color-mix() functions, in their legacy or modern
+// syntax. The keyword none stands for a zero component.
+//
+// Colors depending on their context, like currentcolor, system colors,
+// relative colors and colors using var() or calc(), cannot be resolved
+// and are reported as errors.
+func Parse(s string) (Color, error) {
+	var (
+		toks []*scanner.Token
+		sc   = scanner.New(s)
+	)
+	for t := sc.Next(); t.Type != scanner.TokenEOF; t = sc.Next() {
+		switch t.Type {
+		case scanner.TokenError:
+			return Color{}, fmt.Errorf("color: %s in %q", t.Value, s)
+		case scanner.TokenS, scanner.TokenComment:
+			continue
+		}
+		toks = append(toks, t)
+	}
+	p := &parser{src: s, toks: toks}
+	c, err := p.parseColor()
+	if err == nil && p.pos < len(p.toks) {
+		err = p.errorf()
+	}
+	return c, err
+}
+
+type parser struct {
+	src  string
+	toks []*scanner.Token
+	pos  int
+}
+
+// errorf returns an error about the color of p, blaming the current token.
+func (p *parser) errorf() error {
+	what := "end of color"
+	if p.pos < len(p.toks) {
+		what = strconv.Quote(p.toks[p.pos].Value)
+	}
+	return fmt.Errorf("color: invalid color %q: unexpected %s", p.src, what)
+}
+
+// tok returns the current token, or nil at the end of the color.
+func (p *parser) tok() *scanner.Token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return nil
+}
+
+// char consumes the char token c and reports whether it was there.
+func (p *parser) char(c string) bool {
+	if t := p.tok(); t != nil && t.Type == scanner.TokenChar && t.Value == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// ident consumes the ident, case-insensitively, and reports whether it
+// was there.
+func (p *parser) ident(ident string) bool {
+	if t := p.tok(); t != nil && t.Type == scanner.TokenIdent && strings.EqualFold(t.Value, ident) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseColor() (Color, error) {
+	t := p.tok()
+	if t == nil {
+		return Color{}, p.errorf()
+	}
+	switch t.Type {
+	case scanner.TokenHash:
+		c, ok := parseHex(t.Value[1:])
+		if !ok {
+			return Color{}, p.errorf()
+		}
+		p.pos++
+		return c, nil
+	case scanner.TokenIdent:
+		name := strings.ToLower(t.Value)
+		if name == "transparent" {
+			p.pos++
+			return Color{}, nil
+		}
+		rgb, ok := named[name]
+		if !ok {
+			if name == "currentcolor" {
+				return Color{}, fmt.Errorf("color: cannot resolve %s in %q", t.Value, p.src)
+			}
+			return Color{}, p.errorf()
+		}
+		p.pos++
+		return Color{
+			R:     float64(rgb>>16) / 255,
+			G:     float64(rgb>>8&0xff) / 255,
+			B:     float64(rgb&0xff) / 255,
+			Alpha: 1,
+		}, nil
+	case scanner.TokenFunction:
+		name := strings.ToLower(strings.TrimSuffix(t.Value, "("))
+		p.pos++
+		if p.ident("from") {
+			return Color{}, fmt.Errorf("color: cannot resolve relative color %q", p.src)
+		}
+		switch name {
+		case "rgb", "rgba", "hsl", "hsla", "hwb":
+			return p.parseSRGB(name)
+		case "lab", "lch", "oklab", "oklch":
+			return p.parseLab(name)
+		case "color":
+			return p.parseColorFunction()
+		case "color-mix":
+			return p.parseMix()
+		case "var", "env", "calc", "attr":
+			return Color{}, fmt.Errorf("color: cannot resolve %s) in %q", t.Value, p.src)
+		}
+	}
+	return Color{}, p.errorf()
+}
+
+// parseHex parses the digits of a hex color.
+func parseHex(s string) (Color, bool) {
+	switch len(s) {
+	case 3, 4:
+		var b strings.Builder
+		for _, r := range s {
+			b.WriteRune(r)
+			b.WriteRune(r)
+		}
+		s = b.String()
+	case 6, 8:
+	default:
+		return Color{}, false
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return Color{}, false
+	}
+	return Color{
+		R:     float64(n>>24) / 255,
+		G:     float64(n>>16&0xff) / 255,
+		B:     float64(n>>8&0xff) / 255,
+		Alpha: float64(n&0xff) / 255,
+	}, true
+}
+
+// value is a numeric argument of a color function.
+type value struct {
+	num float64
+	// unit is "%" for a percentage, the lowercased unit of a dimension,
+	// or "" for a number.
+	unit string
+	// none is set for the keyword none.
+	none bool
+}
+
+// parseValue parses a number, a percentage, a dimension or none.
+func (p *parser) parseValue() (value, bool) {
+	if p.ident("none") {
+		return value{none: true}, true
+	}
+	start := p.pos
+	sign := 1.0
+	if p.char("-") {
+		sign = -1
+	} else {
+		p.char("+")
+	}
+	t := p.tok()
+	if t == nil {
+		p.pos = start
+		return value{}, false
+	}
+	num, unit := t.Value, ""
+	switch t.Type {
+	case scanner.TokenNumber:
+	case scanner.TokenPercentage:
+		num, unit = strings.TrimSuffix(num, "%"), "%"
+	case scanner.TokenDimension:
+		digits := strings.TrimLeft(num, "+-")
+		i := len(num) - len(digits) + strings.IndexFunc(digits, func(r rune) bool {
+			return r != '.' && (r < '0' || r > '9')
+		})
+		num, unit = num[:i], strings.ToLower(num[i:])
+	default:
+		p.pos = start
+		return value{}, false
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		p.pos = start
+		return value{}, false
+	}
+	p.pos++
+	return value{num: sign * n, unit: unit}, true
+}
+
+// parseArgs parses the three components and the optional alpha of a
+// color function, up to its closing parenthesis. Components are separated
+// by whitespace, or by commas if legacy is set.
+func (p *parser) parseArgs(legacy bool) ([3]value, value, error) {
+	var (
+		v     [3]value
+		alpha = value{num: 1}
+		ok    bool
+	)
+	for i := range v {
+		if i > 0 && legacy && !p.char(",") {
+			return v, alpha, p.errorf()
+		}
+		if v[i], ok = p.parseValue(); !ok {
+			return v, alpha, p.errorf()
+		}
+	}
+	if legacy && p.char(",") || !legacy && p.char("/") {
+		if alpha, ok = p.parseValue(); !ok {
+			return v, alpha, p.errorf()
+		}
+	}
+	if !p.char(")") {
+		return v, alpha, p.errorf()
+	}
+	return v, alpha, nil
+}
+
+// legacy reports whether the arguments of the function starting at the
+// current token use the legacy comma-separated syntax.
+func (p *parser) legacy() bool {
+	depth := 0
+	for _, t := range p.toks[p.pos:] {
+		switch {
+		case t.Type == scanner.TokenFunction:
+			depth++
+		case t.Type == scanner.TokenChar && t.Value == ")":
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case t.Type == scanner.TokenChar && t.Value == "," && depth == 0:
+			return true
+		}
+	}
+	return false
+}
+
+// parseSRGB parses the arguments of rgb(), rgba(), hsl(), hsla() and
+// hwb().
+func (p *parser) parseSRGB(name string) (Color, error) {
+	legacy := name != "hwb" && p.legacy()
+	v, alpha, err := p.parseArgs(legacy)
+	if err != nil {
+		return Color{}, err
+	}
+	if legacy {
+		for _, c := range v {
+			if c.none {
+				return Color{}, fmt.Errorf("color: invalid color %q: none in legacy syntax", p.src)
+			}
+		}
+	}
+	a := clip(fraction(alpha, 1))
+	switch name {
+	case "rgb", "rgba":
+		var rgb [3]float64
+		for i, c := range v {
+			if c.unit != "" && c.unit != "%" {
+				return Color{}, fmt.Errorf("color: invalid color %q: unexpected unit %s", p.src, c.unit)
+			}
+			rgb[i] = clip(fraction(c, 255))
+		}
+		return Color{R: rgb[0], G: rgb[1], B: rgb[2], Alpha: a}, nil
+	}
+	h, ok := hue(v[0])
+	if !ok {
+		return Color{}, fmt.Errorf("color: invalid color %q: invalid hue", p.src)
+	}
+	coords := [3]float64{h, fraction(v[1], 100), fraction(v[2], 100)}
+	return fromSpace(lookupSpace(strings.TrimSuffix(name, "a")), coords, a), nil
+}
+
+// labRanges holds the values 100% stands for in the components of the
+// lab-like functions.
+var labRanges = map[string][3]float64{
+	"lab":   {100, 125, 125},
+	"lch":   {100, 150, 0},
+	"oklab": {1, 0.4, 0.4},
+	"oklch": {1, 0.4, 0},
+}
+
+// parseLab parses the arguments of lab(), lch(), oklab() and oklch().
+func (p *parser) parseLab(name string) (Color, error) {
+	v, alpha, err := p.parseArgs(false)
+	if err != nil {
+		return Color{}, err
+	}
+	var (
+		ranges = labRanges[name]
+		coords [3]float64
+		s      = lookupSpace(name)
+	)
+	for i, c := range v {
+		if i == s.hue {
+			h, ok := hue(c)
+			if !ok {
+				return Color{}, fmt.Errorf("color: invalid color %q: invalid hue", p.src)
+			}
+			coords[i] = h
+			continue
+		}
+		if c.unit != "" && c.unit != "%" {
+			return Color{}, fmt.Errorf("color: invalid color %q: unexpected unit %s", p.src, c.unit)
+		}
+		if c.unit == "%" {
+			coords[i] = c.num / 100 * ranges[i]
+		} else {
+			coords[i] = c.num
+		}
+	}
+	// The lightness is clamped, and so is the chroma of polar colors.
+	coords[0] = math.Max(0, math.Min(ranges[0], coords[0]))
+	if s.hue >= 0 {
+		coords[1] = math.Max(0, coords[1])
+	}
+	return fromSpace(s, coords, clip(fraction(alpha, 1))), nil
+}
+
+// predefined holds the color spaces of color().
+var predefined = map[string]bool{
+	"srgb":        true,
+	"srgb-linear": true,
+	"display-p3":  true,
+	"xyz":         true,
+	"xyz-d50":     true,
+	"xyz-d65":     true,
+}
+
+// parseColorFunction parses the arguments of color().
+func (p *parser) parseColorFunction() (Color, error) {
+	t := p.tok()
+	if t == nil || t.Type != scanner.TokenIdent {
+		return Color{}, p.errorf()
+	}
+	s := lookupSpace(t.Value)
+	if !predefined[strings.ToLower(t.Value)] {
+		return Color{}, fmt.Errorf("color: unsupported color space %q in %q", t.Value, p.src)
+	}
+	p.pos++
+	v, alpha, err := p.parseArgs(false)
+	if err != nil {
+		return Color{}, err
+	}
+	var coords [3]float64
+	for i, c := range v {
+		coords[i] = fraction(c, 1)
+	}
+	return fromSpace(s, coords, clip(fraction(alpha, 1))), nil
+}
+
+// fraction returns the number or percentage v as a fraction of max, which
+// 100% stands for. none is 0.
+func fraction(v value, max float64) float64 {
+	switch {
+	case v.none:
+		return 0
+	case v.unit == "%":
+		return v.num / 100
+	}
+	return v.num / max
+}
+
+// hue returns the hue v in degrees. none is NaN, the missing hue.
+func hue(v value) (float64, bool) {
+	if v.none {
+		return math.NaN(), true
+	}
+	switch v.unit {
+	case "", "deg":
+		return v.num, true
+	case "rad":
+		return v.num * 180 / math.Pi, true
+	case "grad":
+		return v.num * 0.9, true
+	case "turn":
+		return v.num * 360, true
+	}
+	return 0, false
+}
@@ -0,0 +1,317 @@
+package color
+
+import (
+	"math"
+	"strings"
+)
+
+// space is a color space colors can be specified and interpolated in.
+type space struct {
+	// toXYZ and fromXYZ convert coordinates from and to CIE XYZ with a
+	// D65 white point.
+	toXYZ, fromXYZ func([3]float64) [3]float64
+	// hue is the index of the hue component of a polar space, or -1.
+	hue int
+}
+
+// spaces maps the names of color spaces, as written in color() and
+// color-mix(), to the color spaces.
+var spaces = map[string]*space{
+	"srgb":        {toXYZ: srgbToXYZ, fromXYZ: xyzToSRGB, hue: -1},
+	"srgb-linear": {toXYZ: linearToXYZ, fromXYZ: xyzToLinear, hue: -1},
+	"display-p3":  {toXYZ: p3ToXYZ, fromXYZ: xyzToP3, hue: -1},
+	"xyz":         {toXYZ: identity, fromXYZ: identity, hue: -1},
+	"xyz-d65":     {toXYZ: identity, fromXYZ: identity, hue: -1},
+	"xyz-d50":     {toXYZ: d50ToD65, fromXYZ: d65ToD50, hue: -1},
+	"lab":         {toXYZ: labToXYZ, fromXYZ: xyzToLab, hue: -1},
+	"lch":         {toXYZ: lchToXYZ, fromXYZ: xyzToLCH, hue: 2},
+	"oklab":       {toXYZ: oklabToXYZ, fromXYZ: xyzToOKLab, hue: -1},
+	"oklch":       {toXYZ: oklchToXYZ, fromXYZ: xyzToOKLCH, hue: 2},
+	"hsl":         {toXYZ: hslToXYZ, fromXYZ: xyzToHSL, hue: 0},
+	"hwb":         {toXYZ: hwbToXYZ, fromXYZ: xyzToHWB, hue: 0},
+}
+
+// lookupSpace returns the color space named name, or nil.
+func lookupSpace(name string) *space {
+	return spaces[strings.ToLower(name)]
+}
+
+// fromSpace returns the color of coordinates v in s.
+func fromSpace(s *space, v [3]float64, alpha float64) Color {
+	for i := range v {
+		if math.IsNaN(v[i]) {
+			v[i] = 0
+		}
+	}
+	rgb := xyzToSRGB(s.toXYZ(v))
+	return Color{R: rgb[0], G: rgb[1], B: rgb[2], Alpha: alpha}
+}
+
+// in returns the coordinates of c in s. The hue of an achromatic color
+// is missing, and set to NaN.
+func (c Color) in(s *space) [3]float64 {
+	v := s.fromXYZ(srgbToXYZ([3]float64{c.R, c.G, c.B}))
+	if s.hue >= 0 && achromatic(s, v) {
+		v[s.hue] = math.NaN()
+	}
+	return v
+}
+
+// achromatic reports whether the coordinates v of the polar space s are
+// those of a gray, whose hue is powerless.
+func achromatic(s *space, v [3]float64) bool {
+	const eps = 1e-4
+	switch s {
+	case spaces["hsl"]:
+		return v[1] < eps
+	case spaces["hwb"]:
+		return v[1]+v[2] >= 1-eps
+	}
+	return v[1] < eps
+}
+
+func identity(v [3]float64) [3]float64 { return v }
+
+func mul(m [3][3]float64, v [3]float64) [3]float64 {
+	return [3]float64{
+		m[0][0]*v[0] + m[0][1]*v[1] + m[0][2]*v[2],
+		m[1][0]*v[0] + m[1][1]*v[1] + m[1][2]*v[2],
+		m[2][0]*v[0] + m[2][1]*v[1] + m[2][2]*v[2],
+	}
+}
+
+// The conversion matrices come from the sample code of CSS Color Level 4
+// (https://www.w3.org/TR/css-color-4/#color-conversion-code).
+var (
+	linearSRGBToXYZ = [3][3]float64{
+		{0.41239079926595934, 0.357584339383878, 0.1804807884018343},
+		{0.21263900587151027, 0.715168678767756, 0.07219231536073371},
+		{0.01933081871559182, 0.11919477979462598, 0.9505321522496607},
+	}
+	xyzToLinearSRGB = [3][3]float64{
+		{3.2409699419045226, -1.537383177570094, -0.4986107602930034},
+		{-0.9692436362808796, 1.8759675015077202, 0.04155505740717559},
+		{0.05563007969699366, -0.20397695888897652, 1.0569715142428786},
+	}
+	linearP3ToXYZ = [3][3]float64{
+		{0.4865709486482162, 0.26566769316909306, 0.1982172852343625},
+		{0.2289745640697488, 0.6917385218365064, 0.079286914093745},
+		{0, 0.04511338185890264, 1.043944368900976},
+	}
+	xyzToLinearP3 = [3][3]float64{
+		{2.493496911941425, -0.9313836179191239, -0.40271078445071684},
+		{-0.8294889695615747, 1.7626640603183463, 0.023624685841943577},
+		{0.03584583024378447, -0.07617238926804182, 0.9568845240076872},
+	}
+	// Bradford chromatic adaptation between the D65 and D50 white points.
+	d65ToD50Matrix = [3][3]float64{
+		{1.0479297925449969, 0.022946870601609652, -0.05019226628920524},
+		{0.02962780877005599, 0.9904344267538799, -0.017073799063418826},
+		{-0.009243040646204504, 0.015055191490298152, 0.7518742814281371},
+	}
+	d50ToD65Matrix = [3][3]float64{
+		{0.955473421488075, -0.02309845494876471, 0.06325924320057072},
+		{-0.0283697093338637, 1.0099953980813041, 0.021041441191917323},
+		{0.012314014864481998, -0.020507649298898964, 1.330365926242124},
+	}
+	xyzToLMS = [3][3]float64{
+		{0.8190224379967030, 0.3619062600528904, -0.1288737815209879},
+		{0.0329836539323885, 0.9292868615863434, 0.0361446663506424},
+		{0.0481771893596242, 0.2642395317527308, 0.6335478284694309},
+	}
+	lmsToOKLab = [3][3]float64{
+		{0.2104542683093140, 0.7936177747023054, -0.0040720430116193},
+		{1.9779985324311684, -2.4285922420485799, 0.4505937096173791},
+		{0.0259040424655478, 0.7827717124575296, -0.8086757549230774},
+	}
+	okLabToLMS = [3][3]float64{
+		{1, 0.3963377773761749, 0.2158037573099136},
+		{1, -0.1055613458156586, -0.0638541728258133},
+		{1, -0.0894841775298119, -1.2914855480194092},
+	}
+	lmsToXYZ = [3][3]float64{
+		{1.2268798758459243, -0.5578149944602171, 0.2813910456659647},
+		{-0.0405757452148008, 1.1122868032803170, -0.0717110580655164},
+		{-0.0763729366746601, -0.4214933324022432, 1.5869240198367816},
+	}
+	// d50White is the D50 white point, which lab() is relative to.
+	d50White = [3]float64{0.3457 / 0.3585, 1, (1 - 0.3457 - 0.3585) / 0.3585}
+)
+
+// toLinear removes the gamma encoding of sRGB and display-p3.
+func toLinear(v [3]float64) [3]float64 {
+	for i, c := range v {
+		if a := math.Abs(c); a > 0.04045 {
+			v[i] = math.Copysign(math.Pow((a+0.055)/1.055, 2.4), c)
+		} else {
+			v[i] = c / 12.92
+		}
+	}
+	return v
+}
+
+// toGamma applies the gamma encoding of sRGB and display-p3.
+func toGamma(v [3]float64) [3]float64 {
+	for i, c := range v {
+		if a := math.Abs(c); a > 0.0031308 {
+			v[i] = math.Copysign(1.055*math.Pow(a, 1/2.4)-0.055, c)
+		} else {
+			v[i] = 12.92 * c
+		}
+	}
+	return v
+}
+
+func srgbToXYZ(v [3]float64) [3]float64   { return mul(linearSRGBToXYZ, toLinear(v)) }
+func xyzToSRGB(v [3]float64) [3]float64   { return toGamma(mul(xyzToLinearSRGB, v)) }
+func linearToXYZ(v [3]float64) [3]float64 { return mul(linearSRGBToXYZ, v) }
+func xyzToLinear(v [3]float64) [3]float64 { return mul(xyzToLinearSRGB, v) }
+func p3ToXYZ(v [3]float64) [3]float64     { return mul(linearP3ToXYZ, toLinear(v)) }
+func xyzToP3(v [3]float64) [3]float64     { return toGamma(mul(xyzToLinearP3, v)) }
+func d50ToD65(v [3]float64) [3]float64    { return mul(d50ToD65Matrix, v) }
+func d65ToD50(v [3]float64) [3]float64    { return mul(d65ToD50Matrix, v) }
+
+const (
+	labEpsilon = 216.0 / 24389
+	labKappa   = 24389.0 / 27
+)
+
+func labToXYZ(v [3]float64) [3]float64 {
+	l, a, b := v[0], v[1], v[2]
+	f1 := (l + 16) / 116
+	f0 := a/500 + f1
+	f2 := f1 - b/200
+	inv := func(f float64) float64 {
+		if f3 := f * f * f; f3 > labEpsilon {
+			return f3
+		}
+		return (116*f - 16) / labKappa
+	}
+	y := l / labKappa
+	if l > labKappa*labEpsilon {
+		y = f1 * f1 * f1
+	}
+	return d50ToD65([3]float64{inv(f0) * d50White[0], y * d50White[1], inv(f2) * d50White[2]})
+}
+
+func xyzToLab(v [3]float64) [3]float64 {
+	v = d65ToD50(v)
+	var f [3]float64
+	for i := range v {
+		x := v[i] / d50White[i]
+		if x > labEpsilon {
+			f[i] = math.Cbrt(x)
+		} else {
+			f[i] = (labKappa*x + 16) / 116
+		}
+	}
+	return [3]float64{116*f[1] - 16, 500 * (f[0] - f[1]), 200 * (f[1] - f[2])}
+}
+
+func oklabToXYZ(v [3]float64) [3]float64 {
+	lms := mul(okLabToLMS, v)
+	for i, c := range lms {
+		lms[i] = c * c * c
+	}
+	return mul(lmsToXYZ, lms)
+}
+
+func xyzToOKLab(v [3]float64) [3]float64 {
+	lms := mul(xyzToLMS, v)
+	for i, c := range lms {
+		lms[i] = math.Cbrt(c)
+	}
+	return mul(lmsToOKLab, lms)
+}
+
+// toPolar converts the rectangular coordinates of lab or oklab to those
+// of lch or oklch, with the hue in degrees.
+func toPolar(v [3]float64) [3]float64 {
+	h := math.Atan2(v[2], v[1]) * 180 / math.Pi
+	return [3]float64{v[0], math.Hypot(v[1], v[2]), normalizeHue(h)}
+}
+
+func fromPolar(v [3]float64) [3]float64 {
+	h := v[2] * math.Pi / 180
+	return [3]float64{v[0], v[1] * math.Cos(h), v[1] * math.Sin(h)}
+}
+
+func lchToXYZ(v [3]float64) [3]float64   { return labToXYZ(fromPolar(v)) }
+func xyzToLCH(v [3]float64) [3]float64   { return toPolar(xyzToLab(v)) }
+func oklchToXYZ(v [3]float64) [3]float64 { return oklabToXYZ(fromPolar(v)) }
+func xyzToOKLCH(v [3]float64) [3]float64 { return toPolar(xyzToOKLab(v)) }
+
+// hslToRGB converts hue, saturation and lightness, the latter two from 0
+// to 1, to sRGB.
+func hslToRGB(v [3]float64) [3]float64 {
+	h, s, l := normalizeHue(v[0]), v[1], v[2]
+	f := func(n float64) float64 {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		return l - a*math.Max(-1, math.Min(k-3, math.Min(9-k, 1)))
+	}
+	return [3]float64{f(0), f(8), f(4)}
+}
+
+func rgbToHSL(v [3]float64) [3]float64 {
+	r, g, b := v[0], v[1], v[2]
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l := (min + max) / 2
+	d := max - min
+	var h, s float64
+	if d != 0 {
+		if l != 0 && l != 1 {
+			s = (max - l) / math.Min(l, 1-l)
+		}
+		switch max {
+		case r:
+			h = (g-b)/d + 6
+			if g >= b {
+				h = (g - b) / d
+			}
+		case g:
+			h = (b-r)/d + 2
+		default:
+			h = (r-g)/d + 4
+		}
+		h *= 60
+	}
+	return [3]float64{normalizeHue(h), s, l}
+}
+
+func hslToXYZ(v [3]float64) [3]float64 { return srgbToXYZ(hslToRGB(v)) }
+func xyzToHSL(v [3]float64) [3]float64 { return rgbToHSL(xyzToSRGB(v)) }
+
+// hwbToRGB converts hue, whiteness and blackness, the latter two from 0
+// to 1, to sRGB.
+func hwbToRGB(v [3]float64) [3]float64 {
+	w, b := v[1], v[2]
+	if w+b >= 1 {
+		gray := w / (w + b)
+		return [3]float64{gray, gray, gray}
+	}
+	rgb := hslToRGB([3]float64{v[0], 1, 0.5})
+	for i := range rgb {
+		rgb[i] = rgb[i]*(1-w-b) + w
+	}
+	return rgb
+}
+
+func rgbToHWB(v [3]float64) [3]float64 {
+	hsl := rgbToHSL(v)
+	w := math.Min(v[0], math.Min(v[1], v[2]))
+	b := 1 - math.Max(v[0], math.Max(v[1], v[2]))
+	return [3]float64{hsl[0], w, b}
+}
+
+func hwbToXYZ(v [3]float64) [3]float64 { return srgbToXYZ(hwbToRGB(v)) }
+func xyzToHWB(v [3]float64) [3]float64 { return rgbToHWB(xyzToSRGB(v)) }
+
+// normalizeHue returns the hue h in degrees within [0, 360).
+func normalizeHue(h float64) float64 {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
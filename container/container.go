@@ -0,0 +1,144 @@
+// Package container parses and evaluates the queries of @container rules,
+// following CSS Conditional Rules Level 5
+// (https://www.w3.org/TR/css-conditional-5/#container-queries).
+package container
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/media"
+)
+
+// List is the list of container conditions of a @container rule. A rule
+// applies if any of its conditions matches.
+type List []*Query
+
+// Query is a container condition: a query of the nearest container with
+// the given name.
+//
+//	[ <container-name> ]? <container-query>?
+type Query struct {
+	// Name is the name of the queried container, or "" for the nearest
+	// container able to answer the query.
+	Name string
+	// Cond is the condition on the container, or nil.
+	Cond Condition
+}
+
+// Condition is a container query: *And, *Or, *Not, *Size, *StyleQuery or
+// *GeneralEnclosed. Inside a StyleQuery, And, Or and Not combine *Style
+// features and general enclosed expressions.
+type Condition interface {
+	String() string
+	condition()
+}
+
+// And is a conjunction of conditions.
+type And struct {
+	Conds []Condition
+}
+
+// Or is a disjunction of conditions.
+type Or struct {
+	Conds []Condition
+}
+
+// Not is the negation of a condition.
+type Not struct {
+	Cond Condition
+}
+
+// Size is a size feature, like (min-width: 400px) or (inline-size > 30em).
+type Size struct {
+	// Feature is a *media.Feature or a *media.Range.
+	Feature media.Condition
+}
+
+// Style is a style feature of a style() query, like --x: y, or a custom
+// property name alone.
+type Style struct {
+	// Property is the property name, lowercased unless it is a custom
+	// property.
+	Property string
+	// Value is the value as written, or "" for a property name alone.
+	Value string
+}
+
+// StyleQuery is a style() query.
+type StyleQuery struct {
+	Cond Condition
+}
+
+// GeneralEnclosed is a parenthesized expression or a function that is
+// not a container query, kept for future syntax. It evaluates to
+// Unknown.
+type GeneralEnclosed struct {
+	Text string
+}
+
+func (*And) condition()             {}
+func (*Or) condition()              {}
+func (*Not) condition()             {}
+func (*Size) condition()            {}
+func (*Style) condition()           {}
+func (*StyleQuery) condition()      {}
+func (*GeneralEnclosed) condition() {}
+
+// String returns the list in canonical form.
+func (l List) String() string {
+	queries := make([]string, len(l))
+	for i, q := range l {
+		queries[i] = q.String()
+	}
+	return strings.Join(queries, ", ")
+}
+
+// String returns the query in canonical form.
+func (q *Query) String() string {
+	switch {
+	case q.Cond == nil:
+		return q.Name
+	case q.Name == "":
+		return q.Cond.String()
+	}
+	return q.Name + " " + q.Cond.String()
+}
+
+func (c *And) String() string             { return join(c.Conds, " and ") }
+func (c *Or) String() string              { return join(c.Conds, " or ") }
+func (c *Not) String() string             { return "not " + inParens(c.Cond) }
+func (s *Size) String() string            { return s.Feature.String() }
+func (q *StyleQuery) String() string      { return "style(" + styleArg(q.Cond) + ")" }
+func (g *GeneralEnclosed) String() string { return g.Text }
+
+func (s *Style) String() string {
+	if s.Value == "" {
+		return "(" + s.Property + ")"
+	}
+	return "(" + s.Property + ": " + s.Value + ")"
+}
+
+// styleArg returns the text of c as the argument of style().
+func styleArg(c Condition) string {
+	if s, ok := c.(*Style); ok {
+		return strings.TrimSuffix(strings.TrimPrefix(s.String(), "("), ")")
+	}
+	return c.String()
+}
+
+func join(conds []Condition, sep string) string {
+	parts := make([]string, len(conds))
+	for i, c := range conds {
+		parts[i] = inParens(c)
+	}
+	return strings.Join(parts, sep)
+}
+
+// inParens returns the text of c as an operand of and, or and not.
+func inParens(c Condition) string {
+	switch c.(type) {
+	case *And, *Or, *Not:
+		return "(" + c.String() + ")"
+	}
+	return c.String()
+}
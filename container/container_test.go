@@ -0,0 +1,181 @@
+package container
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ttacon/css/media"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/scanner"
+	"github.com/ttacon/pretty"
+)
+
+func TestParse(t *testing.T) {
+	px := func(n float64) *media.Value { return &media.Value{Num: n, Unit: "px"} }
+	tests := []struct {
+		in   string
+		want List
+	}{
+		{"(min-width: 400px)", List{{Cond: &Size{Feature: &media.Feature{Name: "min-width", Value: px(400)}}}}},
+		{"card", List{{Name: "card"}}},
+		{"Card (400px < Inline-Size)", List{{Name: "Card", Cond: &Size{
+			Feature: &media.Range{Name: "inline-size", Left: px(400), LeftOp: media.LT},
+		}}}},
+		{"sidebar (min-width: 400px) and style(--x: y)", List{{Name: "sidebar", Cond: &And{Conds: []Condition{
+			&Size{Feature: &media.Feature{Name: "min-width", Value: px(400)}},
+			&StyleQuery{Cond: &Style{Property: "--x", Value: "y"}},
+		}}}}},
+		{"not (orientation: portrait)", List{{Cond: &Not{Cond: &Size{
+			Feature: &media.Feature{Name: "orientation", Value: &media.Value{Ident: "portrait"}},
+		}}}}},
+		{"style((--Theme: dark) or (not (--compact)))", List{{Cond: &StyleQuery{Cond: &Or{Conds: []Condition{
+			&Style{Property: "--Theme", Value: "dark"},
+			&Not{Cond: &Style{Property: "--compact"}},
+		}}}}}},
+		{"style(Color: rgb(0 0  0))", List{{Cond: &StyleQuery{Cond: &Style{Property: "color", Value: "rgb(0 0  0)"}}}}},
+		{"a (width > 1px), scroll-state(stuck: top)", List{
+			{Name: "a", Cond: &Size{Feature: &media.Range{Name: "width", RightOp: media.GT, Right: px(1)}}},
+			{Cond: &GeneralEnclosed{Text: "scroll-state(stuck: top)"}},
+		}},
+		{"((width > 1px) or (foo bar))", List{{Cond: &Or{Conds: []Condition{
+			&Size{Feature: &media.Range{Name: "width", RightOp: media.GT, Right: px(1)}},
+			&GeneralEnclosed{Text: "(foo bar)"},
+		}}}}},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %s, want %s", test.in, pretty.Sprintf("%# v", got), pretty.Sprintf("%# v", test.want))
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in, err string
+	}{
+		{"", `container: invalid query "": unexpected end of query`},
+		{"none (width > 1px)", `container: invalid query "none (width > 1px)": unexpected "none"`},
+		{"a b", `container: invalid query "a b": unexpected "b"`},
+		{"(a: 1px) and (b: 2px) or (c: 3px)", `container: invalid query "(a: 1px) and (b: 2px) or (c: 3px)": unexpected "or"`},
+		{"a, ", `container: invalid query "": unexpected end of query`},
+		{"(width > 1px", `container: invalid query "(width > 1px": unexpected "("`},
+	}
+	for _, test := range tests {
+		if _, err := Parse(test.in); err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v, want %s", test.in, err, test.err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"card   (MIN-WIDTH:400PX)", "card (min-width: 400px)"},
+		{"not ((width>1px) and style(--x:y))", "not ((width > 1px) and style(--x: y))"},
+		{"style((--a) and (not (--b: c))) , b", "style((--a) and (not (--b: c))), b"},
+	}
+	for _, test := range tests {
+		l, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if got := l.String(); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	var (
+		page = &Container{Names: []string{"page"}, Type: "size", Width: 1200, Height: 800,
+			Styles: map[string]string{"--theme": "dark", "color": "rgb(0, 0, 0)"}}
+		card = &Container{Names: []string{"card", "box"}, Type: "inline-size", Width: 300, Height: 500,
+			Styles: map[string]string{"--compact": ""}}
+		side      = &Container{Names: []string{"side"}, Type: "inline-size", Width: 200, Height: 600, Vertical: true}
+		ancestors = []*Container{card, page}
+	)
+	tests := []struct {
+		query     string
+		ancestors []*Container
+		want      bool
+	}{
+		{"(width < 400px)", ancestors, true},
+		{"(inline-size > 20em)", ancestors, false},
+		{"(height > 600px)", ancestors, true},
+		{"page (width > 1000px)", ancestors, true},
+		{"box (min-width: 300px)", ancestors, true},
+		{"(orientation: landscape)", ancestors, true},
+		{"(aspect-ratio > 1) and (width < 1000px)", ancestors, false},
+		{"(width < 50vw)", ancestors, false},
+		{"card", ancestors, true},
+		{"missing", ancestors, false},
+		{"missing, card", ancestors, true},
+		{"(hover)", ancestors, false},
+		{"not (hover)", ancestors, false},
+		{"style(--theme: dark)", ancestors, false},
+		{"page style(--theme:  dark)", ancestors, true},
+		{"style(--compact)", ancestors, false},
+		{"style(not (--theme: dark))", ancestors, true},
+		{"page style(color: rgb(0, 0, 0))", ancestors, true},
+		{"style(color: red)", ancestors, false},
+		{"(inline-size > 100px) and (height < 700px)", []*Container{side}, true},
+		{"(width > 100px)", []*Container{side}, false},
+		{"(width > 100px)", nil, false},
+	}
+	for _, test := range tests {
+		l, err := Parse(test.query)
+		if err != nil {
+			t.Errorf("%q: %v", test.query, err)
+			continue
+		}
+		if got := l.Match(test.ancestors); got != test.want {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestUndeclared(t *testing.T) {
+	src := `.layout { container-type: size; container-name: layout; }
+.card { container: card / inline-size; }
+.nav { container-name: nav; }
+.plain { container-type: normal; }
+@container (width > 30em) { .a { color: red; } }
+@container layout (aspect-ratio > 1) { .b { color: red; } }
+@container card (block-size > 10em) { .c { color: red; } }
+@container card (inline-size > 10em), sidebar (width > 1px) { .d { color: red; } }
+@container nav (block-size > 1px) { .e { color: red; } }
+@container style(--x: y) { .f { color: red; } }
+@container card style(--x: y) { .g { color: red; } }
+@container card and { .h { color: red; } }
+`
+	ss, err := parser.New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decls []string
+	for _, d := range Declared(ss) {
+		decls = append(decls, fmt.Sprintf("%v/%s", d.Names, d.Type))
+	}
+	wantDecls := []string{"[layout]/size", "[card]/inline-size", "[nav]/"}
+	if !reflect.DeepEqual(decls, wantDecls) {
+		t.Errorf("got declarations %q, want %q", decls, wantDecls)
+	}
+
+	var got []string
+	for _, ref := range Undeclared(ss) {
+		got = append(got, ref.Query.String())
+	}
+	want := []string{"card (block-size > 10em)", "sidebar (width > 1px)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
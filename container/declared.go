@@ -0,0 +1,148 @@
+package container
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+)
+
+// Declaration is a container established by the container-name,
+// container-type and container declarations in the block of a rule.
+type Declaration struct {
+	// Rule is the rule holding the declarations.
+	Rule ast.Node
+	// Names are the names of the container.
+	Names []string
+	// Type is the container type, or "" if the rule only names the
+	// container and its type is set elsewhere.
+	Type string
+}
+
+// Declared returns the containers declared in ss, in order. Rules setting
+// container-type to normal without naming the container are left out.
+func Declared(ss *ast.Stylesheet) []*Declaration {
+	var decls []*Declaration
+	ast.Inspect(ss, func(n ast.Node) bool {
+		var b *ast.Block
+		switch n := n.(type) {
+		case *ast.QualifiedRule:
+			b = n.Block
+		case *ast.AtRule:
+			b = n.Block
+		}
+		if b == nil || b.DeclList == nil {
+			return true
+		}
+		d := &Declaration{Rule: n}
+		set := false
+		for _, decl := range b.DeclList.Declarations {
+			values := decl.Components
+			switch strings.ToLower(decl.Ident) {
+			case "container-name":
+				d.Names = names(values)
+			case "container-type":
+				d.Type = containerType(values)
+			case "container":
+				// container: <container-name> [ / <container-type> ]?
+				d.Names, d.Type = names(values), "normal"
+				for i, v := range values {
+					if v == "/" {
+						d.Names, d.Type = names(values[:i]), containerType(values[i+1:])
+						break
+					}
+				}
+			default:
+				continue
+			}
+			set = true
+		}
+		if set && (len(d.Names) > 0 || d.Type != "" && d.Type != "normal") {
+			decls = append(decls, d)
+		}
+		return true
+	})
+	return decls
+}
+
+// Reference is a container query of a @container rule.
+type Reference struct {
+	Rule  *ast.AtRule
+	Query *Query
+}
+
+// Undeclared returns the queries of the @container rules of ss that no
+// container declared in ss can answer: those naming a container that is
+// never declared, and those using size features no container with the
+// right name and type can answer. Queries of rules with a malformed
+// prelude are left out.
+func Undeclared(ss *ast.Stylesheet) []*Reference {
+	var (
+		decls = Declared(ss)
+		refs  []*Reference
+	)
+	ast.Inspect(ss, func(n ast.Node) bool {
+		r, ok := n.(*ast.AtRule)
+		if !ok || !strings.EqualFold(r.AtKeyword, "@container") {
+			return true
+		}
+		l, err := Parse(r.Any)
+		if err != nil {
+			return true
+		}
+		for _, q := range l {
+			if !declared(q, decls) {
+				refs = append(refs, &Reference{Rule: r, Query: q})
+			}
+		}
+		return true
+	})
+	return refs
+}
+
+// declared reports whether any of decls can answer q. As the writing mode
+// is unknown, width and height are answered by inline-size containers.
+func declared(q *Query, decls []*Declaration) bool {
+	features := q.SizeFeatures()
+	if q.Name == "" && len(features) == 0 {
+		// Every element is a container for style queries.
+		return true
+	}
+outer:
+	for _, d := range decls {
+		if q.Name != "" && !contains(d.Names, q.Name) {
+			continue
+		}
+		if d.Type == "" {
+			return true
+		}
+		horizontal := &Container{Type: d.Type}
+		vertical := &Container{Type: d.Type, Vertical: true}
+		for _, f := range features {
+			if !horizontal.answers(f) && !vertical.answers(f) {
+				continue outer
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// names returns the container names of a container-name value.
+func names(values []string) []string {
+	if len(values) == 1 && strings.EqualFold(values[0], "none") {
+		return nil
+	}
+	return values
+}
+
+// containerType returns the container type of a container-type value,
+// ignoring keywords other than size and inline-size.
+func containerType(values []string) string {
+	for _, v := range values {
+		switch v = strings.ToLower(v); v {
+		case "size", "inline-size":
+			return v
+		}
+	}
+	return "normal"
+}
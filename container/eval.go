@@ -0,0 +1,275 @@
+package container
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/media"
+)
+
+// Container describes an element established as a query container, as
+// the container queries of its descendants see it.
+type Container struct {
+	// Names are the names given by container-name.
+	Names []string
+	// Type is the value of container-type: "size", "inline-size" or
+	// "normal". Every element is a container for style queries, but only
+	// size and inline-size containers answer size queries, and an
+	// inline-size container only on its inline axis.
+	Type string
+	// Width and Height are the size of the content box in pixels.
+	Width, Height float64
+	// FontSize is the font size of the container in pixels, which em is
+	// relative to. It defaults to 16.
+	FontSize float64
+	// Vertical is set if the writing mode of the container is vertical,
+	// which makes its inline axis the vertical one.
+	Vertical bool
+	// Styles holds the computed values of the properties of the
+	// container, like "--theme": "dark".
+	Styles map[string]string
+}
+
+// Match reports whether any query of l matches its container among
+// ancestors, which are ordered from the nearest.
+func (l List) Match(ancestors []*Container) bool {
+	for _, q := range l {
+		if q.Match(ancestors) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether q matches its container among ancestors, which
+// are ordered from the nearest. A query without a container or evaluating
+// to Unknown does not match.
+func (q *Query) Match(ancestors []*Container) bool {
+	c := q.Container(ancestors)
+	if c == nil {
+		return false
+	}
+	return q.Cond == nil || Eval(q.Cond, c) == media.True
+}
+
+// Container returns the container q is evaluated against: the nearest of
+// ancestors with the name of q that can answer all its size features, or
+// nil.
+func (q *Query) Container(ancestors []*Container) *Container {
+	features := q.SizeFeatures()
+outer:
+	for _, c := range ancestors {
+		if q.Name != "" && !contains(c.Names, q.Name) {
+			continue
+		}
+		for _, f := range features {
+			if !c.answers(f) {
+				continue outer
+			}
+		}
+		return c
+	}
+	return nil
+}
+
+// SizeFeatures returns the names of the size features q uses, without
+// their min- and max- prefixes.
+func (q *Query) SizeFeatures() []string {
+	var names []string
+	var collect func(c Condition)
+	collect = func(c Condition) {
+		switch c := c.(type) {
+		case *And:
+			for _, c := range c.Conds {
+				collect(c)
+			}
+		case *Or:
+			for _, c := range c.Conds {
+				collect(c)
+			}
+		case *Not:
+			collect(c.Cond)
+		case *Size:
+			names = append(names, featureName(c.Feature))
+		}
+	}
+	collect(q.Cond)
+	return names
+}
+
+// Eval evaluates c against the container ctr.
+func Eval(c Condition, ctr *Container) media.Result {
+	switch c := c.(type) {
+	case *And:
+		r := media.True
+		for _, c := range c.Conds {
+			switch Eval(c, ctr) {
+			case media.False:
+				return media.False
+			case media.Unknown:
+				r = media.Unknown
+			}
+		}
+		return r
+	case *Or:
+		r := media.False
+		for _, c := range c.Conds {
+			switch Eval(c, ctr) {
+			case media.True:
+				return media.True
+			case media.Unknown:
+				r = media.Unknown
+			}
+		}
+		return r
+	case *Not:
+		switch Eval(c.Cond, ctr) {
+		case media.True:
+			return media.False
+		case media.False:
+			return media.True
+		}
+		return media.Unknown
+	case *Size:
+		return ctr.size(c.Feature)
+	case *StyleQuery:
+		return Eval(c.Cond, ctr)
+	case *Style:
+		return ctr.style(c)
+	}
+	return media.Unknown
+}
+
+// sizeFeatures are the size features, which name the physical or the
+// logical dimensions of the container.
+var sizeFeatures = map[string]bool{
+	"width":        true,
+	"height":       true,
+	"inline-size":  true,
+	"block-size":   true,
+	"aspect-ratio": true,
+	"orientation":  true,
+}
+
+// featureName returns the name of the media feature f without its min-
+// or max- prefix.
+func featureName(f media.Condition) string {
+	var name string
+	switch f := f.(type) {
+	case *media.Feature:
+		name = f.Name
+		if strings.HasPrefix(name, "min-") || strings.HasPrefix(name, "max-") {
+			name = name[4:]
+		}
+	case *media.Range:
+		name = f.Name
+	}
+	return name
+}
+
+// answers reports whether c can answer queries of the size feature name.
+// Unknown features evaluate to Unknown on every container.
+func (c *Container) answers(name string) bool {
+	size := c.Type == "size"
+	inline := size || c.Type == "inline-size"
+	switch name {
+	case "inline-size":
+		return inline
+	case "width":
+		return size || inline && !c.Vertical
+	case "height":
+		return size || inline && c.Vertical
+	case "block-size", "aspect-ratio", "orientation":
+		return size
+	}
+	return true
+}
+
+// viewportUnits are the units that are relative to the viewport, whose
+// size is unknown.
+var viewportUnits = map[string]bool{
+	"vw": true, "vh": true, "vi": true, "vb": true, "vmin": true, "vmax": true,
+	"svw": true, "svh": true, "lvw": true, "lvh": true, "dvw": true, "dvh": true,
+}
+
+// size evaluates the size feature f, a *media.Feature or a *media.Range,
+// as the media feature of a viewport the size of c.
+func (c *Container) size(f media.Condition) media.Result {
+	name := featureName(f)
+	if !sizeFeatures[name] || !c.answers(name) {
+		return media.Unknown
+	}
+	physical := name
+	switch name {
+	case "inline-size":
+		physical = "width"
+		if c.Vertical {
+			physical = "height"
+		}
+	case "block-size":
+		physical = "height"
+		if c.Vertical {
+			physical = "width"
+		}
+	}
+	var q media.Condition
+	switch f := f.(type) {
+	case *media.Feature:
+		if f.Value != nil && viewportUnits[f.Value.Unit] {
+			return media.Unknown
+		}
+		g := *f
+		g.Name = strings.Replace(f.Name, name, physical, 1)
+		q = &g
+	case *media.Range:
+		for _, v := range []*media.Value{f.Left, f.Right} {
+			if v != nil && viewportUnits[v.Unit] {
+				return media.Unknown
+			}
+		}
+		g := *f
+		g.Name = physical
+		q = &g
+	}
+	env := &media.Env{Width: c.Width, Height: c.Height, FontSize: c.FontSize}
+	return media.Eval(q, env)
+}
+
+// style evaluates the style feature s. Custom properties missing from
+// c.Styles have their initial, guaranteed-invalid value; other missing
+// properties are Unknown.
+func (c *Container) style(s *Style) media.Result {
+	v, ok := c.Styles[s.Property]
+	custom := strings.HasPrefix(s.Property, "--")
+	switch {
+	case !ok && !custom:
+		return media.Unknown
+	case s.Value == "" && !custom:
+		// Whether v is the initial value of the property is unknown.
+		return media.Unknown
+	case s.Value == "":
+		return result(ok && strings.TrimSpace(v) != "")
+	}
+	return result(ok && normalize(v) == normalize(s.Value))
+}
+
+// normalize collapses the whitespace of the value v, so that values
+// differing only in whitespace compare equal.
+func normalize(v string) string {
+	return strings.Join(strings.Fields(v), " ")
+}
+
+func result(b bool) media.Result {
+	if b {
+		return media.True
+	}
+	return media.False
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ttacon/css/media"
+	"github.com/ttacon/css/scanner"
+)
+
+// Parse parses the prelude of a @container rule, a comma-separated list
+// of container conditions:
+//
+//	<container-condition> = [ <container-name> ]? <container-query>?
+//
+// A malformed condition invalidates the whole @container rule.
+func Parse(s string) (List, error) {
+	var toks []*scanner.Token
+	sc := scanner.New(s)
+	for t := sc.Next(); t.Type != scanner.TokenEOF; t = sc.Next() {
+		if t.Type == scanner.TokenError {
+			return nil, fmt.Errorf("container: %s in %q", t.Value, s)
+		}
+		toks = append(toks, t)
+	}
+
+	var l List
+	for _, part := range split(toks) {
+		p := &queryParser{src: s, toks: significant(part)}
+		q, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, q)
+	}
+	return l, nil
+}
+
+type queryParser struct {
+	src  string
+	toks []*scanner.Token
+	pos  int
+}
+
+// errorf returns an error about the condition of p, blaming the current
+// token.
+func (p *queryParser) errorf() error {
+	var (
+		q   = strings.TrimSpace(p.text(p.toks))
+		got = "end of query"
+	)
+	if t := p.tok(); t != nil {
+		got = fmt.Sprintf("%q", t.Value)
+	}
+	return fmt.Errorf("container: invalid query %q: unexpected %s", q, got)
+}
+
+// tok returns the current token, or nil at the end of the condition.
+func (p *queryParser) tok() *scanner.Token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return nil
+}
+
+// keyword returns the lowercased value of the current token if it is an
+// ident, or "".
+func (p *queryParser) keyword() string {
+	if t := p.tok(); t != nil && t.Type == scanner.TokenIdent {
+		return strings.ToLower(t.Value)
+	}
+	return ""
+}
+
+// text returns the source text spanned by toks.
+func (p *queryParser) text(toks []*scanner.Token) string {
+	if len(toks) == 0 {
+		return ""
+	}
+	last := toks[len(toks)-1]
+	return p.src[toks[0].Offset : last.Offset+len(last.Value)]
+}
+
+// parseQuery parses a whole container condition.
+func (p *queryParser) parseQuery() (*Query, error) {
+	if len(p.toks) == 0 {
+		return nil, p.errorf()
+	}
+	q := &Query{}
+	switch kw := p.keyword(); kw {
+	case "":
+	case "not":
+		// "not" is not a valid name, and starts the query.
+	case "none", "and", "or", "initial", "inherit", "unset", "revert", "revert-layer", "default":
+		return nil, p.errorf()
+	default:
+		q.Name = p.tok().Value
+		p.pos++
+	}
+	if p.tok() != nil {
+		c, err := p.parseCondition(false)
+		if err != nil {
+			return nil, err
+		}
+		q.Cond = c
+	}
+	if p.tok() != nil {
+		return nil, p.errorf()
+	}
+	return q, nil
+}
+
+// parseCondition parses a container query, or the argument of style() if
+// style is set:
+//
+//	not <query-in-parens>
+//	| <query-in-parens> [ [ and <query-in-parens> ]* | [ or <query-in-parens> ]* ]
+func (p *queryParser) parseCondition(style bool) (Condition, error) {
+	if p.keyword() == "not" {
+		p.pos++
+		c, err := p.parseInParens(style)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Cond: c}, nil
+	}
+	c, err := p.parseInParens(style)
+	if err != nil {
+		return nil, err
+	}
+	op := p.keyword()
+	if op != "and" && op != "or" {
+		return c, nil
+	}
+	conds := []Condition{c}
+	for p.keyword() == op {
+		p.pos++
+		c, err := p.parseInParens(style)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, c)
+	}
+	if op == "and" {
+		return &And{Conds: conds}, nil
+	}
+	return &Or{Conds: conds}, nil
+}
+
+// parseInParens parses a parenthesized query, a size feature, a style()
+// query or a general enclosed expression. If style is set, it parses style
+// features instead of size features, and style() is not allowed.
+func (p *queryParser) parseInParens(style bool) (Condition, error) {
+	t := p.tok()
+	if t == nil || !(t.Type == scanner.TokenFunction || isChar(t, "(")) {
+		return nil, p.errorf()
+	}
+	end := p.closing()
+	if end < 0 {
+		return nil, p.errorf()
+	}
+	inner := p.toks[p.pos+1 : end]
+	text := p.text(p.toks[p.pos : end+1])
+	p.pos = end + 1
+
+	if t.Type == scanner.TokenFunction {
+		if !style && strings.EqualFold(t.Value, "style(") {
+			sub := &queryParser{src: p.src, toks: inner}
+			if c, err := sub.parseCondition(true); err == nil && sub.tok() == nil {
+				return &StyleQuery{Cond: c}, nil
+			}
+			if s := p.parseStyle(inner); s != nil {
+				return &StyleQuery{Cond: s}, nil
+			}
+		}
+		return &GeneralEnclosed{Text: text}, nil
+	}
+
+	sub := &queryParser{src: p.src, toks: inner}
+	if c, err := sub.parseCondition(style); err == nil && sub.tok() == nil {
+		return c, nil
+	}
+	if style {
+		if s := p.parseStyle(inner); s != nil {
+			return s, nil
+		}
+	} else if f := media.ParseFeature(p.text(inner)); f != nil {
+		return &Size{Feature: f}, nil
+	}
+	return &GeneralEnclosed{Text: text}, nil
+}
+
+// parseStyle parses the tokens of a style feature without its
+// parentheses, or returns nil:
+//
+//	<style-feature> = <declaration> | <property-name>
+func (p *queryParser) parseStyle(toks []*scanner.Token) *Style {
+	if len(toks) == 0 || toks[0].Type != scanner.TokenIdent {
+		return nil
+	}
+	name := toks[0].Value
+	if !strings.HasPrefix(name, "--") {
+		name = strings.ToLower(name)
+	}
+	switch {
+	case len(toks) == 1:
+		return &Style{Property: name}
+	case len(toks) == 2 || !isChar(toks[1], ":"):
+		return nil
+	}
+	for _, t := range toks[2:] {
+		if t.Type == scanner.TokenChar && strings.ContainsAny(t.Value, ";{}") {
+			return nil
+		}
+	}
+	return &Style{Property: name, Value: p.text(toks[2:])}
+}
+
+// closing returns the index of the parenthesis closing the current token,
+// or -1.
+func (p *queryParser) closing() int {
+	depth := 0
+	for i := p.pos; i < len(p.toks); i++ {
+		t := p.toks[i]
+		switch {
+		case t.Type == scanner.TokenFunction || isChar(t, "("):
+			depth++
+		case isChar(t, ")"):
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isChar(t *scanner.Token, c string) bool {
+	return t.Type == scanner.TokenChar && t.Value == c
+}
+
+// significant returns toks without whitespace and comments.
+func significant(toks []*scanner.Token) []*scanner.Token {
+	var sig []*scanner.Token
+	for _, t := range toks {
+		if t.Type != scanner.TokenS && t.Type != scanner.TokenComment {
+			sig = append(sig, t)
+		}
+	}
+	return sig
+}
+
+// split splits toks at the commas outside parentheses.
+func split(toks []*scanner.Token) [][]*scanner.Token {
+	var (
+		parts [][]*scanner.Token
+		start int
+		depth int
+	)
+	for i, t := range toks {
+		switch {
+		case t.Type == scanner.TokenFunction || isChar(t, "("):
+			depth++
+		case isChar(t, ")"):
+			depth--
+		case isChar(t, ",") && depth == 0:
+			parts = append(parts, toks[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, toks[start:])
+}
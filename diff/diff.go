@@ -0,0 +1,300 @@
+// Package diff compares stylesheets semantically: it reports the rules
+// added, removed and moved between two stylesheets, and the declarations
+// changed in the rules they share, ignoring the differences that do not
+// change their meaning.
+//
+// Rules are identified by their context, the at-rules and style rules they
+// are nested in, like @media print, and by their selectors, whose order
+// does not matter, or for other rules by their at-keyword and prelude, like
+// @keyframes spin. The rules of a stylesheet with the same identity are
+// merged, their declarations taken in order. Whitespace, comments, quotes,
+// the syntax of colors and numbers, and the form of shorthand values are
+// ignored, declarations overridden by later or more important ones are
+// left out, and complete sets of longhands are compared as their
+// shorthand.
+//
+// Merge merges the changes from a stylesheet to another into a third one
+// derived from the first, reporting the properties changed on both sides.
+package diff
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/shorthand"
+)
+
+// Kind is the kind of a change.
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Changed
+	// Moved is the kind of a rule found in another context, like in a
+	// different @media rule or @layer.
+	Moved
+)
+
+var kinds = [...]string{
+	Added:   "added",
+	Removed: "removed",
+	Changed: "changed",
+	Moved:   "moved",
+}
+
+func (k Kind) String() string {
+	return kinds[k]
+}
+
+// Change is a change to a rule.
+type Change struct {
+	Kind Kind
+	// Context holds the at-rules and style rules the rule is nested in,
+	// outermost first, like "@media print" or ".card".
+	Context []string
+	// OldContext is the context of a moved rule in the old stylesheet.
+	OldContext []string
+	// Rule identifies the rule in its context: the selectors of a style
+	// rule or a keyframe, or the at-keyword and prelude of other rules,
+	// like "@font-face" followed by the font family. The declarations of
+	// the rules nested in a style rule without a selector, like @media,
+	// are given to the rule "&".
+	Rule string
+	// Declarations holds the declarations changed, all of them for added
+	// and removed rules.
+	Declarations []*DeclarationChange
+}
+
+// DeclarationChange is a change to the declarations of a property.
+type DeclarationChange struct {
+	// Kind is Added, Removed or Changed.
+	Kind     Kind
+	Property string
+	// Old and New hold the normalized values of the declarations of the
+	// property in the old and new rules, in order, followed by !important
+	// for important ones.
+	Old, New []string
+}
+
+// Diff returns the changes from the stylesheet old to new: those to the
+// rules of new in order, followed by the rules removed from old.
+func Diff(old, new *ast.Stylesheet) []*Change {
+	a, b := flatten(old), flatten(new)
+	var (
+		changes []*Change
+		added   = map[string][]*Change{}
+	)
+	for _, r := range b.list {
+		o := a.rules[r.id()]
+		if o == nil {
+			c := &Change{Kind: Added, Context: r.context, Rule: r.key, Declarations: compare(nil, r)}
+			changes = append(changes, c)
+			added[r.key] = append(added[r.key], c)
+			continue
+		}
+		if decls := compare(o, r); len(decls) > 0 {
+			changes = append(changes, &Change{Kind: Changed, Context: r.context, Rule: r.key, Declarations: decls})
+		}
+	}
+
+	var removed []*rule
+	for _, r := range a.list {
+		if b.rules[r.id()] == nil {
+			removed = append(removed, r)
+		}
+	}
+	count := map[string]int{}
+	for _, r := range removed {
+		count[r.key]++
+	}
+	for _, r := range removed {
+		// A rule added and removed once in different contexts moved.
+		if cs := added[r.key]; len(cs) == 1 && count[r.key] == 1 {
+			c := cs[0]
+			c.Kind, c.OldContext = Moved, r.context
+			c.Declarations = compare(r, b.rules[c.id()])
+			continue
+		}
+		changes = append(changes, &Change{Kind: Removed, Context: r.context, Rule: r.key, Declarations: compare(r, nil)})
+	}
+	return changes
+}
+
+func (c *Change) id() string {
+	return id(append(append([]string(nil), c.Context...), c.Rule))
+}
+
+// compare returns the changes to the declarations from the rule a to b,
+// either of which may be nil.
+func compare(a, b *rule) []*DeclarationChange {
+	aprops, av := normalized(a)
+	bprops, bv := normalized(b)
+	var (
+		props []string
+		seen  = map[string]bool{}
+	)
+	for _, p := range append(aprops, bprops...) {
+		if !seen[p] {
+			seen[p] = true
+			props = append(props, p)
+		}
+	}
+	var changes []*DeclarationChange
+	for _, p := range props {
+		old, new := av[p], bv[p]
+		c := &DeclarationChange{Kind: Changed, Property: p, Old: old, New: new}
+		switch {
+		case old == nil:
+			c.Kind = Added
+		case new == nil:
+			c.Kind = Removed
+		case equal(old, new):
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// normalized returns the properties of the effective declarations of r,
+// which may be nil, in order, and their normalized values.
+func normalized(r *rule) ([]string, map[string][]string) {
+	var (
+		props  []string
+		values = map[string][]string{}
+	)
+	if r == nil {
+		return nil, values
+	}
+	for _, d := range shorthand.Collapse(effective(r.decls())) {
+		p := property(d)
+		v := value(p, d.Components)
+		if d.Important {
+			v += " !important"
+		}
+		if values[p] == nil {
+			props = append(props, p)
+		}
+		values[p] = append(values[p], v)
+	}
+	return props, values
+}
+
+// effective returns decls without the declarations later ones override:
+// those of a property a later declaration sets too, unless it is less
+// important, and those of a property a more important one sets. A
+// shorthand declaration partly overridden is replaced by the longhands
+// left. Declarations of the same property and importance are kept, as
+// fallbacks for the browsers that don't support the later ones.
+func effective(decls []*ast.Declaration) []*ast.Declaration {
+	var out []*ast.Declaration
+	for _, d := range decls {
+		var (
+			next []*ast.Declaration
+			keep = []*ast.Declaration{d}
+		)
+		for _, e := range out {
+			switch {
+			case property(e) == property(d) && e.Important == d.Important || !overlap(e, d):
+				next = append(next, e)
+			case e.Important && !d.Important:
+				next = append(next, e)
+				keep = without(keep, e)
+			default:
+				next = append(next, without([]*ast.Declaration{e}, d)...)
+			}
+		}
+		out = append(next, keep...)
+	}
+	return out
+}
+
+// without returns decls without the longhands the declaration o sets,
+// shorthands it sets part of being expanded into their longhands. A
+// shorthand that cannot be expanded, like one with var(), is kept.
+func without(decls []*ast.Declaration, o *ast.Declaration) []*ast.Declaration {
+	var out []*ast.Declaration
+	for _, d := range decls {
+		switch {
+		case !overlap(d, o):
+			out = append(out, d)
+		case subset(longhands(property(d)), longhands(property(o))):
+		default:
+			expanded, err := shorthand.Expand(d)
+			if err != nil || len(expanded) == 1 {
+				out = append(out, d)
+				continue
+			}
+			out = append(out, without(expanded, o)...)
+		}
+	}
+	return out
+}
+
+// longhands returns the longhands the property p sets: p itself, or the
+// longhands of a shorthand.
+func longhands(p string) []string {
+	if l := shorthand.Longhands(p); l != nil {
+		return l
+	}
+	return []string{p}
+}
+
+// overlap reports whether the declarations a and b set a longhand in
+// common.
+func overlap(a, b *ast.Declaration) bool {
+	return overlaps(property(a), property(b))
+}
+
+// overlaps reports whether the properties a and b set a longhand in
+// common.
+func overlaps(a, b string) bool {
+	lb := longhands(b)
+	for _, l := range longhands(a) {
+		if contains(lb, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// subset reports whether all of a is in b.
+func subset(a, b []string) bool {
+	for _, s := range a {
+		if !contains(b, s) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// property returns the property of d, lowercased unless it is a custom
+// property.
+func property(d *ast.Declaration) string {
+	if strings.HasPrefix(d.Ident, "--") {
+		return d.Ident
+	}
+	return strings.ToLower(d.Ident)
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
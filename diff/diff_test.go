@@ -0,0 +1,156 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/scanner"
+)
+
+func parse(t *testing.T, src string) *ast.Stylesheet {
+	t.Helper()
+	ss, err := parser.New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ss
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		old, new, want string
+	}{
+		{
+			// Equivalent stylesheets.
+			`/* v1 */ .a,.b>c{color:RED;background:url('x.png') no-repeat;margin:0 0 0 0;width:.50em;font:12px Open  Sans,serif}
+@media screen and (min-width:10px){.c{content:'a'}}`,
+			`.b > c, .a {
+  color: #f00;
+  background: url("x.png") no-repeat;
+  margin: 0;
+  width: 0.5em;
+  font: 12px "Open Sans", serif;
+}
+@media screen and (min-width: 10px) { .c { content: "a" } }`,
+			"",
+		},
+		{
+			`.a { color: red; margin: 0 } .b { top: 0 } .gone { top: 0 }`,
+			`.a { color: blue; padding: 1px } .b { top: 0 !important } .new { top: 0 }`,
+			`~ .a
+    ~ color: #ff0000 -> #0000ff
+    - margin: 0
+    + padding: 1px
+~ .b
+    ~ top: 0 -> 0 !important
++ .new
+    + top: 0
+- .gone
+    - top: 0
+`,
+		},
+		{
+			// Rules moving to another context.
+			`.a { top: 0 } @media print { .b { top: 0 } } @layer base { .c { top: 0 } }`,
+			`@media print { .a { top: 0 } } .b { top: 0 } @layer theme { .c { top: 1px } }`,
+			`> @media print { .a } (from .a)
+> .b (from @media print { .b })
+> @layer theme { .c } (from @layer base { .c })
+    ~ top: 0 -> 1px
+`,
+		},
+		{
+			// Longhands compare as their shorthand, and duplicates as
+			// fallbacks.
+			`.a { margin-top: 0; margin-right: 0; margin-bottom: 0; margin-left: 0; display: -webkit-box; display: flex }`,
+			`.a { margin: 0 } .a { display: flex }`,
+			`~ .a
+    ~ display: -webkit-box; flex -> flex
+`,
+		},
+		{
+			// Overridden declarations are left out.
+			`.a { margin: 0 } .b { top: 0 !important } .c { margin: 1px } .d { margin: 0 }`,
+			`.a { margin-left: 5px; margin: 0 } .b { top: 1px; top: 0 !important } .c { margin: 2px; margin-left: 1px !important; margin: 1px } .d { margin: 0; margin-left: 1px }`,
+			`~ .c
+    - margin: 1px
+    + margin-left: 1px !important
+    + margin-top: 1px
+    + margin-right: 1px
+    + margin-bottom: 1px
+~ .d
+    ~ margin: 0 -> 0 0 0 1px
+`,
+		},
+		{
+			// Custom properties compare token by token.
+			`.a { --x: .5; --y: a/**/ b; --z: RED }`,
+			`.a { --x: 0.5; --y: a  b; --z: red }`,
+			`~ .a
+    ~ --x: .5 -> 0.5
+    ~ --z: RED -> red
+`,
+		},
+		{
+			`@keyframes spin { from { top: 0 } to { top: 1px } }
+@font-face { font-family: A; src: url(a.woff) }
+@import "a.css" screen;
+.x { &:hover { color: red } @media print { top: 0 } }`,
+			`@keyframes spin { 0% { top: 0 } 100% { top: 2px } }
+@font-face { font-family: "A"; src: url(b.woff) }
+@import url(a.css) print;
+.x { &:hover { color: red } @media print { top: 1px } }`,
+			`~ @keyframes spin { 100% }
+    ~ top: 1px -> 2px
+~ @font-face "A"
+    ~ src: url("a.woff") -> url("b.woff")
++ @import url("a.css") print
+~ .x { @media print { & } }
+    ~ top: 0 -> 1px
+- @import url("a.css") screen
+`,
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteText(&buf, Diff(parse(t, test.old), parse(t, test.new))); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("Diff(%s, %s):\n%s\nexpected:\n%s", test.old, test.new, got, test.want)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	changes := Diff(parse(t, `@media print { .a { top: 0 } }`), parse(t, `.a { top: 0; left: 0 }`))
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, changes); err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {
+    "kind": "moved",
+    "context": [],
+    "oldContext": [
+      "@media print"
+    ],
+    "rule": ".a",
+    "declarations": [
+      {
+        "kind": "added",
+        "property": "left",
+        "new": [
+          "0"
+        ]
+      }
+    ]
+  }
+]
+`
+	if got := buf.String(); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
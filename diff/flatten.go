@@ -0,0 +1,264 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/scanner"
+)
+
+// rule is a rule of a flattened stylesheet.
+type rule struct {
+	context []string
+	key     string
+	// places holds the nodes the rule is merged from.
+	places []place
+}
+
+// place is where a node of a rule, or the owner of a container, is in the
+// tree.
+type place struct {
+	// node is the node holding the declarations of the rule, the
+	// statement, like @import, the rule is, or the owner.
+	node ast.Rule
+	// list is the list of rules holding node, nested in parent.
+	list   *[]ast.Rule
+	parent []string
+	// block is the block of node holding the declarations, if any.
+	block *ast.Block
+}
+
+// container is a list of rules nested in a context.
+type container struct {
+	list *[]ast.Rule
+	// owner is the place of the rule whose block holds list, or nil for
+	// the top level.
+	owner *place
+}
+
+func (r *rule) id() string {
+	return id(append(append([]string(nil), r.context...), r.key))
+}
+
+// decls returns the declarations of the rule, in order.
+func (r *rule) decls() []*ast.Declaration {
+	var decls []*ast.Declaration
+	for _, l := range r.places {
+		decls = append(decls, declarations(l.block)...)
+	}
+	return decls
+}
+
+func id(context []string) string {
+	return strings.Join(context, "\x00")
+}
+
+// sheet is a flattened stylesheet: its rules, merged by identity.
+type sheet struct {
+	list  []*rule
+	rules map[string]*rule
+	// containers maps the ids of contexts to their lists of rules.
+	containers map[string]*container
+}
+
+func flatten(ss *ast.Stylesheet) *sheet {
+	s := &sheet{
+		rules:      map[string]*rule{},
+		containers: map[string]*container{"": {list: &ss.Children}},
+	}
+	s.walk(&ss.Children, nil)
+	return s
+}
+
+// add adds the place l to the rule key in the context.
+func (s *sheet) add(context []string, key string, l place) {
+	r := &rule{context: context, key: key}
+	if old := s.rules[r.id()]; old != nil {
+		r = old
+	} else {
+		s.rules[r.id()] = r
+		s.list = append(s.list, r)
+	}
+	r.places = append(r.places, l)
+}
+
+// contain records the block of the rule at owner as the container of the
+// rules nested in context, if there is none yet.
+func (s *sheet) contain(context []string, owner place) {
+	if s.containers[id(context)] == nil {
+		s.containers[id(context)] = &container{list: &owner.block.Rules, owner: &owner}
+	}
+}
+
+// walk adds the rules of list, nested in context.
+func (s *sheet) walk(list *[]ast.Rule, context []string) {
+	for _, r := range *list {
+		at := place{node: r, list: list, parent: context}
+		switch r := r.(type) {
+		case *ast.QualifiedRule:
+			var sels []string
+			for _, c := range r.Components {
+				sels = append(sels, c.Name)
+			}
+			s.block(context, selectors(sels), at, r.Block)
+		case *ast.AtRule:
+			head := strings.ToLower(r.AtKeyword)
+			if p := text(r.Any, false); p != "" {
+				head += " " + p
+			}
+			s.group(context, head, at, r.Block)
+		case *ast.LayerRule:
+			if r.Block == nil {
+				s.add(context, "@layer "+strings.Join(r.Names, ", "), at)
+				continue
+			}
+			head := "@layer"
+			if len(r.Names) > 0 {
+				head += " " + r.Names[0]
+			}
+			s.group(context, head, at, r.Block)
+		case *ast.ScopeRule:
+			head := "@scope"
+			if len(r.Roots) > 0 {
+				head += " (" + selectors(r.Roots) + ")"
+			}
+			if len(r.Limits) > 0 {
+				head += " to (" + selectors(r.Limits) + ")"
+			}
+			if r.Block != nil {
+				at.block = r.Block
+				s.add(nest(context, head), ":scope", at)
+				s.contain(nest(context, head), at)
+				s.walk(&r.Block.Rules, nest(context, head))
+			}
+		case *ast.ImportRule:
+			head := "@import url(" + scanner.Quote(r.URL, '"') + ")"
+			switch {
+			case r.Layered && r.Layer == "":
+				head += " layer"
+			case r.Layered:
+				head += " layer(" + r.Layer + ")"
+			}
+			if r.Supports != "" {
+				head += " supports(" + text(r.Supports, false) + ")"
+			}
+			if r.Media != "" {
+				head += " " + text(r.Media, false)
+			}
+			s.add(context, head, at)
+		case *ast.KeyframesRule:
+			head := strings.ToLower(r.AtKeyword) + " " + r.Name
+			if r.Block != nil {
+				at.block = r.Block
+				s.contain(nest(context, head), at)
+				s.walk(&r.Block.Rules, nest(context, head))
+			}
+		case *ast.Keyframe:
+			s.block(context, keyframeSelectors(r.Selectors), at, r.Block)
+		case *ast.FontFaceRule:
+			s.block(context, fontFace(r), at, r.Block)
+		case *ast.PageRule:
+			head := "@page"
+			if len(r.Selectors) > 0 {
+				head += " " + selectors(r.Selectors)
+			}
+			s.block(context, head, at, r.Block)
+		case *ast.MarginRule:
+			s.block(context, strings.ToLower(r.AtKeyword), at, r.Block)
+		case *ast.CounterStyleRule:
+			s.block(context, "@counter-style "+r.Name, at, r.Block)
+		case *ast.PropertyRule:
+			s.block(context, "@property "+r.Name, at, r.Block)
+		case *ast.FontFeatureValuesRule:
+			head := "@font-feature-values " + strings.Join(r.FontFamilies, ", ")
+			if r.Block != nil {
+				at.block = r.Block
+				s.contain(nest(context, head), at)
+				s.walk(&r.Block.Rules, nest(context, head))
+			}
+		case *ast.FeatureValuesBlock:
+			s.block(context, strings.ToLower(r.AtKeyword), at, r.Block)
+		}
+	}
+}
+
+// block adds the rule key with the declarations of b, and the rules
+// nested in b.
+func (s *sheet) block(context []string, key string, at place, b *ast.Block) {
+	at.block = b
+	s.add(context, key, at)
+	if b != nil {
+		s.contain(nest(context, key), at)
+		s.walk(&b.Rules, nest(context, key))
+	}
+}
+
+// group adds the rules of the group rule head, like @media print. The
+// declarations of a group rule nested in a style rule go to the rule "&";
+// a statement, or a rule with declarations at the top level, is a rule.
+func (s *sheet) group(context []string, head string, at place, b *ast.Block) {
+	at.block = b
+	switch {
+	case b == nil:
+		s.add(context, head, at)
+		return
+	case inStyle(context):
+		s.add(nest(context, head), "&", at)
+	case len(declarations(b)) > 0:
+		s.add(context, head, at)
+	}
+	s.contain(nest(context, head), at)
+	s.walk(&b.Rules, nest(context, head))
+}
+
+// nest returns the context of the rules nested in head in context.
+func nest(context []string, head string) []string {
+	return append(append([]string(nil), context...), head)
+}
+
+// inStyle reports whether the context has a style rule.
+func inStyle(context []string) bool {
+	for _, c := range context {
+		if !strings.HasPrefix(c, "@") {
+			return true
+		}
+	}
+	return false
+}
+
+func declarations(b *ast.Block) []*ast.Declaration {
+	if b == nil || b.DeclList == nil {
+		return nil
+	}
+	return b.DeclList.Declarations
+}
+
+// keyframeSelectors returns the normalized keyframe selectors, from and to
+// being 0% and 100%.
+func keyframeSelectors(list []string) string {
+	var sels []string
+	for _, s := range list {
+		switch s = text(s, false); strings.ToLower(s) {
+		case "from":
+			s = "0%"
+		case "to":
+			s = "100%"
+		}
+		sels = append(sels, s)
+	}
+	return selectors(sels)
+}
+
+// fontFace identifies the @font-face rule r by the family, style and
+// weight it defines.
+func fontFace(r *ast.FontFaceRule) string {
+	key := "@font-face"
+	for _, p := range []string{"font-family", "font-style", "font-weight"} {
+		for _, d := range declarations(r.Block) {
+			if strings.EqualFold(d.Ident, p) {
+				key += " " + value(p, d.Components)
+			}
+		}
+	}
+	return key
+}
@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var textMarks = [...]string{
+	Added:   "+",
+	Removed: "-",
+	Changed: "~",
+	Moved:   ">",
+}
+
+// WriteText writes the changes in a human-readable form, one rule per
+// line followed by its declarations:
+//
+//	~ @media print { .card }
+//	    ~ color: #ff0000 -> #0000ff
+//	    + margin: 0
+//	> @layer base { .btn } (from .btn)
+//
+// Added rules are marked +, removed ones -, changed ones ~ and moved ones >.
+func WriteText(w io.Writer, changes []*Change) error {
+	for _, c := range changes {
+		line := textMarks[c.Kind] + " " + location(c.Context, c.Rule)
+		if c.Kind == Moved {
+			line += " (from " + location(c.OldContext, c.Rule) + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, d := range c.Declarations {
+			var line string
+			switch d.Kind {
+			case Added:
+				line = d.Property + ": " + strings.Join(d.New, "; ")
+			case Removed:
+				line = d.Property + ": " + strings.Join(d.Old, "; ")
+			default:
+				line = d.Property + ": " + strings.Join(d.Old, "; ") + " -> " + strings.Join(d.New, "; ")
+			}
+			if _, err := fmt.Fprintf(w, "    %s %s\n", textMarks[d.Kind], line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// location returns the rule in its context, like "@media print { .a }".
+func location(context []string, rule string) string {
+	s := rule
+	for i := len(context) - 1; i >= 0; i-- {
+		s = context[i] + " { " + s + " }"
+	}
+	return s
+}
+
+type jsonChange struct {
+	Kind         string             `json:"kind"`
+	Context      []string           `json:"context"`
+	OldContext   []string           `json:"oldContext,omitempty"`
+	Rule         string             `json:"rule"`
+	Declarations []*jsonDeclaration `json:"declarations"`
+}
+
+type jsonDeclaration struct {
+	Kind     string   `json:"kind"`
+	Property string   `json:"property"`
+	Old      []string `json:"old,omitempty"`
+	New      []string `json:"new,omitempty"`
+}
+
+// WriteJSON writes the changes as a JSON array.
+func WriteJSON(w io.Writer, changes []*Change) error {
+	out := []jsonChange{}
+	for _, c := range changes {
+		jc := jsonChange{
+			Kind:         c.Kind.String(),
+			Context:      c.Context,
+			OldContext:   c.OldContext,
+			Rule:         c.Rule,
+			Declarations: []*jsonDeclaration{},
+		}
+		if jc.Context == nil {
+			jc.Context = []string{}
+		}
+		for _, d := range c.Declarations {
+			jc.Declarations = append(jc.Declarations, &jsonDeclaration{
+				Kind:     d.Kind.String(),
+				Property: d.Property,
+				Old:      d.Old,
+				New:      d.New,
+			})
+		}
+		out = append(out, jc)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,393 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/shorthand"
+)
+
+// Conflict is a change made both from base to ours and from base to theirs
+// that Merge cannot reconcile.
+type Conflict struct {
+	Context []string
+	Rule    string
+	// Property is the property changed on both sides, or empty if one side
+	// removed the rule while the other changed it.
+	Property string
+	// Base, Ours and Theirs hold the normalized values of the declarations
+	// of the property, as in DeclarationChange, nil where there are none.
+	// Ours is followed by the declarations of the properties setting some
+	// of the same longhands that ours changed, like margin for
+	// margin-left, written as "margin: 1px".
+	// In a conflict on the rule, the side that removed the rule is nil and
+	// the other holds the properties it changed.
+	Base, Ours, Theirs []string
+}
+
+func (c *Conflict) String() string {
+	loc := location(c.Context, c.Rule)
+	switch {
+	case c.Property != "":
+		return fmt.Sprintf("%s: %s: base %s, ours %s, theirs %s", loc, c.Property, values(c.Base), values(c.Ours), values(c.Theirs))
+	case c.Ours == nil:
+		return fmt.Sprintf("%s: removed in ours, changed in theirs (%s)", loc, strings.Join(c.Theirs, ", "))
+	default:
+		return fmt.Sprintf("%s: changed in ours (%s), removed in theirs", loc, strings.Join(c.Ours, ", "))
+	}
+}
+
+func values(v []string) string {
+	if v == nil {
+		return "(none)"
+	}
+	return strings.Join(v, "; ")
+}
+
+// Merge merges the changes from the stylesheet base to theirs into a copy
+// of ours, such as the changes of a new version of a theme into a
+// customized copy of the previous one, and returns it.
+//
+// Rules are identified as by Diff. The rules added in theirs are inserted
+// next to the rules around them in theirs, and those removed in theirs are
+// removed unless ours changed them. The declarations of the properties
+// theirs changed in a rule replace those of ours, with their longhands,
+// unless ours changed them too, or changed a property setting some of the
+// same longhands, like margin for margin-left: such conflicts, and the
+// rules one side changed that the other removed, are returned in order
+// and resolved in favor of ours. Changing a property the same way on both
+// sides is not a conflict.
+func Merge(base, ours, theirs *ast.Stylesheet) (*ast.Stylesheet, []*Conflict) {
+	merged := ast.Clone(ours).(*ast.Stylesheet)
+	b := flatten(base)
+	m := &merger{ours: flatten(merged), theirs: flatten(theirs), copies: map[ast.Rule]ast.Rule{}}
+	for k, c := range m.theirs.containers {
+		if o := m.ours.containers[k]; o != nil && c.owner != nil {
+			m.copies[c.owner.node] = o.owner.node
+		}
+	}
+	for _, r := range m.theirs.list {
+		if o := m.ours.rules[r.id()]; o != nil {
+			for _, p := range r.places {
+				m.copies[p.node] = o.places[0].node
+			}
+		}
+	}
+
+	var conflicts []*Conflict
+	for _, rt := range m.theirs.list {
+		rb, rm := b.rules[rt.id()], m.ours.rules[rt.id()]
+		switch {
+		case rb == nil && rm == nil:
+			m.insert(rt)
+		case rm == nil:
+			if changes := compare(rb, rt); len(changes) > 0 {
+				conflicts = append(conflicts, &Conflict{Context: rt.context, Rule: rt.key, Theirs: properties(changes)})
+			}
+		default:
+			_, ov := normalized(rm)
+			ours := compare(rb, rm)
+			for _, c := range compare(rb, rt) {
+				o := ov[c.Property]
+				others := overlapping(ours, c.Property)
+				switch {
+				case equal(o, c.New) && others == nil:
+				case equal(o, c.Old) && others == nil:
+					m.set(rm, c.Property, rt)
+				default:
+					conflicts = append(conflicts, &Conflict{
+						Context:  rt.context,
+						Rule:     rt.key,
+						Property: c.Property,
+						Base:     c.Old,
+						Ours:     append(o, others...),
+						Theirs:   c.New,
+					})
+				}
+			}
+		}
+	}
+
+	var removed []*rule
+	for _, rb := range b.list {
+		rm := m.ours.rules[rb.id()]
+		if rm == nil || m.theirs.rules[rb.id()] != nil {
+			continue
+		}
+		if changes := compare(rb, rm); len(changes) > 0 {
+			conflicts = append(conflicts, &Conflict{Context: rb.context, Rule: rb.key, Ours: properties(changes)})
+			continue
+		}
+		removed = append(removed, rm)
+	}
+	// Nested rules are removed first, to remove the rules they leave
+	// empty.
+	for i := len(removed) - 1; i >= 0; i-- {
+		m.delete(removed[i])
+	}
+	return merged, conflicts
+}
+
+// overlapping returns the declarations of ours changes to the properties
+// other than p setting some of its longhands, like margin for margin-left,
+// as "property: values".
+func overlapping(changes []*DeclarationChange, p string) []string {
+	var decls []string
+	for _, c := range changes {
+		if c.Property != p && overlaps(c.Property, p) {
+			decls = append(decls, c.Property+": "+values(c.New))
+		}
+	}
+	return decls
+}
+
+func properties(changes []*DeclarationChange) []string {
+	var props []string
+	for _, c := range changes {
+		props = append(props, c.Property)
+	}
+	return props
+}
+
+type merger struct {
+	ours, theirs *sheet
+	// copies maps the nodes of theirs to their counterparts in ours.
+	copies map[ast.Rule]ast.Rule
+}
+
+// set replaces the declarations of the property p in the rule rm of ours,
+// and of the properties it sets, by those of the rule rt of theirs, in place
+// of the first one replaced or at the end of rm.
+func (m *merger) set(rm *rule, p string, rt *rule) {
+	covers := map[string]bool{p: true}
+	for _, l := range shorthand.Longhands(p) {
+		covers[l] = true
+	}
+	for _, s := range shorthand.Shorthands() {
+		inside := true
+		for _, l := range shorthand.Longhands(s) {
+			inside = inside && covers[l]
+		}
+		if inside {
+			covers[s] = true
+		}
+	}
+	var decls []*ast.Declaration
+	for _, d := range rt.decls() {
+		if covers[property(d)] {
+			decls = append(decls, d)
+		}
+	}
+
+	var (
+		at    *ast.Block
+		index int
+	)
+	for _, pl := range rm.places {
+		if pl.block == nil || pl.block.DeclList == nil {
+			continue
+		}
+		var kept []*ast.Declaration
+		for _, d := range pl.block.DeclList.Declarations {
+			if !covers[property(d)] {
+				kept = append(kept, d)
+			} else if at == nil {
+				at, index = pl.block, len(kept)
+			}
+		}
+		pl.block.DeclList.Declarations = kept
+	}
+	if at == nil {
+		for _, pl := range rm.places {
+			if pl.block != nil {
+				at, index = pl.block, len(declarations(pl.block))
+			}
+		}
+	}
+	if at == nil || len(decls) == 0 {
+		return
+	}
+	if at.DeclList == nil {
+		at.DeclList = &ast.DeclarationList{}
+	}
+	list := append([]*ast.Declaration(nil), at.DeclList.Declarations[:index]...)
+	for _, d := range decls {
+		list = append(list, detach(d).(*ast.Declaration))
+	}
+	at.DeclList.Declarations = append(list, at.DeclList.Declarations[index:]...)
+}
+
+// delete removes the declarations of the rule r of ours, and its nodes
+// left empty.
+func (m *merger) delete(r *rule) {
+	for _, pl := range r.places {
+		if pl.block != nil && pl.block.DeclList != nil {
+			pl.block.DeclList.Declarations = nil
+		}
+		if pl.block == nil || empty(pl.block) {
+			m.remove(pl)
+		}
+	}
+}
+
+// remove removes the node at pl from ours, and the rule holding it if it
+// is left empty.
+func (m *merger) remove(pl place) {
+	i := indexOf(*pl.list, pl.node)
+	if i < 0 {
+		return
+	}
+	*pl.list = append((*pl.list)[:i:i], (*pl.list)[i+1:]...)
+	if c := m.ours.containers[id(pl.parent)]; c != nil && c.owner != nil && c.list == pl.list && empty(c.owner.block) {
+		m.remove(*c.owner)
+	}
+}
+
+// empty reports whether the block b has neither declarations nor rules
+// other than comments.
+func empty(b *ast.Block) bool {
+	if len(declarations(b)) > 0 {
+		return false
+	}
+	for _, r := range b.Rules {
+		if _, ok := r.(*ast.Comment); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// insert inserts in ours a copy of the rule rt of theirs, without the rules
+// nested in it, which are inserted on their own.
+func (m *merger) insert(rt *rule) {
+	pl := rt.places[0]
+	context := nest(rt.context, rt.key)
+	if len(pl.parent) < len(rt.context) {
+		// The declarations of a group rule nested in a style rule, or of
+		// @scope.
+		context = rt.context
+	}
+	if c := m.ours.containers[id(context)]; c != nil && c.owner != nil {
+		// The rule of ours holding the rules nested in rt has no
+		// declarations yet.
+		b := c.owner.block
+		if b.DeclList == nil {
+			b.DeclList = &ast.DeclarationList{}
+		}
+		for _, d := range rt.decls() {
+			b.DeclList.Declarations = append(b.DeclList.Declarations, detach(d).(*ast.Declaration))
+		}
+		return
+	}
+
+	n := detach(pl.node)
+	b := ast.BlockOf(n)
+	if b != nil {
+		b.Rules, b.DeclList = nil, nil
+		for _, d := range rt.decls() {
+			if b.DeclList == nil {
+				b.DeclList = &ast.DeclarationList{}
+			}
+			b.DeclList.Declarations = append(b.DeclList.Declarations, detach(d).(*ast.Declaration))
+		}
+	}
+	list := m.container(pl.parent)
+	m.put(n, pl, list)
+	for _, p := range rt.places {
+		m.copies[p.node] = n
+	}
+	if b != nil {
+		m.ours.contain(context, place{node: n, list: list, parent: pl.parent, block: b})
+	}
+}
+
+// container returns the list of rules of ours nested in context, inserting
+// an empty copy of the rule of theirs holding them if there is none.
+func (m *merger) container(context []string) *[]ast.Rule {
+	if c := m.ours.containers[id(context)]; c != nil {
+		return c.list
+	}
+	owner := m.theirs.containers[id(context)].owner
+	n := detach(owner.node)
+	b := ast.BlockOf(n)
+	b.Rules, b.DeclList = nil, nil
+	list := m.container(owner.parent)
+	m.put(n, *owner, list)
+	m.copies[owner.node] = n
+	m.ours.contain(context, place{node: n, list: list, parent: owner.parent, block: b})
+	return &b.Rules
+}
+
+// put inserts n, the copy of the node at pl in theirs, in list after the
+// copy of the closest node preceding it in theirs, or else before that of
+// the closest node following it, or else at the end, but always after the
+// statements, like @import, starting list.
+func (m *merger) put(n ast.Rule, pl place, list *[]ast.Rule) {
+	start := 0
+	for start < len(*list) && isStatement((*list)[start]) {
+		start++
+	}
+	i := len(*list)
+	siblings := *pl.list
+	at := indexOf(siblings, pl.node)
+	for j := at - 1; j >= 0; j-- {
+		if k := indexOf(*list, m.copies[siblings[j]]); k >= 0 {
+			i = k + 1
+			break
+		}
+	}
+	if i == len(*list) {
+		for j := at + 1; j < len(siblings); j++ {
+			if k := indexOf(*list, m.copies[siblings[j]]); k >= 0 {
+				i = k
+				break
+			}
+		}
+	}
+	if i < start {
+		i = start
+	}
+	*list = append((*list)[:i:i], append([]ast.Rule{n}, (*list)[i:]...)...)
+}
+
+func indexOf(list []ast.Rule, n ast.Rule) int {
+	for i, r := range list {
+		if r == n && n != nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// isStatement reports whether r is a comment or an at-rule without a
+// block, like @import.
+func isStatement(r ast.Rule) bool {
+	switch r := r.(type) {
+	case *ast.Comment, *ast.ImportRule:
+		return true
+	case *ast.LayerRule:
+		return r.Block == nil
+	case *ast.AtRule:
+		return r.Block == nil
+	}
+	return false
+}
+
+// detach returns a copy of n without the positions and Raw of its nodes,
+// so that it is printed formatted in its new place.
+func detach(n ast.Node) ast.Node {
+	n = ast.Clone(n)
+	ast.Inspect(n, func(n ast.Node) bool {
+		if v := reflect.ValueOf(n); v.Kind() == reflect.Ptr && !v.IsNil() {
+			for _, name := range []string{"Span", "Raw"} {
+				if f := v.Elem().FieldByName(name); f.IsValid() {
+					f.Set(reflect.Zero(f.Type()))
+				}
+			}
+		}
+		return true
+	})
+	return n
+}
@@ -0,0 +1,230 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/printer"
+	"github.com/ttacon/css/scanner"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		base, ours, theirs string
+		want               string
+		conflicts          []string
+	}{
+		{
+			// Changes to different properties.
+			`.a { color: red; margin: 0 }`,
+			`.a { color: blue; margin: 0 }`,
+			`.a { color: red; margin: 1px 2px }`,
+			".a {\n  color: blue;\n  margin: 1px 2px;\n}\n",
+			nil,
+		},
+		{
+			// Changes to the same property.
+			`.a { color: red; top: 0 }`,
+			`.a { color: blue; top: 0 }`,
+			`.a { color: green; top: 0 }`,
+			".a {\n  color: blue;\n  top: 0;\n}\n",
+			[]string{".a: color: base #ff0000, ours #0000ff, theirs #008000"},
+		},
+		{
+			// The same change on both sides.
+			`.a { color: red }`,
+			`.a { color: #00f }`,
+			`.a { color: blue }`,
+			".a {\n  color: #00f;\n}\n",
+			nil,
+		},
+		{
+			// Longhands are replaced with their shorthand, and
+			// properties removed.
+			`.a { margin: 0; top: 0 }`,
+			`.a { margin-top: 0; margin-right: 0; margin-bottom: 0; margin-left: 0; color: red; top: 0 }`,
+			`.a { margin: 1px }`,
+			".a {\n  margin: 1px;\n  color: red;\n}\n",
+			nil,
+		},
+		{
+			// A shorthand changed on one side and one of its longhands
+			// on the other.
+			`.a { margin: 0 }`,
+			`.a { margin: 1px }`,
+			`.a { margin: 0; margin-left: 5px }`,
+			".a {\n  margin: 1px;\n}\n",
+			[]string{".a: margin: base 0, ours 1px, theirs 0 0 0 5px"},
+		},
+		{
+			`.a { margin: 0; top: 0 }`,
+			`.a { margin: 1px; top: 0 }`,
+			`.a { margin: 0; margin-left: 5px !important; top: 1px }`,
+			".a {\n  margin: 1px;\n  top: 1px;\n}\n",
+			[]string{
+				".a: margin: base 0, ours 1px, theirs (none)",
+				".a: margin-top: base (none), ours margin: 1px, theirs 0",
+				".a: margin-right: base (none), ours margin: 1px, theirs 0",
+				".a: margin-bottom: base (none), ours margin: 1px, theirs 0",
+				".a: margin-left: base (none), ours margin: 1px, theirs 5px !important",
+			},
+		},
+		{
+			`.a { margin: 0 }`,
+			`.a { margin: 0; margin-left: 5px !important }`,
+			`.a { margin: 1px }`,
+			".a {\n  margin: 0;\n  margin-left: 5px !important;\n}\n",
+			[]string{".a: margin: base 0, ours margin-top: 0; margin-right: 0; margin-bottom: 0; margin-left: 5px !important, theirs 1px"},
+		},
+		{
+			// Rules added after their predecessor, and into new and
+			// existing group rules.
+			`@import "a.css"; .a { top: 0 } .b { top: 0 }`,
+			`@import "a.css"; .b { top: 0 } .a { top: 0 } @media print { .d { top: 0 } }`,
+			`@import "a.css"; .z { top: 0 } .a { top: 0 } .c { top: 1px } .b { top: 0 } @media print { .e { top: 2px } } @supports (display: grid) { .f { display: grid } }`,
+			`@import "a.css";
+.z {
+  top: 0;
+}
+.b {
+  top: 0;
+}
+.a {
+  top: 0;
+}
+.c {
+  top: 1px;
+}
+@media print {
+  .d {
+    top: 0;
+  }
+  .e {
+    top: 2px;
+  }
+}
+@supports (display: grid) {
+  .f {
+    display: grid;
+  }
+}
+`,
+			nil,
+		},
+		{
+			// Rules removed, unless changed in ours.
+			`@import "a.css"; .a { top: 0 } .b { top: 0 } @media print { .c { top: 0 } } .d { top: 0 }`,
+			`@import "a.css"; .a { top: 0 } .b { top: 1px } @media print { .c { top: 0 } } .d { top: 0 }`,
+			`.d { top: 1px }`,
+			".b {\n  top: 1px;\n}\n.d {\n  top: 1px;\n}\n",
+			[]string{".b: changed in ours (top), removed in theirs"},
+		},
+		{
+			// Rules removed in ours.
+			`.a { top: 0 } .b { top: 0 }`,
+			`.c { top: 0 }`,
+			`.a { top: 1px } .b { top: 0 }`,
+			".c {\n  top: 0;\n}\n",
+			[]string{".a: removed in ours, changed in theirs (top)"},
+		},
+		{
+			// Nested rules.
+			`.x { color: red; &:hover { color: blue } }`,
+			`.x { color: red; &:hover { color: blue } .y { top: 0 } }`,
+			`.x { color: red; &:hover { color: green } @media print { color: black } } .n { .m { top: 0 } }`,
+			`.x {
+  color: red;
+  &:hover {
+    color: green;
+  }
+  @media print {
+    color: black;
+  }
+  .y {
+    top: 0;
+  }
+}
+.n {
+  .m {
+    top: 0;
+  }
+}
+`,
+			nil,
+		},
+	}
+	for _, test := range tests {
+		merged, conflicts := Merge(parse(t, test.base), parse(t, test.ours), parse(t, test.theirs))
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, merged); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("Merge(%s, %s, %s):\n%s\nexpected:\n%s", test.base, test.ours, test.theirs, got, test.want)
+		}
+		var got []string
+		for _, c := range conflicts {
+			got = append(got, c.String())
+		}
+		if !equal(got, test.conflicts) {
+			t.Errorf("Merge(%s, %s, %s): conflicts %q, expected %q", test.base, test.ours, test.theirs, got, test.conflicts)
+		}
+	}
+}
+
+func TestMergeLossless(t *testing.T) {
+	parse := func(src string) *ast.Stylesheet {
+		ss, err := parser.NewWithMode(scanner.New(src), parser.Lossless).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ss
+	}
+	base := `.a {
+  color: red;
+  margin: 0;
+}
+.b { top: 0 }
+`
+	ours := `/* customer */
+.a {
+  color: blue; /* brand */
+  margin: 0;
+}
+
+.b { top: 0 }
+`
+	theirs := `.a {
+  color: red;
+  margin: 1px;
+  padding: 2px;
+}
+.new { left: 0 }
+.b { top: 0 }
+`
+	want := `/* customer */
+.a {
+  color: blue; /* brand */
+  margin: 1px;
+  padding: 2px;
+}
+.new {
+  left: 0;
+}
+
+.b { top: 0 }
+`
+	merged, conflicts := Merge(parse(base), parse(ours), parse(theirs))
+	if len(conflicts) > 0 {
+		t.Errorf("unexpected conflicts %v", conflicts)
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, merged); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,296 @@
+package diff
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ttacon/css/color"
+	"github.com/ttacon/css/printer"
+	"github.com/ttacon/css/scanner"
+	"github.com/ttacon/css/shorthand"
+)
+
+// The normalized forms make equal the values that are written differently
+// but mean the same: strings and URLs take double quotes, colors the
+// syntax of color.Color.String, and numbers their shortest decimal form,
+// like 0.5 for .50. Whitespace and comments are dropped.
+
+// tokens returns the tokens of the components comps, without whitespace
+// and comments.
+func tokens(comps ...string) []*scanner.Token {
+	var toks []*scanner.Token
+	for _, c := range comps {
+		sc := scanner.New(c)
+		for t := sc.Next(); t.Type != scanner.TokenEOF && t.Type != scanner.TokenError; t = sc.Next() {
+			if t.Type != scanner.TokenS && t.Type != scanner.TokenComment {
+				toks = append(toks, t)
+			}
+		}
+	}
+	return toks
+}
+
+// value returns the normalized value of a declaration of property with the
+// components comps. The values of shorthands are given in their shortest
+// form. Those of custom properties are only stripped of whitespace and
+// comments, the other differences being significant to what uses them.
+func value(property string, comps []string) string {
+	if strings.HasPrefix(property, "--") {
+		var out []string
+		for _, t := range tokens(comps...) {
+			out = append(out, t.Value)
+		}
+		return printer.Components(out)
+	}
+	if shorthand.IsShorthand(property) {
+		if values, err := shorthand.ExpandValue(property, comps); err == nil {
+			if f, ok := values["font-family"]; ok {
+				values["font-family"] = nil
+				for _, t := range tokens(families(tokens(f...))) {
+					values["font-family"] = append(values["font-family"], t.Value)
+				}
+			}
+			if short, ok := shorthand.CollapseValue(property, values); ok {
+				comps = short
+			}
+		}
+	}
+	toks := tokens(comps...)
+	if strings.EqualFold(property, "font-family") {
+		return families(toks)
+	}
+	colors := isColorProperty(property)
+	var out []string
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		switch t.Type {
+		case scanner.TokenFunction:
+			if !colorFunctions[strings.ToLower(strings.TrimSuffix(t.Value, "("))] {
+				break
+			}
+			end := closing(toks, i)
+			if c, ok := parseColor(toks[i : end+1]); ok {
+				out = append(out, c)
+				i = end
+				continue
+			}
+		case scanner.TokenHash:
+			if c, ok := parseColor(toks[i : i+1]); ok {
+				out = append(out, c)
+				continue
+			}
+		case scanner.TokenIdent:
+			if colors && !strings.EqualFold(t.Value, "currentcolor") {
+				if c, ok := parseColor(toks[i : i+1]); ok {
+					out = append(out, c)
+					continue
+				}
+			}
+		}
+		out = append(out, normalizeToken(t))
+	}
+	return printer.Components(out)
+}
+
+// families returns the normalized list of font families toks: the family
+// names quoted, the generic families, like serif, as keywords.
+func families(toks []*scanner.Token) string {
+	var (
+		list []string
+		name []string
+	)
+	end := func() {
+		switch {
+		case len(name) == 1 && genericFamilies[strings.ToLower(name[0])]:
+			list = append(list, strings.ToLower(name[0]))
+		case len(name) > 0:
+			list = append(list, scanner.Quote(strings.Join(name, " "), '"'))
+		}
+		name = nil
+	}
+	for _, t := range toks {
+		switch t.Type {
+		case scanner.TokenIdent:
+			name = append(name, scanner.Unquote(t.Value))
+		case scanner.TokenString:
+			end()
+			list = append(list, normalizeToken(t))
+		default:
+			end()
+			if t.Value != "," {
+				list = append(list, t.Value)
+			}
+		}
+	}
+	end()
+	return strings.Join(list, ", ")
+}
+
+// normalizeToken returns the normalized text of strings, URLs and numbers,
+// and the text of other tokens.
+func normalizeToken(t *scanner.Token) string {
+	switch t.Type {
+	case scanner.TokenString:
+		return scanner.Quote(scanner.Unquote(t.Value), '"')
+	case scanner.TokenURI:
+		u := strings.TrimSpace(t.Value[len("url(") : len(t.Value)-1])
+		return "url(" + scanner.Quote(scanner.Unquote(u), '"') + ")"
+	case scanner.TokenNumber, scanner.TokenPercentage, scanner.TokenDimension:
+		n := numberLen(t.Value)
+		f, err := strconv.ParseFloat(t.Value[:n], 64)
+		if err != nil {
+			return t.Value
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64) + strings.ToLower(t.Value[n:])
+	}
+	return t.Value
+}
+
+func parseColor(toks []*scanner.Token) (string, bool) {
+	var text []string
+	for _, t := range toks {
+		text = append(text, t.Value)
+	}
+	c, err := color.Parse(printer.Components(text))
+	if err != nil {
+		return "", false
+	}
+	return c.String(), true
+}
+
+// closing returns the index of the token closing the function toks[i], or
+// the last index if it is not closed.
+func closing(toks []*scanner.Token, i int) int {
+	depth := 0
+	for j := i; j < len(toks); j++ {
+		switch {
+		case toks[j].Type == scanner.TokenFunction, toks[j].Value == "(":
+			depth++
+		case toks[j].Value == ")":
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(toks) - 1
+}
+
+// selectors returns the normalized text of a selector list: its selectors
+// normalized and sorted, as their order does not matter.
+func selectors(list []string) string {
+	var sels []string
+	for _, s := range list {
+		sels = append(sels, text(s, true))
+	}
+	sort.Strings(sels)
+	return strings.Join(sels, ", ")
+}
+
+// text returns the normalized text of a selector or at-rule prelude, with
+// whitespace collapsed and the combinators of a selector spaced.
+func text(s string, selector bool) string {
+	var (
+		b     strings.Builder
+		space bool
+	)
+	sc := scanner.New(s)
+	for t := sc.Next(); t.Type != scanner.TokenEOF && t.Type != scanner.TokenError; t = sc.Next() {
+		v := t.Value
+		switch {
+		case t.Type == scanner.TokenS, t.Type == scanner.TokenComment:
+			space = true
+			continue
+		case t.Type != scanner.TokenChar:
+		case selector && (v == ">" || v == "+" || v == "~"), v == ",":
+			if v != "," {
+				b.WriteString(" ")
+			}
+			b.WriteString(v + " ")
+			space = false
+			continue
+		case !selector && v == ":":
+			b.WriteString(": ")
+			space = false
+			continue
+		case v == ")":
+			space = false
+		}
+		if space && b.Len() > 0 {
+			if last := b.String()[b.Len()-1]; last != ' ' && last != '(' {
+				b.WriteByte(' ')
+			}
+		}
+		space = false
+		if selector {
+			if t.Type == scanner.TokenString {
+				v = scanner.Quote(scanner.Unquote(v), '"')
+			}
+		} else {
+			v = normalizeToken(t)
+		}
+		b.WriteString(v)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// isColorProperty reports whether the identifiers in the value of property
+// may be named colors, rather than names like those of font families.
+func isColorProperty(property string) bool {
+	p := strings.ToLower(property)
+	if strings.Contains(p, "color") {
+		return true
+	}
+	for _, prefix := range []string{"background", "border", "outline", "box-shadow", "text-shadow", "text-decoration", "column-rule", "fill", "stroke"} {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// genericFamilies holds the keywords font-family takes.
+var genericFamilies = map[string]bool{
+	"serif": true, "sans-serif": true, "monospace": true, "cursive": true,
+	"fantasy": true, "system-ui": true, "ui-serif": true, "ui-sans-serif": true,
+	"ui-monospace": true, "ui-rounded": true, "math": true, "emoji": true,
+	"fangsong": true, "inherit": true, "initial": true, "unset": true,
+	"revert": true, "revert-layer": true,
+}
+
+var colorFunctions = map[string]bool{
+	"rgb": true, "rgba": true, "hsl": true, "hsla": true, "hwb": true,
+	"lab": true, "lch": true, "oklab": true, "oklch": true, "color": true,
+	"color-mix": true,
+}
+
+// numberLen returns the length of the number starting s.
+func numberLen(s string) int {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i+1 < len(s) && s[i] == '.' && isDigit(s[i+1]) {
+		for i++; i < len(s) && isDigit(s[i]); i++ {
+		}
+	}
+	if i+1 < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if s[j] == '+' || s[j] == '-' {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			for i = j; i < len(s) && isDigit(s[i]); i++ {
+			}
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
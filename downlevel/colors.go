@@ -0,0 +1,75 @@
+package downlevel
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/color"
+)
+
+// labFunctions holds the color functions of CSS Color Level 4 lacking in
+// older browsers.
+var labFunctions = map[string]bool{
+	"lab(":   true,
+	"lch(":   true,
+	"oklab(": true,
+	"oklch(": true,
+}
+
+// mixColors returns the components of a value with its color-mix()
+// functions evaluated, mapped into the sRGB gamut. Those that cannot be
+// resolved statically, like mixes of var() colors, are left as they are.
+func mixColors(comps []string) []string {
+	out, _ := replaceColors(comps, func(fn string) bool {
+		return fn == "color-mix("
+	})
+	return out
+}
+
+// labFallback returns the components of a value with its lab(), lch(),
+// oklab() and oklch() colors written as rgb colors, mapped into the sRGB
+// gamut. It reports false if the value has no such color, or if one of
+// them cannot be resolved statically.
+func labFallback(comps []string) ([]string, bool) {
+	out, n := replaceColors(comps, func(fn string) bool {
+		return labFunctions[fn]
+	})
+	if n == 0 {
+		return nil, false
+	}
+	for _, c := range out {
+		if labFunctions[strings.ToLower(c)] {
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// replaceColors returns a copy of comps with the color functions match
+// reports true for written as rgb colors, and the number of colors
+// replaced.
+func replaceColors(comps []string, match func(fn string) bool) ([]string, int) {
+	var (
+		out []string
+		n   int
+	)
+	for i := 0; i < len(comps); i++ {
+		if !match(strings.ToLower(comps[i])) {
+			out = append(out, comps[i])
+			continue
+		}
+		j := closing(comps, i)
+		if j < 0 {
+			out = append(out, comps[i:]...)
+			break
+		}
+		c, err := color.Parse(strings.Join(comps[i:j+1], " "))
+		if err != nil {
+			out = append(out, comps[i])
+			continue
+		}
+		out = append(out, c.ToGamut().String())
+		n++
+		i = j
+	}
+	return out, n
+}
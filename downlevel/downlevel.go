@@ -0,0 +1,173 @@
+// Package downlevel lowers modern CSS syntax for browsers that don't
+// support it, following the support data of the browsers package:
+//
+//   - native nesting is flattened into plain style rules;
+//   - lab(), lch(), oklab() and oklch() colors get an rgb fallback;
+//   - color-mix() is evaluated;
+//   - media queries in the range syntax use min- and max- features;
+//   - :is() is expanded into a list of selectors where possible;
+//   - logical properties get physical fallbacks.
+//
+// Each transform only runs if one of the targets lacks the feature.
+package downlevel
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/browsers"
+	"github.com/ttacon/css/scanner"
+)
+
+// Downleveler lowers stylesheets for the browser versions of Targets.
+type Downleveler struct {
+	Targets []browsers.Target
+	// Data is the support data to follow, or nil for browsers.Default().
+	Data *browsers.Data
+}
+
+// Downlevel lowers ss for targets with the default support data.
+func Downlevel(ss *ast.Stylesheet, targets []browsers.Target) {
+	d := &Downleveler{Targets: targets}
+	d.Downlevel(ss)
+}
+
+// Downlevel rewrites the syntax of ss that one of the targets doesn't
+// support.
+//
+// Nested rules are flattened first, so that the other transforms see
+// plain style rules. Fallbacks, like the rgb() value of an oklch()
+// declaration, are inserted before the node they replace for older
+// browsers; rewrites that are exact, like those of media queries, replace
+// the original syntax. Values that cannot be resolved statically, like
+// colors using var(), are left as they are.
+func (d *Downleveler) Downlevel(ss *ast.Stylesheet) {
+	data := d.Data
+	if data == nil {
+		data = browsers.Default()
+	}
+	l := &downleveler{Downleveler: d, data: data}
+	if l.lacks("nesting") {
+		ss.Children = flatten(ss.Children, l.lacks("is-selector"))
+	}
+	l.rules(ss.Children)
+}
+
+type downleveler struct {
+	*Downleveler
+	data *browsers.Data
+}
+
+// lacks reports whether one of the targets doesn't support the feature.
+func (l *downleveler) lacks(feature string) bool {
+	return !l.data.Supports(feature, l.Targets)
+}
+
+func (l *downleveler) rules(list []ast.Rule) {
+	for _, r := range list {
+		switch r := r.(type) {
+		case *ast.QualifiedRule:
+			if l.lacks("is-selector") {
+				expandIs(r)
+			}
+		case *ast.AtRule:
+			if l.lacks("media-range-syntax") && strings.EqualFold(r.AtKeyword, "@media") {
+				r.Any = lowerMedia(r.Any)
+			}
+		case *ast.ImportRule:
+			if l.lacks("media-range-syntax") {
+				r.Media = lowerMedia(r.Media)
+			}
+		}
+		if b := ast.BlockOf(r); b != nil {
+			l.rules(b.Rules)
+			if b.DeclList != nil {
+				b.DeclList.Declarations = l.declarations(b.DeclList.Declarations)
+			}
+		}
+	}
+}
+
+// declarations returns decls with their values lowered and their
+// fallbacks inserted.
+func (l *downleveler) declarations(decls []*ast.Declaration) []*ast.Declaration {
+	var out []*ast.Declaration
+	for _, d := range decls {
+		if strings.HasPrefix(d.Ident, "--") {
+			out = append(out, d)
+			continue
+		}
+		if l.lacks("color-mix") {
+			d.Components = mixColors(d.Components)
+		}
+		if l.lacks("lab-colors") {
+			if comps, ok := labFallback(d.Components); ok {
+				c := ast.CopyDeclaration(d)
+				c.Components = comps
+				out = append(out, c)
+			}
+		}
+		if l.lacks("logical-properties") {
+			// The logical declaration is kept after its fallbacks, for
+			// the browsers supporting it and the right-to-left and
+			// vertical writing modes.
+			out = append(out, physical(d)...)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// tokens returns the tokens of s, up to the end or the first invalid
+// token.
+func tokens(s string) []*scanner.Token {
+	var (
+		toks []*scanner.Token
+		sc   = scanner.New(s)
+	)
+	for t := sc.Next(); t.Type != scanner.TokenEOF && t.Type != scanner.TokenError; t = sc.Next() {
+		toks = append(toks, t)
+	}
+	return toks
+}
+
+// text joins toks, with runs of whitespace written as a single space and
+// leading and trailing whitespace left out.
+func text(toks []*scanner.Token) string {
+	var b strings.Builder
+	space := false
+	for _, t := range toks {
+		if t.Type == scanner.TokenS {
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteString(t.Value)
+	}
+	return b.String()
+}
+
+func isChar(t *scanner.Token, c string) bool {
+	return t.Type == scanner.TokenChar && t.Value == c
+}
+
+// closing returns the index of the component closing the function or
+// parenthesis opened by comps[i], or -1 if it is not closed.
+func closing(comps []string, i int) int {
+	depth := 0
+	for j := i; j < len(comps); j++ {
+		switch {
+		case comps[j] == "(" || strings.HasSuffix(comps[j], "("):
+			depth++
+		case comps[j] == ")":
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,249 @@
+package downlevel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ttacon/css/browsers"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/printer"
+	"github.com/ttacon/css/scanner"
+)
+
+func downlevel(t *testing.T, targets, src string) string {
+	t.Helper()
+	ts, err := browsers.ParseTargets(targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := parser.NewWithMode(scanner.New(src), parser.Lossless).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Downlevel(ss, ts)
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, ss); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestDownlevel(t *testing.T) {
+	tests := []struct {
+		targets   string
+		src, want string
+	}{
+		// Nesting.
+		{
+			"chrome 100",
+			`.a, .b {
+  color: red;
+  &:hover {
+    color: blue; /* c */
+    .c { top: 0 }
+  }
+  margin: 0;
+}
+`,
+			`.a, .b {
+  color: red;
+}
+.a:hover, .b:hover {
+  color: blue; /* c */
+}
+.a:hover .c, .b:hover .c {
+  top: 0;
+}
+.a, .b {
+  margin: 0;
+}
+`,
+		},
+		{
+			"chrome 100",
+			`div { span & { a: b } &.x { c: d } > p { e: f } }
+.p .q { .x & { a: b } & + & { c: d } }
+.a, #b { & .c { a: b } }
+.a, .b { & + & { a: b } & + .c { d: e } }
+.a { @media print { top: 0; .b { x: y } } }
+.s { @scope (.card) to (.end) { img { a: b } } }
+`,
+			`span div {
+  a: b;
+}
+div.x {
+  c: d;
+}
+div > p {
+  e: f;
+}
+.x :is(.p .q) {
+  a: b;
+}
+.p .q + :is(.p .q) {
+  c: d;
+}
+:is(.a, #b) .c {
+  a: b;
+}
+:is(.a, .b) + :is(.a, .b) {
+  a: b;
+}
+.a + .c, .b + .c {
+  d: e;
+}
+@media print {
+  .a {
+    top: 0;
+  }
+  .a .b {
+    x: y;
+  }
+}
+@scope (.s .card) to (.end) {
+  img {
+    a: b;
+  }
+}
+`,
+		},
+		{
+			// Without :is(), parents of different specificities are
+			// written out one by one.
+			"chrome 60, safari 10",
+			`.a, #b { &:hover { a: b } .c & { c: d } & + & { e: f } }
+`,
+			`.a:hover, #b:hover {
+  a: b;
+}
+.c .a, .c #b {
+  c: d;
+}
+.a + .a, .a + #b, #b + .a, #b + #b {
+  e: f;
+}
+`,
+		},
+		{
+			// :is() is only expanded where it keeps its meaning and
+			// specificity.
+			"chrome 80",
+			`:is(h1, h2) > .b, .x :is(.c, .d):is(.e, .f), div:is(span, p), :is(.a .b, .c .d) .x, :is(.a, #b) { top: 0 }
+`,
+			`h1 > .b, h2 > .b, .x .c.e, .x .c.f, .x .d.e, .x .d.f, div:is(span, p), .a .b .x, .c .d .x, :is(.a, #b) { top: 0 }
+`,
+		},
+
+		// Colors.
+		{
+			"chrome 100",
+			`.a { color: oklch(70% 0.1 -20); border: 1px solid lab(50 100 -100 / 0.5); fill: oklch(var(--l) 0.1 20) }
+`,
+			`.a { color: #c886b2; color: oklch(70% 0.1 -20); border: 1px solid rgba(189, 45, 255, 0.5); border: 1px solid lab(50 100 -100 / 0.5); fill: oklch(var(--l) 0.1 20) }
+`,
+		},
+		{
+			"chrome 100",
+			`.a { background: color-mix(in srgb, red 50%, blue); color: color-mix(in oklch, oklch(60% 0.2 20), white); fill: color-mix(in srgb, var(--x), red) }
+`,
+			`.a { background: #800080; color: #f8a4a3; fill: color-mix(in srgb, var(--x), red) }
+`,
+		},
+
+		// Media queries.
+		{
+			"chrome 100",
+			`@media screen and (400px < width <= 700px), not (color > 2), (aspect-ratio > 16/9) { .a { top: 0 } }
+@media not ((width < 40em) and (height > 1px)), not ((width < 40em) or (color)), (color) and (not (width < 1px)) {}
+@media (width < 40em) and (height = 10px) {}
+@import "a.css" (width >= 10px);
+@media (min-width: 10px) {}
+`,
+			`@media screen and (min-width: 400.001px) and (max-width: 700px), not all and (min-color: 3), (aspect-ratio > 16/9) { .a { top: 0 } }
+@media not all and (max-width: 39.999em) and (min-height: 1.001px), not ((width < 40em) or (color)), (color) and (not (width < 1px)) {}
+@media (max-width: 39.999em) and (height: 10px) {}
+@import "a.css" (min-width: 10px);
+@media (min-width: 10px) {}
+`,
+		},
+
+		// Logical properties.
+		{
+			"safari 14",
+			`.a {
+  margin-inline: 1px calc(2px + 1em) !important; /* c */
+  padding-block: 3px;
+  inset: 1px 2px 3px;
+  border-inline-start-color: red;
+  border-block: 1px solid;
+  inline-size: 10px;
+  border-start-end-radius: 2px;
+  inset-inline-end: 0;
+}
+`,
+			`.a {
+  margin-left: 1px !important;
+  margin-right: calc(2px + 1em) !important;
+  margin-inline: 1px calc(2px + 1em) !important; /* c */
+  padding-top: 3px;
+  padding-bottom: 3px;
+  padding-block: 3px;
+  top: 1px;
+  right: 2px;
+  bottom: 3px;
+  left: 2px;
+  inset: 1px 2px 3px;
+  border-left-color: red;
+  border-inline-start-color: red;
+  border-top: 1px solid;
+  border-bottom: 1px solid;
+  border-block: 1px solid;
+  width: 10px;
+  inline-size: 10px;
+  border-top-right-radius: 2px;
+  border-start-end-radius: 2px;
+  right: 0;
+  inset-inline-end: 0;
+}
+`,
+		},
+
+		// Targets supporting everything are left alone.
+		{
+			"chrome 120, firefox 120, safari 17.2",
+			`.a { color: oklch(70% 0.1 20); margin-inline: 0; &:is(.b, .c) { top: 0 } }
+@media (width >= 40em) {}
+`,
+			`.a { color: oklch(70% 0.1 20); margin-inline: 0; &:is(.b, .c) { top: 0 } }
+@media (width >= 40em) {}
+`,
+		},
+	}
+	for _, test := range tests {
+		if got := downlevel(t, test.targets, test.src); got != test.want {
+			t.Errorf("%s:\n%s\nexpected:\n%s\ngot:\n%s", test.targets, test.src, test.want, got)
+		}
+	}
+}
+
+func TestNestSelector(t *testing.T) {
+	tests := []struct {
+		parent, sel, want string
+	}{
+		{".a", "&:hover", ".a:hover"},
+		{".a", ".b", ".a .b"},
+		{".a", "> .b", ".a > .b"},
+		{".a", ".b &", ".b .a"},
+		{".a", "&&", ".a.a"},
+		{"div", ".x&", ".x:is(div)"},
+		{"div", "&.x", "div.x"},
+		{".a .b", "& + .c", ".a .b + .c"},
+		{".a .b", ".c + &", ".c + :is(.a .b)"},
+		{".a > .b", "&.c", ".a > .b.c"},
+	}
+	for _, test := range tests {
+		if got := nestSelector(test.parent, test.sel); got != test.want {
+			t.Errorf("nestSelector(%q, %q) = %q, expected %q", test.parent, test.sel, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package downlevel
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+)
+
+// The logical properties are mapped to physical ones for the horizontal
+// left-to-right writing mode of most content: inline-start is left,
+// inline-end right, block-start top and block-end bottom.
+
+// logicalLonghands maps logical longhand properties to their physical
+// equivalents.
+var logicalLonghands = map[string]string{
+	"inline-size":     "width",
+	"block-size":      "height",
+	"min-inline-size": "min-width",
+	"min-block-size":  "min-height",
+	"max-inline-size": "max-width",
+	"max-block-size":  "max-height",
+
+	"inset-inline-start": "left",
+	"inset-inline-end":   "right",
+	"inset-block-start":  "top",
+	"inset-block-end":    "bottom",
+
+	"border-start-start-radius": "border-top-left-radius",
+	"border-start-end-radius":   "border-top-right-radius",
+	"border-end-start-radius":   "border-bottom-left-radius",
+	"border-end-end-radius":     "border-bottom-right-radius",
+}
+
+// sides maps the logical sides to the physical ones.
+var sides = map[string]string{
+	"inline-start": "left",
+	"inline-end":   "right",
+	"block-start":  "top",
+	"block-end":    "bottom",
+}
+
+// axes maps the logical axes to their physical start and end sides.
+var axes = map[string][2]string{
+	"inline": {"left", "right"},
+	"block":  {"top", "bottom"},
+}
+
+// physical returns the physical declarations falling back for the logical
+// declaration d, or nil if d is not logical.
+func physical(d *ast.Declaration) []*ast.Declaration {
+	prop := strings.ToLower(d.Ident)
+	if p, ok := logicalLonghands[prop]; ok {
+		return []*ast.Declaration{withIdent(d, p, d.Components)}
+	}
+	values := splitValues(d.Components)
+	if prop == "inset" {
+		if len(values) == 0 || len(values) > 4 {
+			return nil
+		}
+		// Like margin: top, right, bottom and left, the missing values
+		// copying the opposite side.
+		if len(values) == 1 {
+			values = append(values, values[0])
+		}
+		for len(values) < 4 {
+			values = append(values, values[len(values)-2])
+		}
+		return []*ast.Declaration{
+			withIdent(d, "top", values[0]),
+			withIdent(d, "right", values[1]),
+			withIdent(d, "bottom", values[2]),
+			withIdent(d, "left", values[3]),
+		}
+	}
+	for _, box := range []string{"margin", "padding", "border", "inset"} {
+		rest := strings.TrimPrefix(prop, box+"-")
+		if rest == prop {
+			continue
+		}
+		// The suffix of the border longhands, like -width.
+		suffix := ""
+		if box == "border" {
+			for _, s := range []string{"-width", "-style", "-color"} {
+				if strings.HasSuffix(rest, s) {
+					rest, suffix = strings.TrimSuffix(rest, s), s
+					break
+				}
+			}
+		}
+		name := func(side string) string {
+			if box == "inset" {
+				return side
+			}
+			return box + "-" + side + suffix
+		}
+		if side, ok := sides[rest]; ok {
+			return []*ast.Declaration{withIdent(d, name(side), d.Components)}
+		}
+		axis, ok := axes[rest]
+		if !ok {
+			return nil
+		}
+		// border-inline and border-block take a single border value for
+		// both sides, the others one value per side.
+		if box == "border" && suffix == "" || len(values) == 1 {
+			return []*ast.Declaration{
+				withIdent(d, name(axis[0]), d.Components),
+				withIdent(d, name(axis[1]), d.Components),
+			}
+		}
+		if len(values) != 2 {
+			return nil
+		}
+		return []*ast.Declaration{
+			withIdent(d, name(axis[0]), values[0]),
+			withIdent(d, name(axis[1]), values[1]),
+		}
+	}
+	return nil
+}
+
+// withIdent returns a copy of d, without its comments, for the property
+// ident with the components comps.
+func withIdent(d *ast.Declaration, ident string, comps []string) *ast.Declaration {
+	c := ast.CopyDeclaration(d)
+	c.Ident = ident
+	c.Components = append([]string(nil), comps...)
+	return c
+}
+
+// splitValues splits the components of a value into its space-separated
+// values, a function with its arguments being one value.
+func splitValues(comps []string) [][]string {
+	var values [][]string
+	for i := 0; i < len(comps); i++ {
+		j := i
+		if strings.HasSuffix(comps[i], "(") {
+			if j = closing(comps, i); j < 0 {
+				j = len(comps) - 1
+			}
+		}
+		values = append(values, comps[i:j+1])
+		i = j
+	}
+	return values
+}
@@ -0,0 +1,170 @@
+package downlevel
+
+import (
+	"math"
+
+	"github.com/ttacon/css/media"
+)
+
+// lowerMedia returns the media query list s with its features in the
+// range syntax written as min- and max- features:
+//
+//	(width >= 40em)            (min-width: 40em)
+//	(400px < width <= 700px)   (min-width: 400.001px) and (max-width: 700px)
+//
+// s is returned as it is if it has no range to rewrite. A negated
+// condition becomes a negated query, like not all and (max-width: 40em).
+// Queries with a range that cannot be written with min- and max- features,
+// like a strict comparison of ratios or one in a negation nested in a
+// condition, are left as they are.
+func lowerMedia(s string) string {
+	list, err := media.Parse(s)
+	if err != nil {
+		return s
+	}
+	changed := false
+	for _, q := range list {
+		if q.Cond == nil {
+			continue
+		}
+		// A negated condition is written as a negated query, "not all
+		// and", the only negation before the range syntax.
+		cond, negated := q.Cond, false
+		if n, ok := q.Cond.(*media.Not); ok && q.Type == "" {
+			cond, negated = n.Cond, true
+		}
+		// A query that cannot be rewritten is left as it is: browsers
+		// without range syntax only ignore that query of the list.
+		c, ok := lowerCondition(cond)
+		if !ok || c == cond {
+			continue
+		}
+		if negated {
+			if _, ok := c.(*media.Or); ok {
+				continue
+			}
+			q.Not, q.Type = true, "all"
+		}
+		q.Cond = c
+		changed = true
+	}
+	if !changed {
+		return s
+	}
+	return list.String()
+}
+
+// lowerCondition returns c with its ranges rewritten, or c itself if it
+// has none. It reports false if a range cannot be rewritten.
+func lowerCondition(c media.Condition) (media.Condition, bool) {
+	switch c := c.(type) {
+	case *media.And:
+		conds, ok := lowerConditions(c.Conds)
+		if conds == nil || !ok {
+			return c, ok
+		}
+		return &media.And{Conds: conds}, true
+	case *media.Or:
+		conds, ok := lowerConditions(c.Conds)
+		if conds == nil || !ok {
+			return c, ok
+		}
+		return &media.Or{Conds: conds}, true
+	case *media.Not:
+		// Only whole queries can be negated without the range syntax.
+		cond, ok := lowerCondition(c.Cond)
+		return c, ok && cond == c.Cond
+	case *media.Range:
+		return lowerRange(c)
+	}
+	return c, true
+}
+
+// lowerConditions returns conds with their ranges rewritten, or nil if
+// they have none.
+func lowerConditions(conds []media.Condition) ([]media.Condition, bool) {
+	var (
+		out     = make([]media.Condition, len(conds))
+		changed bool
+	)
+	for i, c := range conds {
+		l, ok := lowerCondition(c)
+		if !ok {
+			return nil, false
+		}
+		out[i] = l
+		changed = changed || l != c
+	}
+	if !changed {
+		return nil, true
+	}
+	return out, true
+}
+
+// lowerRange returns the range r as plain features.
+func lowerRange(r *media.Range) (media.Condition, bool) {
+	var conds []media.Condition
+	if r.Left != nil {
+		// "v < name" compares the feature from the other side.
+		f, ok := feature(r.Name, flip(r.LeftOp), r.Left)
+		if !ok {
+			return r, false
+		}
+		conds = append(conds, f)
+	}
+	if r.Right != nil {
+		f, ok := feature(r.Name, r.RightOp, r.Right)
+		if !ok {
+			return r, false
+		}
+		conds = append(conds, f)
+	}
+	if len(conds) == 1 {
+		return conds[0], true
+	}
+	return &media.And{Conds: conds}, true
+}
+
+// feature returns the plain feature for "name op v".
+func feature(name string, op media.Op, v *media.Value) (*media.Feature, bool) {
+	if op == media.EQ {
+		return &media.Feature{Name: name, Value: v}, true
+	}
+	prefix := "min-"
+	if op == media.LT || op == media.LE {
+		prefix = "max-"
+	}
+	if op == media.LT || op == media.GT {
+		// Strict comparisons exclude the value itself: the closest value
+		// on the right side is used instead.
+		if v.Ident != "" || v.Den != 0 {
+			return nil, false
+		}
+		step := 0.001
+		if v.Unit == "" {
+			// Unitless range features, like color, are integers.
+			step = 1
+		}
+		if op == media.LT {
+			step = -step
+		}
+		v = &media.Value{Num: math.Round((v.Num+step)*1000) / 1000, Unit: v.Unit}
+	}
+	return &media.Feature{Name: prefix + name, Value: v}, true
+}
+
+// flip returns the operator comparing the operands of op the other way
+// around.
+func flip(op media.Op) media.Op {
+	switch op {
+	case media.LT:
+		return media.GT
+	case media.LE:
+		return media.GE
+	case media.GT:
+		return media.LT
+	case media.GE:
+		return media.LE
+	}
+	return op
+}
@@ -0,0 +1,341 @@
+package downlevel
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/cascade"
+	"github.com/ttacon/css/scanner"
+)
+
+// flatten returns list with the rules nested in its style rules moved out
+// of them, after the rule they were nested in:
+//
+//	.a { color: red; &:hover { color: blue } }
+//
+// becomes
+//
+//	.a { color: red }
+//	.a:hover { color: blue }
+//
+// Conditional group rules nested in a style rule, like @media, get a copy
+// of the style rule holding their declarations, and a nested @scope rule
+// gets its scoping roots resolved against the selectors of the style
+// rule.
+//
+// Unless plain is set, parents of different specificities are written as
+// a single :is() selector, which keeps the specificity of &. Plain is for
+// browsers without :is(): the selectors are resolved against each parent
+// in turn, taking the specificity of that parent.
+func flatten(list []ast.Rule, plain bool) []ast.Rule {
+	var out []ast.Rule
+	for _, r := range list {
+		if r, ok := r.(*ast.QualifiedRule); ok {
+			out = append(out, flattenRule(r, plain)...)
+			continue
+		}
+		if b := ast.BlockOf(r); b != nil {
+			b.Rules = flatten(b.Rules, plain)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// flattenRule returns the style rule r, split around the rules nested in
+// it, followed by those rules.
+func flattenRule(r *ast.QualifiedRule, plain bool) []ast.Rule {
+	if r.Block == nil || len(r.Block.Rules) == 0 {
+		return []ast.Rule{r}
+	}
+	var (
+		out   []ast.Rule
+		decls []*ast.Declaration
+		first = true
+		sels  = selectors(r)
+	)
+	// emit appends the declarations read since the last nested rule to
+	// out, in r for the first ones and in a copy of r for the others, so
+	// that they keep their order relative to the nested rules.
+	emit := func() {
+		if len(decls) == 0 {
+			return
+		}
+		if first {
+			r.Block.DeclList.Declarations = decls
+			r.Block.Rules = nil
+			out = append(out, r)
+			first = false
+		} else {
+			out = append(out, styleRule(sels, decls))
+		}
+		decls = nil
+	}
+	for _, c := range r.Block.Children() {
+		switch c := c.(type) {
+		case *ast.Declaration:
+			decls = append(decls, c)
+
+		case *ast.QualifiedRule:
+			emit()
+			setSelectors(c, nest(sels, selectors(c), plain))
+			unraw(c)
+			out = append(out, flattenRule(c, plain)...)
+
+		case *ast.ScopeRule:
+			emit()
+			c.Roots = nest(sels, c.Roots, plain)
+			if len(c.Roots) == 0 {
+				c.Roots = parentSelectors(sels, plain)
+			}
+			unraw(c)
+			if c.Block != nil {
+				c.Block.Rules = flatten(c.Block.Rules, plain)
+			}
+			out = append(out, c)
+
+		case ast.Rule:
+			emit()
+			if b := ast.BlockOf(c); b != nil && isGroup(c) {
+				unraw(b)
+				inner := styleRule(sels, nil)
+				inner.Block = b
+				rules := flattenRule(inner, plain)
+				switch c := c.(type) {
+				case *ast.AtRule:
+					c.Block, c.Raw = &ast.Block{Rules: rules}, nil
+				case *ast.LayerRule:
+					c.Block, c.Raw = &ast.Block{Rules: rules}, nil
+				}
+			}
+			out = append(out, c)
+		}
+	}
+	emit()
+	if first && r.Block.DeclList != nil && len(out) > 0 {
+		// All of r was made of nested rules: its trailing comments go to
+		// the first rule of the output.
+		if q, ok := out[0].(*ast.QualifiedRule); ok && q.Block != nil && q.Block.DeclList != nil {
+			q.Block.DeclList.Trailing = append(q.Block.DeclList.Trailing, r.Block.DeclList.Trailing...)
+		}
+	}
+	return out
+}
+
+// unraw removes the Raw of n and of the nodes in it, so that they are
+// printed formatted at their new depth.
+func unraw(n ast.Node) {
+	ast.Inspect(n, func(n ast.Node) bool {
+		if v := reflect.ValueOf(n); v.Kind() == reflect.Ptr && !v.IsNil() {
+			if f := v.Elem().FieldByName("Raw"); f.IsValid() {
+				f.Set(reflect.Zero(f.Type()))
+			}
+		}
+		return true
+	})
+}
+
+// isGroup reports whether r is a group rule that may be nested in a
+// style rule.
+func isGroup(r ast.Rule) bool {
+	switch r := r.(type) {
+	case *ast.AtRule:
+		switch strings.ToLower(r.AtKeyword) {
+		case "@media", "@supports", "@container", "@layer", "@starting-style":
+			return true
+		}
+	case *ast.LayerRule:
+		return true
+	}
+	return false
+}
+
+// styleRule returns a new style rule with the selectors sels and the
+// declarations decls.
+func styleRule(sels []string, decls []*ast.Declaration) *ast.QualifiedRule {
+	r := &ast.QualifiedRule{Block: &ast.Block{DeclList: &ast.DeclarationList{Declarations: decls}}}
+	setSelectors(r, sels)
+	return r
+}
+
+func selectors(r *ast.QualifiedRule) []string {
+	sels := make([]string, len(r.Components))
+	for i, c := range r.Components {
+		sels[i] = strings.TrimSpace(c.Name)
+	}
+	return sels
+}
+
+func setSelectors(r *ast.QualifiedRule, sels []string) {
+	r.Components = make([]*ast.ComponentValue, len(sels))
+	for i, sel := range sels {
+		r.Components[i] = &ast.ComponentValue{Name: sel}
+	}
+}
+
+// parentSelectors returns the selectors of a style rule as the parents of
+// nested selectors: as they are if they share the same specificity, and
+// otherwise as a single :is() selector, which has the specificity of the
+// most specific of them, like the nesting selector &. If plain is set,
+// they are returned as they are.
+func parentSelectors(sels []string, plain bool) []string {
+	if plain {
+		return sels
+	}
+	for _, sel := range sels[1:] {
+		if cascade.Specificity(sel) != cascade.Specificity(sels[0]) {
+			return []string{":is(" + strings.Join(sels, ", ") + ")"}
+		}
+	}
+	return sels
+}
+
+// nest returns the nested selectors sels resolved against the selectors
+// of the parent rule, the first parent first. A selector using & more than
+// once, like & + &, has each & match any of the parents, so that it is
+// resolved once against all of them as :is(.a, .b) rather than against
+// each parent in turn; if plain is set, it is resolved against every
+// combination of parents instead.
+func nest(parents, sels []string, plain bool) []string {
+	var (
+		out []string
+		ps  = parentSelectors(parents, plain)
+	)
+	for i, p := range ps {
+		for _, sel := range sels {
+			switch n := nestings(sel); {
+			case len(ps) == 1 || n < 2:
+				out = append(out, nestSelector(p, sel))
+			case i > 0:
+			case plain:
+				out = append(out, combinations(ps, sel, n)...)
+			default:
+				out = append(out, nestSelector(":is("+strings.Join(ps, ", ")+")", sel))
+			}
+		}
+	}
+	return out
+}
+
+// combinations returns the selector sel, with n nesting selectors,
+// resolved against every combination of the parents ps, the first parent
+// first.
+func combinations(ps []string, sel string, n int) []string {
+	var (
+		out    []string
+		chosen = make([]string, n)
+		choose func(i int)
+	)
+	choose = func(i int) {
+		if i == n {
+			out = append(out, nestEach(sel, chosen))
+			return
+		}
+		for _, p := range ps {
+			chosen[i] = p
+			choose(i + 1)
+		}
+	}
+	choose(0)
+	return out
+}
+
+// nestings returns the number of nesting selectors & in sel.
+func nestings(sel string) int {
+	n := 0
+	for _, t := range tokens(sel) {
+		if isChar(t, "&") {
+			n++
+		}
+	}
+	return n
+}
+
+// nestSelector returns the nested selector sel with the nesting selector &
+// replaced by parent. A selector without & is relative to parent:
+//
+//	.a      parent .a
+//	> .a    parent > .a
+//
+// parent is written as it is where this selects the same elements, and
+// as :is(parent) elsewhere.
+func nestSelector(parent, sel string) string {
+	n := nestings(sel)
+	if n == 0 {
+		return parent + " " + text(tokens(sel))
+	}
+	parents := make([]string, n)
+	for i := range parents {
+		parents[i] = parent
+	}
+	return nestEach(sel, parents)
+}
+
+// nestEach returns the selector sel with its nesting selectors replaced
+// by parents, in order, as nestSelector does.
+func nestEach(sel string, parents []string) string {
+	var (
+		b       strings.Builder
+		toks    = tokens(sel)
+		leading = true
+		k       int
+	)
+	for i, t := range toks {
+		if !isChar(t, "&") {
+			if t.Type != scanner.TokenS {
+				leading = false
+			}
+			b.WriteString(t.Value)
+			continue
+		}
+		// A type selector comes first in a compound selector, and a
+		// complex one can only be written as is at the start.
+		parent := parents[k]
+		k++
+		compound, typed := isCompound(tokens(parent)), startsWithType(parent)
+		atStart := i == 0 || toks[i-1].Type == scanner.TokenS || isCombinator(toks[i-1])
+		if leading || compound && (atStart || !typed) {
+			b.WriteString(parent)
+		} else {
+			b.WriteString(":is(" + parent + ")")
+		}
+		leading = false
+	}
+	return text(tokens(b.String()))
+}
+
+// isCompound reports whether the selector toks is a compound selector,
+// without combinators.
+func isCompound(toks []*scanner.Token) bool {
+	depth := 0
+	for i, t := range toks {
+		switch {
+		case t.Type == scanner.TokenFunction || isChar(t, "("):
+			depth++
+		case isChar(t, ")"):
+			depth--
+		case depth == 0 && isCombinator(t):
+			return false
+		case depth == 0 && t.Type == scanner.TokenS && i > 0 && i < len(toks)-1:
+			return false
+		}
+	}
+	return true
+}
+
+func isCombinator(t *scanner.Token) bool {
+	return isChar(t, ">") || isChar(t, "+") || isChar(t, "~")
+}
+
+// startsWithType reports whether sel starts with a type or universal
+// selector.
+func startsWithType(sel string) bool {
+	for _, t := range tokens(sel) {
+		if t.Type == scanner.TokenS {
+			continue
+		}
+		return t.Type == scanner.TokenIdent || isChar(t, "*") || isChar(t, "|")
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package downlevel
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/cascade"
+	"github.com/ttacon/css/scanner"
+)
+
+// expandIs rewrites the selectors of r using :is() as lists of plain
+// selectors where this keeps their meaning and specificity:
+//
+//	.a :is(.b, .c) > p    .a .b > p, .a .c > p
+//
+// The selectors of an :is() are only written out if they have the same
+// specificity, as :is() takes that of the most specific one. Complex
+// selectors can only be written out at the start of a selector, and type
+// selectors at the start of a compound selector.
+func expandIs(r *ast.QualifiedRule) {
+	var (
+		sels    []string
+		changed bool
+	)
+	for _, sel := range selectors(r) {
+		exp := expandSelector(sel)
+		changed = changed || len(exp) != 1 || exp[0] != sel
+		sels = append(sels, exp...)
+	}
+	if changed {
+		setSelectors(r, sels)
+	}
+}
+
+// expandSelector returns the selectors sel expands to.
+func expandSelector(sel string) []string {
+	toks := tokens(sel)
+	depth := 0
+	for i, t := range toks {
+		switch {
+		case t.Type == scanner.TokenFunction && depth == 0 && strings.EqualFold(t.Value, "is(") &&
+			i > 0 && isChar(toks[i-1], ":") && (i < 2 || !isChar(toks[i-2], ":")):
+			j := closingToken(toks, i)
+			if j < 0 {
+				return []string{sel}
+			}
+			args := splitArgs(toks[i+1 : j])
+			if !expandable(toks[:i-1], args) {
+				depth++
+				continue
+			}
+			var out []string
+			for _, arg := range args {
+				s := raw(toks[:i-1]) + text(arg) + raw(toks[j+1:])
+				out = append(out, expandSelector(text(tokens(s)))...)
+			}
+			return out
+		case t.Type == scanner.TokenFunction || isChar(t, "("):
+			depth++
+		case isChar(t, ")"):
+			depth--
+		}
+	}
+	return []string{sel}
+}
+
+// expandable reports whether the arguments args of an :is() preceded by
+// the tokens before can be written out.
+func expandable(before []*scanner.Token, args [][]*scanner.Token) bool {
+	if len(args) == 0 {
+		return false
+	}
+	start := len(before) == 0 || before[len(before)-1].Type == scanner.TokenS || isCombinator(before[len(before)-1])
+	spec := cascade.Specificity(text(args[0]))
+	for _, arg := range args {
+		s := text(arg)
+		if s == "" || cascade.Specificity(s) != spec || strings.Contains(s, "::") {
+			return false
+		}
+		if !isCompound(tokens(s)) && text(before) != "" {
+			return false
+		}
+		if !start && startsWithType(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// raw joins toks as they are.
+func raw(toks []*scanner.Token) string {
+	var b strings.Builder
+	for _, t := range toks {
+		b.WriteString(t.Value)
+	}
+	return b.String()
+}
+
+// splitArgs splits toks at their top-level commas.
+func splitArgs(toks []*scanner.Token) [][]*scanner.Token {
+	var (
+		args  [][]*scanner.Token
+		depth int
+		from  int
+	)
+	for i, t := range toks {
+		switch {
+		case t.Type == scanner.TokenFunction || isChar(t, "("):
+			depth++
+		case isChar(t, ")"):
+			depth--
+		case depth == 0 && isChar(t, ","):
+			args = append(args, toks[from:i])
+			from = i + 1
+		}
+	}
+	return append(args, toks[from:])
+}
+
+// closingToken returns the index of the token closing the function opened
+// by toks[i], or -1 if it is not closed.
+func closingToken(toks []*scanner.Token, i int) int {
+	depth := 0
+	for j := i; j < len(toks); j++ {
+		switch {
+		case toks[j].Type == scanner.TokenFunction || isChar(toks[j], "("):
+			depth++
+		case isChar(toks[j], ")"):
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,82 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config selects and configures the rules a Linter runs. It is usually
+// read from a JSON file of the form:
+//
+//	{
+//		"rules": {
+//			"no-important": "off",
+//			"id-selectors": "error",
+//			"max-specificity": {"severity": "warning", "max": "0,3,0"}
+//		}
+//	}
+//
+// A rule set to a string only changes its severity. A rule set to an
+// object takes its severity from the "severity" key, if present, and
+// passes the remaining keys to the rule as options.
+type Config struct {
+	Rules map[string]RuleConfig `json:"rules"`
+}
+
+// defaultSeverity marks a RuleConfig that keeps the rule's own severity.
+const defaultSeverity Severity = -1
+
+// RuleConfig is the configuration of a single rule.
+type RuleConfig struct {
+	Severity Severity
+	Options  map[string]interface{}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (rc *RuleConfig) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		sev, err := ParseSeverity(name)
+		if err != nil {
+			return err
+		}
+		*rc = RuleConfig{Severity: sev}
+		return nil
+	}
+
+	var opts map[string]interface{}
+	if err := json.Unmarshal(data, &opts); err != nil {
+		return fmt.Errorf("rule configuration must be a severity or an object")
+	}
+	*rc = RuleConfig{Severity: defaultSeverity}
+	if v, ok := opts["severity"]; ok {
+		name, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("severity must be a string, got %v", v)
+		}
+		sev, err := ParseSeverity(name)
+		if err != nil {
+			return err
+		}
+		rc.Severity = sev
+		delete(opts, "severity")
+	}
+	if len(opts) > 0 {
+		rc.Options = opts
+	}
+	return nil
+}
+
+// LoadConfig reads a JSON configuration file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,184 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// FileResult holds the diagnostics reported for one file.
+type FileResult struct {
+	Filename    string
+	Diagnostics []*Diagnostic
+}
+
+// filename returns the name of the file d refers to.
+func filename(r FileResult, d *Diagnostic) string {
+	if d.Source != "" {
+		return d.Source
+	}
+	return r.Filename
+}
+
+// WriteText writes one diagnostic per line in the form
+// "file:line:column: severity: message (rule)".
+func WriteText(w io.Writer, results []FileResult) error {
+	for _, r := range results {
+		for _, d := range r.Diagnostics {
+			sep := ":"
+			if d.Line == 0 {
+				sep = ": "
+			}
+			if _, err := fmt.Fprintf(w, "%s%s%s\n", filename(r, d), sep, d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type jsonDiagnostic struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Rule      string `json:"rule"`
+	Message   string `json:"message"`
+	Fixable   bool   `json:"fixable"`
+}
+
+// WriteJSON writes the diagnostics as a JSON array.
+func WriteJSON(w io.Writer, results []FileResult) error {
+	out := []jsonDiagnostic{}
+	for _, r := range results {
+		for _, d := range r.Diagnostics {
+			out = append(out, jsonDiagnostic{
+				File:      filename(r, d),
+				Line:      d.Line,
+				Column:    d.Column,
+				EndLine:   d.EndLine,
+				EndColumn: d.EndColumn,
+				Severity:  d.Severity.String(),
+				Rule:      d.Rule,
+				Message:   d.Message,
+				Fixable:   d.Fix != nil,
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// SARIF 2.1.0, trimmed to the parts csslint fills in.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+var sarifLevels = map[Severity]string{
+	Info:    "note",
+	Warning: "warning",
+	Error:   "error",
+}
+
+// WriteSARIF writes the diagnostics as a SARIF 2.1.0 log.
+func WriteSARIF(w io.Writer, results []FileResult) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "csslint"}},
+		Results: []sarifResult{},
+	}
+	ids := []string{SyntaxRule}
+	for _, r := range Rules() {
+		ids = append(ids, r.Name())
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id})
+	}
+	for _, r := range results {
+		for _, d := range r.Diagnostics {
+			loc := sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: filename(r, d)},
+				},
+			}
+			if d.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{
+					StartLine:   d.Line,
+					StartColumn: d.Column,
+					EndLine:     d.EndLine,
+					EndColumn:   d.EndColumn,
+				}
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    d.Rule,
+				Level:     sarifLevels[d.Severity],
+				Message:   sarifMessage{Text: d.Message},
+				Locations: []sarifLocation{loc},
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,422 @@
+// Package lint checks CSS stylesheets for common problems.
+//
+// Each check is a Rule: a Go type that is handed every node of a parsed
+// stylesheet and reports Diagnostics through a Context. The built-in rules
+// are registered on init and can be enabled, disabled or re-leveled with a
+// Config:
+//
+//	l, err := lint.New(cfg)
+//	...
+//	diags := l.Lint(src)
+//
+// Rules can be switched off from within a stylesheet with comments, from
+// the comment to the end of the block holding it, or of the stylesheet:
+//
+//	/* lint-disable */                   all rules
+//	/* lint-disable no-important, ... */ only the named rules
+//
+// and switched back on before that with lint-enable comments, naming the
+// rules or not in the same way.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/scanner"
+	"github.com/ttacon/css/sourcemap"
+)
+
+// Severity is the importance of a Diagnostic.
+type Severity int
+
+const (
+	// Off disables a rule.
+	Off Severity = iota
+	Info
+	Warning
+	Error
+)
+
+var severityNames = map[Severity]string{
+	Off:     "off",
+	Info:    "info",
+	Warning: "warning",
+	Error:   "error",
+}
+
+// String returns the name of the severity, as used in configuration files.
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity returns the Severity with the given name.
+func ParseSeverity(name string) (Severity, error) {
+	for s, n := range severityNames {
+		if strings.EqualFold(n, name) {
+			return s, nil
+		}
+	}
+	return Off, fmt.Errorf("unknown severity %q", name)
+}
+
+// Diagnostic is a problem reported by a Rule.
+type Diagnostic struct {
+	Rule     string
+	Severity Severity
+	// Line and Column locate the start of the problem, and EndLine and
+	// EndColumn the character following it; they are zero when the
+	// position is unknown.
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+	// Source, if set, is the file the position refers to, when it is
+	// not the linted one; see Remap.
+	Source  string
+	Message string
+	// Fix, if non-nil, repairs the problem.
+	Fix *Fix
+}
+
+// String returns the diagnostic in the form "line:column: severity: message (rule)".
+// The position is left out when it is unknown.
+func (d *Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s (%s)", d.Severity, d.Message, d.Rule)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s (%s)",
+		d.Line, d.Column, d.Severity, d.Message, d.Rule)
+}
+
+// Fix is an automatic repair of a Diagnostic.
+type Fix struct {
+	Message string
+	// Apply edits the stylesheet in place.
+	Apply func()
+}
+
+// Rule is a single lint check.
+type Rule interface {
+	// Name returns the identifier of the rule, as used in configuration
+	// files and lint-disable comments.
+	Name() string
+	// Severity returns the severity the rule reports with by default.
+	Severity() Severity
+	// Visit is called once for every node in the stylesheet, in the
+	// order of ast.Inspect.
+	Visit(ctx *Context, node ast.Node)
+}
+
+// Configurable is implemented by rules that take options.
+type Configurable interface {
+	Rule
+	// Configure returns a copy of the rule using the given options.
+	Configure(opts map[string]interface{}) (Rule, error)
+}
+
+var registry = map[string]Rule{}
+
+// Register makes a rule available to Linters. It panics if a rule with
+// the same name is already registered.
+func Register(r Rule) {
+	if _, dup := registry[r.Name()]; dup {
+		panic("lint: Register called twice for rule " + r.Name())
+	}
+	registry[r.Name()] = r
+}
+
+// Rules returns all registered rules, sorted by name.
+func Rules() []Rule {
+	rules := make([]Rule, 0, len(registry))
+	for _, r := range registry {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name() < rules[j].Name()
+	})
+	return rules
+}
+
+// Lookup returns the registered rule with the given name.
+func Lookup(name string) (Rule, bool) {
+	r, ok := registry[name]
+	return r, ok
+}
+
+// Context is handed to rules to report diagnostics.
+type Context struct {
+	rule     Rule
+	severity Severity
+	diags    *[]*Diagnostic
+}
+
+// Report records a problem with node. fix may be nil.
+func (c *Context) Report(node ast.Node, msg string, fix *Fix) {
+	d := &Diagnostic{
+		Rule:     c.rule.Name(),
+		Severity: c.severity,
+		Message:  msg,
+		Fix:      fix,
+	}
+	if pos := node.Pos(); pos.IsValid() {
+		d.Line, d.Column = pos.Line, pos.Column
+		d.EndLine, d.EndColumn = node.End().Line, node.End().Column
+	}
+	*c.diags = append(*c.diags, d)
+}
+
+// Reportf is like Report without a fix, formatting the message with fmt.Sprintf.
+func (c *Context) Reportf(node ast.Node, format string, args ...interface{}) {
+	c.Report(node, fmt.Sprintf(format, args...), nil)
+}
+
+type enabledRule struct {
+	rule     Rule
+	severity Severity
+}
+
+// Linter runs a configured set of rules.
+type Linter struct {
+	rules []enabledRule
+}
+
+// New returns a Linter running the registered rules as configured by cfg.
+// A nil cfg runs every rule at its default severity.
+func New(cfg *Config) (*Linter, error) {
+	l := &Linter{}
+	for _, r := range Rules() {
+		sev := r.Severity()
+		if cfg != nil {
+			if rc, ok := cfg.Rules[r.Name()]; ok {
+				if rc.Severity != defaultSeverity {
+					sev = rc.Severity
+				}
+				if len(rc.Options) > 0 {
+					c, ok := r.(Configurable)
+					if !ok {
+						return nil, fmt.Errorf("lint: rule %s takes no options", r.Name())
+					}
+					var err error
+					if r, err = c.Configure(rc.Options); err != nil {
+						return nil, fmt.Errorf("lint: rule %s: %v", c.Name(), err)
+					}
+				}
+			}
+		}
+		if sev == Off {
+			continue
+		}
+		l.rules = append(l.rules, enabledRule{r, sev})
+	}
+	if cfg != nil {
+		for name := range cfg.Rules {
+			if _, ok := registry[name]; !ok {
+				return nil, fmt.Errorf("lint: unknown rule %q", name)
+			}
+		}
+	}
+	return l, nil
+}
+
+// SyntaxRule is the rule name of the diagnostics reporting parse errors.
+const SyntaxRule = "syntax"
+
+// Lint parses src and runs the rules over it, honoring lint-disable
+// comments. Parse errors are reported as Error diagnostics of SyntaxRule;
+// the rules still run over the parts of src that could be parsed.
+func (l *Linter) Lint(src string) []*Diagnostic {
+	ss, err := parser.New(scanner.New(src)).Parse()
+	var diags []*Diagnostic
+	if errs, ok := err.(parser.ErrorList); ok {
+		for _, e := range errs {
+			diags = append(diags, &Diagnostic{
+				Rule:      SyntaxRule,
+				Severity:  Error,
+				Line:      e.Start.Line,
+				Column:    e.Start.Column,
+				EndLine:   e.End.Line,
+				EndColumn: e.End.Column,
+				Message:   e.Msg,
+			})
+		}
+	}
+	disabled := directives(src)
+	for _, d := range l.LintStylesheet(ss) {
+		if disabled.off(d) {
+			continue
+		}
+		diags = append(diags, d)
+	}
+	sortDiagnostics(diags)
+	return diags
+}
+
+// LintStylesheet runs the rules over an already parsed stylesheet. The
+// diagnostics are sorted by position.
+func (l *Linter) LintStylesheet(ss *ast.Stylesheet) []*Diagnostic {
+	var diags []*Diagnostic
+	for _, er := range l.rules {
+		ctx := &Context{rule: er.rule, severity: er.severity, diags: &diags}
+		ast.Inspect(ss, func(n ast.Node) bool {
+			if n != nil {
+				er.rule.Visit(ctx, n)
+			}
+			return true
+		})
+	}
+	sortDiagnostics(diags)
+	return diags
+}
+
+// sortDiagnostics sorts diags by position, those without one first,
+// keeping the order of diagnostics at the same position.
+func sortDiagnostics(diags []*Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i], diags[j]
+		return before(ast.Position{Line: a.Line, Column: a.Column}, ast.Position{Line: b.Line, Column: b.Column})
+	})
+}
+
+// Remap maps the positions of diagnostics back to the original sources
+// of the linted stylesheet using its source map, setting their Source.
+// Diagnostics at unmapped positions are left alone.
+func Remap(diags []*Diagnostic, m *sourcemap.Map) {
+	for _, d := range diags {
+		if d.Line == 0 {
+			continue
+		}
+		source, span, ok := m.RemapSpan(ast.Span{
+			From: ast.Position{Line: d.Line, Column: d.Column},
+			To:   ast.Position{Line: d.EndLine, Column: d.EndColumn},
+		})
+		if !ok {
+			continue
+		}
+		d.Source = source
+		d.Line, d.Column = span.From.Line, span.From.Column
+		d.EndLine, d.EndColumn = span.To.Line, span.To.Column
+	}
+}
+
+func before(a, b ast.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// disabled records the regions of a stylesheet in which rules are switched
+// off by lint-disable comments.
+type disabled []region
+
+// region is the part of a stylesheet from a lint-disable comment to the
+// matching lint-enable comment or the end of the enclosing block, in which
+// rule, or every rule but those in except if it is empty, is switched off.
+type region struct {
+	rule     string
+	except   []string
+	from, to ast.Position
+}
+
+// off reports whether the diagnostic d lies in a region switching off its
+// rule. Diagnostics without a position are never switched off.
+func (ds disabled) off(d *Diagnostic) bool {
+	if d.Line == 0 {
+		return false
+	}
+	pos := ast.Position{Line: d.Line, Column: d.Column}
+	for _, r := range ds {
+		if (r.rule == "" && !contains(r.except, d.Rule) || r.rule == d.Rule) && !before(pos, r.from) && (r.to.Line == 0 || before(pos, r.to)) {
+			return true
+		}
+	}
+	return false
+}
+
+// directives scans src for lint-disable and lint-enable comments. A
+// lint-enable comment ends the regions of the rules it names, or all of
+// them; the end of the block holding a lint-disable comment ends its
+// regions too. A lint-enable comment naming rules splits a region
+// switching off every rule, the rest of which leaves them on.
+func directives(src string) disabled {
+	var (
+		ds disabled
+		// open holds the indexes in ds of the regions not ended yet, and
+		// depths the block depth of their comment.
+		open   []int
+		depths []int
+		depth  int
+	)
+	end := func(i int, pos ast.Position) {
+		ds[open[i]].to = pos
+		open = append(open[:i], open[i+1:]...)
+		depths = append(depths[:i], depths[i+1:]...)
+	}
+	s := scanner.New(src)
+	for t := s.Next(); t.Type != scanner.TokenEOF && t.Type != scanner.TokenError; t = s.Next() {
+		pos := ast.Position{Line: t.Line, Column: t.Column}
+		switch {
+		case t.Type == scanner.TokenChar && t.Value == "{":
+			depth++
+		case t.Type == scanner.TokenChar && t.Value == "}":
+			depth--
+			for i := len(open) - 1; i >= 0; i-- {
+				if depths[i] > depth {
+					end(i, pos)
+				}
+			}
+		case t.Type == scanner.TokenComment:
+			text := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(t.Value, "/*"), "*/"))
+			fields := strings.Fields(text)
+			if len(fields) == 0 {
+				continue
+			}
+			var names []string
+			for _, name := range strings.Split(strings.Join(fields[1:], " "), ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					names = append(names, name)
+				}
+			}
+			switch fields[0] {
+			case "lint-disable":
+				if names == nil {
+					names = []string{""}
+				}
+				for _, name := range names {
+					open = append(open, len(ds))
+					depths = append(depths, depth)
+					ds = append(ds, region{rule: name, from: pos})
+				}
+			case "lint-enable":
+				for i := len(open) - 1; i >= 0; i-- {
+					r := ds[open[i]]
+					switch {
+					case names == nil || contains(names, r.rule):
+						end(i, pos)
+					case r.rule == "":
+						d := depths[i]
+						end(i, pos)
+						open = append(open, len(ds))
+						depths = append(depths, d)
+						except := append(append([]string(nil), r.except...), names...)
+						ds = append(ds, region{except: except, from: pos})
+					}
+				}
+			}
+		}
+	}
+	return ds
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/scanner"
+	"github.com/ttacon/css/sourcemap"
+)
+
+type lintTest struct {
+	text  string
+	rules []string
+}
+
+func TestRules(t *testing.T) {
+	var tests = []lintTest{
+		{`.a { color: red; }`, nil},
+		{`.a { color: red; color: blue; }`, []string{"duplicate-properties"}},
+		{`.a { colour: red; }`, []string{"unknown-properties"}},
+		{`.a { -webkit-appearance: none; }`, []string{"vendor-prefixes"}},
+		{`.a { }`, []string{"empty-blocks"}},
+		{`.a { color: red !important; }`, []string{"no-important"}},
+		{`.a { color: red ! IMPORTANT; content: "!important"; }`, []string{"no-important"}},
+		{`#a { color: red; }`, []string{"id-selectors"}},
+		{`#a .b .c .d .e { color: red; }`, []string{"id-selectors", "max-specificity"}},
+		{`.a { color: #abcd; }`, nil},
+		{`.a { color: #abcde; }`, []string{"invalid-hex-colors"}},
+		{`.a { margin: 0px 1px; }`, []string{"zero-units"}},
+		{`.a { margin: 0 1px; }`, nil},
+		{`/* lint-disable */ .a { margin: 0px; }`, nil},
+		{`/* lint-disable zero-units */ #a { margin: 0px; }`, []string{"id-selectors"}},
+		{`.a { margin: 0px; } /* lint-disable */`, []string{"zero-units"}},
+		{`.a { margin: 0px; } /* lint-disable zero-units */ .b { margin: 0px; }`, []string{"zero-units"}},
+		{`.a { /* lint-disable */ margin: 0px; } .b { margin: 0px; }`, []string{"zero-units"}},
+		{`/* lint-disable zero-units, id-selectors */ #a { margin: 0px; } /* lint-enable zero-units */ .b { margin: 0px; } #c { margin: 0 }`, []string{"zero-units"}},
+		{`/* lint-disable */ .a { margin: 0px; } /* lint-enable */ .b { margin: 0px; }`, []string{"zero-units"}},
+		{`/* lint-disable */ #a { margin: 0px; } /* lint-enable zero-units */ #b { margin: 0px; }`, []string{"zero-units"}},
+		{"/*\tlint-disable\n  zero-units */ .a { margin: 0px; }", nil},
+		{`@media print { .a { margin: 0px; } .b {} }`, []string{"empty-blocks", "zero-units"}},
+		{`.a { margin 0px; color: red; color: red; }`, []string{"duplicate-properties", "syntax"}},
+		{`@font-face { font-family: x; src: local(x); font-display: swap; }`, nil},
+		{`@font-face { font-family: x; src: local(x); colour: red; }`, []string{"syntax"}},
+		{`@-webkit-keyframes x { from { colour: red } to {} }`, []string{"empty-blocks", "unknown-properties", "vendor-prefixes"}},
+		{`.a { container: card / inline-size; } @container card (width > 40em) { .b { color: red; } }`, nil},
+		{`.a { container-type: inline-size; } @container (aspect-ratio > 1) { .b { color: red; } }`, []string{"undeclared-containers"}},
+		{`:root { --a: var(--b, 0); --b: calc(var(--a) + 1px); --c: var(--a); }`, []string{"variable-cycles"}},
+		{`:root { --a: var(--b, 0); } .a { --b: var(--c); }`, nil},
+	}
+
+	l, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		diags := l.Lint(test.text)
+		var got []string
+		for _, d := range diags {
+			got = append(got, d.Rule)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.rules) {
+			t.Errorf("%s: expected %v, got %v", test.text, test.rules, got)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := json.Unmarshal([]byte(`{
+		"rules": {
+			"id-selectors": "off",
+			"zero-units": "error",
+			"max-specificity": {"max": "0,1,0"}
+		}
+	}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := l.Lint(`#a { margin: 0px; }`)
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.Rule+":"+d.Severity.String())
+	}
+	sort.Strings(got)
+	want := []string{"max-specificity:warning", "zero-units:error"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if _, err := New(&Config{Rules: map[string]RuleConfig{"nope": {Severity: Error}}}); err == nil {
+		t.Error("expected an error for an unknown rule")
+	}
+}
+
+func TestFix(t *testing.T) {
+	ss, err := parser.New(scanner.New(`.a { margin: 0px 0em 1px; color: red; color: red; }`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range l.LintStylesheet(ss) {
+		if d.Fix != nil {
+			d.Fix.Apply()
+		}
+	}
+	decls := declarations(ss.Children[0]).Declarations
+	if len(decls) != 2 {
+		t.Fatalf("expected 2 declarations, got %d", len(decls))
+	}
+	if got := strings.Join(decls[0].Components, " "); got != "0 0 1px" {
+		t.Errorf("expected margin %q, got %q", "0 0 1px", got)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteSARIF(&buf, []FileResult{{
+		Filename: "a.css",
+		Diagnostics: []*Diagnostic{
+			{Rule: "no-important", Severity: Warning, Message: "!important used on \"color\""},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("unexpected SARIF log: %s", buf.String())
+	}
+	if r := log.Runs[0].Results[0]; r.RuleID != "no-important" || r.Level != "warning" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	var ids []string
+	for _, r := range log.Runs[0].Tool.Driver.Rules {
+		ids = append(ids, r.ID)
+	}
+	if !sort.StringsAreSorted(ids) || !contains(ids, SyntaxRule) {
+		t.Errorf("unexpected rules: %v", ids)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	l, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range l.Lint(".a {\n  margin: 0px;\n}\n.b { color }") {
+		got = append(got, fmt.Sprintf("%d:%d-%d:%d %s", d.Line, d.Column, d.EndLine, d.EndColumn, d.Rule))
+	}
+	// The diagnostics are sorted by position.
+	want := []string{"2:3-2:15 zero-units", "4:1-4:13 empty-blocks", "4:12-4:13 syntax"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRemap(t *testing.T) {
+	g := sourcemap.NewGenerator("a.css")
+	g.Add(sourcemap.Mapping{GenLine: 2, GenColumn: 3, Source: "a.scss", OrigLine: 7, OrigColumn: 5})
+	g.Add(sourcemap.Mapping{GenLine: 2, GenColumn: 15, Source: "a.scss", OrigLine: 7, OrigColumn: 17})
+	src := ".a {\n  margin: 0px;\n}\n/*# sourceMappingURL=data:application/json;base64," +
+		base64.StdEncoding.EncodeToString(g.Map().Bytes()) + " */\n"
+	m, err := sourcemap.Load(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := l.Lint(src)
+	Remap(diags, m)
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, []FileResult{{Filename: "a.css", Diagnostics: diags}}); err != nil {
+		t.Fatal(err)
+	}
+	want := "a.scss:7:5: info: unit on zero length \"0px\" (zero-units)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if d := diags[0]; d.EndLine != 7 || d.EndColumn != 17 {
+		t.Errorf("expected the end at 7:17, got %d:%d", d.EndLine, d.EndColumn)
+	}
+}
@@ -0,0 +1,482 @@
+package lint
+
+// knownProperties lists the standard CSS properties.
+var knownProperties = map[string]bool{
+	"accent-color":                  true,
+	"align-content":                 true,
+	"align-items":                   true,
+	"align-self":                    true,
+	"align-tracks":                  true,
+	"all":                           true,
+	"anchor-name":                   true,
+	"animation":                     true,
+	"animation-composition":         true,
+	"animation-delay":               true,
+	"animation-direction":           true,
+	"animation-duration":            true,
+	"animation-fill-mode":           true,
+	"animation-iteration-count":     true,
+	"animation-name":                true,
+	"animation-play-state":          true,
+	"animation-range":               true,
+	"animation-range-end":           true,
+	"animation-range-start":         true,
+	"animation-timeline":            true,
+	"animation-timing-function":     true,
+	"appearance":                    true,
+	"aspect-ratio":                  true,
+	"backdrop-filter":               true,
+	"backface-visibility":           true,
+	"background":                    true,
+	"background-attachment":         true,
+	"background-blend-mode":         true,
+	"background-clip":               true,
+	"background-color":              true,
+	"background-image":              true,
+	"background-origin":             true,
+	"background-position":           true,
+	"background-position-x":         true,
+	"background-position-y":         true,
+	"background-repeat":             true,
+	"background-size":               true,
+	"block-size":                    true,
+	"border":                        true,
+	"border-block":                  true,
+	"border-block-color":            true,
+	"border-block-end":              true,
+	"border-block-end-color":        true,
+	"border-block-end-style":        true,
+	"border-block-end-width":        true,
+	"border-block-start":            true,
+	"border-block-start-color":      true,
+	"border-block-start-style":      true,
+	"border-block-start-width":      true,
+	"border-block-style":            true,
+	"border-block-width":            true,
+	"border-bottom":                 true,
+	"border-bottom-color":           true,
+	"border-bottom-left-radius":     true,
+	"border-bottom-right-radius":    true,
+	"border-bottom-style":           true,
+	"border-bottom-width":           true,
+	"border-collapse":               true,
+	"border-color":                  true,
+	"border-end-end-radius":         true,
+	"border-end-start-radius":       true,
+	"border-image":                  true,
+	"border-image-outset":           true,
+	"border-image-repeat":           true,
+	"border-image-slice":            true,
+	"border-image-source":           true,
+	"border-image-width":            true,
+	"border-inline":                 true,
+	"border-inline-color":           true,
+	"border-inline-end":             true,
+	"border-inline-end-color":       true,
+	"border-inline-end-style":       true,
+	"border-inline-end-width":       true,
+	"border-inline-start":           true,
+	"border-inline-start-color":     true,
+	"border-inline-start-style":     true,
+	"border-inline-start-width":     true,
+	"border-inline-style":           true,
+	"border-inline-width":           true,
+	"border-left":                   true,
+	"border-left-color":             true,
+	"border-left-style":             true,
+	"border-left-width":             true,
+	"border-radius":                 true,
+	"border-right":                  true,
+	"border-right-color":            true,
+	"border-right-style":            true,
+	"border-right-width":            true,
+	"border-spacing":                true,
+	"border-start-end-radius":       true,
+	"border-start-start-radius":     true,
+	"border-style":                  true,
+	"border-top":                    true,
+	"border-top-color":              true,
+	"border-top-left-radius":        true,
+	"border-top-right-radius":       true,
+	"border-top-style":              true,
+	"border-top-width":              true,
+	"border-width":                  true,
+	"bottom":                        true,
+	"box-decoration-break":          true,
+	"box-shadow":                    true,
+	"box-sizing":                    true,
+	"break-after":                   true,
+	"break-before":                  true,
+	"break-inside":                  true,
+	"caption-side":                  true,
+	"caret":                         true,
+	"caret-color":                   true,
+	"caret-shape":                   true,
+	"clear":                         true,
+	"clip":                          true,
+	"clip-path":                     true,
+	"clip-rule":                     true,
+	"color":                         true,
+	"color-interpolation":           true,
+	"color-interpolation-filters":   true,
+	"color-scheme":                  true,
+	"column-count":                  true,
+	"column-fill":                   true,
+	"column-gap":                    true,
+	"column-rule":                   true,
+	"column-rule-color":             true,
+	"column-rule-style":             true,
+	"column-rule-width":             true,
+	"column-span":                   true,
+	"column-width":                  true,
+	"columns":                       true,
+	"contain":                       true,
+	"contain-intrinsic-block-size":  true,
+	"contain-intrinsic-height":      true,
+	"contain-intrinsic-inline-size": true,
+	"contain-intrinsic-size":        true,
+	"contain-intrinsic-width":       true,
+	"container":                     true,
+	"container-name":                true,
+	"container-type":                true,
+	"content":                       true,
+	"content-visibility":            true,
+	"counter-increment":             true,
+	"counter-reset":                 true,
+	"counter-set":                   true,
+	"cursor":                        true,
+	"cx":                            true,
+	"cy":                            true,
+	"d":                             true,
+	"direction":                     true,
+	"display":                       true,
+	"dominant-baseline":             true,
+	"empty-cells":                   true,
+	"field-sizing":                  true,
+	"fill":                          true,
+	"fill-opacity":                  true,
+	"fill-rule":                     true,
+	"filter":                        true,
+	"flex":                          true,
+	"flex-basis":                    true,
+	"flex-direction":                true,
+	"flex-flow":                     true,
+	"flex-grow":                     true,
+	"flex-shrink":                   true,
+	"flex-wrap":                     true,
+	"float":                         true,
+	"flood-color":                   true,
+	"flood-opacity":                 true,
+	"font":                          true,
+	"font-family":                   true,
+	"font-feature-settings":         true,
+	"font-kerning":                  true,
+	"font-language-override":        true,
+	"font-optical-sizing":           true,
+	"font-palette":                  true,
+	"font-size":                     true,
+	"font-size-adjust":              true,
+	"font-stretch":                  true,
+	"font-style":                    true,
+	"font-synthesis":                true,
+	"font-synthesis-small-caps":     true,
+	"font-synthesis-style":          true,
+	"font-synthesis-weight":         true,
+	"font-variant":                  true,
+	"font-variant-alternates":       true,
+	"font-variant-caps":             true,
+	"font-variant-east-asian":       true,
+	"font-variant-emoji":            true,
+	"font-variant-ligatures":        true,
+	"font-variant-numeric":          true,
+	"font-variant-position":         true,
+	"font-variation-settings":       true,
+	"font-weight":                   true,
+	"font-width":                    true,
+	"forced-color-adjust":           true,
+	"gap":                           true,
+	"grid":                          true,
+	"grid-area":                     true,
+	"grid-auto-columns":             true,
+	"grid-auto-flow":                true,
+	"grid-auto-rows":                true,
+	"grid-column":                   true,
+	"grid-column-end":               true,
+	"grid-column-gap":               true,
+	"grid-column-start":             true,
+	"grid-gap":                      true,
+	"grid-row":                      true,
+	"grid-row-end":                  true,
+	"grid-row-gap":                  true,
+	"grid-row-start":                true,
+	"grid-template":                 true,
+	"grid-template-areas":           true,
+	"grid-template-columns":         true,
+	"grid-template-rows":            true,
+	"hanging-punctuation":           true,
+	"height":                        true,
+	"hyphenate-character":           true,
+	"hyphenate-limit-chars":         true,
+	"hyphens":                       true,
+	"image-orientation":             true,
+	"image-rendering":               true,
+	"image-resolution":              true,
+	"initial-letter":                true,
+	"inline-size":                   true,
+	"inset":                         true,
+	"inset-block":                   true,
+	"inset-block-end":               true,
+	"inset-block-start":             true,
+	"inset-inline":                  true,
+	"inset-inline-end":              true,
+	"inset-inline-start":            true,
+	"isolation":                     true,
+	"justify-content":               true,
+	"justify-items":                 true,
+	"justify-self":                  true,
+	"justify-tracks":                true,
+	"left":                          true,
+	"letter-spacing":                true,
+	"lighting-color":                true,
+	"line-break":                    true,
+	"line-clamp":                    true,
+	"line-height":                   true,
+	"line-height-step":              true,
+	"list-style":                    true,
+	"list-style-image":              true,
+	"list-style-position":           true,
+	"list-style-type":               true,
+	"margin":                        true,
+	"margin-block":                  true,
+	"margin-block-end":              true,
+	"margin-block-start":            true,
+	"margin-bottom":                 true,
+	"margin-inline":                 true,
+	"margin-inline-end":             true,
+	"margin-inline-start":           true,
+	"margin-left":                   true,
+	"margin-right":                  true,
+	"margin-top":                    true,
+	"margin-trim":                   true,
+	"marker":                        true,
+	"marker-end":                    true,
+	"marker-mid":                    true,
+	"marker-start":                  true,
+	"mask":                          true,
+	"mask-border":                   true,
+	"mask-border-mode":              true,
+	"mask-border-outset":            true,
+	"mask-border-repeat":            true,
+	"mask-border-slice":             true,
+	"mask-border-source":            true,
+	"mask-border-width":             true,
+	"mask-clip":                     true,
+	"mask-composite":                true,
+	"mask-image":                    true,
+	"mask-mode":                     true,
+	"mask-origin":                   true,
+	"mask-position":                 true,
+	"mask-repeat":                   true,
+	"mask-size":                     true,
+	"mask-type":                     true,
+	"math-depth":                    true,
+	"math-shift":                    true,
+	"math-style":                    true,
+	"max-block-size":                true,
+	"max-height":                    true,
+	"max-inline-size":               true,
+	"max-width":                     true,
+	"min-block-size":                true,
+	"min-height":                    true,
+	"min-inline-size":               true,
+	"min-width":                     true,
+	"mix-blend-mode":                true,
+	"object-fit":                    true,
+	"object-position":               true,
+	"offset":                        true,
+	"offset-anchor":                 true,
+	"offset-distance":               true,
+	"offset-path":                   true,
+	"offset-position":               true,
+	"offset-rotate":                 true,
+	"opacity":                       true,
+	"order":                         true,
+	"orphans":                       true,
+	"outline":                       true,
+	"outline-color":                 true,
+	"outline-offset":                true,
+	"outline-style":                 true,
+	"outline-width":                 true,
+	"overflow":                      true,
+	"overflow-anchor":               true,
+	"overflow-block":                true,
+	"overflow-clip-margin":          true,
+	"overflow-inline":               true,
+	"overflow-wrap":                 true,
+	"overflow-x":                    true,
+	"overflow-y":                    true,
+	"overlay":                       true,
+	"overscroll-behavior":           true,
+	"overscroll-behavior-block":     true,
+	"overscroll-behavior-inline":    true,
+	"overscroll-behavior-x":         true,
+	"overscroll-behavior-y":         true,
+	"padding":                       true,
+	"padding-block":                 true,
+	"padding-block-end":             true,
+	"padding-block-start":           true,
+	"padding-bottom":                true,
+	"padding-inline":                true,
+	"padding-inline-end":            true,
+	"padding-inline-start":          true,
+	"padding-left":                  true,
+	"padding-right":                 true,
+	"padding-top":                   true,
+	"page":                          true,
+	"page-break-after":              true,
+	"page-break-before":             true,
+	"page-break-inside":             true,
+	"paint-order":                   true,
+	"perspective":                   true,
+	"perspective-origin":            true,
+	"place-content":                 true,
+	"place-items":                   true,
+	"place-self":                    true,
+	"pointer-events":                true,
+	"position":                      true,
+	"position-anchor":               true,
+	"position-area":                 true,
+	"position-try":                  true,
+	"position-try-fallbacks":        true,
+	"position-try-order":            true,
+	"position-visibility":           true,
+	"print-color-adjust":            true,
+	"quotes":                        true,
+	"r":                             true,
+	"resize":                        true,
+	"right":                         true,
+	"rotate":                        true,
+	"row-gap":                       true,
+	"ruby-align":                    true,
+	"ruby-position":                 true,
+	"rx":                            true,
+	"ry":                            true,
+	"scale":                         true,
+	"scroll-behavior":               true,
+	"scroll-margin":                 true,
+	"scroll-margin-block":           true,
+	"scroll-margin-block-end":       true,
+	"scroll-margin-block-start":     true,
+	"scroll-margin-bottom":          true,
+	"scroll-margin-inline":          true,
+	"scroll-margin-inline-end":      true,
+	"scroll-margin-inline-start":    true,
+	"scroll-margin-left":            true,
+	"scroll-margin-right":           true,
+	"scroll-margin-top":             true,
+	"scroll-padding":                true,
+	"scroll-padding-block":          true,
+	"scroll-padding-block-end":      true,
+	"scroll-padding-block-start":    true,
+	"scroll-padding-bottom":         true,
+	"scroll-padding-inline":         true,
+	"scroll-padding-inline-end":     true,
+	"scroll-padding-inline-start":   true,
+	"scroll-padding-left":           true,
+	"scroll-padding-right":          true,
+	"scroll-padding-top":            true,
+	"scroll-snap-align":             true,
+	"scroll-snap-stop":              true,
+	"scroll-snap-type":              true,
+	"scroll-timeline":               true,
+	"scroll-timeline-axis":          true,
+	"scroll-timeline-name":          true,
+	"scrollbar-color":               true,
+	"scrollbar-gutter":              true,
+	"scrollbar-width":               true,
+	"shape-image-threshold":         true,
+	"shape-margin":                  true,
+	"shape-outside":                 true,
+	"shape-rendering":               true,
+	"speak":                         true,
+	"speak-as":                      true,
+	"stop-color":                    true,
+	"stop-opacity":                  true,
+	"stroke":                        true,
+	"stroke-dasharray":              true,
+	"stroke-dashoffset":             true,
+	"stroke-linecap":                true,
+	"stroke-linejoin":               true,
+	"stroke-miterlimit":             true,
+	"stroke-opacity":                true,
+	"stroke-width":                  true,
+	"tab-size":                      true,
+	"table-layout":                  true,
+	"text-align":                    true,
+	"text-align-last":               true,
+	"text-anchor":                   true,
+	"text-combine-upright":          true,
+	"text-decoration":               true,
+	"text-decoration-color":         true,
+	"text-decoration-line":          true,
+	"text-decoration-skip":          true,
+	"text-decoration-skip-ink":      true,
+	"text-decoration-style":         true,
+	"text-decoration-thickness":     true,
+	"text-emphasis":                 true,
+	"text-emphasis-color":           true,
+	"text-emphasis-position":        true,
+	"text-emphasis-style":           true,
+	"text-indent":                   true,
+	"text-justify":                  true,
+	"text-orientation":              true,
+	"text-overflow":                 true,
+	"text-rendering":                true,
+	"text-shadow":                   true,
+	"text-size-adjust":              true,
+	"text-spacing-trim":             true,
+	"text-transform":                true,
+	"text-underline-offset":         true,
+	"text-underline-position":       true,
+	"text-wrap":                     true,
+	"text-wrap-mode":                true,
+	"text-wrap-style":               true,
+	"timeline-scope":                true,
+	"top":                           true,
+	"touch-action":                  true,
+	"transform":                     true,
+	"transform-box":                 true,
+	"transform-origin":              true,
+	"transform-style":               true,
+	"transition":                    true,
+	"transition-behavior":           true,
+	"transition-delay":              true,
+	"transition-duration":           true,
+	"transition-property":           true,
+	"transition-timing-function":    true,
+	"translate":                     true,
+	"unicode-bidi":                  true,
+	"user-select":                   true,
+	"vector-effect":                 true,
+	"vertical-align":                true,
+	"view-timeline":                 true,
+	"view-timeline-axis":            true,
+	"view-timeline-inset":           true,
+	"view-timeline-name":            true,
+	"view-transition-class":         true,
+	"view-transition-name":          true,
+	"visibility":                    true,
+	"white-space":                   true,
+	"white-space-collapse":          true,
+	"widows":                        true,
+	"width":                         true,
+	"will-change":                   true,
+	"word-break":                    true,
+	"word-spacing":                  true,
+	"word-wrap":                     true,
+	"writing-mode":                  true,
+	"x":                             true,
+	"y":                             true,
+	"z-index":                       true,
+	"zoom":                          true,
+}
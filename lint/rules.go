@@ -0,0 +1,395 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/cascade"
+	"github.com/ttacon/css/container"
+	"github.com/ttacon/css/vars"
+)
+
+func init() {
+	Register(duplicateProperties{})
+	Register(unknownProperties{})
+	Register(emptyBlocks{})
+	Register(noImportant{})
+	Register(idSelectors{})
+	Register(maxSpecificity{max: [3]int{1, 3, 3}})
+	Register(vendorPrefixes{})
+	Register(invalidHexColors{})
+	Register(zeroUnits{})
+	Register(undeclaredContainers{})
+	Register(variableCycles{})
+}
+
+// declarations returns the declarations directly inside node, if any.
+func declarations(node ast.Node) *ast.DeclarationList {
+	b := ast.BlockOf(node)
+	if b == nil {
+		return nil
+	}
+	return b.DeclList
+}
+
+// duplicate-properties ////////////////////////////////////////////////
+
+type duplicateProperties struct{}
+
+func (duplicateProperties) Name() string       { return "duplicate-properties" }
+func (duplicateProperties) Severity() Severity { return Warning }
+
+func (duplicateProperties) Visit(ctx *Context, node ast.Node) {
+	list := declarations(node)
+	if list == nil {
+		return
+	}
+	seen := map[string]*ast.Declaration{}
+	for _, d := range list.Declarations {
+		name := strings.ToLower(d.Ident)
+		prev, ok := seen[name]
+		seen[name] = d
+		if !ok {
+			continue
+		}
+		var fix *Fix
+		if strings.Join(prev.Components, " ") == strings.Join(d.Components, " ") {
+			fix = &Fix{
+				Message: "remove the earlier declaration",
+				Apply:   func() { removeDeclaration(list, prev) },
+			}
+		}
+		ctx.Report(d, fmt.Sprintf("duplicate property %q", d.Ident), fix)
+	}
+}
+
+func removeDeclaration(list *ast.DeclarationList, d *ast.Declaration) {
+	for i, decl := range list.Declarations {
+		if decl == d {
+			list.Declarations = append(list.Declarations[:i], list.Declarations[i+1:]...)
+			return
+		}
+	}
+}
+
+// unknown-properties //////////////////////////////////////////////////
+
+type unknownProperties struct{}
+
+func (unknownProperties) Name() string       { return "unknown-properties" }
+func (unknownProperties) Severity() Severity { return Error }
+
+func (unknownProperties) Visit(ctx *Context, node ast.Node) {
+	switch node.(type) {
+	case *ast.QualifiedRule, *ast.AtRule, *ast.Keyframe:
+	default:
+		// The declarations of other rules are descriptors, checked by
+		// the parser.
+		return
+	}
+	list := declarations(node)
+	if list == nil {
+		return
+	}
+	for _, d := range list.Declarations {
+		name := strings.ToLower(d.Ident)
+		if strings.HasPrefix(name, "--") || vendorPrefix(name) != "" {
+			continue
+		}
+		if !knownProperties[name] {
+			ctx.Reportf(d, "unknown property %q", d.Ident)
+		}
+	}
+}
+
+// empty-blocks ////////////////////////////////////////////////////////
+
+type emptyBlocks struct{}
+
+func (emptyBlocks) Name() string       { return "empty-blocks" }
+func (emptyBlocks) Severity() Severity { return Warning }
+
+func (emptyBlocks) Visit(ctx *Context, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Stylesheet:
+		// Top-level rules can be fixed by removing them from the sheet.
+		for _, r := range n.Children {
+			if !isEmptyRule(r) {
+				continue
+			}
+			r := r
+			ctx.Report(r, "empty block", &Fix{
+				Message: "remove the rule",
+				Apply:   func() { removeRule(n, r) },
+			})
+		}
+	case *ast.Block:
+		for _, r := range n.Rules {
+			if isEmptyRule(r) {
+				ctx.Reportf(r, "empty block")
+			}
+		}
+	}
+}
+
+func isEmptyRule(r ast.Rule) bool {
+	if r, ok := r.(*ast.QualifiedRule); ok {
+		return isEmptyBlock(r.Block)
+	}
+	b := ast.BlockOf(r)
+	return b != nil && isEmptyBlock(b)
+}
+
+func isEmptyBlock(b *ast.Block) bool {
+	if b == nil {
+		return true
+	}
+	if len(b.Rules) > 0 {
+		return false
+	}
+	return b.DeclList == nil || len(b.DeclList.Declarations) == 0
+}
+
+func removeRule(ss *ast.Stylesheet, r ast.Rule) {
+	for i, rule := range ss.Children {
+		if rule == r {
+			ss.Children = append(ss.Children[:i], ss.Children[i+1:]...)
+			return
+		}
+	}
+}
+
+// no-important ////////////////////////////////////////////////////////
+
+type noImportant struct{}
+
+func (noImportant) Name() string       { return "no-important" }
+func (noImportant) Severity() Severity { return Warning }
+
+func (noImportant) Visit(ctx *Context, node ast.Node) {
+	d, ok := node.(*ast.Declaration)
+	if !ok {
+		return
+	}
+	if d.Important {
+		ctx.Reportf(d, "!important used on %q", d.Ident)
+	}
+}
+
+// id-selectors ////////////////////////////////////////////////////////
+
+type idSelectors struct{}
+
+func (idSelectors) Name() string       { return "id-selectors" }
+func (idSelectors) Severity() Severity { return Warning }
+
+func (idSelectors) Visit(ctx *Context, node ast.Node) {
+	r, ok := node.(*ast.QualifiedRule)
+	if !ok {
+		return
+	}
+	for _, c := range r.Components {
+		if cascade.Specificity(c.Name)[0] > 0 {
+			ctx.Reportf(r, "selector %q uses an ID", c.Name)
+		}
+	}
+}
+
+// max-specificity /////////////////////////////////////////////////////
+
+type maxSpecificity struct {
+	max [3]int
+}
+
+func (maxSpecificity) Name() string       { return "max-specificity" }
+func (maxSpecificity) Severity() Severity { return Warning }
+
+// Configure accepts a "max" option of the form "a,b,c".
+func (m maxSpecificity) Configure(opts map[string]interface{}) (Rule, error) {
+	for k, v := range opts {
+		if k != "max" {
+			return nil, fmt.Errorf("unknown option %q", k)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("max must be a string like \"0,3,0\"")
+		}
+		parts := strings.Split(s, ",")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("max must be a string like \"0,3,0\"")
+		}
+		for i, p := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil {
+				return nil, fmt.Errorf("invalid max %q", s)
+			}
+			m.max[i] = n
+		}
+	}
+	return m, nil
+}
+
+func (m maxSpecificity) Visit(ctx *Context, node ast.Node) {
+	r, ok := node.(*ast.QualifiedRule)
+	if !ok {
+		return
+	}
+	for _, c := range r.Components {
+		if spec := cascade.Specificity(c.Name); cascade.CompareSpecificity(spec, m.max) > 0 {
+			ctx.Reportf(r, "selector %q has specificity %d,%d,%d, more than %d,%d,%d",
+				c.Name, spec[0], spec[1], spec[2], m.max[0], m.max[1], m.max[2])
+		}
+	}
+}
+
+// vendor-prefixes /////////////////////////////////////////////////////
+
+type vendorPrefixes struct{}
+
+func (vendorPrefixes) Name() string       { return "vendor-prefixes" }
+func (vendorPrefixes) Severity() Severity { return Info }
+
+var vendorPrefixList = []string{"-webkit-", "-moz-", "-ms-", "-o-"}
+
+// vendorPrefix returns the vendor prefix of name, or "".
+func vendorPrefix(name string) string {
+	name = strings.ToLower(name)
+	for _, p := range vendorPrefixList {
+		if strings.HasPrefix(name, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+func (vendorPrefixes) Visit(ctx *Context, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.AtRule:
+		if vendorPrefix(strings.TrimPrefix(n.AtKeyword, "@")) != "" {
+			ctx.Reportf(n, "vendor-prefixed at-rule %q", n.AtKeyword)
+		}
+	case *ast.KeyframesRule:
+		if vendorPrefix(strings.TrimPrefix(n.AtKeyword, "@")) != "" {
+			ctx.Reportf(n, "vendor-prefixed at-rule %q", n.AtKeyword)
+		}
+	case *ast.QualifiedRule:
+		for _, c := range n.Components {
+			for _, p := range vendorPrefixList {
+				if strings.Contains(c.Name, ":"+p) {
+					ctx.Reportf(n, "vendor-prefixed selector %q", c.Name)
+					break
+				}
+			}
+		}
+	case *ast.Declaration:
+		if vendorPrefix(n.Ident) != "" {
+			ctx.Reportf(n, "vendor-prefixed property %q", n.Ident)
+			return
+		}
+		for _, c := range n.Components {
+			if vendorPrefix(c) != "" {
+				ctx.Reportf(n, "vendor-prefixed value %q", c)
+				return
+			}
+		}
+	}
+}
+
+// invalid-hex-colors //////////////////////////////////////////////////
+
+type invalidHexColors struct{}
+
+func (invalidHexColors) Name() string       { return "invalid-hex-colors" }
+func (invalidHexColors) Severity() Severity { return Error }
+
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+func (invalidHexColors) Visit(ctx *Context, node ast.Node) {
+	d, ok := node.(*ast.Declaration)
+	if !ok || strings.HasPrefix(d.Ident, "--") {
+		return
+	}
+	for _, c := range d.Components {
+		if strings.HasPrefix(c, "#") && !hexColor.MatchString(c) {
+			ctx.Reportf(d, "invalid hex color %q", c)
+		}
+	}
+}
+
+// zero-units //////////////////////////////////////////////////////////
+
+type zeroUnits struct{}
+
+func (zeroUnits) Name() string       { return "zero-units" }
+func (zeroUnits) Severity() Severity { return Info }
+
+var zeroLength = regexp.MustCompile(`^[+-]?(?:0+(?:\.0*)?|\.0+)` +
+	`(?i:px|em|rem|ex|rex|ch|rch|ic|ric|cap|rcap|lh|rlh|vw|vh|vi|vb|vmin|vmax|` +
+	`svw|svh|lvw|lvh|dvw|dvh|cqw|cqh|cqi|cqb|cqmin|cqmax|cm|mm|q|in|pt|pc)$`)
+
+func (zeroUnits) Visit(ctx *Context, node ast.Node) {
+	d, ok := node.(*ast.Declaration)
+	if !ok || strings.HasPrefix(d.Ident, "--") {
+		return
+	}
+	depth := 0
+	for i, c := range d.Components {
+		switch {
+		case strings.HasSuffix(c, "("):
+			depth++
+		case c == ")":
+			depth--
+		case depth == 0 && zeroLength.MatchString(c):
+			// Units are significant inside functions such as calc().
+			i := i
+			ctx.Report(d, fmt.Sprintf("unit on zero length %q", c), &Fix{
+				Message: "drop the unit",
+				Apply:   func() { d.Components[i] = "0" },
+			})
+		}
+	}
+}
+
+// undeclared-containers ///////////////////////////////////////////////
+
+type undeclaredContainers struct{}
+
+func (undeclaredContainers) Name() string       { return "undeclared-containers" }
+func (undeclaredContainers) Severity() Severity { return Warning }
+
+func (undeclaredContainers) Visit(ctx *Context, node ast.Node) {
+	ss, ok := node.(*ast.Stylesheet)
+	if !ok {
+		return
+	}
+	for _, ref := range container.Undeclared(ss) {
+		if ref.Query.Name != "" {
+			ctx.Reportf(ref.Rule, "no container named %q can answer %q", ref.Query.Name, ref.Query.String())
+		} else {
+			ctx.Reportf(ref.Rule, "no declared container can answer %q", ref.Query.String())
+		}
+	}
+}
+
+// variable-cycles //////////////////////////////////////////////////////
+
+type variableCycles struct{}
+
+func (variableCycles) Name() string       { return "variable-cycles" }
+func (variableCycles) Severity() Severity { return Error }
+
+func (variableCycles) Visit(ctx *Context, node ast.Node) {
+	ss, ok := node.(*ast.Stylesheet)
+	if !ok {
+		return
+	}
+	a := vars.Analyze(ss)
+	for _, cycle := range a.Cycles() {
+		for _, d := range a.Definitions(cycle[0]) {
+			ctx.Reportf(d.Node, "custom properties %s reference each other, making their values invalid", strings.Join(cycle, ", "))
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package media
+
+import (
+	"math"
+	"strings"
+)
+
+// Env describes the device and the user preferences media queries are
+// evaluated against.
+type Env struct {
+	// Type is the media type, like "screen" or "print". It defaults to
+	// "screen".
+	Type string
+	// Width and Height are the size of the viewport in CSS pixels.
+	Width, Height float64
+	// DeviceWidth and DeviceHeight are the size of the screen in CSS
+	// pixels. They default to the size of the viewport.
+	DeviceWidth, DeviceHeight float64
+	// Resolution is the pixel density in dppx. It defaults to 1.
+	Resolution float64
+	// FontSize is the initial font size in pixels, which em and rem are
+	// relative to. It defaults to 16.
+	FontSize float64
+	// Color is the number of bits per color component, or 0 on a
+	// monochrome device. It defaults to 8.
+	Color int
+	// ColorIndex is the number of entries of the color lookup table.
+	ColorIndex int
+	// Monochrome is the number of bits per pixel on a monochrome device.
+	Monochrome int
+	// ColorScheme is the value of prefers-color-scheme, "light" or
+	// "dark". It defaults to "light".
+	ColorScheme string
+	// Features holds the values of the other discrete features by name,
+	// like "hover": "none" or "prefers-reduced-motion": "reduce". Missing
+	// features take the value of a desktop browser with default
+	// preferences, listed in Defaults.
+	Features map[string]string
+}
+
+// Defaults holds the values of the discrete features missing from
+// Env.Features.
+var Defaults = map[string]string{
+	"any-hover":                    "hover",
+	"any-pointer":                  "fine",
+	"color-gamut":                  "srgb",
+	"display-mode":                 "browser",
+	"dynamic-range":                "standard",
+	"environment-blending":         "opaque",
+	"forced-colors":                "none",
+	"grid":                         "0",
+	"hover":                        "hover",
+	"inverted-colors":              "none",
+	"nav-controls":                 "back",
+	"overflow-block":               "scroll",
+	"overflow-inline":              "scroll",
+	"pointer":                      "fine",
+	"prefers-contrast":             "no-preference",
+	"prefers-reduced-data":         "no-preference",
+	"prefers-reduced-motion":       "no-preference",
+	"prefers-reduced-transparency": "no-preference",
+	"scan":                         "progressive",
+	"scripting":                    "enabled",
+	"update":                       "fast",
+	"video-dynamic-range":          "standard",
+}
+
+// Result is the result of a media condition, in three-valued logic.
+type Result int
+
+const (
+	False Result = iota
+	True
+	// Unknown is the result of conditions using unknown features or
+	// values, and of general enclosed expressions.
+	Unknown
+)
+
+func (r Result) String() string {
+	return [...]string{"false", "true", "unknown"}[r]
+}
+
+// Match reports whether any query of l matches env. An empty list matches
+// every environment.
+func (l List) Match(env *Env) bool {
+	if len(l) == 0 {
+		return true
+	}
+	for _, q := range l {
+		if q.Match(env) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether q matches env. A query evaluating to Unknown does
+// not match.
+func (q *Query) Match(env *Env) bool {
+	r := True
+	if q.Type != "" && q.Type != "all" && q.Type != env.mediaType() {
+		r = False
+	}
+	if r == True && q.Cond != nil {
+		r = Eval(q.Cond, env)
+	}
+	if q.Not {
+		r = not(r)
+	}
+	return r == True
+}
+
+// Eval evaluates c against env.
+func Eval(c Condition, env *Env) Result {
+	switch c := c.(type) {
+	case *And:
+		r := True
+		for _, c := range c.Conds {
+			switch Eval(c, env) {
+			case False:
+				return False
+			case Unknown:
+				r = Unknown
+			}
+		}
+		return r
+	case *Or:
+		r := False
+		for _, c := range c.Conds {
+			switch Eval(c, env) {
+			case True:
+				return True
+			case Unknown:
+				r = Unknown
+			}
+		}
+		return r
+	case *Not:
+		return not(Eval(c.Cond, env))
+	case *Feature:
+		return env.feature(c)
+	case *Range:
+		return env.rangeFeature(c)
+	}
+	return Unknown
+}
+
+func not(r Result) Result {
+	switch r {
+	case True:
+		return False
+	case False:
+		return True
+	}
+	return Unknown
+}
+
+func result(b bool) Result {
+	if b {
+		return True
+	}
+	return False
+}
+
+// kind is the type of the values of a range feature.
+type kind int
+
+const (
+	length kind = iota + 1
+	ratio
+	resolution
+	integer
+)
+
+// rangeFeatures are the features that accept min- and max- prefixes and
+// the range syntax.
+var rangeFeatures = map[string]kind{
+	"width":               length,
+	"height":              length,
+	"device-width":        length,
+	"device-height":       length,
+	"aspect-ratio":        ratio,
+	"device-aspect-ratio": ratio,
+	"resolution":          resolution,
+	"color":               integer,
+	"color-index":         integer,
+	"monochrome":          integer,
+}
+
+// discreteFeatures are the features taking keywords, with their values.
+var discreteFeatures = map[string][]string{
+	"any-hover":                    {"none", "hover"},
+	"any-pointer":                  {"none", "coarse", "fine"},
+	"color-gamut":                  {"srgb", "p3", "rec2020"},
+	"display-mode":                 {"fullscreen", "standalone", "minimal-ui", "browser", "picture-in-picture"},
+	"dynamic-range":                {"standard", "high"},
+	"environment-blending":         {"opaque", "additive", "subtractive"},
+	"forced-colors":                {"none", "active"},
+	"grid":                         {"0", "1"},
+	"hover":                        {"none", "hover"},
+	"inverted-colors":              {"none", "inverted"},
+	"nav-controls":                 {"none", "back"},
+	"orientation":                  {"portrait", "landscape"},
+	"overflow-block":               {"none", "scroll", "paged"},
+	"overflow-inline":              {"none", "scroll"},
+	"pointer":                      {"none", "coarse", "fine"},
+	"prefers-color-scheme":         {"light", "dark"},
+	"prefers-contrast":             {"no-preference", "less", "more", "custom"},
+	"prefers-reduced-data":         {"no-preference", "reduce"},
+	"prefers-reduced-motion":       {"no-preference", "reduce"},
+	"prefers-reduced-transparency": {"no-preference", "reduce"},
+	"scan":                         {"interlace", "progressive"},
+	"scripting":                    {"none", "initial-only", "enabled"},
+	"update":                       {"none", "slow", "fast"},
+	"video-dynamic-range":          {"standard", "high"},
+}
+
+func (env *Env) feature(f *Feature) Result {
+	name, prefix := f.Name, ""
+	if strings.HasPrefix(name, "min-") || strings.HasPrefix(name, "max-") {
+		name, prefix = name[4:], name[:3]
+	}
+	if k, ok := rangeFeatures[name]; ok {
+		actual := env.number(name)
+		if f.Value == nil {
+			if prefix != "" {
+				return Unknown
+			}
+			return result(actual != 0)
+		}
+		v, ok := env.resolve(f.Value, k)
+		if !ok {
+			return Unknown
+		}
+		switch prefix {
+		case "min":
+			return result(compare(actual, GE, v))
+		case "max":
+			return result(compare(actual, LE, v))
+		}
+		return result(compare(actual, EQ, v))
+	}
+
+	values, ok := discreteFeatures[name]
+	if !ok || prefix != "" {
+		return Unknown
+	}
+	actual := env.keyword(name)
+	if f.Value == nil {
+		return result(actual != "none" && actual != "no-preference" && actual != "0")
+	}
+	want := f.Value.Ident
+	if name == "grid" && f.Value.Unit == "" && f.Value.Den == 0 &&
+		(f.Value.Num == 0 || f.Value.Num == 1) {
+		want = formatNum(f.Value.Num)
+	}
+	for _, v := range values {
+		if v == want {
+			return result(actual == want)
+		}
+	}
+	return Unknown
+}
+
+func (env *Env) rangeFeature(r *Range) Result {
+	k, ok := rangeFeatures[r.Name]
+	if !ok {
+		return Unknown
+	}
+	actual := env.number(r.Name)
+	res := True
+	if r.Left != nil {
+		v, ok := env.resolve(r.Left, k)
+		if !ok {
+			return Unknown
+		}
+		if !compare(v, r.LeftOp, actual) {
+			res = False
+		}
+	}
+	if r.Right != nil {
+		v, ok := env.resolve(r.Right, k)
+		if !ok {
+			return Unknown
+		}
+		if !compare(actual, r.RightOp, v) {
+			res = False
+		}
+	}
+	return res
+}
+
+// compare compares a and b with op, allowing for rounding errors so that
+// ratios like 1920/1080 and 16/9 are equal.
+func compare(a float64, op Op, b float64) bool {
+	eq := math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+	switch op {
+	case LT:
+		return a < b && !eq
+	case LE:
+		return a < b || eq
+	case GT:
+		return a > b && !eq
+	case GE:
+		return a > b || eq
+	}
+	return eq
+}
+
+// number returns the value of the range feature name in env, in px, dppx,
+// or as a ratio.
+func (env *Env) number(name string) float64 {
+	switch name {
+	case "width":
+		return env.Width
+	case "height":
+		return env.Height
+	case "device-width":
+		return env.deviceWidth()
+	case "device-height":
+		return env.deviceHeight()
+	case "aspect-ratio":
+		return div(env.Width, env.Height)
+	case "device-aspect-ratio":
+		return div(env.deviceWidth(), env.deviceHeight())
+	case "resolution":
+		return env.resolution()
+	case "color":
+		if env.Color == 0 && env.Monochrome == 0 {
+			return 8
+		}
+		return float64(env.Color)
+	case "color-index":
+		return float64(env.ColorIndex)
+	case "monochrome":
+		return float64(env.Monochrome)
+	}
+	return 0
+}
+
+// keyword returns the value of the discrete feature name in env.
+func (env *Env) keyword(name string) string {
+	switch name {
+	case "orientation":
+		if env.Height >= env.Width {
+			return "portrait"
+		}
+		return "landscape"
+	case "prefers-color-scheme":
+		if env.ColorScheme == "" {
+			return "light"
+		}
+		return env.ColorScheme
+	}
+	if v, ok := env.Features[name]; ok {
+		return v
+	}
+	return Defaults[name]
+}
+
+// resolve returns v in the unit of the values of features of kind k.
+func (env *Env) resolve(v *Value, k kind) (float64, bool) {
+	if v.Ident != "" {
+		return 0, false
+	}
+	switch k {
+	case length:
+		if v.Den != 0 {
+			return 0, false
+		}
+		if v.Unit == "" {
+			return v.Num, v.Num == 0
+		}
+		f, ok := env.lengthUnit(v.Unit)
+		return v.Num * f, ok
+	case ratio:
+		if v.Unit != "" {
+			return 0, false
+		}
+		if v.Den == 0 {
+			return v.Num, true
+		}
+		return div(v.Num, v.Den), true
+	case resolution:
+		f, ok := resolutionUnits[v.Unit]
+		return v.Num * f, ok && v.Den == 0
+	case integer:
+		return v.Num, v.Unit == "" && v.Den == 0 && v.Num == math.Trunc(v.Num)
+	}
+	return 0, false
+}
+
+// lengthUnit returns the size of the length unit u in px.
+func (env *Env) lengthUnit(u string) (float64, bool) {
+	em := env.FontSize
+	if em == 0 {
+		em = 16
+	}
+	switch u {
+	case "px":
+		return 1, true
+	case "em", "rem", "lh", "rlh":
+		return em, true
+	case "ex", "ch", "rex", "rch":
+		return em / 2, true
+	case "cm":
+		return 96 / 2.54, true
+	case "mm":
+		return 96 / 25.4, true
+	case "q":
+		return 96 / 101.6, true
+	case "in":
+		return 96, true
+	case "pt":
+		return 96.0 / 72, true
+	case "pc":
+		return 16, true
+	case "vw":
+		return env.Width / 100, true
+	case "vh":
+		return env.Height / 100, true
+	case "vmin":
+		return math.Min(env.Width, env.Height) / 100, true
+	case "vmax":
+		return math.Max(env.Width, env.Height) / 100, true
+	}
+	return 0, false
+}
+
+// resolutionUnits holds the resolution units in dppx.
+var resolutionUnits = map[string]float64{
+	"dppx": 1,
+	"x":    1,
+	"dpi":  1.0 / 96,
+	"dpcm": 2.54 / 96,
+}
+
+func (env *Env) mediaType() string {
+	if env.Type == "" {
+		return "screen"
+	}
+	return strings.ToLower(env.Type)
+}
+
+func (env *Env) deviceWidth() float64 {
+	if env.DeviceWidth == 0 {
+		return env.Width
+	}
+	return env.DeviceWidth
+}
+
+func (env *Env) deviceHeight() float64 {
+	if env.DeviceHeight == 0 {
+		return env.Height
+	}
+	return env.DeviceHeight
+}
+
+func (env *Env) resolution() float64 {
+	if env.Resolution == 0 {
+		return 1
+	}
+	return env.Resolution
+}
+
+func div(a, b float64) float64 {
+	if b == 0 {
+		return math.Inf(1)
+	}
+	return a / b
+}
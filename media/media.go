@@ -0,0 +1,199 @@
+// Package media parses and evaluates media queries, following Media
+// Queries Level 4 and 5 (https://www.w3.org/TR/mediaqueries-5/).
+package media
+
+import (
+	"strconv"
+	"strings"
+)
+
+// List is a media query list. An empty list matches every environment.
+type List []*Query
+
+// Query is a media query:
+//
+//	[ not | only ]? <media-type> [ and <condition> ]?
+//	<condition>
+type Query struct {
+	Not  bool
+	Only bool
+	// Type is the lowercased media type, or "" if the query is only a
+	// condition.
+	Type string
+	// Cond is the condition of the query, or nil.
+	Cond Condition
+}
+
+// Condition is a media condition: *And, *Or, *Not, *Feature, *Range or
+// *GeneralEnclosed.
+type Condition interface {
+	String() string
+	condition()
+}
+
+// And is a conjunction of conditions.
+type And struct {
+	Conds []Condition
+}
+
+// Or is a disjunction of conditions.
+type Or struct {
+	Conds []Condition
+}
+
+// Not is the negation of a condition.
+type Not struct {
+	Cond Condition
+}
+
+// Feature is a media feature in the plain or the boolean syntax, like
+// (min-width: 40em) or (hover).
+type Feature struct {
+	// Name is the lowercased name of the feature, including any min- or
+	// max- prefix.
+	Name string
+	// Value is the value of the feature, or nil in the boolean syntax.
+	Value *Value
+}
+
+// Range is a media feature in the range syntax, like (width >= 40em) or
+// (400px <= width < 700px):
+//
+//	[ Left LeftOp ]? Name [ RightOp Right ]?
+type Range struct {
+	Name    string
+	Left    *Value
+	LeftOp  Op
+	RightOp Op
+	Right   *Value
+}
+
+// GeneralEnclosed is a parenthesized expression or a function that is
+// not a media condition, kept for future syntax. It evaluates to
+// Unknown.
+type GeneralEnclosed struct {
+	Text string
+}
+
+func (*And) condition()             {}
+func (*Or) condition()              {}
+func (*Not) condition()             {}
+func (*Feature) condition()         {}
+func (*Range) condition()           {}
+func (*GeneralEnclosed) condition() {}
+
+// Op is a comparison operator of the range syntax.
+type Op int
+
+const (
+	LT Op = iota + 1 // <
+	LE               // <=
+	GT               // >
+	GE               // >=
+	EQ               // =
+)
+
+var opNames = map[Op]string{LT: "<", LE: "<=", GT: ">", GE: ">=", EQ: "="}
+
+func (op Op) String() string {
+	return opNames[op]
+}
+
+// Value is the value of a media feature: a number with an optional unit,
+// a ratio, or an ident.
+type Value struct {
+	// Ident is the lowercased value of an ident.
+	Ident string
+	// Num is the value of a number or a dimension, or the numerator of a
+	// ratio.
+	Num float64
+	// Unit is the lowercased unit of a dimension.
+	Unit string
+	// Den is the denominator of a ratio, or 0.
+	Den float64
+}
+
+// String returns the media query list in canonical form.
+func (l List) String() string {
+	queries := make([]string, len(l))
+	for i, q := range l {
+		queries[i] = q.String()
+	}
+	return strings.Join(queries, ", ")
+}
+
+// String returns the media query in canonical form.
+func (q *Query) String() string {
+	var parts []string
+	switch {
+	case q.Not:
+		parts = append(parts, "not")
+	case q.Only:
+		parts = append(parts, "only")
+	}
+	if q.Type != "" {
+		parts = append(parts, q.Type)
+		if q.Cond != nil {
+			parts = append(parts, "and")
+		}
+	}
+	if q.Cond != nil {
+		parts = append(parts, q.Cond.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (c *And) String() string { return join(c.Conds, " and ") }
+func (c *Or) String() string  { return join(c.Conds, " or ") }
+func (c *Not) String() string { return "not " + inParens(c.Cond) }
+
+func (f *Feature) String() string {
+	if f.Value == nil {
+		return "(" + f.Name + ")"
+	}
+	return "(" + f.Name + ": " + f.Value.String() + ")"
+}
+
+func (r *Range) String() string {
+	s := r.Name
+	if r.Left != nil {
+		s = r.Left.String() + " " + r.LeftOp.String() + " " + s
+	}
+	if r.Right != nil {
+		s += " " + r.RightOp.String() + " " + r.Right.String()
+	}
+	return "(" + s + ")"
+}
+
+func (g *GeneralEnclosed) String() string { return g.Text }
+
+func (v *Value) String() string {
+	switch {
+	case v.Ident != "":
+		return v.Ident
+	case v.Den != 0:
+		return formatNum(v.Num) + "/" + formatNum(v.Den)
+	}
+	return formatNum(v.Num) + v.Unit
+}
+
+func formatNum(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func join(conds []Condition, sep string) string {
+	parts := make([]string, len(conds))
+	for i, c := range conds {
+		parts[i] = inParens(c)
+	}
+	return strings.Join(parts, sep)
+}
+
+// inParens returns the text of c as an operand of and, or and not.
+func inParens(c Condition) string {
+	switch c.(type) {
+	case *And, *Or, *Not:
+		return "(" + c.String() + ")"
+	}
+	return c.String()
+}
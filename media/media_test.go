@@ -0,0 +1,231 @@
+package media
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ttacon/pretty"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want List
+	}{
+		{"", List{}},
+		{"screen", List{{Type: "screen"}}},
+		{"not print, ONLY Screen", List{{Not: true, Type: "print"}, {Only: true, Type: "screen"}}},
+		{"screen and (min-width: 40em)", List{{
+			Type: "screen",
+			Cond: &Feature{Name: "min-width", Value: &Value{Num: 40, Unit: "em"}},
+		}}},
+		{"(min-width: -1.5em)", List{{
+			Cond: &Feature{Name: "min-width", Value: &Value{Num: -1.5, Unit: "em"}},
+		}}},
+		{"(hover) and (pointer: fine)", List{{
+			Cond: &And{Conds: []Condition{
+				&Feature{Name: "hover"},
+				&Feature{Name: "pointer", Value: &Value{Ident: "fine"}},
+			}},
+		}}},
+		{"not ((color) or (monochrome))", List{{
+			Cond: &Not{Cond: &Or{Conds: []Condition{
+				&Feature{Name: "color"},
+				&Feature{Name: "monochrome"},
+			}}},
+		}}},
+		{"(400px <= width <= 700px)", List{{
+			Cond: &Range{Name: "width", Left: &Value{Num: 400, Unit: "px"}, LeftOp: LE,
+				RightOp: LE, Right: &Value{Num: 700, Unit: "px"}},
+		}}},
+		{"(width>=600px)", List{{
+			Cond: &Range{Name: "width", RightOp: GE, Right: &Value{Num: 600, Unit: "px"}},
+		}}},
+		{"(16/9 < aspect-ratio)", List{{
+			Cond: &Range{Name: "aspect-ratio", Left: &Value{Num: 16, Den: 9}, LeftOp: LT},
+		}}},
+		{"(resolution: 1.5dppx)", List{{
+			Cond: &Feature{Name: "resolution", Value: &Value{Num: 1.5, Unit: "dppx"}},
+		}}},
+		{"(prefers-color-scheme: dark)", List{{
+			Cond: &Feature{Name: "prefers-color-scheme", Value: &Value{Ident: "dark"}},
+		}}},
+		{"screen and (foo bar) and func(x)", List{{
+			Type: "screen",
+			Cond: &And{Conds: []Condition{
+				&GeneralEnclosed{Text: "(foo bar)"},
+				&GeneralEnclosed{Text: "func(x)"},
+			}},
+		}}},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %s, want %s", test.in, pretty.Sprintf("%# v", got), pretty.Sprintf("%# v", test.want))
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  string
+	}{
+		{"screen and (a) or (b), print", "not all, print",
+			`media: invalid query "screen and (a) or (b)": unexpected "or"`},
+		{"(a) and (b) or (c)", "not all",
+			`media: invalid query "(a) and (b) or (c)": unexpected "or"`},
+		{"only (color)", "not all",
+			`media: invalid query "only (color)": unexpected "only"`},
+		{"screen and", "not all",
+			`media: invalid query "screen and": unexpected end of query`},
+		{"screen, , print", "screen, not all, print",
+			`media: invalid query "": unexpected end of query`},
+		{"and", "not all",
+			`media: invalid query "and": unexpected "and"`},
+		{"screen and(color)", "not all",
+			`media: invalid query "screen and(color)": unexpected "and("`},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.in)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v, want %s", test.in, err, test.err)
+		}
+		if got.String() != test.want {
+			t.Errorf("%q: got %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"SCREEN AND (MIN-WIDTH:40EM)", "screen and (min-width: 40em)"},
+		{"not all and (monochrome)", "not all and (monochrome)"},
+		{"(not (hover)) or ((a) and (b))", "(not (hover)) or ((a) and (b))"},
+		{"( 400px<=width< 50em ), (aspect-ratio: 16 / 9)", "(400px <= width < 50em), (aspect-ratio: 16/9)"},
+		{"(width > -1px)", "(width > -1px)"},
+	}
+	for _, test := range tests {
+		l, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if got := l.String(); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	var (
+		phone = &Env{
+			Width: 390, Height: 844, Resolution: 3, ColorScheme: "dark",
+			Features: map[string]string{"hover": "none", "pointer": "coarse", "any-hover": "none", "any-pointer": "coarse"},
+		}
+		desktop = &Env{Width: 1920, Height: 1080}
+		printer = &Env{Type: "print", Width: 816, Height: 1056, Color: 0, Monochrome: 1,
+			Features: map[string]string{"overflow-block": "paged", "update": "none"}}
+	)
+	tests := []struct {
+		query                   string
+		phone, desktop, printer bool
+	}{
+		{"", true, true, true},
+		{"all", true, true, true},
+		{"screen", true, true, false},
+		{"print", false, false, true},
+		{"not print", true, true, false},
+		{"only screen and (max-width: 480px)", true, false, false},
+		{"(min-width: 768px)", false, true, true},
+		{"(360px <= width <= 700px)", true, false, false},
+		{"(700px < width)", false, true, true},
+		{"(width < 50em)", true, false, false},
+		{"(width = 1920px)", false, true, false},
+		{"(orientation: portrait)", true, false, true},
+		{"(aspect-ratio: 16/9)", false, true, false},
+		{"(min-aspect-ratio: 1)", false, true, false},
+		{"(min-resolution: 2dppx)", true, false, false},
+		{"(resolution >= 192dpi)", true, false, false},
+		{"(hover: hover) and (pointer: fine)", false, true, true},
+		{"(hover)", false, true, true},
+		{"(any-pointer: coarse)", true, false, false},
+		{"(prefers-color-scheme: dark)", true, false, false},
+		{"(prefers-reduced-motion)", false, false, false},
+		{"(prefers-reduced-motion: no-preference)", true, true, true},
+		{"(color)", true, true, false},
+		{"(monochrome)", false, false, true},
+		{"(overflow-block: paged)", false, false, true},
+		{"(update)", true, true, false},
+		{"(grid: 0)", true, true, true},
+		{"screen, print and (monochrome)", true, true, true},
+		{"(max-width: 480px) or (min-width: 1200px)", true, true, false},
+		{"not ((width < 500px) or (height < 500px))", false, true, true},
+		{"not screen and (color)", false, false, true},
+
+		// Unknown features, values and syntax never match, even negated.
+		{"(unknown-feature)", false, false, false},
+		{"not (unknown-feature)", false, false, false},
+		{"(hover: maybe)", false, false, false},
+		{"(width: portrait)", false, false, false},
+		{"(min-hover: hover)", false, false, false},
+		{"(min-width: 40)", false, false, false},
+		{"(width: 0)", false, false, false},
+		{"not all and (foo bar)", false, false, false},
+		{"(foo bar) or (min-width: 0)", true, true, true},
+		{"tv", false, false, false},
+		{"screen and (a) or (b), print", false, false, true},
+	}
+	for _, test := range tests {
+		l, _ := Parse(test.query)
+		for _, c := range []struct {
+			name string
+			env  *Env
+			want bool
+		}{
+			{"phone", phone, test.phone},
+			{"desktop", desktop, test.desktop},
+			{"printer", printer, test.printer},
+		} {
+			if got := l.Match(c.env); got != c.want {
+				t.Errorf("%q on %s: got %v, want %v", test.query, c.name, got, c.want)
+			}
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	env := &Env{Width: 800, Height: 600}
+	tests := []struct {
+		cond string
+		want Result
+	}{
+		{"(width: 800px)", True},
+		{"(width: 801px)", False},
+		{"(min-width: 50vw)", True},
+		{"(max-width: 8.5in)", True},
+		{"(foo)", Unknown},
+		{"(foo) and (width: 801px)", False},
+		{"(foo) and (width: 800px)", Unknown},
+		{"(foo) or (width: 800px)", True},
+		{"not (foo)", Unknown},
+		{"func(x)", Unknown},
+	}
+	for _, test := range tests {
+		l, err := Parse(test.cond)
+		if err != nil {
+			t.Errorf("%q: %v", test.cond, err)
+			continue
+		}
+		if got := Eval(l[0].Cond, env); got != test.want {
+			t.Errorf("%q: got %s, want %s", test.cond, got, test.want)
+		}
+	}
+}
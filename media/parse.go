@@ -0,0 +1,430 @@
+package media
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ttacon/css/scanner"
+)
+
+// Parse parses a media query list, like the prelude of a @media rule.
+//
+// As in browsers, a malformed query does not invalidate the list: it is
+// replaced by "not all", which matches nothing. Parse returns the list
+// along with an error describing the first malformed query.
+func Parse(s string) (List, error) {
+	var (
+		toks  []*scanner.Token
+		sc    = scanner.New(s)
+		first error
+	)
+	for t := sc.Next(); t.Type != scanner.TokenEOF; t = sc.Next() {
+		if t.Type == scanner.TokenError {
+			return List{notAll()}, fmt.Errorf("media: %s in %q", t.Value, s)
+		}
+		toks = append(toks, t)
+	}
+	if len(significant(toks)) == 0 {
+		return List{}, nil
+	}
+
+	var l List
+	for _, part := range split(toks) {
+		p := &parser{src: s, toks: significant(part)}
+		q, err := p.parseQuery()
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			q = notAll()
+		}
+		l = append(l, q)
+	}
+	return l, first
+}
+
+// notAll returns the query replacing a malformed query.
+func notAll() *Query {
+	return &Query{Not: true, Type: "all"}
+}
+
+type parser struct {
+	src  string
+	toks []*scanner.Token
+	pos  int
+}
+
+// errorf returns an error about the query of p, blaming the current token.
+func (p *parser) errorf() error {
+	var (
+		q   = strings.TrimSpace(p.text(p.toks))
+		got = "end of query"
+	)
+	if t := p.tok(); t != nil {
+		got = fmt.Sprintf("%q", t.Value)
+	}
+	return fmt.Errorf("media: invalid query %q: unexpected %s", q, got)
+}
+
+// tok returns the current token, or nil at the end of the query.
+func (p *parser) tok() *scanner.Token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return nil
+}
+
+// keyword returns the lowercased value of the current token if it is an
+// ident, or "".
+func (p *parser) keyword() string {
+	if t := p.tok(); t != nil && t.Type == scanner.TokenIdent {
+		return strings.ToLower(t.Value)
+	}
+	return ""
+}
+
+// identAt reports whether the token at i is an ident.
+func (p *parser) identAt(i int) bool {
+	return i < len(p.toks) && p.toks[i].Type == scanner.TokenIdent
+}
+
+// text returns the source text spanned by toks.
+func (p *parser) text(toks []*scanner.Token) string {
+	if len(toks) == 0 {
+		return ""
+	}
+	last := toks[len(toks)-1]
+	return p.src[toks[0].Offset : last.Offset+len(last.Value)]
+}
+
+// parseQuery parses a whole media query:
+//
+//	<media-condition>
+//	| [ not | only ]? <media-type> [ and <media-condition-without-or> ]?
+func (p *parser) parseQuery() (*Query, error) {
+	if len(p.toks) == 0 {
+		return nil, p.errorf()
+	}
+	q := &Query{}
+	kw := p.keyword()
+	if kw == "" || kw == "not" && !p.identAt(p.pos+1) {
+		c, err := p.parseCondition(true)
+		if err != nil {
+			return nil, err
+		}
+		q.Cond = c
+	} else {
+		if (kw == "not" || kw == "only") && p.identAt(p.pos+1) {
+			q.Not, q.Only = kw == "not", kw == "only"
+			p.pos++
+		}
+		switch kw = p.keyword(); kw {
+		case "", "not", "only", "and", "or", "layer":
+			return nil, p.errorf()
+		}
+		q.Type = kw
+		p.pos++
+		if p.keyword() == "and" {
+			p.pos++
+			c, err := p.parseCondition(false)
+			if err != nil {
+				return nil, err
+			}
+			q.Cond = c
+		}
+	}
+	if p.tok() != nil {
+		return nil, p.errorf()
+	}
+	return q, nil
+}
+
+// parseCondition parses a media condition, which may only be a
+// disjunction if allowOr is set:
+//
+//	not <media-in-parens>
+//	| <media-in-parens> [ [ and <media-in-parens> ]* | [ or <media-in-parens> ]* ]
+func (p *parser) parseCondition(allowOr bool) (Condition, error) {
+	if p.keyword() == "not" {
+		p.pos++
+		c, err := p.parseInParens()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Cond: c}, nil
+	}
+	c, err := p.parseInParens()
+	if err != nil {
+		return nil, err
+	}
+	op := p.keyword()
+	if op != "and" && (op != "or" || !allowOr) {
+		return c, nil
+	}
+	conds := []Condition{c}
+	for p.keyword() == op {
+		p.pos++
+		c, err := p.parseInParens()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, c)
+	}
+	if op == "and" {
+		return &And{Conds: conds}, nil
+	}
+	return &Or{Conds: conds}, nil
+}
+
+// parseInParens parses a parenthesized condition, a media feature or a
+// general enclosed expression.
+func (p *parser) parseInParens() (Condition, error) {
+	t := p.tok()
+	switch {
+	case t == nil:
+		return nil, p.errorf()
+	case t.Type == scanner.TokenFunction:
+		end := p.closing()
+		if end < 0 {
+			return nil, p.errorf()
+		}
+		g := &GeneralEnclosed{Text: p.text(p.toks[p.pos : end+1])}
+		p.pos = end + 1
+		return g, nil
+	case t.Type != scanner.TokenChar || t.Value != "(":
+		return nil, p.errorf()
+	}
+	end := p.closing()
+	if end < 0 {
+		return nil, p.errorf()
+	}
+	inner := p.toks[p.pos+1 : end]
+	text := p.text(p.toks[p.pos : end+1])
+	p.pos = end + 1
+
+	sub := &parser{src: p.src, toks: inner}
+	if c, err := sub.parseCondition(true); err == nil && sub.tok() == nil {
+		return c, nil
+	}
+	if c := parseFeature(inner); c != nil {
+		return c, nil
+	}
+	return &GeneralEnclosed{Text: text}, nil
+}
+
+// closing returns the index of the parenthesis closing the current token,
+// or -1.
+func (p *parser) closing() int {
+	depth := 0
+	for i := p.pos; i < len(p.toks); i++ {
+		t := p.toks[i]
+		switch {
+		case t.Type == scanner.TokenFunction || t.Type == scanner.TokenChar && t.Value == "(":
+			depth++
+		case t.Type == scanner.TokenChar && t.Value == ")":
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// ParseFeature parses a media feature without its parentheses, like
+// "min-width: 40em" or "400px <= width", into a *Feature or a *Range. It
+// returns nil if s is not a media feature. Container queries share this
+// syntax for their size features.
+func ParseFeature(s string) Condition {
+	var (
+		toks []*scanner.Token
+		sc   = scanner.New(s)
+	)
+	for t := sc.Next(); t.Type != scanner.TokenEOF; t = sc.Next() {
+		if t.Type == scanner.TokenError {
+			return nil
+		}
+		toks = append(toks, t)
+	}
+	return parseFeature(significant(toks))
+}
+
+// parseFeature parses the tokens of a media feature without its
+// parentheses, or returns nil:
+//
+//	<mf-name> | <mf-name> : <mf-value>
+//	| <mf-name> <mf-comparison> <mf-value>
+//	| <mf-value> <mf-comparison> <mf-name>
+//	| <mf-value> <mf-lt> <mf-name> <mf-lt> <mf-value>
+//	| <mf-value> <mf-gt> <mf-name> <mf-gt> <mf-value>
+func parseFeature(toks []*scanner.Token) Condition {
+	switch {
+	case len(toks) == 0:
+		return nil
+	case len(toks) == 1 && toks[0].Type == scanner.TokenIdent:
+		return &Feature{Name: strings.ToLower(toks[0].Value)}
+	case len(toks) > 1 && toks[0].Type == scanner.TokenIdent && isChar(toks[1], ":"):
+		v := parseValue(toks[2:])
+		if v == nil {
+			return nil
+		}
+		return &Feature{Name: strings.ToLower(toks[0].Value), Value: v}
+	}
+
+	// Split the range at its comparisons.
+	var (
+		operands [][]*scanner.Token
+		ops      []Op
+		start    int
+	)
+	for i := 0; i < len(toks); i++ {
+		op, n := comparison(toks, i)
+		if n == 0 {
+			continue
+		}
+		operands = append(operands, toks[start:i])
+		ops = append(ops, op)
+		i += n - 1
+		start = i + 1
+	}
+	operands = append(operands, toks[start:])
+
+	name := func(toks []*scanner.Token) string {
+		if len(toks) == 1 && toks[0].Type == scanner.TokenIdent {
+			return strings.ToLower(toks[0].Value)
+		}
+		return ""
+	}
+	switch len(operands) {
+	case 2:
+		if n := name(operands[0]); n != "" {
+			if v := parseValue(operands[1]); v != nil && v.Ident == "" {
+				return &Range{Name: n, RightOp: ops[0], Right: v}
+			}
+		}
+		if n := name(operands[1]); n != "" {
+			if v := parseValue(operands[0]); v != nil && v.Ident == "" {
+				return &Range{Name: n, Left: v, LeftOp: ops[0]}
+			}
+		}
+	case 3:
+		n := name(operands[1])
+		if n == "" || isLess(ops[0]) != isLess(ops[1]) || ops[0] == EQ || ops[1] == EQ {
+			return nil
+		}
+		left, right := parseValue(operands[0]), parseValue(operands[2])
+		if left == nil || right == nil || left.Ident != "" || right.Ident != "" {
+			return nil
+		}
+		return &Range{Name: n, Left: left, LeftOp: ops[0], RightOp: ops[1], Right: right}
+	}
+	return nil
+}
+
+// comparison returns the comparison at toks[i] and its number of tokens,
+// or 0 if there is none.
+func comparison(toks []*scanner.Token, i int) (Op, int) {
+	t := toks[i]
+	if t.Type != scanner.TokenChar {
+		return 0, 0
+	}
+	eq := i+1 < len(toks) && isChar(toks[i+1], "=") && toks[i+1].Offset == t.Offset+1
+	switch {
+	case t.Value == "<" && eq:
+		return LE, 2
+	case t.Value == ">" && eq:
+		return GE, 2
+	case t.Value == "<":
+		return LT, 1
+	case t.Value == ">":
+		return GT, 1
+	case t.Value == "=":
+		return EQ, 1
+	}
+	return 0, 0
+}
+
+func isLess(op Op) bool {
+	return op == LT || op == LE
+}
+
+// parseValue parses the tokens of a media feature value, or returns nil:
+//
+//	<number> | <dimension> | <ident> | <ratio>
+func parseValue(toks []*scanner.Token) *Value {
+	switch {
+	case len(toks) == 1 && toks[0].Type == scanner.TokenIdent:
+		return &Value{Ident: strings.ToLower(toks[0].Value)}
+	case len(toks) == 3 && isChar(toks[1], "/"):
+		num, ok1 := number(toks[:1])
+		den, ok2 := number(toks[2:])
+		if !ok1 || !ok2 {
+			return nil
+		}
+		return &Value{Num: num, Den: den}
+	}
+	if len(toks) == 0 {
+		return nil
+	}
+	if t := toks[0]; len(toks) == 1 && t.Type == scanner.TokenDimension {
+		digits := strings.TrimLeft(t.Value, "+-")
+		i := len(t.Value) - len(digits) + strings.IndexFunc(digits, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		num, err := strconv.ParseFloat(t.Value[:i], 64)
+		if err != nil {
+			return nil
+		}
+		return &Value{Num: num, Unit: strings.ToLower(t.Value[i:])}
+	}
+	num, ok := number(toks)
+	if !ok {
+		return nil
+	}
+	return &Value{Num: num}
+}
+
+// number returns the value of a number, signed or not.
+func number(toks []*scanner.Token) (float64, bool) {
+	if len(toks) != 1 || toks[0].Type != scanner.TokenNumber {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(toks[0].Value, 64)
+	return n, err == nil
+}
+
+func isChar(t *scanner.Token, c string) bool {
+	return t.Type == scanner.TokenChar && t.Value == c
+}
+
+// significant returns toks without whitespace and comments.
+func significant(toks []*scanner.Token) []*scanner.Token {
+	var sig []*scanner.Token
+	for _, t := range toks {
+		if t.Type != scanner.TokenS && t.Type != scanner.TokenComment {
+			sig = append(sig, t)
+		}
+	}
+	return sig
+}
+
+// split splits toks at its top-level commas.
+func split(toks []*scanner.Token) [][]*scanner.Token {
+	var (
+		parts [][]*scanner.Token
+		depth int
+		start int
+	)
+	for i, t := range toks {
+		switch {
+		case t.Type == scanner.TokenFunction || isChar(t, "("):
+			depth++
+		case isChar(t, ")"):
+			depth--
+		case depth == 0 && isChar(t, ","):
+			parts = append(parts, toks[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, toks[start:])
+}
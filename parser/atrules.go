@@ -0,0 +1,514 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/scanner"
+)
+
+// marginAtRules are the margin boxes of a @page block.
+var marginAtRules = map[string]bool{
+	"@top-left-corner":     true,
+	"@top-left":            true,
+	"@top-center":          true,
+	"@top-right":           true,
+	"@top-right-corner":    true,
+	"@bottom-left-corner":  true,
+	"@bottom-left":         true,
+	"@bottom-center":       true,
+	"@bottom-right":        true,
+	"@bottom-right-corner": true,
+	"@left-top":            true,
+	"@left-middle":         true,
+	"@left-bottom":         true,
+	"@right-top":           true,
+	"@right-middle":        true,
+	"@right-bottom":        true,
+}
+
+// featureValuesAtRules are the blocks of a @font-feature-values block,
+// with the maximum number of feature indices of their values, or -1 if
+// there is none.
+var featureValuesAtRules = map[string]int{
+	"@stylistic":         1,
+	"@historical-forms":  1,
+	"@styleset":          -1,
+	"@character-variant": 2,
+	"@swash":             1,
+	"@ornaments":         1,
+	"@annotation":        1,
+}
+
+// typedAtRule returns the node of the at-rule at, with the given prelude
+// and block, if the at-rule has its own node type in the block of the
+// at-rule parent. It returns nil for other at-rules, and for those with an
+// invalid prelude.
+func (p *Parser) typedAtRule(parent string, at *scanner.Token, prelude []*scanner.Token,
+	block *ast.Block, sp ast.Span, raw func(interface{}) *ast.Raw) ast.Rule {
+
+	keyword := strings.ToLower(at.Value)
+	if block == nil && keyword != "@layer" && keyword != "@import" {
+		return nil
+	}
+	var (
+		toks = significant(prelude)
+		r    ast.Rule
+		bad  *scanner.Token
+	)
+	switch {
+	case parent == "@page" && marginAtRules[keyword]:
+		bad = firstOr(toks, nil)
+		n := &ast.MarginRule{Span: sp, AtKeyword: at.Value, Block: block}
+		n.Raw, r = raw(n), n
+
+	case parent == "@font-feature-values":
+		if _, ok := featureValuesAtRules[keyword]; !ok {
+			return nil
+		}
+		bad = firstOr(toks, nil)
+		n := &ast.FeatureValuesBlock{Span: sp, AtKeyword: at.Value, Block: block}
+		n.Raw, r = raw(n), n
+
+	case keyword == "@font-face":
+		bad = firstOr(toks, nil)
+		p.checkRequired(at, block, "font-family", "src")
+		n := &ast.FontFaceRule{Span: sp, Block: block}
+		n.Raw, r = raw(n), n
+
+	case keyframesAtRules[keyword]:
+		if len(toks) != 1 || !(toks[0].Type == scanner.TokenString || isCustomIdent(toks[0])) {
+			bad = firstOr(toks, at)
+		}
+		n := &ast.KeyframesRule{Span: sp, AtKeyword: at.Value, Name: join(prelude), Block: block}
+		n.Raw, r = raw(n), n
+
+	case keyword == "@page":
+		var selectors []string
+		selectors, bad = pageSelectors(toks)
+		n := &ast.PageRule{Span: sp, Selectors: selectors, Block: block}
+		n.Raw, r = raw(n), n
+
+	case keyword == "@counter-style":
+		name := strings.ToLower(join(prelude))
+		if len(toks) != 1 || !isCustomIdent(toks[0]) || name == "decimal" || name == "disc" {
+			bad = firstOr(toks, at)
+		}
+		n := &ast.CounterStyleRule{Span: sp, Name: join(prelude), Block: block}
+		n.Raw, r = raw(n), n
+
+	case keyword == "@property":
+		if len(toks) != 1 || toks[0].Type != scanner.TokenIdent || !strings.HasPrefix(toks[0].Value, "--") {
+			bad = firstOr(toks, at)
+		} else {
+			p.checkPropertyRule(at, block)
+		}
+		n := &ast.PropertyRule{Span: sp, Name: join(prelude), Block: block}
+		n.Raw, r = raw(n), n
+
+	case keyword == "@font-feature-values":
+		var families []string
+		families, bad = familyNames(at, prelude)
+		n := &ast.FontFeatureValuesRule{Span: sp, FontFamilies: families, Block: block}
+		n.Raw, r = raw(n), n
+
+	case keyword == "@layer":
+		var names []string
+		names, bad = layerNames(toks, block != nil)
+		if bad == nil && len(names) == 0 && block == nil {
+			bad = at
+		}
+		n := &ast.LayerRule{Span: sp, Names: names, Block: block}
+		n.Raw, r = raw(n), n
+
+	case keyword == "@import" && parent == "" && block == nil:
+		var n *ast.ImportRule
+		if n, bad = importRule(at, prelude); bad == nil {
+			n.Span = sp
+			n.Raw, r = raw(n), n
+		}
+
+	case keyword == "@scope":
+		var start, end []string
+		start, end, bad = scopePrelude(prelude)
+		n := &ast.ScopeRule{Span: sp, Roots: start, Limits: end, Block: block}
+		n.Raw, r = raw(n), n
+
+	default:
+		return nil
+	}
+
+	if bad != nil {
+		p.error(bad, ErrInvalidPrelude, nil,
+			fmt.Sprintf("invalid prelude %q for %s", join(prelude), at.Value))
+		return nil
+	}
+	return r
+}
+
+// parseKeyframe parses the keyframe starting at the current token,
+// preceded by the tokens from before. It returns nil if the keyframe is
+// invalid.
+func (p *Parser) parseKeyframe(before int) *ast.Keyframe {
+	start := p.pos
+	if !p.parsePrelude() {
+		return nil
+	}
+	var (
+		prelude = p.toks[start:p.pos]
+		textEnd = p.pos
+		open    = p.tok()
+		block   = p.parseStyleBlock()
+	)
+	var selectors []string
+	for _, sel := range split(prelude) {
+		if bad := checkKeyframeSelector(sel, open); bad != nil {
+			p.error(bad, ErrInvalidSelector, nil,
+				fmt.Sprintf("invalid keyframe selector %q: unexpected %s", join(prelude), describe(bad)))
+			return nil
+		}
+		selectors = append(selectors, join(sel))
+	}
+
+	kf := &ast.Keyframe{
+		Span:      span(p.toks[start:p.pos]),
+		Selectors: selectors,
+		Block:     block,
+	}
+	kf.Raw = p.raw(kf, before, start, textEnd, p.pos, p.pos)
+	return kf
+}
+
+// checkKeyframeSelector returns the first token of sel that cannot appear
+// there in a keyframe selector, or nil if there is none. next is the token
+// following sel, blamed if sel is empty.
+//
+//	from | to | <percentage> | <timeline-range-name> <percentage>
+func checkKeyframeSelector(sel []*scanner.Token, next *scanner.Token) *scanner.Token {
+	toks := significant(sel)
+	switch {
+	case len(toks) == 1 && toks[0].Type == scanner.TokenIdent:
+		if v := strings.ToLower(toks[0].Value); v == "from" || v == "to" {
+			return nil
+		}
+	case len(toks) == 1 && toks[0].Type == scanner.TokenPercentage:
+		return nil
+	case len(toks) == 2 && toks[0].Type == scanner.TokenIdent:
+		if toks[1].Type == scanner.TokenPercentage {
+			return nil
+		}
+		return toks[1]
+	case len(toks) == 0:
+		return next
+	}
+	return toks[0]
+}
+
+// pageSelectors returns the page selectors of a @page prelude, or the
+// first invalid token of the prelude:
+//
+//	[ <ident>? [ ':' [ left | right | first | blank ] ]* ]#
+func pageSelectors(toks []*scanner.Token) ([]string, *scanner.Token) {
+	if len(toks) == 0 {
+		return nil, nil
+	}
+	var selectors []string
+	for _, sel := range split(toks) {
+		if len(sel) == 0 {
+			return nil, toks[0]
+		}
+		var buf strings.Builder
+		for i := 0; i < len(sel); i++ {
+			t := sel[i]
+			switch {
+			case i == 0 && t.Type == scanner.TokenIdent:
+				buf.WriteString(t.Value)
+			case t.Type == scanner.TokenChar && t.Value == ":" && i+1 < len(sel) &&
+				adjacent(t, sel[i+1]) && isPseudoPage(sel[i+1]):
+				i++
+				buf.WriteString(":" + sel[i].Value)
+			default:
+				return nil, t
+			}
+			// Pseudo-pages follow the page name without spaces.
+			if i+1 < len(sel) && !adjacent(sel[i], sel[i+1]) {
+				return nil, sel[i+1]
+			}
+		}
+		selectors = append(selectors, buf.String())
+	}
+	return selectors, nil
+}
+
+func isPseudoPage(t *scanner.Token) bool {
+	if t.Type != scanner.TokenIdent {
+		return false
+	}
+	switch strings.ToLower(t.Value) {
+	case "left", "right", "first", "blank":
+		return true
+	}
+	return false
+}
+
+// familyNames returns the family names of the @font-feature-values rule
+// at, or the first invalid token of its prelude. A family name is a string
+// or a sequence of idents.
+func familyNames(at *scanner.Token, prelude []*scanner.Token) ([]string, *scanner.Token) {
+	var families []string
+	for _, name := range split(prelude) {
+		toks := significant(name)
+		switch {
+		case len(toks) == 0:
+			return nil, firstOr(significant(prelude), at)
+		case len(toks) == 1 && toks[0].Type == scanner.TokenString:
+		default:
+			for _, t := range toks {
+				if t.Type != scanner.TokenIdent {
+					return nil, t
+				}
+			}
+		}
+		families = append(families, join(name))
+	}
+	return families, nil
+}
+
+// layerNames returns the layer names of a @layer prelude, or the first
+// invalid token of the prelude. The prelude of a block has at most one
+// name.
+//
+//	<layer-name> = <ident> [ '.' <ident> ]*
+func layerNames(toks []*scanner.Token, block bool) ([]string, *scanner.Token) {
+	if len(toks) == 0 {
+		return nil, nil
+	}
+	var names []string
+	for i, name := range split(toks) {
+		if i > 0 && block {
+			return nil, firstOr(name, toks[len(toks)-1])
+		}
+		if len(name) == 0 {
+			return nil, toks[0]
+		}
+		for j, t := range name {
+			if j > 0 && !adjacent(name[j-1], t) {
+				return nil, t
+			}
+			if j%2 == 1 && t.Type == scanner.TokenChar && t.Value == "." {
+				continue
+			}
+			if j%2 == 1 || t.Type != scanner.TokenIdent || isWideKeyword(t) {
+				return nil, t
+			}
+		}
+		if len(name)%2 == 0 {
+			return nil, name[len(name)-1]
+		}
+		names = append(names, join(name))
+	}
+	return names, nil
+}
+
+// importRule returns the @import rule with the given prelude, or the
+// first invalid token of the prelude.
+//
+//	<url> [ layer | layer(<layer-name>) ]? [ supports(<condition>) ]? <media-query-list>?
+func importRule(at *scanner.Token, prelude []*scanner.Token) (*ast.ImportRule, *scanner.Token) {
+	var (
+		n = &ast.ImportRule{}
+		i = skipTrivia(prelude, 0)
+	)
+	if i == len(prelude) {
+		return nil, at
+	}
+	switch t := prelude[i]; {
+	case t.Type == scanner.TokenString:
+		n.URL, i = scanner.Unquote(t.Value), i+1
+	case t.Type == scanner.TokenURI:
+		n.URL, i = scanner.Unquote(strings.TrimSpace(t.Value[len("url("):len(t.Value)-1])), i+1
+	case isFunction(t, "url("):
+		var args []*scanner.Token
+		args, i = tokenArgs(prelude, i)
+		sig := significant(args)
+		if len(sig) != 1 || sig[0].Type != scanner.TokenString {
+			return nil, firstOr(sig, t)
+		}
+		n.URL = scanner.Unquote(sig[0].Value)
+	default:
+		return nil, t
+	}
+
+	if i = skipTrivia(prelude, i); i < len(prelude) {
+		switch t := prelude[i]; {
+		case t.Type == scanner.TokenIdent && strings.EqualFold(t.Value, "layer"):
+			n.Layered, i = true, i+1
+		case isFunction(t, "layer("):
+			var args []*scanner.Token
+			args, i = tokenArgs(prelude, i)
+			names, bad := layerNames(significant(args), true)
+			if bad == nil && len(names) == 0 {
+				bad = t
+			}
+			if bad != nil {
+				return nil, bad
+			}
+			n.Layered, n.Layer = true, names[0]
+		}
+	}
+	if i = skipTrivia(prelude, i); i < len(prelude) && isFunction(prelude[i], "supports(") {
+		var args []*scanner.Token
+		args, i = tokenArgs(prelude, i)
+		n.Supports = join(args)
+	}
+	n.Media = join(prelude[i:])
+	return n, nil
+}
+
+// scopePrelude returns the selectors of the scoping roots and limits of a
+// @scope prelude, or the first invalid token of the prelude.
+//
+//	[ ( <scope-start> ) ]? [ to ( <scope-end> ) ]?
+func scopePrelude(prelude []*scanner.Token) (start, end []string, bad *scanner.Token) {
+	i := skipTrivia(prelude, 0)
+	if i < len(prelude) && isChar(prelude[i], "(") {
+		if start, i, bad = scopeSelectors(prelude, i); bad != nil {
+			return nil, nil, bad
+		}
+		i = skipTrivia(prelude, i)
+	}
+	if i < len(prelude) && prelude[i].Type == scanner.TokenIdent && strings.EqualFold(prelude[i].Value, "to") {
+		to := prelude[i]
+		if i = skipTrivia(prelude, i+1); i == len(prelude) || !isChar(prelude[i], "(") {
+			return nil, nil, firstOr(prelude[i:], to)
+		}
+		if end, i, bad = scopeSelectors(prelude, i); bad != nil {
+			return nil, nil, bad
+		}
+		i = skipTrivia(prelude, i)
+	}
+	if i < len(prelude) {
+		return nil, nil, prelude[i]
+	}
+	return start, end, nil
+}
+
+// scopeSelectors returns the selectors in the parentheses starting at
+// toks[i], and the index following them.
+func scopeSelectors(toks []*scanner.Token, i int) ([]string, int, *scanner.Token) {
+	open := toks[i]
+	args, i := tokenArgs(toks, i)
+	if len(significant(args)) == 0 {
+		return nil, i, open
+	}
+	if bad := checkSelector(args); bad != nil {
+		return nil, i, bad
+	}
+	var sels []string
+	for _, c := range selectors(args) {
+		sels = append(sels, c.Name)
+	}
+	return sels, i, nil
+}
+
+// tokenArgs returns the argument tokens of the function starting at
+// toks[i], and the index following the function.
+func tokenArgs(toks []*scanner.Token, i int) ([]*scanner.Token, int) {
+	depth := 0
+	for j := i; j < len(toks); j++ {
+		switch t := toks[j]; {
+		case closingFor(t) != "":
+			depth++
+		case isClosingParen(t):
+			if depth--; depth == 0 {
+				return toks[i+1 : j], j + 1
+			}
+		}
+	}
+	return toks[i+1:], len(toks)
+}
+
+// skipTrivia returns the index of the first token of toks from i that is
+// not whitespace or a comment.
+func skipTrivia(toks []*scanner.Token, i int) int {
+	for i < len(toks) && isTrivia(toks[i]) {
+		i++
+	}
+	return i
+}
+
+func isChar(t *scanner.Token, c string) bool {
+	return t.Type == scanner.TokenChar && t.Value == c
+}
+
+func isFunction(t *scanner.Token, name string) bool {
+	return t.Type == scanner.TokenFunction && strings.EqualFold(t.Value, name)
+}
+
+// isWideKeyword reports whether t is a CSS-wide keyword.
+func isWideKeyword(t *scanner.Token) bool {
+	switch strings.ToLower(t.Value) {
+	case "initial", "inherit", "unset", "revert", "revert-layer":
+		return true
+	}
+	return false
+}
+
+// adjacent reports whether t is immediately followed by next.
+func adjacent(t, next *scanner.Token) bool {
+	return next.Offset == t.Offset+len(t.Value)
+}
+
+// isCustomIdent reports whether t is an ident that may name something,
+// that is not a CSS-wide keyword or "none".
+func isCustomIdent(t *scanner.Token) bool {
+	if t.Type != scanner.TokenIdent {
+		return false
+	}
+	switch strings.ToLower(t.Value) {
+	case "none", "initial", "inherit", "unset", "revert", "revert-layer", "default":
+		return false
+	}
+	return true
+}
+
+// significant returns toks without whitespace and comments.
+func significant(toks []*scanner.Token) []*scanner.Token {
+	var sig []*scanner.Token
+	for _, t := range toks {
+		if !isTrivia(t) {
+			sig = append(sig, t)
+		}
+	}
+	return sig
+}
+
+// split splits toks at its top-level commas.
+func split(toks []*scanner.Token) [][]*scanner.Token {
+	var (
+		parts [][]*scanner.Token
+		depth int
+		start int
+	)
+	for i, t := range toks {
+		switch {
+		case closingFor(t) != "":
+			depth++
+		case isClosingParen(t):
+			depth--
+		case depth == 0 && isComma(t):
+			parts = append(parts, toks[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, toks[start:])
+}
+
+// firstOr returns the first token of toks, or t if there is none.
+func firstOr(toks []*scanner.Token, t *scanner.Token) *scanner.Token {
+	if len(toks) > 0 {
+		return toks[0]
+	}
+	return t
+}
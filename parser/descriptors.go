@@ -0,0 +1,521 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/printer"
+	"github.com/ttacon/css/scanner"
+)
+
+// A validator reports whether the components of a descriptor form a valid
+// value.
+type validator func(comps []string) bool
+
+// descriptors maps the at-rules with descriptors to the validators of
+// their descriptors. A nil validator accepts any value.
+var descriptors = map[string]map[string]validator{
+	"@font-face": {
+		"font-family":             isFamilyName,
+		"src":                     isFontSources,
+		"font-style":              isFontStyle,
+		"font-weight":             rangeOf(isFontWeight),
+		"font-stretch":            rangeOf(isFontStretch),
+		"font-display":            keywords("auto", "block", "swap", "fallback", "optional"),
+		"unicode-range":           isUnicodeRanges,
+		"font-feature-settings":   nil,
+		"font-variation-settings": nil,
+		"font-named-instance":     nil,
+		"font-language-override":  nil,
+		"ascent-override":         isMetricOverride,
+		"descent-override":        isMetricOverride,
+		"line-gap-override":       isMetricOverride,
+		"size-adjust":             isPercentage,
+	},
+	"@counter-style": {
+		"system":           isCounterSystem,
+		"negative":         symbols(1, 2),
+		"prefix":           symbols(1, 1),
+		"suffix":           symbols(1, 1),
+		"range":            isCounterRange,
+		"pad":              isCounterPad,
+		"fallback":         isIdent,
+		"symbols":          symbols(1, -1),
+		"additive-symbols": isAdditiveSymbols,
+		"speak-as":         isIdent,
+	},
+	"@property": {
+		"syntax":        isString,
+		"inherits":      keywords("true", "false"),
+		"initial-value": nil,
+	},
+	"@font-feature-values": {
+		"font-display": keywords("auto", "block", "swap", "fallback", "optional"),
+	},
+}
+
+// checkDescriptor reports an error at t, the name of d, if d is not a
+// valid descriptor of the at-rule whose block is being parsed.
+func (p *Parser) checkDescriptor(t *scanner.Token, d *ast.Declaration) {
+	name := strings.ToLower(d.Ident)
+	if max, ok := featureValuesAtRules[p.parent]; ok {
+		if !isFeatureIndices(d.Components, max) {
+			p.invalidDescriptor(t, d)
+		}
+		return
+	}
+	table, ok := descriptors[p.parent]
+	// Vendor-prefixed and custom descriptors are not checked.
+	if !ok || strings.HasPrefix(name, "-") {
+		return
+	}
+	valid, ok := table[name]
+	if !ok {
+		p.error(t, ErrInvalidDescriptor, nil,
+			fmt.Sprintf("unknown descriptor %q in %s", d.Ident, p.parent))
+		return
+	}
+	// Descriptors cannot be important.
+	if valid != nil && !valid(d.Components) || d.Important {
+		p.invalidDescriptor(t, d)
+	}
+}
+
+func (p *Parser) invalidDescriptor(t *scanner.Token, d *ast.Declaration) {
+	parent := p.parent
+	if _, ok := featureValuesAtRules[parent]; ok {
+		parent = "@font-feature-values " + parent
+	}
+	value := printer.Components(d.Components)
+	if d.Important {
+		value += " !important"
+	}
+	p.error(t, ErrInvalidDescriptor, nil,
+		fmt.Sprintf("invalid value %q for descriptor %q in %s", value, d.Ident, parent))
+}
+
+// checkRequired reports an error at the at-rule at if its block lacks
+// one of the given descriptors.
+func (p *Parser) checkRequired(at *scanner.Token, block *ast.Block, names ...string) {
+	var missing []string
+	for _, name := range names {
+		if descriptor(block, name) == nil {
+			missing = append(missing, strconv.Quote(name))
+		}
+	}
+	if len(missing) > 0 {
+		p.error(at, ErrInvalidDescriptor, nil,
+			fmt.Sprintf("missing descriptor %s in %s", strings.Join(missing, " and "), at.Value))
+	}
+}
+
+// checkPropertyRule reports an error if the @property rule at lacks a
+// required descriptor. initial-value is optional for the universal
+// syntax "*".
+func (p *Parser) checkPropertyRule(at *scanner.Token, block *ast.Block) {
+	required := []string{"syntax", "inherits"}
+	if d := descriptor(block, "syntax"); d == nil || len(d.Components) != 1 ||
+		strings.TrimSpace(scanner.Unquote(d.Components[0])) != "*" {
+		required = append(required, "initial-value")
+	}
+	p.checkRequired(at, block, required...)
+}
+
+// descriptor returns the last declaration of block named name, or nil.
+func descriptor(block *ast.Block, name string) *ast.Declaration {
+	if block.DeclList == nil {
+		return nil
+	}
+	var found *ast.Declaration
+	for _, d := range block.DeclList.Declarations {
+		if strings.EqualFold(d.Ident, name) {
+			found = d
+		}
+	}
+	return found
+}
+
+// FontSources parses the components of the src descriptor of @font-face:
+//
+//	[ <url> [ format(<font-format>) ]? [ tech(<font-tech>#) ]? | local(<family-name>) ]#
+func FontSources(comps []string) ([]*ast.FontSource, error) {
+	var sources []*ast.FontSource
+	for _, entry := range splitComponents(comps) {
+		src, ok := fontSource(entry)
+		if !ok {
+			return nil, fmt.Errorf("invalid src entry %q", strings.Join(entry, " "))
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+func fontSource(comps []string) (*ast.FontSource, bool) {
+	if len(comps) == 0 {
+		return nil, false
+	}
+	var (
+		src   = &ast.FontSource{}
+		first = strings.ToLower(comps[0])
+		i     int
+	)
+	switch {
+	case tokenOf(comps[0]).Type == scanner.TokenURI:
+		v := comps[0][len("url(") : len(comps[0])-1]
+		src.URL, i = scanner.Unquote(strings.TrimSpace(v)), 1
+	case (first == "url(" || first == "src(") && len(comps) >= 3 && isString(comps[1:2]) && comps[2] == ")":
+		src.URL, i = scanner.Unquote(comps[1]), 3
+	case first == "local(":
+		args, n := functionArgs(comps)
+		if !isFamilyName(args) {
+			return nil, false
+		}
+		var names []string
+		for _, a := range args {
+			names = append(names, scanner.Unquote(a))
+		}
+		src.Local = strings.Join(names, " ")
+		return src, n == len(comps)
+	default:
+		return nil, false
+	}
+
+	if i < len(comps) && strings.EqualFold(comps[i], "format(") {
+		args, n := functionArgs(comps[i:])
+		if len(args) != 1 || !(isString(args) || isIdent(args)) {
+			return nil, false
+		}
+		src.Format = scanner.Unquote(args[0])
+		i += n
+	}
+	if i < len(comps) && strings.EqualFold(comps[i], "tech(") {
+		args, n := functionArgs(comps[i:])
+		for _, tech := range splitComponents(args) {
+			if !isIdent(tech) {
+				return nil, false
+			}
+			src.Tech = append(src.Tech, tech[0])
+		}
+		i += n
+	}
+	return src, i == len(comps)
+}
+
+// functionArgs returns the arguments of the function starting comps, and
+// the number of components up to its closing parenthesis.
+func functionArgs(comps []string) ([]string, int) {
+	depth := 0
+	for i, c := range comps {
+		switch {
+		case strings.HasSuffix(c, "(") || c == "[":
+			depth++
+		case c == ")" || c == "]":
+			if depth--; depth == 0 {
+				return comps[1:i], i + 1
+			}
+		}
+	}
+	return comps[1:], len(comps)
+}
+
+// splitComponents splits comps at its top-level commas.
+func splitComponents(comps []string) [][]string {
+	var (
+		parts [][]string
+		depth int
+		start int
+	)
+	for i, c := range comps {
+		switch {
+		case strings.HasSuffix(c, "(") || c == "[":
+			depth++
+		case c == ")" || c == "]":
+			depth--
+		case depth == 0 && c == ",":
+			parts = append(parts, comps[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, comps[start:])
+}
+
+// tokenOf returns the first token of the component c.
+func tokenOf(c string) *scanner.Token {
+	return scanner.New(c).Next()
+}
+
+// VALIDATORS //////////////////////////////////////////////////////////
+
+func keywords(words ...string) validator {
+	return func(comps []string) bool {
+		if len(comps) != 1 {
+			return false
+		}
+		for _, w := range words {
+			if strings.EqualFold(comps[0], w) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// rangeOf returns a validator for "auto" or one or two values accepted by
+// valid.
+func rangeOf(valid func(c string) bool) validator {
+	return func(comps []string) bool {
+		if len(comps) == 1 && strings.EqualFold(comps[0], "auto") {
+			return true
+		}
+		if len(comps) == 0 || len(comps) > 2 {
+			return false
+		}
+		for _, c := range comps {
+			if !valid(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// symbols returns a validator for min to max counter symbols, or at
+// least min if max is -1.
+func symbols(min, max int) validator {
+	return func(comps []string) bool {
+		if len(comps) < min || (max >= 0 && len(comps) > max) {
+			return false
+		}
+		for _, c := range comps {
+			if !isSymbol(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func isString(comps []string) bool {
+	return len(comps) == 1 && tokenOf(comps[0]).Type == scanner.TokenString
+}
+
+func isIdent(comps []string) bool {
+	return len(comps) == 1 && tokenOf(comps[0]).Type == scanner.TokenIdent
+}
+
+func isPercentage(comps []string) bool {
+	return len(comps) == 1 && tokenOf(comps[0]).Type == scanner.TokenPercentage &&
+		!strings.HasPrefix(comps[0], "-")
+}
+
+// isFamilyName reports whether comps is a font family name: a string or
+// a sequence of idents.
+func isFamilyName(comps []string) bool {
+	if isString(comps) {
+		return true
+	}
+	for _, c := range comps {
+		if tokenOf(c).Type != scanner.TokenIdent {
+			return false
+		}
+	}
+	return len(comps) > 0
+}
+
+func isFontSources(comps []string) bool {
+	_, err := FontSources(comps)
+	return err == nil
+}
+
+// isFontStyle reports whether comps is auto | normal | italic |
+// oblique <angle>{0,2}.
+func isFontStyle(comps []string) bool {
+	if len(comps) == 0 {
+		return false
+	}
+	switch strings.ToLower(comps[0]) {
+	case "auto", "normal", "italic":
+		return len(comps) == 1
+	case "oblique":
+		if len(comps) > 3 {
+			return false
+		}
+		for _, c := range comps[1:] {
+			if !isAngle(c) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isAngle(c string) bool {
+	t := tokenOf(c)
+	if t.Type == scanner.TokenNumber {
+		return isZero(c)
+	}
+	if t.Type != scanner.TokenDimension {
+		return false
+	}
+	lower := strings.ToLower(c)
+	for _, unit := range []string{"deg", "grad", "rad", "turn"} {
+		if strings.HasSuffix(lower, unit) {
+			_, err := strconv.ParseFloat(lower[:len(lower)-len(unit)], 64)
+			return err == nil
+		}
+	}
+	return false
+}
+
+func isFontWeight(c string) bool {
+	switch strings.ToLower(c) {
+	case "normal", "bold":
+		return true
+	}
+	n, err := strconv.ParseFloat(c, 64)
+	return err == nil && n >= 1 && n <= 1000
+}
+
+func isFontStretch(c string) bool {
+	switch strings.ToLower(c) {
+	case "normal", "ultra-condensed", "extra-condensed", "condensed", "semi-condensed",
+		"semi-expanded", "expanded", "extra-expanded", "ultra-expanded":
+		return true
+	}
+	return isPercentage([]string{c})
+}
+
+// isMetricOverride reports whether comps is normal | <percentage>.
+func isMetricOverride(comps []string) bool {
+	return isPercentage(comps) || (len(comps) == 1 && strings.EqualFold(comps[0], "normal"))
+}
+
+// isUnicodeRanges reports whether comps is a comma-separated list of
+// unicode ranges like U+0025-00FF or U+4??.
+func isUnicodeRanges(comps []string) bool {
+	for _, r := range splitComponents(comps) {
+		if len(r) != 1 || !isUnicodeRange(r[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isUnicodeRange(c string) bool {
+	if len(c) < 3 || (c[0] != 'u' && c[0] != 'U') || c[1] != '+' {
+		return false
+	}
+	r := c[2:]
+	if i := strings.IndexByte(r, '-'); i >= 0 {
+		return isHexDigits(r[:i]) && isHexDigits(r[i+1:])
+	}
+	// Wildcards may end a single code point.
+	digits := strings.TrimRight(r, "?")
+	return len(r) <= 6 && (digits == "" || isHexDigits(digits))
+}
+
+// isHexDigits reports whether s is one to six hex digits.
+func isHexDigits(s string) bool {
+	if len(s) == 0 || len(s) > 6 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(c string) bool {
+	n, err := strconv.ParseFloat(c, 64)
+	return err == nil && n == 0
+}
+
+// isInteger reports whether c is an integer of at least min.
+func isInteger(c string, min int) bool {
+	n, err := strconv.Atoi(strings.TrimPrefix(c, "+"))
+	return err == nil && n >= min
+}
+
+// isSymbol reports whether c is a counter symbol: a string, an ident or
+// an image.
+func isSymbol(c string) bool {
+	switch tokenOf(c).Type {
+	case scanner.TokenString, scanner.TokenIdent, scanner.TokenURI:
+		return true
+	}
+	return false
+}
+
+// isCounterSystem reports whether comps is a value of the system
+// descriptor of @counter-style.
+func isCounterSystem(comps []string) bool {
+	if len(comps) == 0 {
+		return false
+	}
+	switch strings.ToLower(comps[0]) {
+	case "cyclic", "numeric", "alphabetic", "symbolic", "additive":
+		return len(comps) == 1
+	case "fixed":
+		return len(comps) == 1 || (len(comps) == 2 && isInteger(comps[1], -1<<31))
+	case "extends":
+		return len(comps) == 2 && isIdent(comps[1:])
+	}
+	return false
+}
+
+// isCounterRange reports whether comps is auto or a list of ranges like
+// "1 infinite".
+func isCounterRange(comps []string) bool {
+	if len(comps) == 1 && strings.EqualFold(comps[0], "auto") {
+		return true
+	}
+	for _, r := range splitComponents(comps) {
+		if len(r) != 2 {
+			return false
+		}
+		for _, bound := range r {
+			if !strings.EqualFold(bound, "infinite") && !isInteger(bound, -1<<31) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isCounterPad reports whether comps is a non-negative integer and a
+// symbol, in any order.
+func isCounterPad(comps []string) bool {
+	return len(comps) == 2 && (isInteger(comps[0], 0) && isSymbol(comps[1]) ||
+		isSymbol(comps[0]) && isInteger(comps[1], 0))
+}
+
+// isAdditiveSymbols reports whether comps is a list of weighted symbols
+// like "10 X, 5 V".
+func isAdditiveSymbols(comps []string) bool {
+	for _, tuple := range splitComponents(comps) {
+		if !isCounterPad(tuple) {
+			return false
+		}
+	}
+	return true
+}
+
+// isFeatureIndices reports whether comps holds one to max feature
+// indices, or at least one if max is -1.
+func isFeatureIndices(comps []string, max int) bool {
+	if len(comps) == 0 || (max >= 0 && len(comps) > max) {
+		return false
+	}
+	for _, c := range comps {
+		if !isInteger(c, 0) {
+			return false
+		}
+	}
+	return true
+}
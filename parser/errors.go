@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/scanner"
+	"github.com/ttacon/css/sourcemap"
+)
+
+// Code identifies the kind of a parse error.
+type Code int
+
+const (
+	// ErrScan is reported for input the scanner cannot tokenize, like an
+	// unclosed comment or string.
+	ErrScan Code = iota + 1
+	// ErrUnexpectedEOF is reported when the input ends inside a rule or
+	// block.
+	ErrUnexpectedEOF
+	// ErrUnexpectedToken is reported for a token that cannot appear where
+	// it is.
+	ErrUnexpectedToken
+	// ErrMissingValue is reported for a declaration without a value.
+	ErrMissingValue
+	// ErrInvalidSelector is reported for a rule whose prelude is not a
+	// selector list, or for an invalid keyframe selector.
+	ErrInvalidSelector
+	// ErrInvalidPrelude is reported for an at-rule like @keyframes or
+	// @page whose prelude is invalid. The rule is kept as an ast.AtRule.
+	ErrInvalidPrelude
+	// ErrInvalidDescriptor is reported for an unknown, invalid or
+	// missing descriptor in an at-rule like @font-face.
+	ErrInvalidDescriptor
+)
+
+var codeNames = map[Code]string{
+	ErrScan:              "scan-error",
+	ErrUnexpectedEOF:     "unexpected-eof",
+	ErrUnexpectedToken:   "unexpected-token",
+	ErrMissingValue:      "missing-value",
+	ErrInvalidSelector:   "invalid-selector",
+	ErrInvalidPrelude:    "invalid-prelude",
+	ErrInvalidDescriptor: "invalid-descriptor",
+}
+
+// String returns the name of the code, like "unexpected-token".
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Code(%d)", int(c))
+}
+
+// MarshalText encodes the code as its name.
+func (c Code) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Error is a problem found while parsing.
+type Error struct {
+	Code Code
+	Msg  string
+	// Start and End delimit the offending token.
+	Start ast.Position
+	End   ast.Position
+	// Source is the original source Start and End are in after Remap, or
+	// empty for the parsed text.
+	Source string
+	// Token is the offending token.
+	Token *scanner.Token
+	// Expected lists what the parser expected instead of Token, if
+	// anything: punctuation like ":" or "{", or kinds of nodes like
+	// "declaration" or "value".
+	Expected []string
+}
+
+// newError returns an error for token t.
+func newError(t *scanner.Token, code Code, expected []string, msg string) *Error {
+	end := tokenEnd(t)
+	if t.Type == scanner.TokenError {
+		// The value is a message, not source text.
+		end = tokenPos(t)
+	}
+	return &Error{
+		Code:     code,
+		Msg:      msg,
+		Start:    tokenPos(t),
+		End:      end,
+		Token:    t,
+		Expected: expected,
+	}
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("%s:%s: %s", e.Source, e.Start, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Start, e.Msg)
+}
+
+// ErrorList is a list of parse errors. The parser returns it sorted by
+// position.
+type ErrorList []*Error
+
+// Len, Less and Swap implement sort.Interface, ordering errors by
+// position and then by message.
+func (l ErrorList) Len() int      { return len(l) }
+func (l ErrorList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+func (l ErrorList) Less(i, j int) bool {
+	if a, b := l[i].Start.Offset, l[j].Start.Offset; a != b {
+		return a < b
+	}
+	return l[i].Msg < l[j].Msg
+}
+
+// Sort sorts the list by position.
+func (l ErrorList) Sort() {
+	sort.Sort(l)
+}
+
+// Error implements the error interface.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Remap maps the positions of the errors back to the original sources of
+// the parsed stylesheet using its source map, setting their Source. Errors
+// at unmapped positions are left alone.
+func (l ErrorList) Remap(m *sourcemap.Map) {
+	for _, e := range l {
+		source, span, ok := m.RemapSpan(ast.Span{From: e.Start, To: e.End})
+		if !ok {
+			continue
+		}
+		e.Source, e.Start, e.End = source, span.From, span.To
+	}
+}
+
+// Err returns an error equivalent to this error list. If the list is
+// empty, Err returns nil.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// expectation returns expected as it is shown in error messages.
+func expectation(expected []string) string {
+	quoted := make([]string, len(expected))
+	for i, e := range expected {
+		if r, _ := utf8.DecodeRuneInString(e); r >= 'a' && r <= 'z' {
+			quoted[i] = e
+		} else {
+			quoted[i] = fmt.Sprintf("%q", e)
+		}
+	}
+	return strings.Join(quoted, " or ")
+}
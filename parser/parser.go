@@ -1,343 +1,944 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
+
 	"github.com/ttacon/css/ast"
 
 	"github.com/ttacon/css/scanner"
 )
 
+// Mode controls optional parser behavior.
+type Mode uint
+
+const (
+	// Lossless records the source text of every node in its Raw field,
+	// so that printing the unmodified tree reproduces the input exactly.
+	Lossless Mode = 1 << iota
+)
+
+// Parser parses CSS following the parsing algorithms of CSS Syntax Level
+// 3 (https://www.w3.org/TR/css-syntax-3/#parsing). Like browsers, it
+// recovers from errors: an invalid declaration is skipped up to the next
+// ';' at the same nesting depth, an invalid rule is skipped up to its
+// matching '}', and at-rules it knows nothing about are kept as is.
 type Parser struct {
-	s     *scanner.Scanner
-	cache []*scanner.Token
+	s    *scanner.Scanner
+	mode Mode
+
+	// toks holds the whole input, ending with an EOF token.
+	toks []*scanner.Token
+	pos  int
+	// depth is the number of blocks enclosing the current token.
+	depth int
+	// parent is the lowercased keyword of the at-rule whose block is
+	// being parsed, or "" in a style rule or at the top level.
+	parent string
+	// nesting is set in the block of a style rule and in the group rules
+	// nested in it, which hold declarations and nested style rules.
+	nesting bool
+
+	errors ErrorList
 }
 
 func New(s *scanner.Scanner) *Parser {
+	return NewWithMode(s, 0)
+}
+
+// NewWithMode returns a parser for s using the given mode.
+func NewWithMode(s *scanner.Scanner, mode Mode) *Parser {
 	return &Parser{
-		s: s,
+		s:    s,
+		mode: mode,
 	}
 }
 
+// Parse parses a stylesheet. The returned stylesheet is never nil and
+// holds everything that could be parsed; if errors were found, the error
+// is an ErrorList.
 func (p *Parser) Parse() (*ast.Stylesheet, error) {
+	p.tokenize()
+	rules, end := p.parseRules(topLevel)
+	ss := &ast.Stylesheet{
+		Span:     span(p.toks),
+		Children: rules,
+	}
+	ss.Raw = p.raw(ss, 0, 0, 0, end, len(p.toks))
+	p.errors.Sort()
+	return ss, p.errors.Err()
+}
+
+// tokenize reads the whole input from the scanner. A scanner error ends
+// the input.
+func (p *Parser) tokenize() {
+	for {
+		t := p.s.Next()
+		if t.Type == scanner.TokenError {
+			p.error(t, ErrScan, nil, t.Value)
+			t = &scanner.Token{
+				Type:   scanner.TokenEOF,
+				Line:   t.Line,
+				Column: t.Column,
+				Offset: t.Offset,
+			}
+		}
+		p.toks = append(p.toks, t)
+		if t.Type == scanner.TokenEOF {
+			return
+		}
+	}
+}
+
+// tok returns the current token.
+func (p *Parser) tok() *scanner.Token {
+	return p.toks[p.pos]
+}
+
+// listKind tells parseRules where a list of rules appears.
+type listKind int
+
+const (
+	// topLevel is the stylesheet itself.
+	topLevel listKind = iota
+	// groupList is the block of a group rule like @media.
+	groupList
+	// keyframeList is the block of @keyframes.
+	keyframeList
+)
+
+// parseRules parses a list of rules up to EOF or, unless kind is
+// topLevel, a closing '}'. It also returns the index following the last
+// rule.
+func (p *Parser) parseRules(kind listKind) ([]ast.Rule, int) {
 	var (
-		t     = p.nextNonWhitespaceToken()
-		rules []ast.Rule
+		rules   []ast.Rule
+		prevEnd = p.pos
 	)
-	// TODO(ttacon): change to use channels/consumption
-	for ; !isEnd(t); t = p.nextNonWhitespaceToken() {
-		if isAtKeyword(t) {
-			// TODO(ttacon): pull out to own method
-			// at-rule     : ATKEYWORD S* any* [ block | ';' S* ];
-			// TODO(ttacon): this needs to actually consume any
-			any := p.nextNonWhitespaceToken()
-
-			// sniff it
-			var (
-				rule *ast.QualifiedRule
-				err  error
-			)
-			next := p.nextNonWhitespaceToken()
-			semiOnly := false
-			if isBlockOpen(next) {
-				next = p.nextNonWhitespaceToken()
-				rule, err = p.parseQualifiedRule(next)
-				if err != nil {
-					return nil, err
+	for {
+		t := p.tok()
+		switch {
+		case t.Type == scanner.TokenEOF:
+			return rules, prevEnd
+		case isClosingBrace(t) && kind != topLevel:
+			return rules, prevEnd
+		case isSpace(t), kind == topLevel && (t.Type == scanner.TokenBOM ||
+			t.Type == scanner.TokenCDO || t.Type == scanner.TokenCDC):
+			p.pos++
+		case t.Type == scanner.TokenComment:
+			rules = append(rules, p.comment(prevEnd, p.pos))
+			p.pos++
+			prevEnd = p.pos
+		case isAtKeyword(t):
+			rules = append(rules, p.parseAtRule(prevEnd, false))
+			prevEnd = p.pos
+		default:
+			// The source of a dropped rule ends up in the Before of the
+			// next node.
+			if kind == keyframeList {
+				if kf := p.parseKeyframe(prevEnd); kf != nil {
+					rules = append(rules, kf)
+					prevEnd = p.pos
 				}
-			} else if isSemiColon(next) {
-				semiOnly = true
-			} else {
-				// this is a parse error
-				return nil, fmt.Errorf("expected opening block or ';', found %v", t)
+			} else if r := p.parseQualifiedRule(prevEnd); r != nil {
+				rules = append(rules, r)
+				prevEnd = p.pos
 			}
+		}
+	}
+}
 
-			rules = append(rules, &ast.AtRule{
-				AtKeyword:     t.Value,
-				Any:           any.Value,
-				QualifiedRule: rule,
-				JustSemi:      semiOnly,
-			})
-		} else if isSelector(t) {
-			newRule, err := p.parseQualifiedRule(t)
-			if err != nil {
-				return nil, err
-			}
+// groupAtRules are the at-rules whose block holds a list of rules.
+var groupAtRules = map[string]bool{
+	"@media":          true,
+	"@supports":       true,
+	"@document":       true,
+	"@-moz-document":  true,
+	"@layer":          true,
+	"@container":      true,
+	"@starting-style": true,
+}
+
+// keyframesAtRules are the at-rules whose block holds keyframes.
+var keyframesAtRules = map[string]bool{
+	"@keyframes":         true,
+	"@-webkit-keyframes": true,
+	"@-moz-keyframes":    true,
+	"@-o-keyframes":      true,
+}
 
-			rules = append(rules, newRule)
+// parseAtRule parses the at-rule starting at the current token, preceded
+// by the tokens from before. nested is set for at-rules inside a
+// declaration block.
+func (p *Parser) parseAtRule(before int, nested bool) ast.Rule {
+	// at-rule     : ATKEYWORD S* any* [ block | ';' S* ];
+	var (
+		start   = p.pos
+		name    = p.tok().Value
+		keyword = strings.ToLower(name)
+		parent  = p.parent
+	)
+	p.pos++
+	for {
+		t := p.tok()
+		if t.Type == scanner.TokenEOF || isSemiColon(t) || isCurlyOpen(t) ||
+			(isClosingBrace(t) && p.depth > 0) {
+			break
+		}
+		p.consumeComponent()
+	}
 
+	var (
+		prelude = p.toks[start+1 : p.pos]
+		block   *ast.Block
+		semi    bool
+		textEnd int
+	)
+	switch t := p.tok(); {
+	case isSemiColon(t):
+		p.pos++
+		semi = true
+		textEnd = p.pos
+	case isCurlyOpen(t):
+		textEnd = p.pos
+		p.parent = keyword
+		switch {
+		case keyframesAtRules[keyword]:
+			block = p.parseBlock(p.ruleContents(keyframeList))
+		case keyword == "@scope":
+			nesting := p.nesting
+			p.nesting = false
+			block = p.parseBlock(p.parseMixedContents)
+			p.nesting = nesting
+		case groupAtRules[keyword] && nested && p.nesting:
+			// A group rule nested in a style rule holds declarations,
+			// which apply to the elements the style rule matches, and
+			// nested style rules.
+			block = p.parseBlock(p.parseMixedContents)
+		case groupAtRules[keyword] && !nested:
+			block = p.parseBlock(p.ruleContents(groupList))
+		default:
+			// Descriptor blocks like @font-face's, group rules nested
+			// in a style rule and unknown at-rules.
+			block = p.parseBlock(p.parseDeclarations)
 		}
+		p.parent = parent
+	default:
+		p.unexpected(t, ";", "{")
+		textEnd = p.pos
 	}
-	return &ast.Stylesheet{rules}, nil
+
+	var (
+		sp  = span(p.toks[start:p.pos])
+		raw = func(n interface{}) *ast.Raw {
+			return p.raw(n, before, start, textEnd, p.pos, p.pos)
+		}
+	)
+	if (block != nil || semi) && (!nested || parent != "" || keyword == "@scope") {
+		if r := p.typedAtRule(parent, p.toks[start], prelude, block, sp, raw); r != nil {
+			return r
+		}
+	}
+	at := &ast.AtRule{
+		Span:      sp,
+		AtKeyword: name,
+		Any:       join(prelude),
+		Block:     block,
+		JustSemi:  semi,
+	}
+	at.Raw = raw(at)
+	return at
 }
 
-func (p *Parser) parseSelector(t *scanner.Token) (string, error) {
-	if t == nil {
-		t = p.nextNonWhitespaceToken()
+// parseQualifiedRule parses the rule starting at the current token,
+// preceded by the tokens from before. It returns nil if the rule is
+// invalid.
+func (p *Parser) parseQualifiedRule(before int) *ast.QualifiedRule {
+	start := p.pos
+	if !p.parsePrelude() {
+		return nil
+	}
+	var (
+		prelude = p.toks[start:p.pos]
+		textEnd = p.pos
+		block   = p.parseStyleBlock()
+	)
+	if bad := checkSelector(prelude); bad != nil {
+		p.error(bad, ErrInvalidSelector, nil,
+			fmt.Sprintf("invalid selector %q: unexpected %s", join(prelude), describe(bad)))
+		return nil
 	}
 
-	var selector = t.Value
+	rule := &ast.QualifiedRule{
+		Span:       span(p.toks[start:p.pos]),
+		Components: selectors(prelude),
+		Block:      block,
+	}
+	rule.Raw = p.raw(rule, before, start, textEnd, p.pos, p.pos)
+	return rule
+}
 
-	// TODO(ttacon): I don't think we need to do any sanity checking here
-	// it should be taken care of by callers of this method ... write tests
-	// to make sure
-	if t.Type == scanner.TokenChar {
-		// we need to consume the next token for the rest of
-		// the class identifier
-		t = p.nextNonWhitespaceToken()
-		if t.Type != scanner.TokenIdent {
-			return "", fmt.Errorf("expected an identifier, got %v", t)
+// parsePrelude consumes the prelude of a qualified rule, up to its
+// block. It reports false if the prelude does not end with a block.
+func (p *Parser) parsePrelude() bool {
+	for {
+		t := p.tok()
+		if t.Type == scanner.TokenEOF {
+			p.unexpected(t, "{")
+			return false
+		}
+		if isCurlyOpen(t) {
+			return true
+		}
+		if isClosingBrace(t) && p.depth > 0 {
+			p.unexpected(t, "{")
+			return false
 		}
-		selector += t.Value
+		p.consumeComponent()
 	}
+}
 
-	t = p.peek()
+// parseStyleBlock parses the block of a style rule starting at the
+// current token, outside of any at-rule's block. Following CSS Nesting
+// (https://www.w3.org/TR/css-nesting-1/), it holds declarations, nested
+// style rules and nested group rules.
+func (p *Parser) parseStyleBlock() *ast.Block {
+	parent, nesting := p.parent, p.nesting
+	p.parent, p.nesting = "", true
+	defer func() { p.parent, p.nesting = parent, nesting }()
+	return p.parseBlock(p.parseMixedContents)
+}
 
-	// sniff for ':'
-	if t.Type == scanner.TokenChar && t.Value == ":" {
-		t = p.nextNonWhitespaceToken()
-		selector += ":"
-		t = p.peek()
+// parseBlock parses the {}-block starting at the current token. Its
+// contents are parsed by contents, which returns the index following the
+// last node it parsed.
+func (p *Parser) parseBlock(contents func(*ast.Block) int) *ast.Block {
+	open := p.pos
+	p.pos++
+	p.depth++
+	block := &ast.Block{}
+	end := contents(block)
+	p.depth--
+
+	if t := p.tok(); isClosingBrace(t) {
+		p.pos++
+	} else {
+		p.unexpected(t, "}")
 	}
+	block.Span = span(p.toks[open:p.pos])
+	block.Raw = p.raw(block, open, open, open+1, end, p.pos)
+	return block
+}
 
-	// check for [, ( first
-	// TODO(ttacon): does this need to be a loop?
-	if (t.Type == scanner.TokenChar && (t.Value == "(" || t.Value == "[")) ||
-		t.Type == scanner.TokenFunction {
-		t = p.nextNonWhitespaceToken()
-		rest, err := p.parseRestOfSelector(t)
-		if err != nil {
-			return "", err
-		}
-		selector += rest
+// ruleContents returns a parser for a block holding a list of rules.
+func (p *Parser) ruleContents(kind listKind) func(*ast.Block) int {
+	return func(b *ast.Block) int {
+		var end int
+		b.Rules, end = p.parseRules(kind)
+		return end
 	}
+}
 
-	for isSelector(t) {
-		t = p.peek()
-		if !isSelector(t) {
-			break
-		}
+// parseDeclarations parses the contents of a declaration block up to the
+// closing '}'. Nested at-rules are added to the block's Rules. It returns
+// the index following the last node.
+func (p *Parser) parseDeclarations(b *ast.Block) int {
+	return p.parseContents(b, false)
+}
 
-		t = p.nextNonWhitespaceToken()
-		compound, err := p.parseSelector(t)
-		if err != nil {
-			return "", err
+// parseMixedContents parses the contents of a block holding both
+// declarations and rules, like the block of a style rule or of @scope, up
+// to the closing '}'. It returns the index following the last node.
+func (p *Parser) parseMixedContents(b *ast.Block) int {
+	return p.parseContents(b, true)
+}
+
+// parseContents parses the contents of a block up to the closing '}'. If
+// rules is set, the block may hold style rules and group rules; otherwise
+// it holds declarations and nested at-rules.
+func (p *Parser) parseContents(b *ast.Block, rules bool) int {
+	var (
+		decls   = &ast.DeclarationList{}
+		prevEnd = p.pos
+		// pending holds the indices of the comments not claimed yet.
+		pending []int
+	)
+	b.DeclList = decls
+	for {
+		t := p.tok()
+		switch {
+		case t.Type == scanner.TokenEOF, isClosingBrace(t):
+			decls.Trailing = p.comments(pending)
+			return prevEnd
+		case isSpace(t), isSemiColon(t):
+			p.pos++
+		case t.Type == scanner.TokenComment:
+			pending = append(pending, p.pos)
+			p.pos++
+		case isAtKeyword(t):
+			// Comments before a rule are entries of the rule list.
+			for _, i := range pending {
+				b.Rules = append(b.Rules, p.comment(prevEnd, i))
+				prevEnd = i + 1
+			}
+			pending = nil
+			b.Rules = append(b.Rules, p.parseAtRule(prevEnd, !rules || p.nesting))
+			prevEnd = p.pos
+		case rules && p.startsRule():
+			for _, i := range pending {
+				b.Rules = append(b.Rules, p.comment(prevEnd, i))
+				prevEnd = i + 1
+			}
+			pending = nil
+			if r := p.parseQualifiedRule(prevEnd); r != nil {
+				b.Rules = append(b.Rules, r)
+				prevEnd = p.pos
+			}
+		case t.Type == scanner.TokenIdent:
+			start := p.pos
+			if len(pending) > 0 {
+				start = pending[0]
+			}
+			decl := p.parseDeclaration()
+			if decl == nil {
+				// Pending comments go to the next declaration.
+				continue
+			}
+			p.checkDescriptor(t, decl)
+			decl.Leading = p.comments(pending)
+			pending = nil
+			decl.Raw = p.raw(decl, prevEnd, start, p.pos, p.pos, p.pos)
+			decls.Declarations = append(decls.Declarations, decl)
+			prevEnd = p.pos
+		default:
+			p.unexpected(t, "declaration")
+			p.skipDeclaration()
 		}
-		selector += fmt.Sprintf(" %s", compound)
 	}
-	return selector, nil
 }
 
-func (p *Parser) parseRestOfSelector(t *scanner.Token) (string, error) {
+// parseDeclaration parses the declaration starting at the current ident
+// token, through its terminating ';' and the comments following it on
+// the same line. An invalid declaration is skipped and nil is returned.
+func (p *Parser) parseDeclaration() *ast.Declaration {
 	var (
-		seen = []string{t.Value}
-		sel  = t.Value
+		start = p.pos
+		ident = p.tok()
 	)
-	if t.Type == scanner.TokenFunction {
-		seen = []string{"("}
-	}
-	t = p.s.Next()
-	for !closedRestOfSelector(seen, t) {
-		// we need to check and append, or close
-		if isBlockOpen(t) {
-			// TODO(ttacon): do we need to differentiate '{'?
-			seen = append(seen, t.Value)
-			sel += t.Value
-			t = p.s.Next()
-			continue
-		}
+	p.pos++
+	decl := &ast.Declaration{Ident: ident.Value}
 
-		opening := openingBrace(t)
-		if opening == "" {
-			sel += t.Value
-			t = p.s.Next()
-			continue
+	for t := p.tok(); isSpace(t) || t.Type == scanner.TokenComment; t = p.tok() {
+		if t.Type == scanner.TokenComment {
+			decl.Trailing = append(decl.Trailing, newComment(t))
 		}
+		p.pos++
+	}
+	if t := p.tok(); t.Type != scanner.TokenChar || t.Value != ":" {
+		p.unexpected(t, ":")
+		p.skipDeclaration()
+		return nil
+	}
+	p.pos++
 
-		if opening == seen[len(seen)-1] {
-			seen = seen[0 : len(seen)-1]
-		} else {
-			return "", fmt.Errorf("was expecting %q, saw %q",
-				seen[len(seen)-1],
-				opening)
+	valueStart := p.pos
+	for {
+		t := p.tok()
+		if t.Type == scanner.TokenEOF || isSemiColon(t) || isClosingBrace(t) {
+			break
 		}
-		sel += t.Value
-		t = p.s.Next()
+		p.consumeComponent()
 	}
-	if t.Type != scanner.TokenChar {
-		return "", fmt.Errorf("expected closing brace, got %v", t)
+	value := p.toks[valueStart:p.pos]
+	// The !important annotation is removed from the value, and its
+	// comments are added after those of the value.
+	var annotation []*scanner.Token
+	if i := important(value); i >= 0 {
+		value, annotation = value[:i], value[i:]
+		decl.Important = true
 	}
-
-	return sel + t.Value, nil
+	if strings.HasPrefix(ident.Value, "--") {
+		var comments []*scanner.Token
+		decl.Components, comments = customValue(value)
+		for _, t := range comments {
+			decl.Trailing = append(decl.Trailing, newComment(t))
+		}
+		decl.Components[0] += strings.Join(unclosed(value), "")
+	} else {
+		for _, t := range value {
+			switch t.Type {
+			case scanner.TokenS:
+			case scanner.TokenComment:
+				decl.Trailing = append(decl.Trailing, newComment(t))
+			default:
+				decl.Components = append(decl.Components, t.Value)
+			}
+		}
+		// Functions and blocks left open at EOF are closed.
+		decl.Components = append(decl.Components, unclosed(value)...)
+		if len(decl.Components) == 0 {
+			p.error(p.tok(), ErrMissingValue, []string{"value"},
+				fmt.Sprintf("expected a value for %q", ident.Value))
+			p.skipDeclaration()
+			return nil
+		}
+		if brace := topLevelBlock(value); brace != nil {
+			p.error(brace, ErrUnexpectedToken, nil,
+				fmt.Sprintf("unexpected '{' in the value of %q", ident.Value))
+			p.skipDeclaration()
+			return nil
+		}
+	}
+	for _, t := range annotation {
+		if t.Type == scanner.TokenComment {
+			decl.Trailing = append(decl.Trailing, newComment(t))
+		}
+	}
+	if isSemiColon(p.tok()) {
+		p.pos++
+	}
+	decl.Span = span(trim(p.toks[start:p.pos]))
+
+	// Claim the comments following the declaration on the same line.
+	for i := p.pos; ; i++ {
+		t := p.toks[i]
+		if t.Type == scanner.TokenComment {
+			decl.Trailing = append(decl.Trailing, newComment(t))
+			p.pos = i + 1
+		} else if !isSpace(t) || strings.Contains(t.Value, "\n") {
+			break
+		}
+	}
+	return decl
 }
 
-func closedRestOfSelector(seen []string, t *scanner.Token) bool {
-	opening := openingBrace(t)
-	if opening == "" {
-		return false
+// customValue returns the components of the value of a custom property,
+// which is kept as written (https://www.w3.org/TR/css-variables-1/): its
+// text, which may be empty and may hold any balanced blocks. The comments
+// around the text are returned apart.
+func customValue(value []*scanner.Token) ([]string, []*scanner.Token) {
+	var comments []*scanner.Token
+	from, to := 0, lastSignificant(value)+1
+	for from < to && isTrivia(value[from]) {
+		from++
 	}
-
-	return len(seen) == 1 && opening == seen[0]
+	for _, t := range append(value[:from:from], value[to:]...) {
+		if t.Type == scanner.TokenComment {
+			comments = append(comments, t)
+		}
+	}
+	var buf bytes.Buffer
+	for _, t := range value[from:to] {
+		buf.WriteString(t.Value)
+	}
+	return []string{buf.String()}, comments
 }
 
-func openingBrace(t *scanner.Token) string {
-	if t.Type != scanner.TokenChar {
-		return ""
+// important returns the index of the !important annotation ending the
+// value, or -1 if it has none. Per css-syntax-3, the annotation is a '!'
+// followed by the ident important, in any case, and whitespace and
+// comments may come between them.
+func important(value []*scanner.Token) int {
+	i := lastSignificant(value)
+	if i < 0 || value[i].Type != scanner.TokenIdent || !strings.EqualFold(value[i].Value, "important") {
+		return -1
 	}
-
-	if t.Value == "]" {
-		return "["
-	} else if t.Value == ")" {
-		return "("
+	j := lastSignificant(value[:i])
+	if j < 0 || value[j].Type != scanner.TokenChar || value[j].Value != "!" {
+		return -1
 	}
-	return ""
+	return j
 }
 
-func (p *Parser) peek() *scanner.Token {
-	if len(p.cache) == 0 {
-		tok := p.nextNonWhitespaceToken()
-		p.cache = append(p.cache, tok)
-		return tok
+// lastSignificant returns the index of the last token of toks that is
+// neither whitespace nor a comment, or -1.
+func lastSignificant(toks []*scanner.Token) int {
+	for i := len(toks) - 1; i >= 0; i-- {
+		if !isTrivia(toks[i]) {
+			return i
+		}
 	}
+	return -1
+}
 
-	return p.cache[0]
+// startsRule reports whether a qualified rule rather than a declaration
+// starts at the current token: whether a '{' comes before the end of the
+// declaration it would otherwise be.
+func (p *Parser) startsRule() bool {
+	if t := p.tok(); t.Type == scanner.TokenIdent && strings.HasPrefix(t.Value, "--") {
+		// The value of a custom property may hold a {}-block.
+		return false
+	}
+	depth := 0
+	for _, t := range p.toks[p.pos:] {
+		switch {
+		case t.Type == scanner.TokenEOF:
+			return false
+		case depth == 0 && isCurlyOpen(t):
+			return true
+		case depth == 0 && (isSemiColon(t) || isClosingBrace(t)):
+			return false
+		case closingFor(t) != "":
+			depth++
+		case isClosingParen(t), isClosingBrace(t):
+			depth--
+		}
+	}
+	return false
 }
 
-func (p *Parser) parseQualifiedRule(entry *scanner.Token) (*ast.QualifiedRule, error) {
-	var (
-		t    = entry
-		name string
-	)
+// skipDeclaration skips component values up to and including the next
+// ';', or up to the '}' closing the enclosing block.
+func (p *Parser) skipDeclaration() {
+	for {
+		t := p.tok()
+		if t.Type == scanner.TokenEOF || isClosingBrace(t) {
+			return
+		}
+		if isSemiColon(t) {
+			p.pos++
+			return
+		}
+		p.consumeComponent()
+	}
+}
 
-	var err error
-	name, err = p.parseSelector(t)
-	if err != nil {
-		return nil, err
+// consumeComponent consumes a component value: a single token, or a
+// simple block or function along with its contents.
+func (p *Parser) consumeComponent() {
+	t := p.tok()
+	if t.Type == scanner.TokenEOF {
+		return
+	}
+	p.pos++
+	closing := closingFor(t)
+	if closing == "" {
+		return
 	}
-	var names = []string{name}
-	t = p.nextNonWhitespaceToken()
-	for t.Type == scanner.TokenChar && t.Value == "," {
-		sel, err := p.parseSelector(nil)
-		if err != nil {
-			return nil, err
+	for {
+		t := p.tok()
+		if t.Type == scanner.TokenEOF {
+			p.unexpected(t, closing)
+			return
+		}
+		if t.Type == scanner.TokenChar && t.Value == closing {
+			p.pos++
+			return
 		}
+		p.consumeComponent()
+	}
+}
 
-		names = append(names, sel)
-		t = p.nextNonWhitespaceToken()
+// comment returns the node for the comment token at index i, preceded by
+// the tokens from before.
+func (p *Parser) comment(before, i int) *ast.Comment {
+	c := newComment(p.toks[i])
+	c.Raw = p.raw(c, before, i, i+1, i+1, i+1)
+	return c
+}
+
+// comments returns the nodes for the comment tokens at the given indices.
+func (p *Parser) comments(indices []int) []*ast.Comment {
+	var cs []*ast.Comment
+	for _, i := range indices {
+		cs = append(cs, newComment(p.toks[i]))
 	}
+	return cs
+}
 
-	block, err := p.parseBlock(t)
+// error records an error at t. Only the first error at a given position
+// is kept: an unclosed block at EOF would otherwise be reported once per
+// enclosing block.
+func (p *Parser) error(t *scanner.Token, code Code, expected []string, msg string) {
+	for _, e := range p.errors {
+		if e.Start.Offset == t.Offset {
+			return
+		}
+	}
+	p.errors = append(p.errors, newError(t, code, expected, msg))
+}
 
-	var components = make([]*ast.ComponentValue, len(names))
-	for i, name := range names {
-		components[i] = &ast.ComponentValue{name}
+// unexpected records an error for the unexpected token t.
+func (p *Parser) unexpected(t *scanner.Token, expected ...string) {
+	code := ErrUnexpectedToken
+	if t.Type == scanner.TokenEOF {
+		code = ErrUnexpectedEOF
 	}
+	p.error(t, code, expected,
+		fmt.Sprintf("unexpected %s, expected %s", describe(t), expectation(expected)))
+}
 
-	return &ast.QualifiedRule{
-		Components: components,
-		Block:      block,
-	}, nil
+func (p *Parser) lossless() bool {
+	return p.mode&Lossless != 0
 }
 
-func (p *Parser) parseBlock(t *scanner.Token) (*ast.Block, error) {
-	if t.Value != "{" {
-		return nil, fmt.Errorf("expected '{', got %q", t.Value)
+// raw returns the Raw of n in lossless mode, and nil otherwise. Its
+// Before is the text of the tokens in [before, start), its Text that of
+// [start, textEnd) and its After that of [afterStart, end).
+func (p *Parser) raw(n interface{}, before, start, textEnd, afterStart, end int) *ast.Raw {
+	if !p.lossless() {
+		return nil
 	}
+	return ast.NewRaw(n, p.text(before, start), p.text(start, textEnd), p.text(afterStart, end))
+}
 
-	decls, err := p.parseDeclarations()
-	if err != nil {
-		return nil, err
+// text returns the source text of the tokens in [from, to).
+func (p *Parser) text(from, to int) string {
+	var buf bytes.Buffer
+	for _, t := range p.toks[from:to] {
+		buf.WriteString(t.Value)
 	}
-	return &ast.Block{
-		DeclList: decls,
-	}, nil
+	return buf.String()
 }
 
-func (p *Parser) nextNonWhitespaceToken() *scanner.Token {
-	if len(p.cache) == 0 {
-		var t = p.s.Next()
-		for t.Type == scanner.TokenS {
-			t = p.s.Next()
+// HELPERS ////////////////////////////////////////////////////////////
+
+// join returns the text of toks without comments, with whitespace
+// collapsed to single spaces.
+func join(toks []*scanner.Token) string {
+	var (
+		buf   bytes.Buffer
+		space bool
+	)
+	for _, t := range toks {
+		switch t.Type {
+		case scanner.TokenS:
+			space = true
+		case scanner.TokenComment:
+		default:
+			if space && buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			space = false
+			buf.WriteString(t.Value)
 		}
-		return t
 	}
-	tok := p.cache[0]
-	// TODO(ttacon): make cache not a slice but a pointer to a single token
-	p.cache = nil
-	return tok
+	return buf.String()
 }
 
-func (p *Parser) parseDeclarations() (*ast.DeclarationList, error) {
-	// sniff @-rule vs decl
-	tok := p.nextNonWhitespaceToken()
-	var decls []*ast.Declaration
-	for ; tok.Value != "}"; tok = p.nextNonWhitespaceToken() {
-		if tok.Type == scanner.TokenAtKeyword {
-			// TODO(ttacon): do it
-			continue
+// selectors splits a qualified rule prelude at its top-level commas.
+func selectors(prelude []*scanner.Token) []*ast.ComponentValue {
+	var (
+		components []*ast.ComponentValue
+		depth      int
+		start      int
+	)
+	for i, t := range prelude {
+		switch {
+		case closingFor(t) != "":
+			depth++
+		case isClosingParen(t):
+			depth--
+		case depth == 0 && isComma(t):
+			components = append(components, selector(prelude[start:i]))
+			start = i + 1
 		}
+	}
+	return append(components, selector(prelude[start:]))
+}
 
-		decl, err := p.parseDeclaration(tok)
-		if err != nil {
-			return nil, err
-		}
+func selector(toks []*scanner.Token) *ast.ComponentValue {
+	return &ast.ComponentValue{
+		Span: span(trim(toks)),
+		Name: join(toks),
+	}
+}
 
-		decls = append(decls, decl)
+func newComment(t *scanner.Token) *ast.Comment {
+	return &ast.Comment{
+		Span: span([]*scanner.Token{t}),
+		Text: t.Value,
 	}
+}
 
-	return &ast.DeclarationList{decls}, nil
+// span returns the source range of toks.
+func span(toks []*scanner.Token) ast.Span {
+	if len(toks) == 0 {
+		return ast.Span{}
+	}
+	return ast.Span{
+		From: tokenPos(toks[0]),
+		To:   tokenEnd(toks[len(toks)-1]),
+	}
 }
 
-func (p *Parser) parseDeclaration(ident *scanner.Token) (*ast.Declaration, error) {
-	tok := p.nextNonWhitespaceToken()
-	if tok.Type != scanner.TokenChar || tok.Value != ":" {
-		return nil, fmt.Errorf("expected ':', got %s", tok.Value)
+// trim returns toks without leading and trailing whitespace and comments.
+func trim(toks []*scanner.Token) []*scanner.Token {
+	for len(toks) > 0 && isTrivia(toks[0]) {
+		toks = toks[1:]
+	}
+	for len(toks) > 0 && isTrivia(toks[len(toks)-1]) {
+		toks = toks[:len(toks)-1]
 	}
+	return toks
+}
+
+// tokenPos returns the position of the first character of t.
+func tokenPos(t *scanner.Token) ast.Position {
+	return ast.Position{Offset: t.Offset, Line: t.Line, Column: t.Column}
+}
+
+// tokenEnd returns the position of the character following t.
+func tokenEnd(t *scanner.Token) ast.Position {
+	end := tokenPos(t)
+	end.Offset += len(t.Value)
+	if i := strings.LastIndex(t.Value, "\n"); i >= 0 {
+		end.Line += strings.Count(t.Value, "\n")
+		end.Column = scanner.Columns(t.Value[i:])
+	} else {
+		end.Column += scanner.Columns(t.Value)
+	}
+	return end
+}
 
-	var components []string
-	tok = p.nextNonWhitespaceToken()
-	for ; tok.Type != scanner.TokenError && tok.Type != scanner.TokenEOF; tok = p.nextNonWhitespaceToken() {
-		if tok.Value == "!important" {
-			return &ast.Declaration{
-				Ident:      ident.Value,
-				Components: append(components, tok.Value),
-			}, nil
+// checkSelector returns the first token of prelude that cannot appear
+// there in a selector list, or nil if there is none.
+func checkSelector(prelude []*scanner.Token) *scanner.Token {
+	var (
+		depth int
+		empty = true
+		last  *scanner.Token
+	)
+	for _, t := range prelude {
+		if last != nil && last.Type == scanner.TokenChar && last.Value == ":" &&
+			t.Type != scanner.TokenIdent && t.Type != scanner.TokenFunction &&
+			(t.Type != scanner.TokenChar || t.Value != ":") {
+			// A colon starts the name of a pseudo-class or pseudo-element.
+			return t
 		}
-		if tok.Value == ";" {
-			break
+		if isSpace(t) || t.Type == scanner.TokenComment {
+			continue
 		}
+		last = t
+		switch {
+		case isCurlyOpen(t):
+			return t
+		case closingFor(t) != "":
+			depth++
+		case isClosingParen(t):
+			if depth == 0 {
+				return t
+			}
+			depth--
+		case depth > 0:
+		case isComma(t):
+			if empty {
+				return t
+			}
+			empty = true
+			continue
+		case !isSelectorToken(t):
+			return t
+		}
+		empty = false
+	}
+	if empty {
+		if last == nil {
+			last = prelude[len(prelude)-1]
+		}
+		return last
+	}
+	return nil
+}
 
-		components = append(components, tok.Value)
+// isSelectorToken reports whether t may appear at the top level of a
+// selector.
+func isSelectorToken(t *scanner.Token) bool {
+	switch t.Type {
+	case scanner.TokenIdent, scanner.TokenHash:
+		return true
+	case scanner.TokenChar:
+		return len(t.Value) == 1 && strings.Contains(".:*>+~|&", t.Value)
 	}
+	return false
+}
 
-	if len(components) == 0 {
-		return nil, fmt.Errorf("expected components, none found")
+// topLevelBlock returns the first {}-block of value that is not nested in
+// another block, or nil.
+func topLevelBlock(value []*scanner.Token) *scanner.Token {
+	depth := 0
+	for _, t := range value {
+		switch {
+		case depth == 0 && isCurlyOpen(t):
+			return t
+		case closingFor(t) != "":
+			depth++
+		case isClosingParen(t), isClosingBrace(t):
+			depth--
+		}
 	}
+	return nil
+}
 
-	return &ast.Declaration{
-		Ident:      ident.Value,
-		Components: components,
-	}, nil
+// unclosed returns the tokens closing the functions and blocks value
+// leaves open, innermost first.
+func unclosed(value []*scanner.Token) []string {
+	var open []string
+	for _, t := range value {
+		if c := closingFor(t); c != "" {
+			open = append(open, c)
+		} else if n := len(open); n > 0 && t.Type == scanner.TokenChar && t.Value == open[n-1] {
+			open = open[:n-1]
+		}
+	}
+	var closing []string
+	for i := len(open) - 1; i >= 0; i-- {
+		closing = append(closing, open[i])
+	}
+	return closing
 }
 
-// HELPERS ////////////////////////////////////////////////////////////
+// closingFor returns the token closing the block or function opened by
+// t, or "" if t opens none.
+func closingFor(t *scanner.Token) string {
+	if t.Type == scanner.TokenFunction {
+		return ")"
+	}
+	if t.Type != scanner.TokenChar {
+		return ""
+	}
+	switch t.Value {
+	case "(":
+		return ")"
+	case "[":
+		return "]"
+	case "{":
+		return "}"
+	}
+	return ""
+}
+
+// describe returns t as it is shown in error messages.
+func describe(t *scanner.Token) string {
+	if t.Type == scanner.TokenEOF {
+		return "EOF"
+	}
+	return fmt.Sprintf("%q", t.Value)
+}
 
 func isClosingBrace(t *scanner.Token) bool {
 	return t.Type == scanner.TokenChar && t.Value == "}"
 }
 
+func isClosingParen(t *scanner.Token) bool {
+	return t.Type == scanner.TokenChar && (t.Value == ")" || t.Value == "]")
+}
+
 func isSpace(t *scanner.Token) bool {
 	return t.Type == scanner.TokenS
 }
 
-func isEnd(t *scanner.Token) bool {
-	return t.Type == scanner.TokenError || t.Type == scanner.TokenEOF
+func isTrivia(t *scanner.Token) bool {
+	return t.Type == scanner.TokenS || t.Type == scanner.TokenComment
 }
 
-func isBlockOpen(t *scanner.Token) bool {
-	if t.Type != scanner.TokenChar {
-		return false
-	}
-	return t.Value == "{" || t.Value == "[" || t.Value == "("
+func isCurlyOpen(t *scanner.Token) bool {
+	return t.Type == scanner.TokenChar && t.Value == "{"
+}
+
+func isComma(t *scanner.Token) bool {
+	return t.Type == scanner.TokenChar && t.Value == ","
 }
 
 func isSemiColon(t *scanner.Token) bool {
@@ -347,9 +948,3 @@ func isSemiColon(t *scanner.Token) bool {
 func isAtKeyword(t *scanner.Token) bool {
 	return t.Type == scanner.TokenAtKeyword
 }
-
-func isSelector(t *scanner.Token) bool {
-	return (t.Type == scanner.TokenChar && t.Value == ".") ||
-		t.Type == scanner.TokenHash ||
-		t.Type == scanner.TokenIdent
-}
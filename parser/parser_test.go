@@ -1,11 +1,15 @@
 package parser
 
 import (
+	"bytes"
+	"fmt"
 	"reflect"
 	"testing"
 
 	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/printer"
 	"github.com/ttacon/css/scanner"
+	"github.com/ttacon/css/sourcemap"
 	"github.com/ttacon/pretty"
 )
 
@@ -210,23 +214,25 @@ func TestParse(t *testing.T) {
 					&ast.AtRule{
 						AtKeyword: "@media",
 						Any:       "print",
-						QualifiedRule: &ast.QualifiedRule{
-							Components: []*ast.ComponentValue{
-								&ast.ComponentValue{Name: "body"},
-							},
-							Block: &ast.Block{
-								DeclList: &ast.DeclarationList{
-									Declarations: []*ast.Declaration{
-										&ast.Declaration{
-											Ident:      "font-size",
-											Components: []string{"12pt"},
+						Block: &ast.Block{
+							Rules: []ast.Rule{
+								&ast.QualifiedRule{
+									Components: []*ast.ComponentValue{
+										&ast.ComponentValue{Name: "body"},
+									},
+									Block: &ast.Block{
+										DeclList: &ast.DeclarationList{
+											Declarations: []*ast.Declaration{
+												&ast.Declaration{
+													Ident:      "font-size",
+													Components: []string{"12pt"},
+												},
+											},
 										},
 									},
 								},
 							},
 						},
-						Block:    (*ast.Block)(nil),
-						JustSemi: false,
 					},
 				},
 			},
@@ -248,23 +254,25 @@ func TestParse(t *testing.T) {
 					&ast.AtRule{
 						AtKeyword: "@media",
 						Any:       "print",
-						QualifiedRule: &ast.QualifiedRule{
-							Components: []*ast.ComponentValue{
-								&ast.ComponentValue{Name: "body"},
-							},
-							Block: &ast.Block{
-								DeclList: &ast.DeclarationList{
-									Declarations: []*ast.Declaration{
-										&ast.Declaration{
-											Ident:      "font-size",
-											Components: []string{"12pt"},
+						Block: &ast.Block{
+							Rules: []ast.Rule{
+								&ast.QualifiedRule{
+									Components: []*ast.ComponentValue{
+										&ast.ComponentValue{Name: "body"},
+									},
+									Block: &ast.Block{
+										DeclList: &ast.DeclarationList{
+											Declarations: []*ast.Declaration{
+												&ast.Declaration{
+													Ident:      "font-size",
+													Components: []string{"12pt"},
+												},
+											},
 										},
 									},
 								},
 							},
 						},
-						Block:    (*ast.Block)(nil),
-						JustSemi: false,
 					},
 					&ast.QualifiedRule{
 						Components: []*ast.ComponentValue{
@@ -311,12 +319,63 @@ p:nth-child(2) {
 				},
 			},
 		},
+		cssTest{
+			text: `
+/* Buttons
+   Styleguide 1.1 */
+.btn {
+  /* base */
+  display: none; /* hidden */
+  color: red /* brand */;
+  /* end */
+}
+/* fin */
+`,
+			node: &ast.Stylesheet{
+				Children: []ast.Rule{
+					&ast.Comment{Text: "/* Buttons\n   Styleguide 1.1 */"},
+					&ast.QualifiedRule{
+						Components: []*ast.ComponentValue{
+							&ast.ComponentValue{Name: ".btn"},
+						},
+						Block: &ast.Block{
+							DeclList: &ast.DeclarationList{
+								Declarations: []*ast.Declaration{
+									&ast.Declaration{
+										Ident:      "display",
+										Components: []string{"none"},
+										Leading: []*ast.Comment{
+											&ast.Comment{Text: "/* base */"},
+										},
+										Trailing: []*ast.Comment{
+											&ast.Comment{Text: "/* hidden */"},
+										},
+									},
+									&ast.Declaration{
+										Ident:      "color",
+										Components: []string{"red"},
+										Trailing: []*ast.Comment{
+											&ast.Comment{Text: "/* brand */"},
+										},
+									},
+								},
+								Trailing: []*ast.Comment{
+									&ast.Comment{Text: "/* end */"},
+								},
+							},
+						},
+					},
+					&ast.Comment{Text: "/* fin */"},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
 		s := scanner.New(test.text)
 		p := New(s)
 		nodes, err := p.Parse()
+		clearSpans(reflect.ValueOf(nodes))
 		if err != test.err {
 			t.Errorf("expected err: %v, got %q", errVal(test.err), errVal(err))
 		} else if !reflect.DeepEqual(nodes, test.node) {
@@ -328,9 +387,628 @@ p:nth-child(2) {
 	}
 }
 
+// clearSpans zeroes the source ranges in the tree v points to, so that
+// it can be compared with a tree built by hand.
+func clearSpans(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			clearSpans(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			clearSpans(v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(ast.Span{}) {
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				clearSpans(v.Field(i))
+			}
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	src := "/* x */\n.a, p > b {\n  color: red; /* c */\n}\n@media print { i { top: 0 } }\n"
+	ss, err := New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		rule  = ss.Children[1].(*ast.QualifiedRule)
+		decl  = rule.Block.DeclList.Declarations[0]
+		media = ss.Children[2].(*ast.AtRule)
+		inner = media.Block.Rules[0].(*ast.QualifiedRule)
+	)
+	var tests = []struct {
+		node ast.Node
+		want string
+	}{
+		{ss, "1:1-6:1"},
+		{ss.Children[0], "1:1-1:8"},
+		{rule, "2:1-4:2"},
+		{rule.Components[1], "2:5-2:10"},
+		{rule.Block, "2:11-4:2"},
+		{decl, "3:3-3:14"},
+		{decl.Trailing[0], "3:15-3:22"},
+		{media, "5:1-5:30"},
+		{inner.Block.DeclList.Declarations[0], "5:20-5:26"},
+	}
+	for _, test := range tests {
+		if got := fmt.Sprintf("%s-%s", test.node.Pos(), test.node.End()); got != test.want {
+			t.Errorf("%T: expected %s, got %s", test.node, test.want, got)
+		}
+	}
+	if pos := rule.Pos(); src[pos.Offset:rule.End().Offset] != ".a, p > b {\n  color: red; /* c */\n}" {
+		t.Errorf("bad offsets for rule: %d-%d", pos.Offset, rule.End().Offset)
+	}
+}
+
 func errVal(err error) string {
 	if err != nil {
 		return err.Error()
 	}
 	return "<nil>"
 }
+
+func TestParseErrors(t *testing.T) {
+	var tests = []struct {
+		text string
+		// want is the recovered stylesheet, printed.
+		want string
+		errs []string
+	}{
+		{
+			text: `.a { color red; margin: 0; }`,
+			want: ".a {\n  margin: 0;\n}\n",
+			errs: []string{`1:12: unexpected "red", expected ":"`},
+		},
+		{
+			text: `.a { color: ; margin: 0 }`,
+			want: ".a {\n  margin: 0;\n}\n",
+			errs: []string{`1:13: expected a value for "color"`},
+		},
+		{
+			text: `.a { x: a{;b}; color: red; }`,
+			want: ".a {\n  color: red;\n}\n",
+			// A value holding a {}-block starts a nested rule.
+			errs: []string{`1:8: invalid selector "x: a": unexpected " "`, `1:13: unexpected "}", expected ":"`},
+		},
+		{
+			text: `.a { 42: x; } .b { color: red; }`,
+			want: ".a {\n}\n.b {\n  color: red;\n}\n",
+			errs: []string{`1:6: unexpected "42", expected declaration`},
+		},
+		{
+			text: `.a; .b { color: red; } .c { color: blue; }`,
+			want: ".c {\n  color: blue;\n}\n",
+			errs: []string{`1:3: invalid selector ".a; .b": unexpected ";"`},
+		},
+		{
+			text: `@foo bar { baz: 1; } @media print { .a { color: red } ,x {} }`,
+			want: "@foo bar {\n  baz: 1;\n}\n@media print {\n  .a {\n    color: red;\n  }\n}\n",
+			errs: []string{`1:55: invalid selector ",x": unexpected ","`},
+		},
+		{
+			text: `.a { color: red; } .b { color: rgb(1, 2`,
+			want: ".a {\n  color: red;\n}\n.b {\n  color: rgb(1, 2);\n}\n",
+			errs: []string{`1:40: unexpected EOF, expected ")"`},
+		},
+		{
+			text: `.b { --x: f([a`,
+			want: ".b {\n  --x: f([a]);\n}\n",
+			errs: []string{`1:15: unexpected EOF, expected "]"`},
+		},
+		{
+			text: `.a { color: red; } @import "x"`,
+			want: ".a {\n  color: red;\n}\n@import \"x\";\n",
+			errs: []string{`1:31: unexpected EOF, expected ";" or "{"`},
+		},
+		{
+			text: `@font-face { font-family: x; src: local(x); font-display: quick; colour: red }`,
+			want: "@font-face {\n  font-family: x;\n  src: local(x);\n  font-display: quick;\n  colour: red;\n}\n",
+			errs: []string{
+				`1:45: invalid value "quick" for descriptor "font-display" in @font-face`,
+				`1:66: unknown descriptor "colour" in @font-face`,
+			},
+		},
+		{
+			text: `@font-face { src: url(a.woff) format(woff) x; }`,
+			want: "@font-face {\n  src: url(a.woff) format(woff) x;\n}\n",
+			errs: []string{
+				`1:1: missing descriptor "font-family" in @font-face`,
+				`1:14: invalid value "url(a.woff) format(woff) x" for descriptor "src" in @font-face`,
+			},
+		},
+		{
+			text: `@property --x { syntax: "<length>"; inherits: maybe }`,
+			want: "@property --x {\n  syntax: \"<length>\";\n  inherits: maybe;\n}\n",
+			errs: []string{
+				`1:1: missing descriptor "initial-value" in @property`,
+				`1:37: invalid value "maybe" for descriptor "inherits" in @property`,
+			},
+		},
+		{
+			text: `@font-face { font-family: x; src: local(x); font-display: swap ! important }`,
+			want: "@font-face {\n  font-family: x;\n  src: local(x);\n  font-display: swap !important;\n}\n",
+			errs: []string{
+				`1:45: invalid value "swap !important" for descriptor "font-display" in @font-face`,
+			},
+		},
+		{
+			text: `@keyframes none { from { top: 0 } } @keyframes x { 50%, bottom { top: 1px } to { top: 2px } }`,
+			want: "@keyframes none {\n  from {\n    top: 0;\n  }\n}\n@keyframes x {\n  to {\n    top: 2px;\n  }\n}\n",
+			errs: []string{
+				`1:12: invalid prelude "none" for @keyframes`,
+				`1:57: invalid keyframe selector "50%, bottom": unexpected "bottom"`,
+			},
+		},
+		{
+			text: `@page : first { @top-left { content: "x" } } @font-feature-values Font { @swash { fancy: 1 2; } }`,
+			want: "@page : first {\n  @top-left {\n    content: \"x\";\n  }\n}\n@font-feature-values Font {\n  @swash {\n    fancy: 1 2;\n  }\n}\n",
+			errs: []string{
+				`1:7: invalid prelude ": first" for @page`,
+				`1:83: invalid value "1 2" for descriptor "fancy" in @font-feature-values @swash`,
+			},
+		},
+		{
+			text: `@layer a b; @layer a, b { } @import url(x.css) layer(); .a {}`,
+			want: "@layer a b;\n@layer a, b {\n}\n@import url(x.css) layer();\n.a {\n}\n",
+			errs: []string{
+				`1:10: invalid prelude "a b" for @layer`,
+				`1:23: invalid prelude "a, b" for @layer`,
+				`1:48: invalid prelude "url(x.css) layer()" for @import`,
+			},
+		},
+		{
+			text: `@scope (.a) top (.b) { } @scope () { } @scope (.a) to { }`,
+			want: "@scope (.a) top (.b) {\n}\n@scope () {\n}\n@scope (.a) to {\n}\n",
+			errs: []string{
+				`1:13: invalid prelude "(.a) top (.b)" for @scope`,
+				`1:33: invalid prelude "()" for @scope`,
+				`1:52: invalid prelude "(.a) to" for @scope`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ss, err := New(scanner.New(test.text)).Parse()
+		var errs []string
+		if list, ok := err.(ErrorList); ok {
+			for _, e := range list {
+				errs = append(errs, e.Error())
+			}
+		} else if err != nil {
+			t.Errorf("%s: expected an ErrorList, got %T", test.text, err)
+		}
+		if !reflect.DeepEqual(errs, test.errs) {
+			t.Errorf("%s: expected errors %q, got %q", test.text, test.errs, errs)
+		}
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, ss); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", test.text, test.want, got)
+		}
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	_, err := New(scanner.New(".a {\n  color red;\n}\n,b { x: 1 }\n.c { /* x")).Parse()
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList, got %v", err)
+	}
+	var got []string
+	for _, e := range list {
+		got = append(got, fmt.Sprintf("%s %s-%s %q %v", e.Code, e.Start, e.End, e.Token.Value, e.Expected))
+	}
+	want := []string{
+		`unexpected-token 2:9-2:12 "red" [:]`,
+		`invalid-selector 4:1-4:2 "," []`,
+		`scan-error 5:6-5:6 "unclosed comment" []`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if list[1].Start.Offset != 20 || list[1].End.Offset != 21 {
+		t.Errorf("expected offsets 20-21, got %d-%d", list[1].Start.Offset, list[1].End.Offset)
+	}
+
+	list = ErrorList{list[2], list[0], list[1]}
+	list.Sort()
+	if list[0].Code != ErrUnexpectedToken || list[2].Code != ErrScan {
+		t.Errorf("errors not sorted by position: %v", list)
+	}
+}
+
+func TestErrorListRemap(t *testing.T) {
+	g := sourcemap.NewGenerator("a.css")
+	g.Add(sourcemap.Mapping{GenLine: 2, GenColumn: 3, Source: "a.scss", OrigLine: 8, OrigColumn: 5})
+	_, err := New(scanner.New(".a {\n  color red;\n}\n,b { x: 1 }")).Parse()
+	list := err.(ErrorList)
+	list.Remap(g.Map())
+	var got []string
+	for _, e := range list {
+		got = append(got, fmt.Sprintf("%s-%s", e, e.End))
+	}
+	want := []string{
+		`a.scss:8:11: unexpected "red", expected ":"-8:14`,
+		`4:1: invalid selector ",b": unexpected ","-4:2`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestAtRules(t *testing.T) {
+	src := `@font-face { font-family: "A"; src: url(a.woff2) format("woff2") tech(variations); }
+@-webkit-keyframes spin { from { top: 0 } 50%, entry 10% { top: 1px } }
+@page toc:first, :left { margin: 1in; @top-left { content: "x" } }
+@counter-style thumbs { system: cyclic; symbols: "A" b; suffix: " "; }
+@property --gap { syntax: "<length>"; inherits: false; initial-value: 0; }
+@font-feature-values Font One, "Two" { font-display: swap; @styleset { nice: 1 3; } }
+`
+	ss, err := New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearSpans(reflect.ValueOf(ss))
+
+	decl := func(ident string, comps ...string) *ast.Declaration {
+		return &ast.Declaration{Ident: ident, Components: comps}
+	}
+	decls := func(ds ...*ast.Declaration) *ast.DeclarationList {
+		return &ast.DeclarationList{Declarations: ds}
+	}
+	want := []ast.Rule{
+		&ast.FontFaceRule{
+			Block: &ast.Block{DeclList: decls(
+				decl("font-family", `"A"`),
+				decl("src", "url(a.woff2)", "format(", `"woff2"`, ")", "tech(", "variations", ")"),
+			)},
+		},
+		&ast.KeyframesRule{
+			AtKeyword: "@-webkit-keyframes",
+			Name:      "spin",
+			Block: &ast.Block{Rules: []ast.Rule{
+				&ast.Keyframe{
+					Selectors: []string{"from"},
+					Block:     &ast.Block{DeclList: decls(decl("top", "0"))},
+				},
+				&ast.Keyframe{
+					Selectors: []string{"50%", "entry 10%"},
+					Block:     &ast.Block{DeclList: decls(decl("top", "1px"))},
+				},
+			}},
+		},
+		&ast.PageRule{
+			Selectors: []string{"toc:first", ":left"},
+			Block: &ast.Block{
+				DeclList: decls(decl("margin", "1in")),
+				Rules: []ast.Rule{
+					&ast.MarginRule{
+						AtKeyword: "@top-left",
+						Block:     &ast.Block{DeclList: decls(decl("content", `"x"`))},
+					},
+				},
+			},
+		},
+		&ast.CounterStyleRule{
+			Name: "thumbs",
+			Block: &ast.Block{DeclList: decls(
+				decl("system", "cyclic"),
+				decl("symbols", `"A"`, "b"),
+				decl("suffix", `" "`),
+			)},
+		},
+		&ast.PropertyRule{
+			Name: "--gap",
+			Block: &ast.Block{DeclList: decls(
+				decl("syntax", `"<length>"`),
+				decl("inherits", "false"),
+				decl("initial-value", "0"),
+			)},
+		},
+		&ast.FontFeatureValuesRule{
+			FontFamilies: []string{"Font One", `"Two"`},
+			Block: &ast.Block{
+				DeclList: decls(decl("font-display", "swap")),
+				Rules: []ast.Rule{
+					&ast.FeatureValuesBlock{
+						AtKeyword: "@styleset",
+						Block:     &ast.Block{DeclList: decls(decl("nice", "1", "3"))},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(ss.Children, want) {
+		t.Errorf("expected: %s\ngot: %s",
+			pretty.Sprintf("%s", want),
+			pretty.Sprintf("%s", ss.Children),
+		)
+	}
+}
+
+func TestLayerRules(t *testing.T) {
+	src := `@import "a.css";
+@import url(b.css) layer;
+@import url("c d.css") layer(base.reset) supports(display: grid) screen and (min-width: 40em);
+@import "e\2e css" supports(not (display: grid));
+@layer reset, base.ui;
+@layer base { @layer ui { .a { color: red } } }
+@layer { .b { color: blue } }
+`
+	ss, err := New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearSpans(reflect.ValueOf(ss))
+
+	rule := func(sel, color string) *ast.QualifiedRule {
+		return &ast.QualifiedRule{
+			Components: []*ast.ComponentValue{{Name: sel}},
+			Block: &ast.Block{DeclList: &ast.DeclarationList{
+				Declarations: []*ast.Declaration{{Ident: "color", Components: []string{color}}},
+			}},
+		}
+	}
+	want := []ast.Rule{
+		&ast.ImportRule{URL: "a.css"},
+		&ast.ImportRule{URL: "b.css", Layered: true},
+		&ast.ImportRule{
+			URL:      "c d.css",
+			Layered:  true,
+			Layer:    "base.reset",
+			Supports: "display: grid",
+			Media:    "screen and (min-width: 40em)",
+		},
+		&ast.ImportRule{URL: "e.css", Supports: "not (display: grid)"},
+		&ast.LayerRule{Names: []string{"reset", "base.ui"}},
+		&ast.LayerRule{
+			Names: []string{"base"},
+			Block: &ast.Block{Rules: []ast.Rule{
+				&ast.LayerRule{
+					Names: []string{"ui"},
+					Block: &ast.Block{Rules: []ast.Rule{rule(".a", "red")}},
+				},
+			}},
+		},
+		&ast.LayerRule{Block: &ast.Block{Rules: []ast.Rule{rule(".b", "blue")}}},
+	}
+	if !reflect.DeepEqual(ss.Children, want) {
+		t.Errorf("expected: %s\ngot: %s",
+			pretty.Sprintf("%s", want),
+			pretty.Sprintf("%s", ss.Children),
+		)
+	}
+}
+
+func TestScopeRules(t *testing.T) {
+	src := `@scope (.card, .panel) to (.content) {
+  color: red;
+  img { color: red }
+  @media print { > p { color: red } }
+}
+.list { @scope (> .item) { .a { color: red } } }
+@scope to (:scope > footer) { .b { color: red } }
+`
+	ss, err := New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearSpans(reflect.ValueOf(ss))
+
+	decls := func(color string) *ast.DeclarationList {
+		return &ast.DeclarationList{
+			Declarations: []*ast.Declaration{{Ident: "color", Components: []string{color}}},
+		}
+	}
+	rule := func(sel string) *ast.QualifiedRule {
+		return &ast.QualifiedRule{
+			Components: []*ast.ComponentValue{{Name: sel}},
+			Block:      &ast.Block{DeclList: decls("red")},
+		}
+	}
+	want := []ast.Rule{
+		&ast.ScopeRule{
+			Roots:  []string{".card", ".panel"},
+			Limits: []string{".content"},
+			Block: &ast.Block{
+				DeclList: decls("red"),
+				Rules: []ast.Rule{
+					rule("img"),
+					&ast.AtRule{AtKeyword: "@media", Any: "print", Block: &ast.Block{
+						Rules: []ast.Rule{rule("> p")},
+					}},
+				},
+			},
+		},
+		&ast.QualifiedRule{
+			Components: []*ast.ComponentValue{{Name: ".list"}},
+			Block: &ast.Block{
+				DeclList: &ast.DeclarationList{},
+				Rules: []ast.Rule{&ast.ScopeRule{
+					Roots: []string{"> .item"},
+					Block: &ast.Block{DeclList: &ast.DeclarationList{}, Rules: []ast.Rule{rule(".a")}},
+				}},
+			},
+		},
+		&ast.ScopeRule{
+			Limits: []string{":scope > footer"},
+			Block:  &ast.Block{DeclList: &ast.DeclarationList{}, Rules: []ast.Rule{rule(".b")}},
+		},
+	}
+	if !reflect.DeepEqual(ss.Children, want) {
+		t.Errorf("expected: %s\ngot: %s",
+			pretty.Sprintf("%s", want),
+			pretty.Sprintf("%s", ss.Children),
+		)
+	}
+}
+
+func TestNesting(t *testing.T) {
+	src := `.card {
+  color: red;
+  &:hover { color: blue }
+  > .title, .x & { font-weight: bold }
+  @media (width > 40em) {
+    padding: 0;
+    .body { margin: 0 }
+  }
+  a:hover { color: green }
+}
+`
+	ss, err := New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearSpans(reflect.ValueOf(ss))
+
+	decls := func(ident string, comps ...string) *ast.DeclarationList {
+		return &ast.DeclarationList{
+			Declarations: []*ast.Declaration{{Ident: ident, Components: comps}},
+		}
+	}
+	rule := func(decls *ast.DeclarationList, sels ...string) *ast.QualifiedRule {
+		r := &ast.QualifiedRule{Block: &ast.Block{DeclList: decls}}
+		for _, sel := range sels {
+			r.Components = append(r.Components, &ast.ComponentValue{Name: sel})
+		}
+		return r
+	}
+	want := []ast.Rule{
+		&ast.QualifiedRule{
+			Components: []*ast.ComponentValue{{Name: ".card"}},
+			Block: &ast.Block{
+				DeclList: decls("color", "red"),
+				Rules: []ast.Rule{
+					rule(decls("color", "blue"), "&:hover"),
+					rule(decls("font-weight", "bold"), "> .title", ".x &"),
+					&ast.AtRule{AtKeyword: "@media", Any: "(width > 40em)", Block: &ast.Block{
+						DeclList: decls("padding", "0"),
+						Rules:    []ast.Rule{rule(decls("margin", "0"), ".body")},
+					}},
+					rule(decls("color", "green"), "a:hover"),
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(ss.Children, want) {
+		t.Errorf("expected: %s\ngot: %s",
+			pretty.Sprintf("%s", want),
+			pretty.Sprintf("%s", ss.Children),
+		)
+	}
+}
+
+func TestCustomProperties(t *testing.T) {
+	var tests = []struct {
+		src      string
+		comps    []string
+		trailing []string
+	}{
+		{`--y:  1px   2px ;`, []string{"1px   2px"}, nil},
+		{`--x: { a: b; c: d };`, []string{"{ a: b; c: d }"}, nil},
+		{`--json: [1,2, {"a": [3; 4]}]`, []string{`[1,2, {"a": [3; 4]}]`}, nil},
+		{`--empty:;`, []string{""}, nil},
+		{`--space: ;`, []string{""}, nil},
+		{`--f: calc( 1px+2px )!important;`, []string{"calc( 1px+2px )"}, nil},
+		{`--i: !IMPORTANT`, []string{""}, nil},
+		{`--c: /* a */ x /* b */ y /* c */ ! important;`, []string{"x /* b */ y"}, []string{"/* a */", "/* c */"}},
+		{`--Case: 'a;b' url(x)`, []string{`'a;b' url(x)`}, nil},
+	}
+	for _, test := range tests {
+		ss, err := New(scanner.New(".a { " + test.src + " }")).Parse()
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		d := ss.Children[0].(*ast.QualifiedRule).Block.DeclList.Declarations[0]
+		if !reflect.DeepEqual(d.Components, test.comps) {
+			t.Errorf("%s: expected components %q, got %q", test.src, test.comps, d.Components)
+		}
+		var trailing []string
+		for _, c := range d.Trailing {
+			trailing = append(trailing, c.Text)
+		}
+		if !reflect.DeepEqual(trailing, test.trailing) {
+			t.Errorf("%s: expected comments %q, got %q", test.src, test.trailing, trailing)
+		}
+	}
+}
+
+func TestImportant(t *testing.T) {
+	var tests = []struct {
+		src       string
+		comps     []string
+		important bool
+	}{
+		{`color: red !important`, []string{"red"}, true},
+		{`color: red!important;`, []string{"red"}, true},
+		{`color: red ! IMPORTANT ;`, []string{"red"}, true},
+		{`color: red /* a */ ! /* b */ Important /* c */;`, []string{"red"}, true},
+		{`color: red;`, []string{"red"}, false},
+		{`content: "!important"`, []string{`"!important"`}, false},
+		{`color: red ! important x`, []string{"red", "!", "important", "x"}, false},
+		{`--x: 1px !important`, []string{"1px"}, true},
+		{`--x: !important`, []string{""}, true},
+	}
+	for _, test := range tests {
+		ss, err := New(scanner.New(".a { " + test.src + " }")).Parse()
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		d := ss.Children[0].(*ast.QualifiedRule).Block.DeclList.Declarations[0]
+		if !reflect.DeepEqual(d.Components, test.comps) || d.Important != test.important {
+			t.Errorf("%s: expected %q, important %v, got %q, important %v", test.src, test.comps, test.important, d.Components, d.Important)
+		}
+	}
+	if _, err := New(scanner.New(".a { color: !important }")).Parse(); err == nil {
+		t.Error("expected an error for a value made of !important only")
+	}
+}
+
+func TestFontSources(t *testing.T) {
+	var tests = []struct {
+		src  string
+		want []*ast.FontSource
+	}{
+		{
+			`local("A B"), local(A  B), url("a.woff2") format(woff2) tech(color-COLRv1, variations), url(a.ttf)`,
+			[]*ast.FontSource{
+				{Local: "A B"},
+				{Local: "A B"},
+				{URL: "a.woff2", Format: "woff2", Tech: []string{"color-COLRv1", "variations"}},
+				{URL: "a.ttf"},
+			},
+		},
+		{`url(a.otf) format("opentype")`, []*ast.FontSource{{URL: "a.otf", Format: "opentype"}}},
+		{`url(a.otf) tech(x) format(woff)`, nil},
+		{`local(a) format(woff)`, nil},
+		{`url(a.otf),`, nil},
+		{`"a.otf"`, nil},
+	}
+	for _, test := range tests {
+		ss, err := New(scanner.New(".a { src: " + test.src + " }")).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := ss.Children[0].(*ast.QualifiedRule).Block.DeclList.Declarations[0]
+		got, err := FontSources(d.Components)
+		if (err != nil) != (test.want == nil) {
+			t.Errorf("%s: unexpected error %v", test.src, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: expected %s, got %s", test.src,
+				pretty.Sprintf("%s", test.want), pretty.Sprintf("%s", got))
+		}
+	}
+}
@@ -0,0 +1,433 @@
+// Package prefixer adds the vendor prefixes a list of target browsers
+// needs to a stylesheet, and removes those none of them needs.
+//
+// Properties like user-select, values like position: sticky, display:
+// flex and gradients, selectors like ::placeholder and @keyframes rules
+// are prefixed, following the support data of the browsers package. The
+// properties listed in a transition are prefixed along with it.
+package prefixer
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/browsers"
+	"github.com/ttacon/css/scanner"
+)
+
+// Prefixer prefixes stylesheets for the browser versions of Targets.
+type Prefixer struct {
+	Targets []browsers.Target
+	// Data is the support data to follow, or nil for browsers.Default().
+	Data *browsers.Data
+	// Keep keeps the prefixed properties, values, selectors and at-rules
+	// none of Targets needs, which are removed otherwise.
+	Keep bool
+}
+
+// Prefix prefixes ss for targets with the default support data.
+func Prefix(ss *ast.Stylesheet, targets []browsers.Target) {
+	p := &Prefixer{Targets: targets}
+	p.Prefix(ss)
+}
+
+// Prefix adds the prefixed variants the targets need to ss, and removes
+// the ones they don't unless p.Keep is set.
+//
+// Prefixed declarations are inserted before the declaration they are a
+// variant of, and prefixed style rules and @keyframes rules before the
+// rule they are a copy of. Variants already present are not added twice.
+// Inside a rule only one vendor supports, like @-webkit-keyframes or a
+// rule for ::-moz-selection, only that vendor's prefixes are added.
+//
+// The variants are copies of the original nodes, positions included, so
+// that they print with the original formatting.
+func (p *Prefixer) Prefix(ss *ast.Stylesheet) {
+	d := p.Data
+	if d == nil {
+		d = browsers.Default()
+	}
+	r := &prefixer{Prefixer: p, data: d}
+	ss.Children = r.rules(ss.Children, "")
+}
+
+type prefixer struct {
+	*Prefixer
+	data *browsers.Data
+}
+
+// needed reports whether the prefixed name of the feature of kind k and
+// unprefixed name base is needed by the targets.
+func (p *prefixer) needed(k browsers.Kind, base, name string) bool {
+	for _, n := range p.data.Prefixed(k, base, p.Targets) {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// prefixed returns the prefixed names of the feature of kind k the
+// targets need, leaving out those of vendors other than only if it is
+// set.
+func (p *prefixer) prefixed(k browsers.Kind, name, only string) []string {
+	var names []string
+	for _, n := range p.data.Prefixed(k, name, p.Targets) {
+		if only == "" || browsers.Prefix(n) == only {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// rules prefixes the rules of list, in a context where only the vendor
+// prefix only applies if it is set.
+func (p *prefixer) rules(list []ast.Rule, only string) []ast.Rule {
+	var out []ast.Rule
+	for _, r := range list {
+		switch r := r.(type) {
+		case *ast.QualifiedRule:
+			out = p.styleRule(out, list, r, only)
+			continue
+		case *ast.KeyframesRule:
+			out = p.keyframes(out, list, r, only)
+			continue
+		}
+		if b := ast.BlockOf(r); b != nil {
+			p.block(b, only)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// styleRule appends r to out, preceded by its prefixed variants, unless
+// it is a prefixed variant the targets don't need.
+func (p *prefixer) styleRule(out, siblings []ast.Rule, r *ast.QualifiedRule, only string) []ast.Rule {
+	for _, pseudo := range pseudos(r) {
+		base := p.data.Unprefixed(browsers.Selector, pseudo)
+		if base != "" && !p.Keep && !p.needed(browsers.Selector, base, pseudo) {
+			return out
+		}
+		if prefix := browsers.Prefix(pseudo); prefix != "" {
+			// Browsers drop a whole rule with a selector they don't
+			// know, so the rule only applies to the vendor.
+			only = prefix
+		}
+	}
+	if r.Block != nil {
+		for _, pseudo := range pseudos(r) {
+			for _, name := range p.prefixed(browsers.Selector, pseudo, only) {
+				c := ast.Clone(r).(*ast.QualifiedRule)
+				for _, cv := range c.Components {
+					cv.Name = replacePseudo(cv.Name, pseudo, name)
+				}
+				if hasRule(siblings, selector(c)) {
+					continue
+				}
+				p.block(c.Block, browsers.Prefix(name))
+				out = append(out, before(c, r))
+			}
+		}
+		p.block(r.Block, only)
+	}
+	return append(out, r)
+}
+
+// keyframes appends r to out, preceded by its prefixed variants, unless
+// it is a prefixed variant the targets don't need.
+func (p *prefixer) keyframes(out, siblings []ast.Rule, r *ast.KeyframesRule, only string) []ast.Rule {
+	keyword := strings.ToLower(r.AtKeyword)
+	if base := p.data.Unprefixed(browsers.AtRule, keyword); base != "" {
+		if !p.Keep && !p.needed(browsers.AtRule, base, keyword) {
+			return out
+		}
+		only = browsers.Prefix(keyword)
+	} else if prefix := browsers.Prefix(keyword); prefix != "" {
+		only = prefix
+	} else {
+		for _, name := range p.prefixed(browsers.AtRule, keyword, only) {
+			if hasKeyframes(siblings, name, r.Name) {
+				continue
+			}
+			c := ast.Clone(r).(*ast.KeyframesRule)
+			c.AtKeyword = name
+			if c.Block != nil {
+				p.block(c.Block, browsers.Prefix(name))
+			}
+			out = append(out, before(c, r))
+		}
+	}
+	if r.Block != nil {
+		p.block(r.Block, only)
+	}
+	return append(out, r)
+}
+
+func (p *prefixer) block(b *ast.Block, only string) {
+	b.Rules = p.rules(b.Rules, only)
+	if b.DeclList != nil {
+		b.DeclList.Declarations = p.declarations(b.DeclList.Declarations, only)
+	}
+}
+
+// declarations returns decls with the prefixed variants the targets need
+// inserted, and those they don't need removed.
+func (p *prefixer) declarations(decls []*ast.Declaration, only string) []*ast.Declaration {
+	var (
+		out   []*ast.Declaration
+		props = map[string]bool{}
+		seen  = map[string]bool{}
+	)
+	for _, d := range decls {
+		props[strings.ToLower(d.Ident)] = true
+		seen[declKey(d)] = true
+	}
+	for _, d := range decls {
+		prop := strings.ToLower(d.Ident)
+		if strings.HasPrefix(prop, "--") {
+			// The value of a custom property is kept as written.
+			out = append(out, d)
+			continue
+		}
+		if !p.Keep && !p.neededDecl(d, props) {
+			continue
+		}
+		for _, name := range p.prefixed(browsers.Property, prop, only) {
+			if props[name] {
+				continue
+			}
+			c := ast.CopyDeclaration(d)
+			c.Ident = name
+			if transitions[prop] {
+				p.transitioned(c, browsers.Prefix(name))
+			}
+			out = append(out, c)
+			props[name] = true
+		}
+		for _, c := range p.valueVariants(d, prop, only) {
+			if key := declKey(c); !seen[key] {
+				out = append(out, c)
+				seen[key] = true
+			}
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// transitions holds the properties whose values list properties, which
+// are prefixed along with them.
+var transitions = map[string]bool{
+	"transition":          true,
+	"transition-property": true,
+}
+
+// valueVariants returns the variants of d, a declaration of prop, with
+// the prefixed values the targets need. A variant has the values of one
+// vendor, like -webkit-linear-gradient( and -webkit-radial-gradient( in
+// the same background, and another one is added for each further name of
+// the vendor, like -webkit-flex after -webkit-box.
+func (p *prefixer) valueVariants(d *ast.Declaration, prop, only string) []*ast.Declaration {
+	var (
+		variants []*ast.Declaration
+		byKey    = map[string]*ast.Declaration{}
+	)
+	for _, v := range d.Components {
+		n := map[string]int{}
+		for _, name := range p.values(prop, v, only) {
+			prefix := browsers.Prefix(name)
+			key := prefix + strconv.Itoa(n[prefix])
+			n[prefix]++
+			c := byKey[key]
+			if c == nil {
+				c = ast.CopyDeclaration(d)
+				byKey[key] = c
+				variants = append(variants, c)
+			}
+			c.Components = replaceValue(c.Components, v, name)
+		}
+	}
+	return variants
+}
+
+// values returns the prefixed variants the targets need of the component
+// v of a declaration of prop: those of a value feature, or those of a
+// property listed in a transition.
+func (p *prefixer) values(prop, v, only string) []string {
+	if transitions[prop] && p.data.Feature(browsers.Property, v) != nil {
+		return p.prefixed(browsers.Property, v, only)
+	}
+	if f := p.data.Feature(browsers.Value, v); f != nil && appliesTo(f, prop) {
+		return p.prefixed(browsers.Value, v, only)
+	}
+	return nil
+}
+
+// transitioned replaces the properties listed in the value of d, the
+// variant with the vendor prefix of a transition, by their variants with
+// the same prefix, like -webkit-transform in -webkit-transition, if the
+// targets need them.
+func (p *prefixer) transitioned(d *ast.Declaration, prefix string) {
+	for _, v := range d.Components {
+		if p.data.Feature(browsers.Property, v) == nil {
+			continue
+		}
+		if names := p.prefixed(browsers.Property, v, prefix); len(names) > 0 {
+			d.Components = replaceValue(d.Components, v, names[0])
+		}
+	}
+}
+
+// neededDecl reports whether d is unprefixed, or a prefixed variant the
+// targets need. display: -webkit-box is kept in a block with the
+// properties, in props, of the legacy line clamping, which needs it.
+func (p *prefixer) neededDecl(d *ast.Declaration, props map[string]bool) bool {
+	prop := strings.ToLower(d.Ident)
+	if base := p.data.Unprefixed(browsers.Property, prop); base != "" && !p.needed(browsers.Property, base, prop) {
+		return false
+	}
+	unprefixedProp := strings.TrimPrefix(prop, browsers.Prefix(prop))
+	for _, v := range d.Components {
+		if transitions[unprefixedProp] {
+			if base := p.data.Unprefixed(browsers.Property, v); base != "" && !p.needed(browsers.Property, base, v) {
+				return false
+			}
+		}
+		base := p.data.Unprefixed(browsers.Value, v)
+		if base == "" || !appliesTo(p.data.Feature(browsers.Value, base), unprefixedProp) {
+			continue
+		}
+		if !p.needed(browsers.Value, base, v) && !lineClamp(prop, v, props) {
+			return false
+		}
+	}
+	return true
+}
+
+// lineClamp reports whether v, a value of prop, is the display the
+// properties props of the legacy line clamping take.
+func lineClamp(prop, v string, props map[string]bool) bool {
+	return prop == "display" && strings.EqualFold(v, "-webkit-box") &&
+		(props["-webkit-line-clamp"] || props["-webkit-box-orient"])
+}
+
+// appliesTo reports whether the value feature f is prefixed in the
+// property prop.
+func appliesTo(f *browsers.Feature, prop string) bool {
+	if len(f.Properties) == 0 {
+		return true
+	}
+	for _, p := range f.Properties {
+		if p == prop {
+			return true
+		}
+	}
+	return false
+}
+
+// before returns the rule c, a copy of r inserted before it. The copy
+// takes the text preceding r, like a comment, and r is left preceded by
+// the line break and indentation only.
+func before(c, r ast.Rule) ast.Rule {
+	if raw := ast.RawOf(r); raw != nil {
+		sep := "\n"
+		if i := strings.LastIndex(raw.Before, "\n"); i >= 0 {
+			sep = raw.Before[i:]
+		}
+		raw.Before = sep
+	}
+	return c
+}
+
+func declKey(d *ast.Declaration) string {
+	return strings.ToLower(d.Ident) + ":" + strings.ToLower(strings.Join(d.Components, " "))
+}
+
+// pseudos returns the pseudo-classes and pseudo-elements without arguments
+// in the selectors of r, lowercased, like ":hover" and "::placeholder".
+func pseudos(r *ast.QualifiedRule) []string {
+	var names []string
+	for _, cv := range r.Components {
+		forEachPseudo(cv.Name, func(name string) string {
+			for _, n := range names {
+				if n == name {
+					return ""
+				}
+			}
+			names = append(names, name)
+			return ""
+		})
+	}
+	return names
+}
+
+// replacePseudo returns sel with the pseudo-class or pseudo-element from
+// replaced by to.
+func replacePseudo(sel, from, to string) string {
+	return forEachPseudo(sel, func(name string) string {
+		if name == from {
+			return to
+		}
+		return ""
+	})
+}
+
+// forEachPseudo calls f for the pseudo-classes and pseudo-elements without
+// arguments of sel, and returns sel with those for which f returns a
+// non-empty string replaced by it.
+func forEachPseudo(sel string, f func(name string) string) string {
+	var (
+		b      strings.Builder
+		colons string
+		sc     = scanner.New(sel)
+	)
+	for t := sc.Next(); t.Type != scanner.TokenEOF && t.Type != scanner.TokenError; t = sc.Next() {
+		switch {
+		case t.Type == scanner.TokenChar && t.Value == ":" && len(colons) < 2:
+			colons += ":"
+			continue
+		case t.Type == scanner.TokenIdent && colons != "":
+			if r := f(colons + strings.ToLower(t.Value)); r != "" {
+				b.WriteString(r)
+				colons = ""
+				continue
+			}
+		}
+		b.WriteString(colons)
+		b.WriteString(t.Value)
+		colons = ""
+	}
+	b.WriteString(colons)
+	return b.String()
+}
+
+func selector(r *ast.QualifiedRule) string {
+	var sels []string
+	for _, cv := range r.Components {
+		sels = append(sels, strings.TrimSpace(cv.Name))
+	}
+	return strings.ToLower(strings.Join(sels, ","))
+}
+
+// hasRule reports whether list holds a style rule with the selector sel.
+func hasRule(list []ast.Rule, sel string) bool {
+	for _, r := range list {
+		if r, ok := r.(*ast.QualifiedRule); ok && selector(r) == sel {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKeyframes reports whether list holds a keyframes rule with the given
+// at-keyword and name.
+func hasKeyframes(list []ast.Rule, keyword, name string) bool {
+	for _, r := range list {
+		if r, ok := r.(*ast.KeyframesRule); ok && strings.EqualFold(r.AtKeyword, keyword) && r.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package prefixer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ttacon/css/browsers"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/printer"
+	"github.com/ttacon/css/scanner"
+)
+
+func prefix(t *testing.T, p *Prefixer, src string) string {
+	t.Helper()
+	ss, err := parser.NewWithMode(scanner.New(src), parser.Lossless).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Prefix(ss)
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, ss); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func targets(t *testing.T, s string) []browsers.Target {
+	t.Helper()
+	ts, err := browsers.ParseTargets(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
+
+func TestPrefix(t *testing.T) {
+	tests := []struct {
+		targets   string
+		keep      bool
+		src, want string
+	}{
+		{
+			"chrome 50, firefox 60, safari 12",
+			false,
+			`.a {
+  user-select: none;
+  position: sticky;
+  color: red;
+}
+`,
+			`.a {
+  -moz-user-select: none;
+  -webkit-user-select: none;
+  user-select: none;
+  position: -webkit-sticky;
+  position: sticky;
+  color: red;
+}
+`,
+		},
+		{
+			// Existing variants are kept and not added twice.
+			"chrome 50, safari 12",
+			false,
+			`.a { -webkit-user-select: text; user-select: none }
+`,
+			`.a { -webkit-user-select: text; user-select: none }
+`,
+		},
+		{
+			// Outdated prefixes are removed.
+			"chrome 120, firefox 120, safari 17",
+			false,
+			`.a {
+  -webkit-user-select: none;
+  -moz-user-select: none;
+  user-select: none;
+  position: -webkit-sticky;
+  -webkit-transform: none;
+  -webkit-box-flex: 1;
+}
+input::-moz-placeholder { color: gray }
+@-moz-keyframes spin { to { top: 0 } }
+`,
+			`.a {
+  -webkit-user-select: none;
+  user-select: none;
+  -webkit-box-flex: 1;
+}
+`,
+		},
+		{
+			"chrome 120, firefox 120, safari 17",
+			true,
+			`.a { -moz-user-select: none; position: -webkit-sticky }
+`,
+			`.a { -moz-user-select: none; position: -webkit-sticky }
+`,
+		},
+		{
+			"firefox 50, ie 11",
+			false,
+			`input::placeholder, textarea::placeholder { color: gray; user-select: none }
+`,
+			`input:-ms-input-placeholder, textarea:-ms-input-placeholder { color: gray; -ms-user-select: none; user-select: none }
+input::-moz-placeholder, textarea::-moz-placeholder { color: gray; -moz-user-select: none; user-select: none }
+input::placeholder, textarea::placeholder { color: gray; -moz-user-select: none; -ms-user-select: none; user-select: none }
+`,
+		},
+		{
+			"chrome 40, firefox 10",
+			false,
+			`@media screen {
+  @keyframes spin { to { transform: rotate(1turn) } }
+  @-moz-keyframes spin { to { transform: rotate(1turn) } }
+}
+`,
+			`@media screen {
+  @-webkit-keyframes spin { to { transform: rotate(1turn) } }
+  @keyframes spin { to { -moz-transform: rotate(1turn); transform: rotate(1turn) } }
+  @-moz-keyframes spin { to { -moz-transform: rotate(1turn); transform: rotate(1turn) } }
+}
+`,
+		},
+		{
+			// The legacy line clamping needs display: -webkit-box.
+			"chrome >= 120, safari >= 17",
+			false,
+			`.clamp { display: -webkit-box; -webkit-box-orient: vertical; -webkit-line-clamp: 3; overflow: hidden }
+.box { display: -webkit-box; display: flex }
+`,
+			`.clamp { display: -webkit-box; -webkit-box-orient: vertical; -webkit-line-clamp: 3; overflow: hidden }
+.box { display: flex }
+`,
+		},
+		{
+			"chrome 120, firefox 120",
+			false,
+			`.a { width: stretch; cursor: grab }
+`,
+			`.a { width: -moz-available; width: -webkit-fill-available; width: stretch; cursor: grab }
+`,
+		},
+	}
+	for _, test := range tests {
+		p := &Prefixer{Targets: targets(t, test.targets), Keep: test.keep}
+		if got := prefix(t, p, test.src); got != test.want {
+			t.Errorf("%s:\n%s\nexpected:\n%s\ngot:\n%s", test.targets, test.src, test.want, got)
+		}
+	}
+}
+
+func TestLoadedData(t *testing.T) {
+	d, err := browsers.Load(bytes.NewReader([]byte(`{
+		"browsers": {"chrome": "-webkit-"},
+		"properties": {"zoom": {"browsers": {"chrome": "*"}}}
+	}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Prefixer{Targets: targets(t, "chrome"), Data: d}
+	want := ".a { -webkit-zoom: 2; zoom: 2; -webkit-user-select: none }\n"
+	if got := prefix(t, p, ".a { zoom: 2; -webkit-user-select: none }\n"); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestPrefixValues(t *testing.T) {
+	tests := []struct {
+		targets   string
+		src, want string
+	}{
+		{
+			"chrome 20, ie 10, safari 8",
+			`.a { display: flex; flex: 1; order: 2 }
+`,
+			`.a { display: -ms-flexbox; display: -webkit-box; display: -webkit-flex; display: flex; -ms-flex: 1; -webkit-flex: 1; flex: 1; -ms-flex-order: 2; -webkit-order: 2; order: 2 }
+`,
+		},
+		{
+			"chrome 25, firefox 15",
+			`.a { background: linear-gradient(to right, red, blue), radial-gradient(circle at top left, red, blue); }
+.b { background-image: repeating-linear-gradient(0deg, red 10%, blue 20%); --g: linear-gradient(red, blue); }
+`,
+			`.a { background: -moz-linear-gradient(left, red, blue), -moz-radial-gradient(top left, circle, red, blue); background: -webkit-linear-gradient(left, red, blue), -webkit-radial-gradient(top left, circle, red, blue); background: linear-gradient(to right, red, blue), radial-gradient(circle at top left, red, blue); }
+.b { background-image: -moz-repeating-linear-gradient(90deg, red 10%, blue 20%); background-image: -webkit-repeating-linear-gradient(90deg, red 10%, blue 20%); background-image: repeating-linear-gradient(0deg, red 10%, blue 20%); --g: linear-gradient(red, blue); }
+`,
+		},
+		{
+			"chrome 30, firefox 15, safari 6",
+			`.a { transition: transform 1s, opacity 1s; }
+`,
+			`.a { -moz-transition: -moz-transform 1s, opacity 1s; -webkit-transition: -webkit-transform 1s, opacity 1s; transition: -moz-transform 1s, opacity 1s; transition: -webkit-transform 1s, opacity 1s; transition: transform 1s, opacity 1s; }
+`,
+		},
+		{
+			"chrome 120, safari 17",
+			`.a { display: -webkit-box; display: -webkit-flex; display: flex; -webkit-flex: 1; transition: -webkit-transform 1s; transition: transform 1s; background: -webkit-linear-gradient(left, red, blue); }
+`,
+			`.a { display: flex; transition: transform 1s; }
+`,
+		},
+	}
+	for _, test := range tests {
+		p := &Prefixer{Targets: targets(t, test.targets)}
+		if got := prefix(t, p, test.src); got != test.want {
+			t.Errorf("%s:\n%s\nexpected:\n%s\ngot:\n%s", test.targets, test.src, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package prefixer
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// replaceValue returns a copy of the components cs of a value with v
+// replaced by its prefixed variant name. The arguments of a gradient are
+// rewritten in the older syntax the prefixed gradients take.
+func replaceValue(cs []string, v, name string) []string {
+	out := make([]string, 0, len(cs))
+	for i := 0; i < len(cs); i++ {
+		if !strings.EqualFold(cs[i], v) {
+			out = append(out, cs[i])
+			continue
+		}
+		out = append(out, name)
+		base := strings.ToLower(strings.TrimPrefix(v, "repeating-"))
+		if base != "linear-gradient(" && base != "radial-gradient(" {
+			continue
+		}
+		end := i + 1
+		for depth := 0; end < len(cs); end++ {
+			if depth == 0 && (cs[end] == "," || cs[end] == ")") {
+				break
+			}
+			if strings.HasSuffix(cs[end], "(") {
+				depth++
+			} else if cs[end] == ")" {
+				depth--
+			}
+		}
+		if base == "linear-gradient(" {
+			out = append(out, oldDirection(cs[i+1:end])...)
+		} else {
+			out = append(out, oldShape(cs[i+1:end])...)
+		}
+		i = end - 1
+	}
+	return out
+}
+
+var opposites = map[string]string{
+	"top":    "bottom",
+	"bottom": "top",
+	"left":   "right",
+	"right":  "left",
+}
+
+// oldDirection returns the first argument of a linear gradient in the
+// syntax of the prefixed gradients: the side the gradient starts from
+// rather than the one it goes to, and angles in degrees counted
+// counterclockwise from the right.
+func oldDirection(arg []string) []string {
+	if len(arg) > 1 && strings.EqualFold(arg[0], "to") {
+		var sides []string
+		for _, s := range arg[1:] {
+			o, ok := opposites[strings.ToLower(s)]
+			if !ok {
+				return arg
+			}
+			sides = append(sides, o)
+		}
+		return sides
+	}
+	if len(arg) == 1 && strings.HasSuffix(strings.ToLower(arg[0]), "deg") {
+		deg, err := strconv.ParseFloat(arg[0][:len(arg[0])-3], 64)
+		if err != nil {
+			return arg
+		}
+		deg = math.Mod(450-deg, 360)
+		if deg < 0 {
+			deg += 360
+		}
+		return []string{strconv.FormatFloat(deg, 'f', -1, 64) + "deg"}
+	}
+	return arg
+}
+
+// oldShape returns the first argument of a radial gradient in the syntax
+// of the prefixed gradients, with the position first, as an argument of
+// its own: "circle at top" becomes "top, circle".
+func oldShape(arg []string) []string {
+	for i, s := range arg {
+		if !strings.EqualFold(s, "at") {
+			continue
+		}
+		out := append([]string(nil), arg[i+1:]...)
+		if i > 0 {
+			out = append(append(out, ","), arg[:i]...)
+		}
+		return out
+	}
+	return arg
+}
@@ -0,0 +1,380 @@
+// Package printer implements printing of CSS AST nodes.
+//
+// Nodes parsed in lossless mode (see parser.Lossless) carry their
+// original source text. When such a node has not been modified since it
+// was parsed, its original text is printed verbatim; otherwise only the
+// node's own text is re-serialized and its children are printed in turn.
+// Printing an unmodified lossless tree thus reproduces the input exactly,
+// and edits only touch the source of the nodes that changed.
+package printer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/scanner"
+	"github.com/ttacon/css/sourcemap"
+)
+
+// Config controls the output of Fprint.
+type Config struct {
+	// Indent is the string used for each level of indentation.
+	Indent string
+
+	// SourceMap, if not nil, receives a mapping from the output position
+	// of every printed node to the node's position in Source. Nodes
+	// without a position are not mapped.
+	SourceMap *sourcemap.Generator
+	// Source is the name of the file the printed nodes were parsed from.
+	Source string
+}
+
+var defaultConfig = Config{Indent: "  "}
+
+// Fprint writes node to w using the default configuration. node may be
+// an *ast.Stylesheet, an ast.Rule or an *ast.Declaration.
+func Fprint(w io.Writer, node interface{}) error {
+	return defaultConfig.Fprint(w, node)
+}
+
+// Fprint writes node to w.
+func (cfg *Config) Fprint(w io.Writer, node interface{}) error {
+	p := &printer{Config: *cfg, line: 1, column: 1}
+	p.node(node, "")
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+type printer struct {
+	Config
+	buf   bytes.Buffer
+	depth int
+
+	// line and column are the output position after the first scanned
+	// bytes of buf.
+	line, column int
+	scanned      int
+
+	// followed reports whether the node being printed in a block is
+	// followed by another one.
+	followed bool
+}
+
+// mapNode records the mapping of n to the current output position.
+func (p *printer) mapNode(n interface{}) {
+	node, ok := n.(ast.Node)
+	if _, sheet := n.(*ast.Stylesheet); p.SourceMap == nil || !ok || sheet || !node.Pos().IsValid() {
+		return
+	}
+	text := string(p.buf.Bytes()[p.scanned:])
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		p.line += strings.Count(text, "\n")
+		p.column = 1
+		text = text[i+1:]
+	}
+	p.column += scanner.Columns(text)
+	p.scanned = p.buf.Len()
+
+	m := sourcemap.Mapping{
+		GenLine:    p.line,
+		GenColumn:  p.column,
+		Source:     p.Source,
+		OrigLine:   node.Pos().Line,
+		OrigColumn: node.Pos().Column,
+	}
+	if d, ok := n.(*ast.Declaration); ok {
+		m.Name = d.Ident
+	}
+	p.SourceMap.Add(m)
+}
+
+// newline returns a line break followed by the indentation for depth.
+func (p *printer) newline(depth int) string {
+	return "\n" + strings.Repeat(p.Indent, depth)
+}
+
+// separator returns the text printed before a node that has no Raw: the
+// line break and indentation of a sibling if it has any, or def.
+func separator(sibling interface{}, def string) string {
+	if raw := ast.RawOf(sibling); raw != nil {
+		if i := strings.LastIndex(raw.Before, "\n"); i >= 0 {
+			return raw.Before[i:]
+		}
+	}
+	return def
+}
+
+// node prints n, preceded by its original leading text or, for nodes
+// without Raw, by sep.
+func (p *printer) node(n interface{}, sep string) {
+	raw := ast.RawOf(n)
+	if raw != nil {
+		p.buf.WriteString(raw.Before)
+	} else {
+		p.buf.WriteString(sep)
+	}
+	verbatim := raw != nil && !raw.Modified(n)
+	if _, ok := n.(*ast.Declaration); !ok || verbatim {
+		p.mapNode(n)
+	}
+
+	switch n := n.(type) {
+	case *ast.Stylesheet:
+		p.rules(n.Children, "")
+		if raw != nil {
+			p.buf.WriteString(raw.After)
+		} else if len(n.Children) > 0 {
+			p.buf.WriteString("\n")
+		}
+
+	case *ast.Comment:
+		if verbatim {
+			p.buf.WriteString(raw.Text)
+		} else {
+			p.buf.WriteString(n.Text)
+		}
+
+	case *ast.AtRule:
+		p.atRule(head(n.AtKeyword, n.Any), n.Block, raw, verbatim)
+
+	case *ast.FontFaceRule:
+		p.atRule("@font-face", n.Block, raw, verbatim)
+
+	case *ast.KeyframesRule:
+		p.atRule(head(n.AtKeyword, n.Name), n.Block, raw, verbatim)
+
+	case *ast.PageRule:
+		p.atRule(head("@page", strings.Join(n.Selectors, ", ")), n.Block, raw, verbatim)
+
+	case *ast.MarginRule:
+		p.atRule(n.AtKeyword, n.Block, raw, verbatim)
+
+	case *ast.CounterStyleRule:
+		p.atRule(head("@counter-style", n.Name), n.Block, raw, verbatim)
+
+	case *ast.PropertyRule:
+		p.atRule(head("@property", n.Name), n.Block, raw, verbatim)
+
+	case *ast.FontFeatureValuesRule:
+		p.atRule(head("@font-feature-values", strings.Join(n.FontFamilies, ", ")), n.Block, raw, verbatim)
+
+	case *ast.FeatureValuesBlock:
+		p.atRule(n.AtKeyword, n.Block, raw, verbatim)
+
+	case *ast.LayerRule:
+		p.atRule(head("@layer", strings.Join(n.Names, ", ")), n.Block, raw, verbatim)
+
+	case *ast.ImportRule:
+		p.atRule(head("@import", importPrelude(n)), nil, raw, verbatim)
+
+	case *ast.ScopeRule:
+		p.atRule(head("@scope", scopePrelude(n)), n.Block, raw, verbatim)
+
+	case *ast.Keyframe:
+		if verbatim {
+			p.buf.WriteString(raw.Text)
+		} else {
+			p.buf.WriteString(strings.Join(n.Selectors, ", ") + " ")
+		}
+		if n.Block != nil {
+			p.node(n.Block, "")
+		}
+
+	case *ast.QualifiedRule:
+		if verbatim {
+			p.buf.WriteString(raw.Text)
+		} else {
+			names := make([]string, len(n.Components))
+			for i, c := range n.Components {
+				names[i] = c.Name
+			}
+			p.buf.WriteString(strings.Join(names, ", ") + " ")
+		}
+		if n.Block != nil {
+			p.node(n.Block, "")
+		}
+
+	case *ast.Block:
+		p.block(n)
+
+	case *ast.Declaration:
+		if verbatim {
+			text := raw.Text
+			if p.followed {
+				text = terminate(text)
+			}
+			p.buf.WriteString(text)
+			break
+		}
+		for _, c := range n.Leading {
+			p.buf.WriteString(c.Text + p.newline(p.depth))
+		}
+		p.mapNode(n)
+		p.buf.WriteString(Declaration(n))
+		for _, c := range n.Trailing {
+			p.buf.WriteString(" " + c.Text)
+		}
+	}
+}
+
+// atRule prints an at-rule starting with head, the at-keyword and the
+// prelude.
+func (p *printer) atRule(head string, block *ast.Block, raw *ast.Raw, verbatim bool) {
+	if verbatim {
+		p.buf.WriteString(raw.Text)
+	} else if block == nil {
+		p.buf.WriteString(head + ";")
+	} else {
+		p.buf.WriteString(head + " ")
+	}
+	if block != nil {
+		p.node(block, "")
+	}
+	if raw != nil {
+		p.buf.WriteString(raw.After)
+	}
+}
+
+// head joins an at-keyword and a prelude.
+func head(keyword, prelude string) string {
+	if prelude == "" {
+		return keyword
+	}
+	return keyword + " " + prelude
+}
+
+// importPrelude returns the prelude of the @import rule n.
+func importPrelude(n *ast.ImportRule) string {
+	parts := []string{scanner.Quote(n.URL, '"')}
+	switch {
+	case n.Layered && n.Layer != "":
+		parts = append(parts, "layer("+n.Layer+")")
+	case n.Layered:
+		parts = append(parts, "layer")
+	}
+	if n.Supports != "" {
+		parts = append(parts, "supports("+n.Supports+")")
+	}
+	if n.Media != "" {
+		parts = append(parts, n.Media)
+	}
+	return strings.Join(parts, " ")
+}
+
+// scopePrelude returns the prelude of the @scope rule n.
+func scopePrelude(n *ast.ScopeRule) string {
+	var parts []string
+	if len(n.Roots) > 0 {
+		parts = append(parts, "("+strings.Join(n.Roots, ", ")+")")
+	}
+	if len(n.Limits) > 0 {
+		parts = append(parts, "to ("+strings.Join(n.Limits, ", ")+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// rules prints a list of rules at the current depth.
+func (p *printer) rules(rules []ast.Rule, first string) {
+	for i, r := range rules {
+		sep := first
+		if i > 0 {
+			sep = separator(rules[i-1], p.newline(p.depth))
+		}
+		p.node(r, sep)
+	}
+}
+
+// block prints a block, including its braces. The raw Before of b has
+// already been printed.
+func (p *printer) block(b *ast.Block) {
+	raw := b.Raw
+	if raw != nil {
+		p.buf.WriteString(raw.Text)
+	} else {
+		p.buf.WriteString("{")
+	}
+	p.depth++
+	children := b.Children()
+	for i, c := range children {
+		var sibling interface{}
+		if i > 0 {
+			sibling = children[i-1]
+		} else if i+1 < len(children) {
+			sibling = children[i+1]
+		}
+		p.followed = i+1 < len(children)
+		p.node(c, separator(sibling, p.newline(p.depth)))
+	}
+	var trailing []*ast.Comment
+	if b.DeclList != nil {
+		trailing = b.DeclList.Trailing
+	}
+	if raw != nil && !raw.Modified(b) {
+		p.depth--
+		p.buf.WriteString(raw.After)
+		return
+	}
+	for _, c := range trailing {
+		p.buf.WriteString(p.newline(p.depth) + c.Text)
+	}
+	p.depth--
+	p.buf.WriteString(p.newline(p.depth) + "}")
+}
+
+// terminate returns the text of a declaration ending with a semicolon,
+// before its trailing comments and whitespace: the last declaration of a
+// block may have none, but not one followed by another.
+func terminate(text string) string {
+	end := len(text)
+	for {
+		trimmed := strings.TrimRight(text[:end], " \t\r\n\f")
+		i := strings.LastIndex(trimmed, "/*")
+		if !strings.HasSuffix(trimmed, "*/") || i < 0 {
+			end = len(trimmed)
+			break
+		}
+		end = i
+	}
+	if strings.HasSuffix(text[:end], ";") {
+		return text
+	}
+	return text[:end] + ";" + text[end:]
+}
+
+// Declaration returns the CSS text of d, without comments, in the form
+// "ident: value;" or "ident: value !important;".
+func Declaration(d *ast.Declaration) string {
+	value := Components(d.Components)
+	if d.Important {
+		if value != "" {
+			value += " "
+		}
+		value += "!important"
+	}
+	return d.Ident + ": " + value + ";"
+}
+
+// Components joins the component values of a declaration with spaces
+// where needed.
+func Components(comps []string) string {
+	var buf bytes.Buffer
+	for i, c := range comps {
+		if i > 0 && needsSpace(comps[i-1], c) {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(c)
+	}
+	return buf.String()
+}
+
+func needsSpace(prev, next string) bool {
+	switch {
+	case strings.HasSuffix(prev, "("), prev == "!":
+		return false
+	case next == ",", next == ")":
+		return false
+	}
+	return true
+}
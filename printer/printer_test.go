@@ -0,0 +1,250 @@
+package printer_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/printer"
+	"github.com/ttacon/css/scanner"
+	"github.com/ttacon/css/sourcemap"
+)
+
+func parse(t *testing.T, src string, mode parser.Mode) *ast.Stylesheet {
+	ss, err := parser.NewWithMode(scanner.New(src), mode).Parse()
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	return ss
+}
+
+func sprint(t *testing.T, n interface{}) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, n); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+var roundTrips = []string{
+	``,
+	`.cool-name { display: none;}`,
+	"\ufeff@charset \"UTF-8\";\r\n\r\n.a{color:red;}",
+	`
+/* Buttons
+   Styleguide 1.1 */
+.btn , .btn-primary{
+  /* base */
+  display:   none; /* hidden */
+  color: red /* brand */ ;
+  /* end */
+}
+
+@media print {
+  body {
+    font-size: 12pt;
+  }
+}
+/* fin */
+`,
+	`#cool-name[name="hello"] { content: "caf\e9  ☕"; background: url( "a.png" ) ; }`,
+	`@font-face{font-family:"A";src:url(a.woff2)format("woff2")}
+@keyframes  spin { from , 50% {top:0} /* end */ to{top:1px} }
+@page :first { margin: 1in; @top-left { content: "x" } }
+`,
+	`@import url( "a.css" )  layer( base ) print ;
+@layer  base , theme;
+@layer theme { .a { color: red } }
+`,
+	`@scope ( .card )to (.content){ color:red; img{border:0} }
+.list { @scope { .a { color: red } } }
+`,
+	`.card{ color:red;&:hover{color:blue}
+  @media print { top:0; .b{ x:y } } margin : 0 }
+`,
+	`:root{ --x:{ a:b; }; --y :  1px   2px/*c*/; --e:;--i: !important }
+`,
+}
+
+func TestLosslessRoundTrip(t *testing.T) {
+	for _, src := range roundTrips {
+		if got := sprint(t, parse(t, src, parser.Lossless)); got != src {
+			t.Errorf("expected:\n%q\ngot:\n%q", src, got)
+		}
+	}
+}
+
+func TestLosslessEdit(t *testing.T) {
+	src := `
+/* doc */
+.a ,.b{
+  color:red;   /* keep */
+  margin :0px;
+}
+
+@media print {
+  body { font-size: 12pt; }
+}
+@keyframes spin {
+  from { top: 0 }
+  to { top: 1px }
+}
+@import url(a.css)  print;
+@layer base { .c { color: blue } }
+`
+	ss := parse(t, src, parser.Lossless)
+	rule := ss.Children[1].(*ast.QualifiedRule)
+	decls := rule.Block.DeclList
+	decls.Declarations[1].Components = []string{"0"}
+	decls.Declarations = append(decls.Declarations, &ast.Declaration{
+		Ident:      "padding",
+		Components: []string{"1px", "2px"},
+	})
+	media := ss.Children[2].(*ast.AtRule)
+	media.Any = "screen"
+	kf := ss.Children[3].(*ast.KeyframesRule)
+	kf.Name = "pulse"
+	kf.Block.Rules[0].(*ast.Keyframe).Selectors = []string{"0%"}
+	imp := ss.Children[4].(*ast.ImportRule)
+	imp.Layered, imp.Layer = true, "vendor"
+	ss.Children[5].(*ast.LayerRule).Names = []string{"theme"}
+
+	want := `
+/* doc */
+.a ,.b{
+  color:red;   /* keep */
+  margin: 0;
+  padding: 1px 2px;
+}
+
+@media screen {
+  body { font-size: 12pt; }
+}
+@keyframes pulse {
+  0% { top: 0 }
+  to { top: 1px }
+}
+@import "a.css" layer(vendor) print;
+@layer theme { .c { color: blue } }
+`
+	if got := sprint(t, ss); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestLosslessAppend(t *testing.T) {
+	for _, test := range []struct{ src, want string }{
+		{".a {\n  x: y\n}", ".a {\n  x: y;\n\n  new: 1;}"},
+		{".a { x: y /* c */ }", ".a { x: y; /* c */ \n  new: 1;}"},
+		{".a { x: y; /* c */ }", ".a { x: y; /* c */\n  new: 1; }"},
+		{".a { x: y; }", ".a { x: y;\n  new: 1; }"},
+		{".a { x: y; .b { z: 0 } }", ".a { x: y;\n  new: 1; .b { z: 0 } }"},
+	} {
+		ss := parse(t, test.src, parser.Lossless)
+		decls := ss.Children[0].(*ast.QualifiedRule).Block.DeclList
+		decls.Declarations = append(decls.Declarations, &ast.Declaration{Ident: "new", Components: []string{"1"}})
+		got := sprint(t, ss)
+		if got != test.want {
+			t.Errorf("%q: expected %q, got %q", test.src, test.want, got)
+		}
+		if _, err := parser.New(scanner.New(got)).Parse(); err != nil {
+			t.Errorf("%q: %v", got, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	src := `/* a */ .a,.b{color:red;margin:0 auto;/* end */} @media print{p{font:12px/1.5 "x",serif;}} .c{--x:{a:b};--e:;--i:!important;color:red! IMPORTANT}`
+	want := `/* a */
+.a, .b {
+  color: red;
+  margin: 0 auto; /* end */
+}
+@media print {
+  p {
+    font: 12px / 1.5 "x", serif;
+  }
+}
+.c {
+  --x: {a:b};
+  --e: ;
+  --i: !important;
+  color: red !important;
+}
+`
+	if got := sprint(t, parse(t, src, 0)); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestSignedNumbers(t *testing.T) {
+	src := `.a{margin:-30px +1px;z-index:-1;transform:rotate(-45deg);color:lab(50% 20 -30);width:calc(1px*-1);height:calc(100% - -2px)}`
+	want := `.a {
+  margin: -30px +1px;
+  z-index: -1;
+  transform: rotate(-45deg);
+  color: lab(50% 20 -30);
+  width: calc(1px * -1);
+  height: calc(100% - -2px);
+}
+`
+	if got := sprint(t, parse(t, src, 0)); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	src := ".a,.b{color:red}\n@media print{p{margin:0}}\n.😀{margin:0}"
+	g := sourcemap.NewGenerator("out.css")
+	g.SetSourceContent("in.css", src)
+	cfg := &printer.Config{Indent: "  ", SourceMap: g, Source: "in.css"}
+	var buf bytes.Buffer
+	if err := cfg.Fprint(&buf, parse(t, src, 0)); err != nil {
+		t.Fatal(err)
+	}
+	m, err := sourcemap.Parse(g.Map().Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Output:
+	// .a, .b {
+	//   color: red;
+	// }
+	// @media print {
+	//   p {
+	//     margin: 0;
+	//   }
+	// }
+	// .😀 {
+	//   margin: 0;
+	// }
+	var tests = []struct {
+		line, column int
+		want         string
+	}{
+		{1, 1, "in.css:1:1"},
+		{1, 8, "in.css:1:6"},
+		{2, 3, "in.css:1:7 color"},
+		{4, 1, "in.css:2:1"},
+		{5, 3, "in.css:2:14"},
+		{6, 5, "in.css:2:16 margin"},
+		// Columns count UTF-16 code units, two for the emoji.
+		{10, 3, "in.css:3:5 margin"},
+	}
+	for _, test := range tests {
+		mapping, ok := m.Lookup(test.line, test.column)
+		got := fmt.Sprintf("%s:%d:%d", mapping.Source, mapping.OrigLine, mapping.OrigColumn)
+		if mapping.Name != "" {
+			got += " " + mapping.Name
+		}
+		if !ok || got != test.want {
+			t.Errorf("%d:%d: expected %s, got %s", test.line, test.column, test.want, got)
+		}
+	}
+	if content, ok := m.SourceContent("in.css"); !ok || content != src {
+		t.Errorf("expected the source content, got %q", content)
+	}
+}
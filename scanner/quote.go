@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Unquote returns the value of a string token: its text without the
+// quotes, with escapes replaced by the characters they stand for. The
+// values of other tokens, like idents, only have their escapes replaced.
+func Unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		s = s[1 : len(s)-1]
+	}
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		j := i
+		for j < len(s) && j-i < 6 && isHex(s[j]) {
+			j++
+		}
+		switch {
+		case j > i:
+			n, _ := strconv.ParseUint(s[i:j], 16, 32)
+			b.WriteRune(rune(n))
+			// A whitespace ends the escape.
+			if j < len(s) && (s[j] == ' ' || s[j] == '\t' || s[j] == '\n') {
+				j++
+			}
+			i = j - 1
+		case s[i] == '\n':
+			// An escaped newline continues the string.
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// Quote returns a CSS string for s, using the quote character q.
+func Quote(s string, q byte) string {
+	var b strings.Builder
+	b.WriteByte(q)
+	for _, r := range s {
+		switch {
+		case r == rune(q) || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString("\\a ")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte(q)
+	return b.String()
+}
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf16"
 	"unicode/utf8"
 )
 
@@ -26,6 +27,8 @@ type Token struct {
 	Value  string
 	Line   int
 	Column int
+	// Offset is the byte offset of the token in the input.
+	Offset int
 }
 
 // String returns a string representation of the token.
@@ -104,14 +107,14 @@ var macroRegexp = regexp.MustCompile(`\{[a-z]+\}`)
 // macros maps macro names to patterns to be expanded.
 var macros = map[string]string{
 	// must be escaped: `\.+*?()|[]{}^$`
-	"ident":      `-?{nmstart}{nmchar}*`,
+	"ident":      `--{nmchar}*|-?{nmstart}{nmchar}*`,
 	"name":       `{nmchar}+`,
 	"nmstart":    `[a-zA-Z_]|{nonascii}|{escape}`,
 	"nonascii":   "[\u0080-\uD7FF\uE000-\uFFFD\U00010000-\U0010FFFF]",
 	"unicode":    `\\[0-9a-fA-F]{1,6}{wc}?`,
 	"escape":     "{unicode}|\\[\u0020-\u007E\u0080-\uD7FF\uE000-\uFFFD\U00010000-\U0010FFFF]",
 	"nmchar":     `[a-zA-Z0-9_-]|{nonascii}|{escape}`,
-	"num":        `[0-9]+|[0-9]*\.[0-9]+`,
+	"num":        `[+-]?(?:[0-9]*\.[0-9]+|[0-9]+)`,
 	"string":     `"(?:{stringchar}|')*"|'(?:{stringchar}|")*'`,
 	"stringchar": `{urlchar}|[ ]|\\{nl}`,
 	"urlchar":    "[\u0009\u0021\u0023-\u0026\u0027-\u007E]|{nonascii}|{escape}",
@@ -158,11 +161,11 @@ var matchOrder = []tokenType{
 	TokenURI,
 	TokenFunction,
 	TokenUnicodeRange,
+	TokenCDC,
 	TokenIdent,
 	TokenDimension,
 	TokenPercentage,
 	TokenNumber,
-	TokenCDC,
 }
 
 func init() {
@@ -181,9 +184,9 @@ func init() {
 // Scanner --------------------------------------------------------------------
 
 // New returns a new CSS scanner for the given input.
+//
+// The values of the emitted tokens, concatenated, reproduce the input.
 func New(input string) *Scanner {
-	// Normalize newlines.
-	input = strings.Replace(input, "\r\n", "\n", -1)
 	return &Scanner{
 		input: input,
 		row:   1,
@@ -211,7 +214,7 @@ func (s *Scanner) Next() *Token {
 		return s.err
 	}
 	if s.pos >= len(s.input) {
-		s.err = &Token{TokenEOF, "", s.row, s.col}
+		s.err = &Token{TokenEOF, "", s.row, s.col, s.pos}
 		return s.err
 	}
 	if s.pos == 0 {
@@ -237,16 +240,21 @@ func (s *Scanner) Next() *Token {
 	case '#':
 		// Another common one: Hash or Char.
 		if match := matchers[TokenHash].FindString(input); match != "" {
-			return s.emitSimple(TokenHash, match)
+			return s.emitToken(TokenHash, match)
 		}
 		return s.emitSimple(TokenChar, "#")
 	case '@':
 		// Another common one: AtKeyword or Char.
 		if match := matchers[TokenAtKeyword].FindString(input); match != "" {
-			return s.emitSimple(TokenAtKeyword, match)
+			return s.emitToken(TokenAtKeyword, match)
 		}
 		return s.emitSimple(TokenChar, "@")
-	case ':', ',', ';', '%', '&', '+', '=', '>', '(', ')', '[', ']', '{', '}':
+	case '+':
+		// A sign starts a number if a digit follows, as in css-syntax-3.
+		if !startsNumber(input[1:]) {
+			return s.emitSimple(TokenChar, "+")
+		}
+	case ':', ',', ';', '%', '&', '=', '>', '(', ')', '[', ']', '{', '}':
 		// More common chars.
 		return s.emitSimple(TokenChar, string(input[0]))
 	case '"', '\'':
@@ -255,7 +263,7 @@ func (s *Scanner) Next() *Token {
 		if match != "" {
 			return s.emitToken(TokenString, match)
 		} else {
-			s.err = &Token{TokenError, "unclosed quotation mark", s.row, s.col}
+			s.err = &Token{TokenError, "unclosed quotation mark", s.row, s.col, s.pos}
 			return s.err
 		}
 	case '/':
@@ -265,7 +273,7 @@ func (s *Scanner) Next() *Token {
 			if match != "" {
 				return s.emitToken(TokenComment, match)
 			} else {
-				s.err = &Token{TokenError, "unclosed comment", s.row, s.col}
+				s.err = &Token{TokenError, "unclosed comment", s.row, s.col, s.pos}
 				return s.err
 			}
 		}
@@ -298,28 +306,47 @@ func (s *Scanner) Next() *Token {
 	// We already handled unclosed quotation marks and comments,
 	// so this can only be a Char.
 	r, width := utf8.DecodeRuneInString(input)
-	token := &Token{TokenChar, string(r), s.row, s.col}
-	s.col += width
+	token := &Token{TokenChar, string(r), s.row, s.col, s.pos}
+	s.col += utf16.RuneLen(r)
 	s.pos += width
 	return token
 }
 
+// startsNumber reports whether s starts with a digit, or a dot followed by
+// a digit.
+func startsNumber(s string) bool {
+	if s != "" && s[0] == '.' {
+		s = s[1:]
+	}
+	return s != "" && '0' <= s[0] && s[0] <= '9'
+}
+
 // updatePosition updates input coordinates based on the consumed text.
 func (s *Scanner) updatePosition(text string) {
-	width := utf8.RuneCountInString(text)
 	lines := strings.Count(text, "\n")
 	s.row += lines
 	if lines == 0 {
-		s.col += width
+		s.col += Columns(text)
 	} else {
-		s.col = utf8.RuneCountInString(text[strings.LastIndex(text, "\n"):])
+		s.col = Columns(text[strings.LastIndex(text, "\n"):])
 	}
-	s.pos += width
+	s.pos += len(text)
+}
+
+// Columns returns the number of columns text spans. Columns count UTF-16
+// code units, as in source maps and SARIF logs, so that a character
+// outside the Basic Multilingual Plane, like an emoji, spans two.
+func Columns(text string) int {
+	n := 0
+	for _, r := range text {
+		n += utf16.RuneLen(r)
+	}
+	return n
 }
 
 // emitToken returns a Token for the string v and updates the scanner position.
 func (s *Scanner) emitToken(t tokenType, v string) *Token {
-	token := &Token{t, v, s.row, s.col}
+	token := &Token{t, v, s.row, s.col, s.pos}
 	s.updatePosition(v)
 	return token
 }
@@ -329,7 +356,7 @@ func (s *Scanner) emitToken(t tokenType, v string) *Token {
 //
 // The string is known to have only ASCII characters and to not have a newline.
 func (s *Scanner) emitSimple(t tokenType, v string) *Token {
-	token := &Token{t, v, s.row, s.col}
+	token := &Token{t, v, s.row, s.col, s.pos}
 	s.col += len(v)
 	s.pos += len(v)
 	return token
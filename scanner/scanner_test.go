@@ -4,7 +4,10 @@
 
 package scanner
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestMatchers(t *testing.T) {
 	// Just basic checks, not exhaustive at all.
@@ -16,6 +19,7 @@ func TestMatchers(t *testing.T) {
 	}
 
 	checkMatch(TokenIdent, "abcd")
+	checkMatch(TokenIdent, "--main-color")
 	checkMatch(TokenString, "\"abcd\"")
 	checkMatch(TokenString, "'abcd'")
 	checkMatch(TokenHash, "#name")
@@ -41,3 +45,79 @@ func TestMatchers(t *testing.T) {
 	checkMatch(TokenChar, "{")
 	checkMatch(TokenBOM, "\uFEFF")
 }
+
+func TestNext(t *testing.T) {
+	input := "a{font:12px/1.5 \"caf\u00e9\"}\r\n"
+	var got string
+	s := New(input)
+	for tok := s.Next(); tok.Type != TokenEOF; tok = s.Next() {
+		if tok.Type == TokenError {
+			t.Fatalf("unexpected error: %v", tok)
+		}
+		if tok.Offset != len(got) {
+			t.Errorf("expected offset %d, got %v at %d", len(got), tok, tok.Offset)
+		}
+		if tok.Value == "1" || tok.Value == ".5" {
+			t.Errorf("number split in two: %v", tok)
+		}
+		got += tok.Value
+	}
+	if got != input {
+		t.Errorf("tokens do not reproduce the input: %q", got)
+	}
+}
+
+func TestDashes(t *testing.T) {
+	var got []string
+	s := New("--x:-y -->")
+	for tok := s.Next(); tok.Type != TokenEOF; tok = s.Next() {
+		got = append(got, tok.Type.String()+" "+tok.Value)
+	}
+	want := "[IDENT --x CHAR : IDENT -y S   CDC -->]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	for s, want := range map[string]string{
+		`"abc"`:         "abc",
+		`'a\'b'`:        "a'b",
+		`"caf\e9  x"`:   "caf\u00e9 x",
+		"\"a\\\nb\"":    "ab",
+		`"\1F600"`:      "\U0001F600",
+		`Foo\ Bar`:      "Foo Bar",
+		`"unterminated`: `"unterminated`,
+	} {
+		if got := Unquote(s); got != want {
+			t.Errorf("Unquote(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, s := range []string{"abc", `a"b'c`, `a\b`, "a\nb", "café"} {
+		for _, q := range []byte{'"', '\''} {
+			if got := Unquote(Quote(s, q)); got != s {
+				t.Errorf("Unquote(Quote(%q, %c)) = %q", s, q, got)
+			}
+		}
+	}
+	if got, want := Quote("a\nb", '"'), `"a\a b"`; got != want {
+		t.Errorf("Quote = %s, want %s", got, want)
+	}
+}
+
+func TestSignedNumbers(t *testing.T) {
+	var got []string
+	s := New("-1px +.5 -5% 1px-2 a - 1 2n+1 -x")
+	for tok := s.Next(); tok.Type != TokenEOF; tok = s.Next() {
+		if tok.Type != TokenS {
+			got = append(got, tok.Type.String()+" "+tok.Value)
+		}
+	}
+	want := "[DIMENSION -1px NUMBER +.5 PERCENTAGE -5% DIMENSION 1px-2 IDENT a CHAR - NUMBER 1 DIMENSION 2n NUMBER +1 IDENT -x]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
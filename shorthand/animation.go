@@ -0,0 +1,229 @@
+package shorthand
+
+// transition and animation take comma-separated lists of transitions and
+// animations, and their longhands lists of as many values.
+
+func init() {
+	shorthands["transition"] = &shorthand{
+		longhands: []string{
+			"transition-property", "transition-duration",
+			"transition-timing-function", "transition-delay",
+			"transition-behavior",
+		},
+		expand:   listExpander(parseTransition),
+		collapse: listCollapser(shortestTransition),
+	}
+	shorthands["animation"] = &shorthand{
+		longhands: []string{
+			"animation-name", "animation-duration",
+			"animation-timing-function", "animation-delay",
+			"animation-iteration-count", "animation-direction",
+			"animation-fill-mode", "animation-play-state",
+		},
+		expand:   listExpander(parseAnimation),
+		collapse: listCollapser(shortestAnimation),
+	}
+}
+
+// listExpander returns the expand function of a shorthand whose items
+// parse parses, given whether the item is alone.
+func listExpander(parse func(values []value, alone bool) ([]value, bool)) func([]value) ([][]string, bool) {
+	return func(values []value) ([][]string, bool) {
+		list := layers(values)
+		if list == nil {
+			return nil, false
+		}
+		var longhands [][][]string
+		for _, l := range list {
+			item, ok := parse(l, len(list) == 1)
+			if !ok {
+				return nil, false
+			}
+			if longhands == nil {
+				longhands = make([][][]string, len(item))
+			}
+			for p, v := range item {
+				longhands[p] = append(longhands[p], v)
+			}
+		}
+		var out [][]string
+		for _, l := range longhands {
+			out = append(out, joinLayers(l))
+		}
+		return out, true
+	}
+}
+
+// listCollapser returns the collapse function of a shorthand whose items
+// shortest returns the shortest value of. The longhands must list as
+// many values.
+func listCollapser(shortest func(item []value) []string) func([][]string) ([]string, bool) {
+	return func(values [][]string) ([]string, bool) {
+		lists := make([][][]string, len(values))
+		for p, v := range values {
+			lists[p] = splitLayers(v)
+			if len(lists[p]) == 0 || len(lists[p]) != len(lists[0]) {
+				return nil, false
+			}
+		}
+		var out [][]string
+		for i := range lists[0] {
+			item := make([]value, len(values))
+			for p := range values {
+				item[p] = lists[p][i]
+			}
+			out = append(out, shortest(item))
+		}
+		return joinLayers(out), true
+	}
+}
+
+// The indexes of the longhands of transition.
+const (
+	trProperty = iota
+	trDuration
+	trTimingFunction
+	trDelay
+	trBehavior
+)
+
+var initialTransition = []value{{"all"}, {"0s"}, {"ease"}, {"0s"}, {"normal"}}
+
+func parseTransition(values []value, alone bool) ([]value, bool) {
+	item := append([]value(nil), initialTransition...)
+	set := map[int]bool{}
+	for _, v := range values {
+		p := -1
+		switch k := v.keyword(); {
+		case v.isTime():
+			p = trDuration
+			if set[p] {
+				p = trDelay
+			}
+		case v.isEasing():
+			p = trTimingFunction
+		case k == "normal", k == "allow-discrete":
+			p = trBehavior
+		case k == "none" && !alone, cssWideKeywords[k]:
+		case k != "":
+			p = trProperty
+		}
+		if p < 0 || set[p] {
+			return nil, false
+		}
+		item[p], set[p] = v, true
+	}
+	return item, true
+}
+
+func shortestTransition(item []value) []string {
+	var comps []string
+	if !equal(item[trProperty], initialTransition[trProperty]) {
+		comps = append(comps, item[trProperty]...)
+	}
+	delay := !equal(item[trDelay], initialTransition[trDelay])
+	if delay || !equal(item[trDuration], initialTransition[trDuration]) {
+		comps = append(comps, item[trDuration]...)
+	}
+	if !equal(item[trTimingFunction], initialTransition[trTimingFunction]) {
+		comps = append(comps, item[trTimingFunction]...)
+	}
+	if delay {
+		comps = append(comps, item[trDelay]...)
+	}
+	if !equal(item[trBehavior], initialTransition[trBehavior]) {
+		comps = append(comps, item[trBehavior]...)
+	}
+	if comps == nil {
+		return []string{"all"}
+	}
+	return comps
+}
+
+// The indexes of the longhands of animation.
+const (
+	anName = iota
+	anDuration
+	anTimingFunction
+	anDelay
+	anIterationCount
+	anDirection
+	anFillMode
+	anPlayState
+)
+
+var initialAnimation = []value{
+	{"none"}, {"0s"}, {"ease"}, {"0s"}, {"1"}, {"normal"}, {"none"}, {"running"},
+}
+
+// animationKeyword returns the longhand other than animation-name taking
+// the keyword k, or -1.
+func animationKeyword(k string) int {
+	switch k {
+	case "infinite":
+		return anIterationCount
+	case "normal", "reverse", "alternate", "alternate-reverse":
+		return anDirection
+	case "none", "forwards", "backwards", "both":
+		return anFillMode
+	case "running", "paused":
+		return anPlayState
+	}
+	if easingKeywords[k] {
+		return anTimingFunction
+	}
+	return -1
+}
+
+func parseAnimation(values []value, alone bool) ([]value, bool) {
+	item := append([]value(nil), initialAnimation...)
+	set := map[int]bool{}
+	for _, v := range values {
+		p := -1
+		k := v.keyword()
+		switch {
+		case v.isTime():
+			p = anDuration
+			if set[p] {
+				p = anDelay
+			}
+		case easingFunctions[v.function()]:
+			p = anTimingFunction
+		case v.isNumber():
+			p = anIterationCount
+		case k != "" && animationKeyword(k) >= 0 && !set[animationKeyword(k)]:
+			// Keywords go to the other longhands first.
+			p = animationKeyword(k)
+		case k != "" && !cssWideKeywords[k], v.isString():
+			p = anName
+		}
+		if p < 0 || set[p] {
+			return nil, false
+		}
+		item[p], set[p] = v, true
+	}
+	return item, true
+}
+
+func shortestAnimation(item []value) []string {
+	var comps []string
+	delay := !equal(item[anDelay], initialAnimation[anDelay])
+	// A name taken for a keyword of another longhand must follow it.
+	clash := -1
+	if k := item[anName].keyword(); k != "none" {
+		clash = animationKeyword(k)
+	}
+	for p := anDuration; p <= anPlayState; p++ {
+		switch {
+		case p == anDuration && delay, p == clash, !equal(item[p], initialAnimation[p]):
+			comps = append(comps, item[p]...)
+		}
+	}
+	if !equal(item[anName], initialAnimation[anName]) {
+		comps = append(comps, item[anName]...)
+	}
+	if comps == nil {
+		return []string{"none"}
+	}
+	return comps
+}
@@ -0,0 +1,219 @@
+package shorthand
+
+// background follows CSS Backgrounds and Borders Level 3
+// (https://www.w3.org/TR/css-backgrounds-3/#background): its value is a
+// comma-separated list of layers, and the longhands other than
+// background-color take a list of as many values.
+
+func init() {
+	shorthands["background"] = &shorthand{
+		longhands: []string{
+			"background-color", "background-image", "background-position",
+			"background-size", "background-repeat", "background-attachment",
+			"background-origin", "background-clip",
+		},
+		expand:   expandBackground,
+		collapse: collapseBackground,
+	}
+}
+
+// The indexes of the longhands of background.
+const (
+	bgColor = iota
+	bgImage
+	bgPosition
+	bgSize
+	bgRepeat
+	bgAttachment
+	bgOrigin
+	bgClip
+)
+
+var initialBackground = [8]value{
+	{"transparent"}, {"none"}, {"0%", "0%"}, {"auto"}, {"repeat"},
+	{"scroll"}, {"padding-box"}, {"border-box"},
+}
+
+var backgroundBoxes = map[string]bool{
+	"border-box": true, "padding-box": true, "content-box": true,
+}
+
+func expandBackground(values []value) ([][]string, bool) {
+	list := layers(values)
+	if list == nil {
+		return nil, false
+	}
+	longhands := make([][][]string, bgClip+1)
+	var color value
+	for i, l := range list {
+		layer, ok := parseBackground(l, i == len(list)-1)
+		if !ok {
+			return nil, false
+		}
+		for p := bgImage; p <= bgClip; p++ {
+			longhands[p] = append(longhands[p], layer[p])
+		}
+		color = layer[bgColor]
+	}
+	out := [][]string{color}
+	for _, l := range longhands[bgImage:] {
+		out = append(out, joinLayers(l))
+	}
+	return out, true
+}
+
+// parseBackground parses a layer of background, which sets the color if
+// it is the final one.
+func parseBackground(values []value, final bool) ([8]value, bool) {
+	var (
+		layer = initialBackground
+		set   [8]bool
+		boxes int
+	)
+	for i := 0; i < len(values); i++ {
+		v, p := values[i], -1
+		switch k := v.keyword(); {
+		case v.isImage(), k == "none":
+			p = bgImage
+		case isPosition(v):
+			p = bgPosition
+			j := i + 1
+			for j < len(values) && j < i+4 && isPosition(values[j]) {
+				j++
+			}
+			v = join(values[i:j]...)
+			if j+1 < len(values) && values[j].is("/") {
+				size, n := parseSize(values[j+1:])
+				if n == 0 || set[bgSize] {
+					return layer, false
+				}
+				layer[bgSize], set[bgSize] = size, true
+				j += 1 + n
+			}
+			i = j - 1
+		case k == "repeat-x", k == "repeat-y":
+			p = bgRepeat
+		case isRepeat(k):
+			p = bgRepeat
+			if i+1 < len(values) && isRepeat(values[i+1].keyword()) {
+				i++
+				v = join(v, values[i])
+			}
+		case k == "scroll", k == "fixed", k == "local":
+			p = bgAttachment
+		case backgroundBoxes[k]:
+			// The first box sets the origin and the clip, the second one
+			// the clip.
+			boxes++
+			switch boxes {
+			case 1:
+				layer[bgOrigin], layer[bgClip] = v, v
+				continue
+			case 2:
+				p = bgClip
+			default:
+				return layer, false
+			}
+		case final && v.isColor():
+			p = bgColor
+		}
+		if p < 0 || set[p] {
+			return layer, false
+		}
+		layer[p], set[p] = v, true
+	}
+	return layer, true
+}
+
+// parseSize parses the background size starting values, and returns it
+// with the number of values it takes, or 0 if there is none.
+func parseSize(values []value) (value, int) {
+	switch k := values[0].keyword(); {
+	case k == "cover", k == "contain":
+		return values[0], 1
+	case k != "auto" && !values[0].isLengthPercentage():
+		return nil, 0
+	}
+	if len(values) > 1 && (values[1].is("auto") || values[1].isLengthPercentage()) {
+		return join(values[:2]...), 2
+	}
+	return values[0], 1
+}
+
+func isPosition(v value) bool {
+	switch v.keyword() {
+	case "left", "right", "top", "bottom", "center":
+		return true
+	}
+	return v.isLengthPercentage()
+}
+
+func isRepeat(k string) bool {
+	return k == "repeat" || k == "space" || k == "round" || k == "no-repeat"
+}
+
+func collapseBackground(values [][]string) ([]string, bool) {
+	if len(splitLayers(values[bgColor])) != 1 {
+		return nil, false
+	}
+	lists := make([][][]string, bgClip+1)
+	for p := bgImage; p <= bgClip; p++ {
+		lists[p] = splitLayers(values[p])
+		if len(lists[p]) != len(lists[bgImage]) {
+			return nil, false
+		}
+	}
+	var out [][]string
+	for i := range lists[bgImage] {
+		var layer [8]value
+		for p := bgImage; p <= bgClip; p++ {
+			layer[p] = lists[p][i]
+		}
+		if i == len(lists[bgImage])-1 {
+			layer[bgColor] = values[bgColor]
+		}
+		comps, ok := shortestBackground(layer)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, comps)
+	}
+	return joinLayers(out), true
+}
+
+// shortestBackground returns the shortest value of the layer, which sets
+// the color if it has one.
+func shortestBackground(layer [8]value) ([]string, bool) {
+	var comps []string
+	add := func(p int) {
+		if !equal(layer[p], initialBackground[p]) {
+			comps = append(comps, layer[p]...)
+		}
+	}
+	add(bgImage)
+	if !equal(layer[bgSize], initialBackground[bgSize]) {
+		comps = append(comps, layer[bgPosition]...)
+		comps = append(append(comps, "/"), layer[bgSize]...)
+	} else {
+		add(bgPosition)
+	}
+	add(bgRepeat)
+	add(bgAttachment)
+	origin, clip := layer[bgOrigin].keyword(), layer[bgClip].keyword()
+	switch {
+	case !backgroundBoxes[origin] || !backgroundBoxes[clip]:
+		return nil, false
+	case origin == clip:
+		comps = append(comps, layer[bgOrigin]...)
+	case origin != "padding-box" || clip != "border-box":
+		comps = append(comps, layer[bgOrigin]...)
+		comps = append(comps, layer[bgClip]...)
+	}
+	if layer[bgColor] != nil {
+		add(bgColor)
+	}
+	if comps == nil {
+		return []string{"none"}, true
+	}
+	return comps, true
+}
@@ -0,0 +1,215 @@
+package shorthand
+
+var boxSides = []string{"top", "right", "bottom", "left"}
+
+var borderStyles = map[string]bool{
+	"none": true, "hidden": true, "dotted": true, "dashed": true,
+	"solid": true, "double": true, "groove": true, "ridge": true,
+	"inset": true, "outset": true,
+}
+
+// borderImage holds the longhands of border-image, which border resets,
+// with their initial values.
+var borderImage = []struct{ name, initial string }{
+	{"border-image-source", "none"},
+	{"border-image-slice", "100%"},
+	{"border-image-width", "1"},
+	{"border-image-outset", "0"},
+	{"border-image-repeat", "stretch"},
+}
+
+func init() {
+	for _, p := range []string{"margin", "padding"} {
+		shorthands[p] = box(p+"-", "", (value).isMarginValue)
+	}
+	shorthands["inset"] = box("", "", (value).isMarginValue)
+	shorthands["border-width"] = box("border-", "-width", (value).isBorderWidth)
+	shorthands["border-style"] = box("border-", "-style", (value).isBorderStyle)
+	shorthands["border-color"] = box("border-", "-color", (value).isColor)
+	for _, side := range boxSides {
+		p := "border-" + side
+		shorthands[p] = &shorthand{
+			longhands: []string{p + "-width", p + "-style", p + "-color"},
+			expand:    expandBorderSide,
+			collapse:  collapseBorderSide,
+		}
+	}
+
+	border := &shorthand{expand: expandBorder, collapse: collapseBorder}
+	for _, suffix := range []string{"-width", "-style", "-color"} {
+		for _, side := range boxSides {
+			border.longhands = append(border.longhands, "border-"+side+suffix)
+		}
+	}
+	for _, l := range borderImage {
+		border.longhands = append(border.longhands, l.name)
+	}
+	shorthands["border"] = border
+}
+
+// box returns a shorthand setting a property for the four sides of a box,
+// like margin, named prefix+side+suffix, whose values are valid if valid
+// reports so.
+func box(prefix, suffix string, valid func(value) bool) *shorthand {
+	s := &shorthand{collapse: collapseBox}
+	for _, side := range boxSides {
+		s.longhands = append(s.longhands, prefix+side+suffix)
+	}
+	s.expand = func(values []value) ([][]string, bool) {
+		if len(values) > 4 {
+			return nil, false
+		}
+		for _, v := range values {
+			if !valid(v) {
+				return nil, false
+			}
+		}
+		sides := expandBox(values)
+		return [][]string{sides[0], sides[1], sides[2], sides[3]}, true
+	}
+	return s
+}
+
+// expandBox returns the values of the top, right, bottom and left sides
+// for 1 to 4 values: the missing ones copy the opposite side.
+func expandBox(values []value) [4]value {
+	var sides [4]value
+	copy(sides[:], values)
+	if len(values) < 2 {
+		sides[1] = sides[0]
+	}
+	if len(values) < 3 {
+		sides[2] = sides[0]
+	}
+	if len(values) < 4 {
+		sides[3] = sides[1]
+	}
+	return sides
+}
+
+// collapseBox returns the shortest list of 1 to 4 values for the top,
+// right, bottom and left values.
+func collapseBox(values [][]string) ([]string, bool) {
+	n := 4
+	if equal(values[3], values[1]) {
+		n = 3
+		if equal(values[2], values[0]) {
+			n = 2
+			if equal(values[1], values[0]) {
+				n = 1
+			}
+		}
+	}
+	var comps []string
+	for _, v := range values[:n] {
+		comps = append(comps, v...)
+	}
+	return comps, true
+}
+
+// border is the width, style and color of a border.
+type border [3]value
+
+var initialBorder = border{{"medium"}, {"none"}, {"currentcolor"}}
+
+// parseBorder parses a border value, whose parts come in any order.
+func parseBorder(values []value) (border, bool) {
+	var (
+		b   = initialBorder
+		set [3]bool
+	)
+	if len(values) > 3 {
+		return b, false
+	}
+	for _, v := range values {
+		i := 0
+		switch {
+		case v.isBorderWidth():
+		case v.isBorderStyle():
+			i = 1
+		case v.isColor():
+			i = 2
+		default:
+			return b, false
+		}
+		if set[i] {
+			return b, false
+		}
+		b[i], set[i] = v, true
+	}
+	return b, true
+}
+
+// shortest returns the shortest value for the border b.
+func (b border) shortest() []string {
+	var comps []string
+	for i, v := range b {
+		if !equal(v, initialBorder[i]) {
+			comps = append(comps, v...)
+		}
+	}
+	if comps == nil {
+		return []string{"none"}
+	}
+	return comps
+}
+
+func expandBorderSide(values []value) ([][]string, bool) {
+	b, ok := parseBorder(values)
+	return [][]string{b[0], b[1], b[2]}, ok
+}
+
+func collapseBorderSide(values [][]string) ([]string, bool) {
+	return border{values[0], values[1], values[2]}.shortest(), true
+}
+
+// expandBorder sets the four sides alike and resets border-image.
+func expandBorder(values []value) ([][]string, bool) {
+	b, ok := parseBorder(values)
+	var longhands [][]string
+	for _, v := range b {
+		for range boxSides {
+			longhands = append(longhands, v)
+		}
+	}
+	for _, l := range borderImage {
+		longhands = append(longhands, []string{l.initial})
+	}
+	return longhands, ok
+}
+
+// collapseBorder requires the sides to be alike and border-image to be
+// initial.
+func collapseBorder(values [][]string) ([]string, bool) {
+	var b border
+	for i := range b {
+		b[i] = values[4*i]
+		for _, v := range values[4*i : 4*i+4] {
+			if !equal(v, b[i]) {
+				return nil, false
+			}
+		}
+	}
+	for i, l := range borderImage {
+		if !value(values[12+i]).is(l.initial) {
+			return nil, false
+		}
+	}
+	return b.shortest(), true
+}
+
+func (v value) isMarginValue() bool {
+	return v.isLengthPercentage() || v.is("auto") || v.function() == "anchor" || v.function() == "anchor-size"
+}
+
+func (v value) isBorderWidth() bool {
+	switch v.keyword() {
+	case "thin", "medium", "thick":
+		return true
+	}
+	return v.isLength()
+}
+
+func (v value) isBorderStyle() bool {
+	return borderStyles[v.keyword()]
+}
@@ -0,0 +1,95 @@
+package shorthand
+
+func init() {
+	shorthands["flex"] = &shorthand{
+		longhands: []string{"flex-grow", "flex-shrink", "flex-basis"},
+		expand:    expandFlex,
+		collapse:  collapseFlex,
+	}
+}
+
+// omittedBasis is the flex basis of a flex value giving flex factors only,
+// like flex: 1. The specification has it 0; browsers serialize it as 0%,
+// which is the same length.
+const omittedBasis = "0%"
+
+// expandFlex expands none, auto, and [<grow> <shrink>?] || <basis>.
+func expandFlex(values []value) ([][]string, bool) {
+	if len(values) == 1 {
+		switch values[0].keyword() {
+		case "none":
+			return [][]string{{"0"}, {"0"}, {"auto"}}, true
+		case "auto":
+			return [][]string{{"1"}, {"1"}, {"auto"}}, true
+		}
+	}
+	var (
+		grow, shrink, basis []string
+		i                   int
+	)
+	if i < len(values) && !isFactor(values[i]) && isBasis(values[i]) {
+		basis = values[i]
+		i++
+	}
+	if i < len(values) && isFactor(values[i]) {
+		grow = values[i]
+		i++
+		if i < len(values) && isFactor(values[i]) {
+			shrink = values[i]
+			i++
+		}
+	}
+	// A zero after two factors is a basis.
+	if basis == nil && i < len(values) && isBasis(values[i]) {
+		basis = values[i]
+		i++
+	}
+	if i < len(values) {
+		return nil, false
+	}
+	if grow == nil {
+		grow = []string{"1"}
+	}
+	if shrink == nil {
+		shrink = []string{"1"}
+	}
+	if basis == nil {
+		basis = []string{omittedBasis}
+	}
+	return [][]string{grow, shrink, basis}, true
+}
+
+func collapseFlex(values [][]string) ([]string, bool) {
+	grow, shrink, basis := value(values[0]), value(values[1]), value(values[2])
+	if !isFactor(grow) || !isFactor(shrink) || !isBasis(basis) {
+		return nil, false
+	}
+	switch {
+	case basis.is("auto") && grow.is("0") && shrink.is("0"):
+		return []string{"none"}, true
+	case basis.is("auto") && grow.is("1") && shrink.is("1"):
+		return []string{"auto"}, true
+	case basis.is(omittedBasis) && shrink.is("1"):
+		return grow, true
+	case basis.is(omittedBasis):
+		return join(grow, shrink), true
+	case shrink.is("1") && !basis.isNumber():
+		// A plain zero basis would be taken for the shrink factor.
+		return join(grow, basis), true
+	}
+	return join(grow, shrink, basis), true
+}
+
+// isFactor reports whether v is a flex factor, a non-negative number.
+func isFactor(v value) bool {
+	unit, ok := v.dimension()
+	return ok && unit == "" && v[0][0] != '-' || v.isMath()
+}
+
+func isBasis(v value) bool {
+	switch v.keyword() {
+	case "auto", "content", "min-content", "max-content", "fit-content":
+		return true
+	}
+	return v.isLengthPercentage() || v.function() == "fit-content"
+}
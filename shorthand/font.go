@@ -0,0 +1,298 @@
+package shorthand
+
+// font follows CSS Fonts Level 4 (https://www.w3.org/TR/css-fonts-4/#font-prop):
+// of the font-variant longhands, the shorthand only sets font-variant-caps
+// to normal or small-caps, and it resets the others, with font-kerning and
+// the like, to their initial values. The system fonts, like font: caption,
+// cannot be expanded, the values of their longhands depending on the
+// system.
+
+var fontStretches = map[string]bool{
+	"ultra-condensed": true, "extra-condensed": true, "condensed": true,
+	"semi-condensed": true, "semi-expanded": true, "expanded": true,
+	"extra-expanded": true, "ultra-expanded": true,
+}
+
+var fontSizes = map[string]bool{
+	"xx-small": true, "x-small": true, "small": true, "medium": true,
+	"large": true, "x-large": true, "xx-large": true, "xxx-large": true,
+	"larger": true, "smaller": true,
+}
+
+// systemFonts are the keywords of font naming the fonts of the system.
+var systemFonts = map[string]bool{
+	"caption": true, "icon": true, "menu": true, "message-box": true,
+	"small-caption": true, "status-bar": true,
+}
+
+// fontResets holds the longhands font resets without setting them, with
+// their initial values.
+var fontResets = []struct{ name, initial string }{
+	{"font-variant-ligatures", "normal"},
+	{"font-variant-alternates", "normal"},
+	{"font-variant-numeric", "normal"},
+	{"font-variant-east-asian", "normal"},
+	{"font-variant-position", "normal"},
+	{"font-variant-emoji", "normal"},
+	{"font-kerning", "auto"},
+	{"font-size-adjust", "none"},
+	{"font-feature-settings", "normal"},
+	{"font-variation-settings", "normal"},
+	{"font-optical-sizing", "auto"},
+	{"font-language-override", "normal"},
+}
+
+func init() {
+	font := &shorthand{
+		longhands: []string{
+			"font-style", "font-variant-caps", "font-weight", "font-stretch",
+			"font-size", "line-height", "font-family",
+		},
+		expand:   expandFont,
+		collapse: collapseFont,
+	}
+	for _, l := range fontResets {
+		font.longhands = append(font.longhands, l.name)
+	}
+	shorthands["font"] = font
+}
+
+// The indexes of the longhands of font.
+const (
+	fontStyle = iota
+	fontVariantCaps
+	fontWeight
+	fontStretch
+	fontSize
+	lineHeight
+	fontFamily
+)
+
+func expandFont(values []value) ([][]string, bool) {
+	font := [][]string{{"normal"}, {"normal"}, {"normal"}, {"normal"}, nil, {"normal"}, nil}
+	set := map[int]bool{}
+	i := 0
+	// Up to four of style, variant, weight and stretch, normal standing
+	// for any of them.
+	for n := 0; i < len(values) && n < 4; i, n = i+1, n+1 {
+		v, p := values[i], -1
+		switch k := v.keyword(); {
+		case k == "normal":
+			continue
+		case k == "italic":
+			p = fontStyle
+		case k == "oblique":
+			p = fontStyle
+			if i+1 < len(values) && values[i+1].isAngle() && !values[i+1].isZero() {
+				i++
+				v = append(value(nil), join(v, values[i])...)
+			}
+		case k == "small-caps":
+			p = fontVariantCaps
+		case k == "bold", k == "bolder", k == "lighter", isWeight(v):
+			p = fontWeight
+		case fontStretches[k]:
+			p = fontStretch
+		}
+		if p < 0 {
+			break
+		}
+		if set[p] {
+			return nil, false
+		}
+		font[p], set[p] = v, true
+	}
+	if i == len(values) || !isFontSize(values[i]) {
+		return nil, false
+	}
+	font[fontSize] = values[i]
+	i++
+	if i+1 < len(values) && values[i].is("/") {
+		if !isLineHeight(values[i+1]) {
+			return nil, false
+		}
+		font[lineHeight] = values[i+1]
+		i += 2
+	}
+	family := values[i:]
+	if !isFamily(family) {
+		return nil, false
+	}
+	font[fontFamily] = join(family...)
+	for _, l := range fontResets {
+		font = append(font, []string{l.initial})
+	}
+	return font, true
+}
+
+// collapseFont requires the longhands font resets to be initial.
+func collapseFont(values [][]string) ([]string, bool) {
+	for i, l := range fontResets {
+		if !value(values[fontFamily+1+i]).is(l.initial) {
+			return nil, false
+		}
+	}
+	switch k := value(values[fontVariantCaps]).keyword(); {
+	case k != "normal" && k != "small-caps":
+		return nil, false
+	case !fontStretches[value(values[fontStretch]).keyword()] && !value(values[fontStretch]).is("normal"):
+		// The shorthand takes keywords only.
+		return nil, false
+	case !isFamily(split(values[fontFamily])):
+		return nil, false
+	}
+	var comps []string
+	for _, v := range values[fontStyle : fontStretch+1] {
+		if !value(v).is("normal") {
+			comps = append(comps, v...)
+		}
+	}
+	comps = append(comps, values[fontSize]...)
+	if !value(values[lineHeight]).is("normal") {
+		comps = append(append(comps, "/"), values[lineHeight]...)
+	}
+	return append(comps, values[fontFamily]...), true
+}
+
+func isWeight(v value) bool {
+	unit, ok := v.dimension()
+	return ok && unit == "" && v[0][0] != '-' && !v.isZero()
+}
+
+func isFontSize(v value) bool {
+	return fontSizes[v.keyword()] || v.isLengthPercentage()
+}
+
+func isLineHeight(v value) bool {
+	return v.is("normal") || v.isNumber() || v.isLengthPercentage()
+}
+
+// isFamily reports whether values are a list of font families: strings
+// or sequences of identifiers.
+func isFamily(values []value) bool {
+	list := layers(values)
+	if list == nil {
+		return false
+	}
+	for _, family := range list {
+		for _, v := range family {
+			if !v.isString() && v.keyword() == "" || len(family) > 1 && v.isString() {
+				return false
+			}
+		}
+		if cssWideKeywords[family[0].keyword()] {
+			return false
+		}
+	}
+	return true
+}
+
+// fontVariants maps the keywords of font-variant to the index of the
+// longhand they set.
+var fontVariants = map[string]int{
+	"common-ligatures": variantLigatures, "no-common-ligatures": variantLigatures,
+	"discretionary-ligatures": variantLigatures, "no-discretionary-ligatures": variantLigatures,
+	"historical-ligatures": variantLigatures, "no-historical-ligatures": variantLigatures,
+	"contextual": variantLigatures, "no-contextual": variantLigatures,
+	"small-caps": variantCaps, "all-small-caps": variantCaps,
+	"petite-caps": variantCaps, "all-petite-caps": variantCaps,
+	"unicase": variantCaps, "titling-caps": variantCaps,
+	"historical-forms": variantAlternates,
+	"lining-nums":      variantNumeric, "oldstyle-nums": variantNumeric,
+	"proportional-nums": variantNumeric, "tabular-nums": variantNumeric,
+	"diagonal-fractions": variantNumeric, "stacked-fractions": variantNumeric,
+	"ordinal": variantNumeric, "slashed-zero": variantNumeric,
+	"jis78": variantEastAsian, "jis83": variantEastAsian, "jis90": variantEastAsian,
+	"jis04": variantEastAsian, "simplified": variantEastAsian,
+	"traditional": variantEastAsian, "full-width": variantEastAsian,
+	"proportional-width": variantEastAsian, "ruby": variantEastAsian,
+	"sub": variantPosition, "super": variantPosition,
+	"text": variantEmoji, "emoji": variantEmoji, "unicode": variantEmoji,
+}
+
+// alternateFunctions are the functions of font-variant-alternates.
+var alternateFunctions = map[string]bool{
+	"stylistic": true, "styleset": true, "character-variant": true,
+	"swash": true, "ornaments": true, "annotation": true,
+}
+
+func init() {
+	shorthands["font-variant"] = &shorthand{
+		longhands: []string{
+			"font-variant-ligatures", "font-variant-caps",
+			"font-variant-alternates", "font-variant-numeric",
+			"font-variant-east-asian", "font-variant-position",
+			"font-variant-emoji",
+		},
+		expand:   expandFontVariant,
+		collapse: collapseFontVariant,
+	}
+}
+
+// The indexes of the longhands of font-variant.
+const (
+	variantLigatures = iota
+	variantCaps
+	variantAlternates
+	variantNumeric
+	variantEastAsian
+	variantPosition
+	variantEmoji
+)
+
+// expandFontVariant expands normal, none, and the values of the
+// longhands in any order. It does not check that the values of a longhand
+// taking several go together.
+func expandFontVariant(values []value) ([][]string, bool) {
+	variant := make([][]string, variantEmoji+1)
+	if len(values) == 1 && values[0].is("none") {
+		variant[variantLigatures] = []string{"none"}
+		values = nil
+	} else if len(values) == 1 && values[0].is("normal") {
+		values = nil
+	}
+	for _, v := range values {
+		p, ok := fontVariants[v.keyword()]
+		if alternateFunctions[v.function()] {
+			p, ok = variantAlternates, true
+		}
+		if !ok {
+			return nil, false
+		}
+		// The caps, position and emoji take a single value.
+		if variant[p] != nil && (p == variantCaps || p == variantPosition || p == variantEmoji) {
+			return nil, false
+		}
+		variant[p] = append(variant[p], v...)
+	}
+	for i := range variant {
+		if variant[i] == nil {
+			variant[i] = []string{"normal"}
+		}
+	}
+	return variant, true
+}
+
+// collapseFontVariant joins the longhands that are not normal. none
+// ligatures cannot go with other values.
+func collapseFontVariant(values [][]string) ([]string, bool) {
+	var comps []string
+	for i, v := range values {
+		switch {
+		case value(v).is("normal"):
+		case value(v).is("none") && i == variantLigatures:
+			for _, w := range values[i+1:] {
+				if !value(w).is("normal") {
+					return nil, false
+				}
+			}
+			return []string{"none"}, true
+		default:
+			comps = append(comps, v...)
+		}
+	}
+	if comps == nil {
+		return []string{"normal"}, true
+	}
+	return comps, true
+}
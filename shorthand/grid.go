@@ -0,0 +1,149 @@
+package shorthand
+
+func init() {
+	shorthands["grid-template"] = &shorthand{
+		longhands: []string{
+			"grid-template-rows", "grid-template-columns", "grid-template-areas",
+		},
+		expand:   expandGridTemplate,
+		collapse: collapseGridTemplate,
+	}
+}
+
+// expandGridTemplate expands none, rows / columns, and the template of
+// areas, whose rows are named by strings, each followed by the size of the
+// row, auto by default.
+func expandGridTemplate(values []value) ([][]string, bool) {
+	none := []string{"none"}
+	if len(values) == 1 && values[0].is("none") {
+		return [][]string{none, none, none}, true
+	}
+	slash := -1
+	for i, v := range values {
+		if v.is("/") {
+			if slash >= 0 {
+				return nil, false
+			}
+			slash = i
+		}
+	}
+	var rows, columns []value
+	if slash < 0 {
+		rows = values
+	} else {
+		rows, columns = values[:slash], values[slash+1:]
+		if len(columns) == 0 || hasAutoRepeat(columns) {
+			return nil, false
+		}
+	}
+	var areas []value
+	for _, v := range rows {
+		if v.isString() {
+			areas = append(areas, v)
+		}
+	}
+	if areas == nil {
+		if slash < 0 || len(rows) == 0 {
+			return nil, false
+		}
+		return [][]string{join(rows...), join(columns...), none}, true
+	}
+
+	// Each string is followed by its size, and the line names around
+	// a row come before and after it.
+	var tracks []value
+	for i := 0; i < len(rows); i++ {
+		switch v := rows[i]; {
+		case v.isString():
+			tracks = append(tracks, value{"auto"})
+		case v[0] == "[":
+			tracks = append(tracks, v)
+			continue
+		default:
+			return nil, false
+		}
+		if i+1 < len(rows) && !rows[i+1].isString() && rows[i+1][0] != "[" {
+			i++
+			if rows[i].function() == "repeat" {
+				return nil, false
+			}
+			tracks[len(tracks)-1] = rows[i]
+		}
+	}
+	if columns == nil {
+		columns = []value{{"none"}}
+	}
+	return [][]string{mergeNames(tracks), join(columns...), join(areas...)}, true
+}
+
+// mergeNames merges adjacent line names, like [a] [b] into [a b], and
+// returns the components of values.
+func mergeNames(values []value) []string {
+	var comps []string
+	for i, v := range values {
+		if v[0] == "[" && i > 0 && values[i-1][0] == "[" {
+			comps = append(comps[:len(comps)-1], v[1:]...)
+			continue
+		}
+		comps = append(comps, v...)
+	}
+	return comps
+}
+
+func hasAutoRepeat(values []value) bool {
+	for _, v := range values {
+		if v.function() == "repeat" && len(v) > 1 {
+			switch value(v[1:2]).keyword() {
+			case "auto-fill", "auto-fit":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func collapseGridTemplate(values [][]string) ([]string, bool) {
+	rows, columns, areas := values[0], values[1], split(values[2])
+	if len(areas) == 1 && areas[0].is("none") {
+		if value(rows).is("none") && value(columns).is("none") {
+			return []string{"none"}, true
+		}
+		return append(append(append([]string(nil), rows...), "/"), columns...), true
+	}
+	for _, a := range areas {
+		if !a.isString() {
+			return nil, false
+		}
+	}
+	// The template sets explicit columns, none if it has no slash.
+	switch {
+	case value(columns).is("none"):
+		columns = nil
+	case hasAutoRepeat(split(columns)):
+		return nil, false
+	}
+	// Each row takes its area string, followed by its size unless auto.
+	var comps []string
+	n := 0
+	for _, v := range split(rows) {
+		switch {
+		case v[0] == "[":
+			comps = append(comps, v...)
+			continue
+		case n == len(areas), v.function() == "repeat", v.is("none"):
+			return nil, false
+		}
+		comps = append(comps, areas[n]...)
+		if !v.is("auto") {
+			comps = append(comps, v...)
+		}
+		n++
+	}
+	if n != len(areas) {
+		return nil, false
+	}
+	if columns != nil {
+		comps = append(append(comps, "/"), columns...)
+	}
+	return comps, true
+}
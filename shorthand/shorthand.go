@@ -0,0 +1,338 @@
+// Package shorthand expands CSS shorthand properties, like margin or
+// font, into their longhands, and collapses complete sets of longhands
+// back into shorthands.
+//
+// A shorthand sets all of its longhands: those its value leaves out are
+// set to their initial values, so that
+//
+//	flex: 1
+//
+// stands for
+//
+//	flex-grow: 1; flex-shrink: 1; flex-basis: 0%
+//
+// Values are handled as the components of declarations (see
+// ast.Declaration). Values referencing custom properties with var() cannot
+// be expanded before substitution, and sets of longhands referencing them
+// are not collapsed.
+package shorthand
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+)
+
+// shorthand is a shorthand property.
+type shorthand struct {
+	longhands []string
+	// expand returns the values of the longhands, in order, for the
+	// values of the shorthand, or false if they are invalid.
+	expand func(values []value) ([][]string, bool)
+	// collapse returns the shortest value of the shorthand for the
+	// values of the longhands, in order, or false if the shorthand cannot
+	// represent them.
+	collapse func(values [][]string) ([]string, bool)
+}
+
+var shorthands = map[string]*shorthand{}
+
+// order is the order in which Collapse tries the shorthands: those
+// setting more longhands come first.
+var order = []string{
+	"border", "border-width", "border-style", "border-color",
+	"border-top", "border-right", "border-bottom", "border-left",
+	"margin", "padding", "inset", "font", "font-variant", "background", "grid-template",
+	"flex", "transition", "animation",
+}
+
+// cssWideKeywords are the keywords every property takes.
+var cssWideKeywords = map[string]bool{
+	"inherit": true, "initial": true, "unset": true, "revert": true,
+	"revert-layer": true,
+}
+
+// IsShorthand reports whether the package knows property as a shorthand.
+func IsShorthand(property string) bool {
+	return shorthands[strings.ToLower(property)] != nil
+}
+
+// Shorthands returns the names of the shorthands known to the package,
+// sorted.
+func Shorthands() []string {
+	var names []string
+	for name := range shorthands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Longhands returns the longhands the shorthand property sets, or nil if
+// property is not a known shorthand. Shorthands made of other shorthands,
+// like border, or setting the longhands of another shorthand, like font
+// setting those of font-variant, are expanded down to longhands.
+func Longhands(property string) []string {
+	s := shorthands[strings.ToLower(property)]
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s.longhands...)
+}
+
+// ExpandValue returns the values of the longhands set by the shorthand
+// property for the components comps of its value, with the initial values
+// of the longhands it leaves out. A CSS-wide keyword, like inherit, sets
+// all of them.
+func ExpandValue(property string, comps []string) (map[string][]string, error) {
+	name := strings.ToLower(property)
+	s := shorthands[name]
+	if s == nil {
+		return nil, fmt.Errorf("shorthand: %s is not a shorthand", property)
+	}
+	if hasVar(comps) {
+		return nil, fmt.Errorf("shorthand: cannot expand %s before substituting var()", property)
+	}
+	values := split(comps)
+	var longhands [][]string
+	if len(values) == 0 {
+		return nil, fmt.Errorf("shorthand: missing value for %s", property)
+	}
+	if len(values) == 1 && cssWideKeywords[values[0].keyword()] {
+		for range s.longhands {
+			longhands = append(longhands, []string{values[0].keyword()})
+		}
+	} else if name == "font" && len(values) == 1 && systemFonts[values[0].keyword()] {
+		return nil, fmt.Errorf("shorthand: cannot expand the system font %s", values[0].keyword())
+	} else {
+		var ok bool
+		if longhands, ok = s.expand(values); !ok {
+			return nil, fmt.Errorf("shorthand: invalid value for %s: %q", property, strings.Join(comps, " "))
+		}
+	}
+	m := map[string][]string{}
+	for i, l := range s.longhands {
+		m[l] = append([]string(nil), longhands[i]...)
+	}
+	return m, nil
+}
+
+// CollapseValue returns the shortest value of the shorthand property
+// setting the longhands to values, which must hold all of them. It
+// reports false if property is not a known shorthand or cannot represent
+// the values.
+func CollapseValue(property string, values map[string][]string) ([]string, bool) {
+	s := shorthands[strings.ToLower(property)]
+	if s == nil {
+		return nil, false
+	}
+	var (
+		longhands [][]string
+		wide      = map[string]bool{}
+	)
+	for _, l := range s.longhands {
+		v, ok := values[l]
+		if !ok || len(v) == 0 || hasVar(v) {
+			return nil, false
+		}
+		if k := value(v).keyword(); len(v) == 1 && cssWideKeywords[k] {
+			wide[k] = true
+		}
+		longhands = append(longhands, v)
+	}
+	switch len(wide) {
+	case 0:
+		comps, ok := s.collapse(longhands)
+		if !ok {
+			return nil, false
+		}
+		// The shorthand must set the longhands to the values as written.
+		back, ok := s.expand(split(comps))
+		if !ok {
+			return nil, false
+		}
+		for i := range back {
+			if !equal(back[i], longhands[i]) {
+				return nil, false
+			}
+		}
+		return comps, true
+	case 1:
+		// The longhands must all be set to the same keyword.
+		for k := range wide {
+			for _, v := range longhands {
+				if !value(v).is(k) {
+					return nil, false
+				}
+			}
+			return []string{k}, true
+		}
+	}
+	return nil, false
+}
+
+// Expand returns the declarations of the longhands set by the shorthand
+// declaration d, in the order of Longhands, or d alone if it is not a
+// shorthand. The longhand declarations are as important as d, and the
+// first one keeps its comments.
+func Expand(d *ast.Declaration) ([]*ast.Declaration, error) {
+	if !IsShorthand(d.Ident) {
+		return []*ast.Declaration{d}, nil
+	}
+	values, err := ExpandValue(d.Ident, d.Components)
+	if err != nil {
+		return nil, err
+	}
+	var decls []*ast.Declaration
+	for i, l := range Longhands(d.Ident) {
+		decls = append(decls, declaration(d, l, values[l], i == 0))
+	}
+	return decls, nil
+}
+
+// Collapse returns decls with the complete sets of longhand declarations
+// replaced by shorthand declarations, where that keeps their meaning. The
+// last declaration of each longhand counts; the longhands of a set must be
+// equally important, and no declaration of a property they overlap may
+// come in between. The shorthand takes the place of the last declaration
+// of the set, with its comments. Shorthand declarations in decls are left
+// as they are.
+func Collapse(decls []*ast.Declaration) []*ast.Declaration {
+	out := append([]*ast.Declaration(nil), decls...)
+	for _, name := range order {
+		out = collapse(out, name)
+	}
+	return out
+}
+
+// collapse collapses the set of longhands of the shorthand name in decls.
+func collapse(decls []*ast.Declaration, name string) []*ast.Declaration {
+	s := shorthands[name]
+	// set maps the longhands of s to the index of their last declaration.
+	set := map[string]int{}
+	for i, d := range decls {
+		if p := strings.ToLower(d.Ident); contains(s.longhands, p) {
+			set[p] = i
+		}
+	}
+	if len(set) < len(s.longhands) {
+		return decls
+	}
+	inSet := func(i int) bool {
+		j, ok := set[strings.ToLower(decls[i].Ident)]
+		return ok && j == i
+	}
+	first, last := len(decls), 0
+	values := map[string][]string{}
+	for p, i := range set {
+		first, last = minInt(first, i), maxInt(last, i)
+		values[p] = decls[i].Components
+	}
+	important := decls[last].Important
+	for i, d := range decls {
+		switch {
+		case inSet(i):
+			if d.Important != important {
+				return decls
+			}
+		case overlaps(d.Ident, s.longhands):
+			// A declaration in between would move behind the shorthand,
+			// and an earlier important one overrides the set.
+			if i > first && i < last || d.Important && !important {
+				return decls
+			}
+		}
+	}
+	comps, ok := CollapseValue(name, values)
+	if !ok {
+		return decls
+	}
+	var out []*ast.Declaration
+	for i, d := range decls {
+		switch {
+		case i == last:
+			out = append(out, declaration(d, name, comps, true))
+		case inSet(i):
+		default:
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// overlaps reports whether the property sets any of longhands.
+func overlaps(property string, longhands []string) bool {
+	p := strings.ToLower(property)
+	if p == "all" {
+		return true
+	}
+	if s := shorthands[p]; s != nil {
+		for _, l := range s.longhands {
+			if contains(longhands, l) {
+				return true
+			}
+		}
+		return false
+	}
+	return contains(longhands, p)
+}
+
+// declaration returns a copy of d for the property ident with the
+// components comps, keeping the comments of d if comments is set.
+func declaration(d *ast.Declaration, ident string, comps []string, comments bool) *ast.Declaration {
+	c := ast.Clone(d).(*ast.Declaration)
+	c.Ident = ident
+	c.Components = append([]string(nil), comps...)
+	if !comments {
+		c.Leading, c.Trailing = nil, nil
+	}
+	return c
+}
+
+func hasVar(comps []string) bool {
+	for _, c := range comps {
+		if strings.EqualFold(c, "var(") || strings.EqualFold(c, "env(") || strings.EqualFold(c, "attr(") {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// equal reports whether the values a and b are written the same, ignoring
+// case.
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,236 @@
+package shorthand
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/printer"
+	"github.com/ttacon/css/scanner"
+)
+
+// declarations parses the declarations of the block src.
+func declarations(t *testing.T, src string) []*ast.Declaration {
+	t.Helper()
+	ss, err := parser.New(scanner.New(".a { " + src + " }")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ss.Children[0].(*ast.QualifiedRule).Block.DeclList.Declarations
+}
+
+func format(decls []*ast.Declaration) string {
+	var s []string
+	for _, d := range decls {
+		s = append(s, printer.Declaration(d))
+	}
+	return strings.Join(s, " ")
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		decl, want string
+		// short is the value the longhands collapse back into.
+		short string
+	}{
+		{"margin: 1px", "margin-top: 1px; margin-right: 1px; margin-bottom: 1px; margin-left: 1px;", "1px"},
+		{"margin: -10px +2px", "margin-top: -10px; margin-right: +2px; margin-bottom: -10px; margin-left: +2px;", "-10px +2px"},
+		{"padding: 1px calc(2px + 1em) 3px", "padding-top: 1px; padding-right: calc(2px + 1em); padding-bottom: 3px; padding-left: calc(2px + 1em);", "1px calc(2px + 1em) 3px"},
+		{"inset: 1px 2px 1px 2px !important", "top: 1px !important; right: 2px !important; bottom: 1px !important; left: 2px !important;", "1px 2px"},
+		{"border-top: red 2px", "border-top-width: 2px; border-top-style: none; border-top-color: red;", "2px red"},
+		{"border-color: red blue", "border-top-color: red; border-right-color: blue; border-bottom-color: red; border-left-color: blue;", "red blue"},
+		{
+			"border: solid",
+			"border-top-width: medium; border-right-width: medium; border-bottom-width: medium; border-left-width: medium; " +
+				"border-top-style: solid; border-right-style: solid; border-bottom-style: solid; border-left-style: solid; " +
+				"border-top-color: currentcolor; border-right-color: currentcolor; border-bottom-color: currentcolor; border-left-color: currentcolor; " +
+				"border-image-source: none; border-image-slice: 100%; border-image-width: 1; border-image-outset: 0; border-image-repeat: stretch;",
+			"solid",
+		},
+		{
+			`font: italic bold 12px/1.5 "Helvetica Neue", sans-serif`,
+			`font-style: italic; font-variant-caps: normal; font-weight: bold; font-stretch: normal; font-size: 12px; line-height: 1.5; font-family: "Helvetica Neue", sans-serif; ` +
+				"font-variant-ligatures: normal; font-variant-alternates: normal; font-variant-numeric: normal; font-variant-east-asian: normal; " +
+				"font-variant-position: normal; font-variant-emoji: normal; font-kerning: auto; font-size-adjust: none; font-feature-settings: normal; " +
+				"font-variation-settings: normal; font-optical-sizing: auto; font-language-override: normal;",
+			`italic bold 12px / 1.5 "Helvetica Neue", sans-serif`,
+		},
+		{
+			"font: normal small-caps 700 condensed larger Times New Roman",
+			"font-style: normal; font-variant-caps: small-caps; font-weight: 700; font-stretch: condensed; font-size: larger; line-height: normal; font-family: Times New Roman; " +
+				"font-variant-ligatures: normal; font-variant-alternates: normal; font-variant-numeric: normal; font-variant-east-asian: normal; " +
+				"font-variant-position: normal; font-variant-emoji: normal; font-kerning: auto; font-size-adjust: none; font-feature-settings: normal; " +
+				"font-variation-settings: normal; font-optical-sizing: auto; font-language-override: normal;",
+			"small-caps 700 condensed larger Times New Roman",
+		},
+		{
+			"font-variant: tabular-nums small-caps stylistic(a) slashed-zero",
+			"font-variant-ligatures: normal; font-variant-caps: small-caps; font-variant-alternates: stylistic(a); font-variant-numeric: tabular-nums slashed-zero; " +
+				"font-variant-east-asian: normal; font-variant-position: normal; font-variant-emoji: normal;",
+			"small-caps stylistic(a) tabular-nums slashed-zero",
+		},
+		{
+			"font-variant: none",
+			"font-variant-ligatures: none; font-variant-caps: normal; font-variant-alternates: normal; font-variant-numeric: normal; " +
+				"font-variant-east-asian: normal; font-variant-position: normal; font-variant-emoji: normal;",
+			"none",
+		},
+		{
+			"background: url(a.png) center / cover no-repeat, linear-gradient(red, blue) padding-box #fff",
+			"background-color: #fff; background-image: url(a.png), linear-gradient(red, blue); background-position: center, 0% 0%; " +
+				"background-size: cover, auto; background-repeat: no-repeat, repeat; background-attachment: scroll, scroll; " +
+				"background-origin: padding-box, padding-box; background-clip: border-box, padding-box;",
+			"url(a.png) center / cover no-repeat, linear-gradient(red, blue) padding-box #fff",
+		},
+		{
+			"background: none",
+			"background-color: transparent; background-image: none; background-position: 0% 0%; background-size: auto; " +
+				"background-repeat: repeat; background-attachment: scroll; background-origin: padding-box; background-clip: border-box;",
+			"none",
+		},
+		{
+			"grid-template: auto 1fr / [a] 100px",
+			"grid-template-rows: auto 1fr; grid-template-columns: [ a ] 100px; grid-template-areas: none;",
+			"auto 1fr / [ a ] 100px",
+		},
+		{
+			`grid-template: [top] "a a" 40px [mid] [x] "b c" / 1fr 2fr`,
+			`grid-template-rows: [ top ] 40px [ mid x ] auto; grid-template-columns: 1fr 2fr; grid-template-areas: "a a" "b c";`,
+			`[ top ] "a a" 40px [ mid x ] "b c" / 1fr 2fr`,
+		},
+		{"flex: 1", "flex-grow: 1; flex-shrink: 1; flex-basis: 0%;", "1"},
+		{"flex: none", "flex-grow: 0; flex-shrink: 0; flex-basis: auto;", "none"},
+		{"flex: 2 10em", "flex-grow: 2; flex-shrink: 1; flex-basis: 10em;", "2 10em"},
+		{"flex: 0 0 0", "flex-grow: 0; flex-shrink: 0; flex-basis: 0;", "0 0 0"},
+		{"flex: content", "flex-grow: 1; flex-shrink: 1; flex-basis: content;", "1 content"},
+		{
+			"transition: opacity .3s ease-in, transform 1s 200ms allow-discrete",
+			"transition-property: opacity, transform; transition-duration: .3s, 1s; transition-timing-function: ease-in, ease; " +
+				"transition-delay: 0s, 200ms; transition-behavior: normal, allow-discrete;",
+			"opacity .3s ease-in, transform 1s 200ms allow-discrete",
+		},
+		{
+			"animation: 1s infinite reverse slide, 2s steps(4, end) both paused",
+			"animation-name: slide, none; animation-duration: 1s, 2s; animation-timing-function: ease, steps(4, end); animation-delay: 0s, 0s; " +
+				"animation-iteration-count: infinite, 1; animation-direction: reverse, normal; animation-fill-mode: none, both; animation-play-state: running, paused;",
+			"1s infinite reverse slide, 2s steps(4, end) both paused",
+		},
+		{
+			// The second keyword is taken for the name.
+			"animation: reverse reverse",
+			"animation-name: reverse; animation-duration: 0s; animation-timing-function: ease; animation-delay: 0s; " +
+				"animation-iteration-count: 1; animation-direction: reverse; animation-fill-mode: none; animation-play-state: running;",
+			"reverse reverse",
+		},
+		{"flex: inherit", "flex-grow: inherit; flex-shrink: inherit; flex-basis: inherit;", "inherit"},
+		{"color: red", "color: red;", ""},
+	}
+	for _, test := range tests {
+		d := declarations(t, test.decl)[0]
+		decls, err := Expand(d)
+		if err != nil {
+			t.Errorf("Expand(%s): %v", test.decl, err)
+			continue
+		}
+		if got := format(decls); got != test.want {
+			t.Errorf("Expand(%s):\n%s\nexpected:\n%s", test.decl, got, test.want)
+		}
+		if !IsShorthand(d.Ident) {
+			continue
+		}
+		values := map[string][]string{}
+		for _, l := range decls {
+			values[l.Ident] = l.Components
+		}
+		comps, ok := CollapseValue(d.Ident, values)
+		if got := printer.Components(comps); got != test.short || ok != (test.short != "") {
+			t.Errorf("CollapseValue(%s) = %q, %v, expected %q", test.decl, got, ok, test.short)
+		}
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	tests := []string{
+		"margin: 1px 2px 3px 4px 5px",
+		"margin: red",
+		"border: solid dashed",
+		"font: bold",
+		"font: caption",
+		"font-variant: none small-caps",
+		"font-variant: sub super",
+		"font: 12px/",
+		"background: red, url(a.png)",
+		"background: 10px / nope",
+		"grid-template: 1fr",
+		"flex: 1 2 3 4",
+		"transition: none, opacity",
+		"animation: 1s 2s 3s",
+		"padding: var(--gap)",
+	}
+	for _, test := range tests {
+		if _, err := Expand(declarations(t, test)[0]); err == nil {
+			t.Errorf("Expand(%s): expected an error", test)
+		}
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{
+			"color: red; margin-top: 0; margin-right: 1px; margin-bottom: 0; margin-left: 1px; top: 0",
+			"color: red; margin: 0 1px; top: 0;",
+		},
+		{
+			// The last declaration of each longhand counts.
+			"flex-grow: 2; flex-basis: auto; flex-shrink: 1; flex-grow: 1",
+			"flex-grow: 2; flex: auto;",
+		},
+		{
+			// Incomplete sets are left alone.
+			"margin-top: 0; margin-right: 0; margin-bottom: 0",
+			"margin-top: 0; margin-right: 0; margin-bottom: 0;",
+		},
+		{
+			// So are those with a declaration in between overlapping them.
+			"margin-top: 0; margin-right: 0; margin: 1px; margin-bottom: 0; margin-left: 0",
+			"margin-top: 0; margin-right: 0; margin: 1px; margin-bottom: 0; margin-left: 0;",
+		},
+		{
+			// font resets font-kerning.
+			"font-style: normal; font-variant-caps: normal; font-weight: normal; font-stretch: normal; font-kerning: none; " +
+				"font-size: 12px; line-height: normal; font-family: serif",
+			"font-style: normal; font-variant-caps: normal; font-weight: normal; font-stretch: normal; font-kerning: none; " +
+				"font-size: 12px; line-height: normal; font-family: serif;",
+		},
+		{
+			"flex-grow: 1 !important; flex-shrink: 1; flex-basis: 0%",
+			"flex-grow: 1 !important; flex-shrink: 1; flex-basis: 0%;",
+		},
+		{
+			"flex-grow: 1 !important; flex-shrink: 1 !important; flex-basis: 0% !important",
+			"flex: 1 !important;",
+		},
+		{
+			"flex-grow: 1; flex-shrink: var(--s); flex-basis: 0%",
+			"flex-grow: 1; flex-shrink: var(--s); flex-basis: 0%;",
+		},
+		{
+			"border-top-width: 1px; border-right-width: 2px; border-bottom-width: 1px; border-left-width: 2px; " +
+				"border-top-style: solid; border-right-style: solid; border-bottom-style: solid; border-left-style: solid; " +
+				"border-top-color: red; border-right-color: red; border-bottom-color: red; border-left-color: red",
+			"border-width: 1px 2px; border-style: solid; border-color: red;",
+		},
+		{
+			"border-top-width: 1px; border-top-style: solid; border-top-color: red",
+			"border-top: 1px solid red;",
+		},
+	}
+	for _, test := range tests {
+		if got := format(Collapse(declarations(t, test.src))); got != test.want {
+			t.Errorf("Collapse(%s):\n%s\nexpected:\n%s", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package shorthand
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/color"
+)
+
+// A value is a component value of a property value, as its components: a
+// single token, a function with its arguments or a bracketed list of line
+// names.
+type value []string
+
+// split splits the components of a property value into its values.
+func split(comps []string) []value {
+	var values []value
+	for i := 0; i < len(comps); i++ {
+		j := i
+		switch c := comps[i]; {
+		case strings.HasSuffix(c, "("):
+			j = closing(comps, i, ")")
+		case c == "[":
+			j = closing(comps, i, "]")
+		}
+		values = append(values, value(comps[i:j+1]))
+		i = j
+	}
+	return values
+}
+
+// closing returns the index of the component closing the function or
+// bracket opened by comps[i], or the last index if it is not closed.
+func closing(comps []string, i int, end string) int {
+	depth := 0
+	for j := i; j < len(comps); j++ {
+		switch c := comps[j]; {
+		case strings.HasSuffix(c, "("), c == "[":
+			depth++
+		case c == ")", c == "]":
+			depth--
+			if depth == 0 && c == end {
+				return j
+			}
+		}
+	}
+	return len(comps) - 1
+}
+
+// layers splits values at their top-level commas. It returns nil if a
+// layer is empty.
+func layers(values []value) [][]value {
+	var (
+		out   [][]value
+		layer []value
+	)
+	for _, v := range values {
+		if v.is(",") {
+			if len(layer) == 0 {
+				return nil
+			}
+			out = append(out, layer)
+			layer = nil
+			continue
+		}
+		layer = append(layer, v)
+	}
+	if len(layer) == 0 {
+		return nil
+	}
+	return append(out, layer)
+}
+
+// join returns the components of values.
+func join(values ...value) []string {
+	var comps []string
+	for _, v := range values {
+		comps = append(comps, v...)
+	}
+	return comps
+}
+
+// joinLayers returns the components of a comma-separated list.
+func joinLayers(list [][]string) []string {
+	var comps []string
+	for i, l := range list {
+		if i > 0 {
+			comps = append(comps, ",")
+		}
+		comps = append(comps, l...)
+	}
+	return comps
+}
+
+// splitLayers splits the components of a comma-separated list.
+func splitLayers(comps []string) [][]string {
+	var list [][]string
+	for _, l := range layers(split(comps)) {
+		list = append(list, join(l...))
+	}
+	return list
+}
+
+// keyword returns the lowercase identifier v is, or "".
+func (v value) keyword() string {
+	if len(v) != 1 || !isIdent(v[0]) {
+		return ""
+	}
+	return strings.ToLower(v[0])
+}
+
+// is reports whether v is the single component c, ignoring case.
+func (v value) is(c string) bool {
+	return len(v) == 1 && strings.EqualFold(v[0], c)
+}
+
+// function returns the lowercase name of the function v is, like "calc",
+// or "".
+func (v value) function() string {
+	if len(v) == 0 || !strings.HasSuffix(v[0], "(") {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(v[0], "("))
+}
+
+// dimension returns the unit of the number v is, "%" for a percentage
+// and "" for a plain number, and reports whether v is a number.
+func (v value) dimension() (string, bool) {
+	if len(v) != 1 || !isNumeric(v[0]) {
+		return "", false
+	}
+	return strings.ToLower(v[0][numberLen(v[0]):]), true
+}
+
+// isZero reports whether v is a plain zero.
+func (v value) isZero() bool {
+	unit, ok := v.dimension()
+	return ok && unit == "" && strings.Trim(v[0], "+-0.") == ""
+}
+
+func (v value) isMath() bool {
+	return mathFunctions[v.function()]
+}
+
+func (v value) isNumber() bool {
+	unit, ok := v.dimension()
+	return ok && unit == "" || v.isMath()
+}
+
+func (v value) isLength() bool {
+	unit, ok := v.dimension()
+	return ok && lengthUnits[unit] || v.isZero() || v.isMath()
+}
+
+func (v value) isLengthPercentage() bool {
+	unit, ok := v.dimension()
+	return ok && unit == "%" || v.isLength()
+}
+
+func (v value) isTime() bool {
+	unit, ok := v.dimension()
+	return ok && (unit == "s" || unit == "ms") || v.isMath()
+}
+
+func (v value) isAngle() bool {
+	unit, ok := v.dimension()
+	return ok && angleUnits[unit] || v.isZero() || v.isMath()
+}
+
+func (v value) isString() bool {
+	return len(v) == 1 && (strings.HasPrefix(v[0], `"`) || strings.HasPrefix(v[0], "'"))
+}
+
+func (v value) isColor() bool {
+	switch {
+	case len(v) == 1 && strings.HasPrefix(v[0], "#"):
+		return true
+	case colorFunctions[v.function()]:
+		return true
+	}
+	switch k := v.keyword(); k {
+	case "":
+		return false
+	case "currentcolor", "transparent":
+		return true
+	default:
+		_, err := color.Parse(k)
+		return err == nil
+	}
+}
+
+func (v value) isImage() bool {
+	if len(v) == 1 && strings.HasPrefix(strings.ToLower(v[0]), "url(") {
+		return true
+	}
+	f := v.function()
+	return imageFunctions[f] || strings.HasSuffix(f, "gradient")
+}
+
+func (v value) isEasing() bool {
+	return easingKeywords[v.keyword()] || easingFunctions[v.function()]
+}
+
+// isNumeric reports whether the token s starts with a number.
+func isNumeric(s string) bool {
+	return numberLen(s) > 0
+}
+
+// numberLen returns the length of the number, signed or not, starting s,
+// or 0.
+func numberLen(s string) int {
+	i, digits := 0, 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	for ; i < len(s) && isDigit(s[i]); i++ {
+		digits++
+	}
+	if i+1 < len(s) && s[i] == '.' && isDigit(s[i+1]) {
+		for i++; i < len(s) && isDigit(s[i]); i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			for i = j; i < len(s) && isDigit(s[i]); i++ {
+			}
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdent(s string) bool {
+	if s == "" || isNumeric(s) || strings.HasSuffix(s, "(") {
+		return false
+	}
+	c := s[0]
+	return c == '-' && len(s) > 1 || c == '_' || c >= 0x80 || 'a' <= c|0x20 && c|0x20 <= 'z'
+}
+
+var mathFunctions = map[string]bool{
+	"calc": true, "min": true, "max": true, "clamp": true, "round": true,
+	"mod": true, "rem": true, "abs": true, "sign": true, "sin": true,
+	"cos": true, "tan": true, "asin": true, "acos": true, "atan": true,
+	"atan2": true, "pow": true, "sqrt": true, "hypot": true, "log": true,
+	"exp": true, "-webkit-calc": true, "-moz-calc": true,
+}
+
+var lengthUnits = map[string]bool{
+	"px": true, "cm": true, "mm": true, "q": true, "in": true, "pt": true,
+	"pc": true, "em": true, "rem": true, "ex": true, "rex": true,
+	"cap": true, "rcap": true, "ch": true, "rch": true, "ic": true,
+	"ric": true, "lh": true, "rlh": true, "vw": true, "vh": true,
+	"vi": true, "vb": true, "vmin": true, "vmax": true, "svw": true,
+	"svh": true, "svi": true, "svb": true, "svmin": true, "svmax": true,
+	"lvw": true, "lvh": true, "lvi": true, "lvb": true, "lvmin": true,
+	"lvmax": true, "dvw": true, "dvh": true, "dvi": true, "dvb": true,
+	"dvmin": true, "dvmax": true, "cqw": true, "cqh": true, "cqi": true,
+	"cqb": true, "cqmin": true, "cqmax": true,
+}
+
+var angleUnits = map[string]bool{
+	"deg": true, "grad": true, "rad": true, "turn": true,
+}
+
+var colorFunctions = map[string]bool{
+	"rgb": true, "rgba": true, "hsl": true, "hsla": true, "hwb": true,
+	"lab": true, "lch": true, "oklab": true, "oklch": true, "color": true,
+	"color-mix": true, "light-dark": true,
+}
+
+var imageFunctions = map[string]bool{
+	"url": true, "src": true, "image": true, "image-set": true,
+	"-webkit-image-set": true, "cross-fade": true, "-webkit-cross-fade": true,
+	"element": true, "-moz-element": true, "paint": true,
+}
+
+var easingKeywords = map[string]bool{
+	"linear": true, "ease": true, "ease-in": true, "ease-out": true,
+	"ease-in-out": true, "step-start": true, "step-end": true,
+}
+
+var easingFunctions = map[string]bool{
+	"linear": true, "cubic-bezier": true, "steps": true,
+}
@@ -0,0 +1,138 @@
+package sourcemap
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Generator builds a source map.
+type Generator struct {
+	file     string
+	mappings []Mapping
+	contents map[string]string
+}
+
+// NewGenerator returns a generator for a map of the generated file
+// named file.
+func NewGenerator(file string) *Generator {
+	return &Generator{
+		file:     file,
+		contents: map[string]string{},
+	}
+}
+
+// Add records a mapping.
+func (g *Generator) Add(m Mapping) {
+	g.mappings = append(g.mappings, m)
+}
+
+// SetSourceContent records the content of source, to be embedded in the
+// map's sourcesContent.
+func (g *Generator) SetSourceContent(source, content string) {
+	g.contents[source] = content
+}
+
+// ApplySourceMap chains m, the map of source, into the generator: every
+// mapping to source is replaced with the position m maps it back to,
+// and the source contents recorded in m are carried over. Mappings for
+// which m has no original position are kept as they are.
+//
+// This turns a map from a file to source, with the map from source to
+// its own sources, into a map from the file to those sources.
+func (g *Generator) ApplySourceMap(m *Map, source string) {
+	for i, mapping := range g.mappings {
+		if mapping.Source != source {
+			continue
+		}
+		orig, ok := m.Lookup(mapping.OrigLine, mapping.OrigColumn)
+		if !ok {
+			continue
+		}
+		g.mappings[i].Source = orig.Source
+		g.mappings[i].OrigLine = orig.OrigLine
+		g.mappings[i].OrigColumn = orig.OrigColumn
+		if orig.Name != "" {
+			g.mappings[i].Name = orig.Name
+		}
+		if content, ok := m.SourceContent(orig.Source); ok {
+			g.contents[orig.Source] = content
+		}
+	}
+}
+
+// Map returns the source map of the recorded mappings.
+func (g *Generator) Map() *Map {
+	m := &Map{
+		Version: 3,
+		File:    g.file,
+		Sources: []string{},
+		Names:   []string{},
+	}
+	mappings := make([]Mapping, len(g.mappings))
+	copy(mappings, g.mappings)
+	sort.SliceStable(mappings, func(i, j int) bool {
+		return before(mappings[i], mappings[j])
+	})
+
+	var (
+		buf     bytes.Buffer
+		sources = map[string]int{}
+		names   = map[string]int{}
+		line    = 1
+		// The fields of a segment are relative to the previous one.
+		column, source, origLine, origColumn, name int
+		first                                      = true
+	)
+	for _, mapping := range mappings {
+		for line < mapping.GenLine {
+			buf.WriteByte(';')
+			line++
+			column = 0
+			first = true
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		writeVLQ(&buf, mapping.GenColumn-1-column)
+		column = mapping.GenColumn - 1
+		if mapping.Source == "" {
+			continue
+		}
+		i, ok := sources[mapping.Source]
+		if !ok {
+			i = len(m.Sources)
+			sources[mapping.Source] = i
+			m.Sources = append(m.Sources, mapping.Source)
+		}
+		writeVLQ(&buf, i-source)
+		source = i
+		writeVLQ(&buf, mapping.OrigLine-1-origLine)
+		origLine = mapping.OrigLine - 1
+		writeVLQ(&buf, mapping.OrigColumn-1-origColumn)
+		origColumn = mapping.OrigColumn - 1
+		if mapping.Name == "" {
+			continue
+		}
+		j, ok := names[mapping.Name]
+		if !ok {
+			j = len(m.Names)
+			names[mapping.Name] = j
+			m.Names = append(m.Names, mapping.Name)
+		}
+		writeVLQ(&buf, j-name)
+		name = j
+	}
+	m.Mappings = buf.String()
+
+	if len(g.contents) > 0 {
+		m.SourcesContent = make([]*string, len(m.Sources))
+		for i, source := range m.Sources {
+			if content, ok := g.contents[source]; ok {
+				m.SourcesContent[i] = &content
+			}
+		}
+	}
+	return m
+}
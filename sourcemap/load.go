@@ -0,0 +1,151 @@
+package sourcemap
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/ttacon/css/ast"
+)
+
+// URL returns the URL of the source map of a stylesheet, as given by its
+// last /*# sourceMappingURL=... */ comment. It reports false if there is
+// none.
+func URL(src string) (string, bool) {
+	for {
+		i := strings.LastIndex(src, "/*")
+		if i < 0 {
+			return "", false
+		}
+		comment := src[i+2:]
+		src = src[:i]
+		end := strings.Index(comment, "*/")
+		if end < 0 {
+			continue
+		}
+		comment = strings.TrimSpace(comment[:end])
+		// "@" is the deprecated form of the marker.
+		if !strings.HasPrefix(comment, "#") && !strings.HasPrefix(comment, "@") {
+			continue
+		}
+		const prefix = "sourceMappingURL="
+		rest := strings.TrimSpace(comment[1:])
+		if !strings.HasPrefix(rest, prefix) {
+			continue
+		}
+		if u := strings.TrimSpace(rest[len(prefix):]); u != "" {
+			return u, true
+		}
+	}
+}
+
+// Load returns the source map of the stylesheet src. Maps inlined as
+// data: URLs are decoded; others are read with read, which is handed the
+// URL of the map. Load returns nil and no error if src has no source
+// map.
+func Load(src string, read func(url string) ([]byte, error)) (*Map, error) {
+	u, ok := URL(src)
+	if !ok {
+		return nil, nil
+	}
+	if !strings.HasPrefix(u, "data:") {
+		data, err := read(u)
+		if err != nil {
+			return nil, err
+		}
+		return Parse(data)
+	}
+
+	comma := strings.IndexByte(u, ',')
+	if comma < 0 {
+		return nil, fmt.Errorf("sourcemap: invalid data URL")
+	}
+	var (
+		header = u[len("data:"):comma]
+		body   = u[comma+1:]
+		data   []byte
+		err    error
+	)
+	if strings.HasSuffix(header, ";base64") {
+		data, err = base64.StdEncoding.DecodeString(body)
+	} else {
+		var s string
+		s, err = url.PathUnescape(body)
+		data = []byte(s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sourcemap: invalid data URL: %v", err)
+	}
+	return Parse(data)
+}
+
+// Remap returns the original source and position of pos, a position in
+// the generated file. A position some columns after the start of a mapping
+// maps to as many columns after its original position. The offset of the
+// result is only known if the map holds the content of the source; it is
+// -1 otherwise. Remap reports false if pos is not mapped.
+func (m *Map) Remap(pos ast.Position) (string, ast.Position, bool) {
+	mapping, ok := m.Lookup(pos.Line, pos.Column)
+	if !ok {
+		return "", ast.Position{}, false
+	}
+	orig := ast.Position{
+		Offset: -1,
+		Line:   mapping.OrigLine,
+		Column: mapping.OrigColumn + pos.Column - mapping.GenColumn,
+	}
+	if content, ok := m.SourceContent(mapping.Source); ok {
+		orig.Offset = offset(content, orig.Line, orig.Column)
+	}
+	return mapping.Source, orig, true
+}
+
+// RemapSpan returns the original source and span of s, a span in the
+// generated file. The end of the result is zero unless it maps to the same
+// source as the start, after it. RemapSpan reports false if the start of s
+// is not mapped.
+func (m *Map) RemapSpan(s ast.Span) (string, ast.Span, bool) {
+	source, from, ok := m.Remap(s.From)
+	if !ok {
+		return "", ast.Span{}, false
+	}
+	span := ast.Span{From: from}
+	if src, to, ok := m.Remap(s.To); ok && src == source &&
+		(to.Line > from.Line || to.Line == from.Line && to.Column >= from.Column) {
+		span.To = to
+	}
+	return source, span, true
+}
+
+// RemapNode returns the original source and span of n, a node of the
+// generated stylesheet, as RemapSpan.
+func (m *Map) RemapNode(n ast.Node) (string, ast.Span, bool) {
+	return m.RemapSpan(ast.Span{From: n.Pos(), To: n.End()})
+}
+
+// offset returns the byte offset of the given line and column in s, or
+// -1 if s is too short. Columns count UTF-16 code units.
+func offset(s string, line, column int) int {
+	off := 0
+	for ; line > 1; line-- {
+		i := strings.IndexByte(s[off:], '\n')
+		if i < 0 {
+			return -1
+		}
+		off += i + 1
+	}
+	for ; column > 1; column-- {
+		if off >= len(s) || s[off] == '\n' {
+			return -1
+		}
+		r, size := utf8.DecodeRuneInString(s[off:])
+		off += size
+		if utf16.RuneLen(r) == 2 {
+			column--
+		}
+	}
+	return off
+}
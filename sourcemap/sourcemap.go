@@ -0,0 +1,176 @@
+// Package sourcemap reads and writes Source Map v3 files
+// (https://sourcemaps.info/spec.html).
+//
+// Positions in this package follow ast.Position: lines and columns start
+// at 1 and columns count characters. They are converted to the zero-based
+// positions of the format when encoding and decoding.
+package sourcemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Map is a decoded source map file.
+type Map struct {
+	Version        int       `json:"version"`
+	File           string    `json:"file,omitempty"`
+	SourceRoot     string    `json:"sourceRoot,omitempty"`
+	Sources        []string  `json:"sources"`
+	SourcesContent []*string `json:"sourcesContent,omitempty"`
+	Names          []string  `json:"names"`
+	Mappings       string    `json:"mappings"`
+
+	// decoded caches the result of Decode.
+	decoded []Mapping
+}
+
+// Mapping maps a position in the generated file to a position in one of
+// its sources. A mapping without Source maps a generated position to
+// nothing.
+type Mapping struct {
+	GenLine    int
+	GenColumn  int
+	Source     string
+	OrigLine   int
+	OrigColumn int
+	// Name is the original name of the mapped symbol, if any.
+	Name string
+}
+
+// Parse decodes a source map file.
+func Parse(data []byte) (*Map, error) {
+	m := &Map{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("sourcemap: %v", err)
+	}
+	if m.Version != 3 {
+		return nil, fmt.Errorf("sourcemap: unsupported version %d", m.Version)
+	}
+	if _, err := m.Decode(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Bytes returns the JSON encoding of m.
+func (m *Map) Bytes() []byte {
+	b, err := json.Marshal(m)
+	if err != nil {
+		// A Map only holds strings and numbers.
+		panic(err)
+	}
+	return b
+}
+
+// source returns the name of the i-th source, prefixed with the source
+// root.
+func (m *Map) source(i int) string {
+	if m.SourceRoot == "" {
+		return m.Sources[i]
+	}
+	return strings.TrimSuffix(m.SourceRoot, "/") + "/" + m.Sources[i]
+}
+
+// SourceContent returns the content of source as recorded in the map,
+// and whether it is there.
+func (m *Map) SourceContent(source string) (string, bool) {
+	for i := range m.Sources {
+		if m.source(i) == source && i < len(m.SourcesContent) && m.SourcesContent[i] != nil {
+			return *m.SourcesContent[i], true
+		}
+	}
+	return "", false
+}
+
+// Decode returns the mappings of m, sorted by generated position.
+func (m *Map) Decode() ([]Mapping, error) {
+	if m.decoded != nil || m.Mappings == "" {
+		return m.decoded, nil
+	}
+	var (
+		mappings []Mapping
+		// The fields of a segment are relative to the previous one.
+		source, origLine, origColumn, name int
+	)
+	for line, group := range strings.Split(m.Mappings, ";") {
+		column := 0
+		for _, segment := range strings.Split(group, ",") {
+			if segment == "" {
+				continue
+			}
+			var (
+				fields []int
+				rest   = segment
+			)
+			for rest != "" {
+				var (
+					n   int
+					err error
+				)
+				if n, rest, err = readVLQ(rest); err != nil {
+					return nil, err
+				}
+				fields = append(fields, n)
+			}
+			if len(fields) != 1 && len(fields) != 4 && len(fields) != 5 {
+				return nil, fmt.Errorf("sourcemap: invalid segment %q", segment)
+			}
+
+			column += fields[0]
+			mapping := Mapping{GenLine: line + 1, GenColumn: column + 1}
+			if len(fields) > 1 {
+				source += fields[1]
+				origLine += fields[2]
+				origColumn += fields[3]
+				if source < 0 || source >= len(m.Sources) {
+					return nil, fmt.Errorf("sourcemap: invalid source index %d", source)
+				}
+				mapping.Source = m.source(source)
+				mapping.OrigLine = origLine + 1
+				mapping.OrigColumn = origColumn + 1
+			}
+			if len(fields) > 4 {
+				name += fields[4]
+				if name < 0 || name >= len(m.Names) {
+					return nil, fmt.Errorf("sourcemap: invalid name index %d", name)
+				}
+				mapping.Name = m.Names[name]
+			}
+			mappings = append(mappings, mapping)
+		}
+	}
+	sort.SliceStable(mappings, func(i, j int) bool {
+		return before(mappings[i], mappings[j])
+	})
+	m.decoded = mappings
+	return mappings, nil
+}
+
+// Lookup returns the mapping covering the given generated position: the
+// last one on that line starting at or before column. It reports false
+// if there is none, or if it maps to no source.
+func (m *Map) Lookup(line, column int) (Mapping, bool) {
+	mappings, err := m.Decode()
+	if err != nil {
+		return Mapping{}, false
+	}
+	i := sort.Search(len(mappings), func(i int) bool {
+		return before(Mapping{GenLine: line, GenColumn: column}, mappings[i])
+	})
+	if i == 0 || mappings[i-1].GenLine != line || mappings[i-1].Source == "" {
+		return Mapping{}, false
+	}
+	return mappings[i-1], true
+}
+
+// before reports whether the generated position of a comes before that
+// of b.
+func before(a, b Mapping) bool {
+	if a.GenLine != b.GenLine {
+		return a.GenLine < b.GenLine
+	}
+	return a.GenColumn < b.GenColumn
+}
@@ -0,0 +1,214 @@
+package sourcemap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/ttacon/css/ast"
+)
+
+func TestVLQ(t *testing.T) {
+	for _, n := range []int{0, 1, -1, 15, 16, -16, 1000, -123456} {
+		var buf bytes.Buffer
+		writeVLQ(&buf, n)
+		got, rest, err := readVLQ(buf.String() + "A")
+		if err != nil || got != n || rest != "A" {
+			t.Errorf("%d: encoded as %q, decoded as %d, %q, %v", n, buf.String(), got, rest, err)
+		}
+	}
+	if _, _, err := readVLQ("g"); err == nil {
+		t.Error("expected an error for a truncated VLQ")
+	}
+}
+
+var testMappings = []Mapping{
+	{GenLine: 1, GenColumn: 1, Source: "a.css", OrigLine: 1, OrigColumn: 1},
+	{GenLine: 1, GenColumn: 5, Source: "a.css", OrigLine: 2, OrigColumn: 3, Name: "color"},
+	{GenLine: 3, GenColumn: 1, Source: "b.css", OrigLine: 1, OrigColumn: 1},
+}
+
+func TestGenerator(t *testing.T) {
+	g := NewGenerator("out.css")
+	for i := len(testMappings) - 1; i >= 0; i-- {
+		g.Add(testMappings[i])
+	}
+	g.SetSourceContent("b.css", "p{}")
+	m := g.Map()
+
+	if m.Mappings != "AAAA,IACEA;;ACDF" {
+		t.Errorf("unexpected mappings %q", m.Mappings)
+	}
+	if !reflect.DeepEqual(m.Sources, []string{"a.css", "b.css"}) || !reflect.DeepEqual(m.Names, []string{"color"}) {
+		t.Errorf("unexpected sources %q or names %q", m.Sources, m.Names)
+	}
+	want := `{"version":3,"file":"out.css","sources":["a.css","b.css"],"sourcesContent":[null,"p{}"],"names":["color"],"mappings":"AAAA,IACEA;;ACDF"}`
+	if got := string(m.Bytes()); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	parsed, err := Parse(m.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mappings, err := parsed.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mappings, testMappings) {
+		t.Errorf("expected %+v, got %+v", testMappings, mappings)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	g := NewGenerator("")
+	for _, m := range testMappings {
+		g.Add(m)
+	}
+	m := g.Map()
+	var tests = []struct {
+		line, column int
+		want         string
+		ok           bool
+	}{
+		{1, 1, "a.css", true},
+		{1, 4, "a.css", true},
+		{1, 9, "color", true},
+		{2, 1, "", false},
+		{3, 7, "b.css", true},
+	}
+	for _, test := range tests {
+		got, ok := m.Lookup(test.line, test.column)
+		if ok != test.ok || (ok && got.Source != test.want && got.Name != test.want) {
+			t.Errorf("%d:%d: expected %q, %v, got %+v, %v", test.line, test.column, test.want, test.ok, got, ok)
+		}
+	}
+}
+
+func TestApplySourceMap(t *testing.T) {
+	// a.css was compiled from a.scss.
+	scss := NewGenerator("a.css")
+	scss.Add(Mapping{GenLine: 1, GenColumn: 1, Source: "a.scss", OrigLine: 5, OrigColumn: 3})
+	scss.Add(Mapping{GenLine: 2, GenColumn: 3, Source: "a.scss", OrigLine: 6, OrigColumn: 5, Name: "color"})
+	scss.SetSourceContent("a.scss", ".a { color: red }")
+	input, err := Parse(scss.Map().Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewGenerator("out.css")
+	g.Add(Mapping{GenLine: 1, GenColumn: 1, Source: "a.css", OrigLine: 1, OrigColumn: 1})
+	g.Add(Mapping{GenLine: 1, GenColumn: 4, Source: "a.css", OrigLine: 2, OrigColumn: 3})
+	g.Add(Mapping{GenLine: 1, GenColumn: 9, Source: "other.css", OrigLine: 1, OrigColumn: 1})
+	g.ApplySourceMap(input, "a.css")
+
+	m := g.Map()
+	mappings, err := m.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Mapping{
+		{GenLine: 1, GenColumn: 1, Source: "a.scss", OrigLine: 5, OrigColumn: 3},
+		{GenLine: 1, GenColumn: 4, Source: "a.scss", OrigLine: 6, OrigColumn: 5, Name: "color"},
+		{GenLine: 1, GenColumn: 9, Source: "other.css", OrigLine: 1, OrigColumn: 1},
+	}
+	if !reflect.DeepEqual(mappings, want) {
+		t.Errorf("expected %+v, got %+v", want, mappings)
+	}
+	if content, ok := m.SourceContent("a.scss"); !ok || content != ".a { color: red }" {
+		t.Errorf("source content not carried over: %q, %v", content, ok)
+	}
+}
+
+func TestURL(t *testing.T) {
+	var tests = []struct {
+		src  string
+		want string
+	}{
+		{".a{}\n/*# sourceMappingURL=a.css.map */\n", "a.css.map"},
+		{".a{}/*@ sourceMappingURL=old.map*/", "old.map"},
+		{"/*# sourceMappingURL=first.map */ .a{} /* note */", "first.map"},
+		{".a{} /* sourceMappingURL=no.map */", ""},
+		{".a{}", ""},
+	}
+	for _, test := range tests {
+		if got, _ := URL(test.src); got != test.want {
+			t.Errorf("%q: expected %q, got %q", test.src, test.want, got)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	g := NewGenerator("a.css")
+	g.Add(Mapping{GenLine: 1, GenColumn: 1, Source: "a.scss", OrigLine: 3, OrigColumn: 2})
+	g.SetSourceContent("a.scss", "// x\n\n  .a {}\n")
+	data := g.Map().Bytes()
+
+	inline := ".a{}\n/*# sourceMappingURL=data:application/json;charset=utf-8;base64," +
+		base64.StdEncoding.EncodeToString(data) + " */"
+	external := ".a{}\n/*# sourceMappingURL=a.css.map */"
+	for _, src := range []string{inline, external} {
+		m, err := Load(src, func(u string) ([]byte, error) {
+			if u != "a.css.map" {
+				t.Errorf("unexpected URL %q", u)
+			}
+			return data, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Two columns into the mapping starting at 1:1.
+		source, pos, ok := m.Remap(ast.Position{Line: 1, Column: 3})
+		if !ok || source != "a.scss" || pos.String() != "3:4" || pos.Offset != 9 {
+			t.Errorf("unexpected position %s %s (offset %d), %v", source, pos, pos.Offset, ok)
+		}
+	}
+
+	if m, err := Load(".a{}", nil); m != nil || err != nil {
+		t.Errorf("expected no map, got %v, %v", m, err)
+	}
+	if _, err := Load("/*# sourceMappingURL=data:application/json;base64,!!! */", nil); err == nil {
+		t.Error("expected an error for a bad data URL")
+	}
+}
+
+func TestRemapSpan(t *testing.T) {
+	g := NewGenerator("a.css")
+	g.Add(Mapping{GenLine: 1, GenColumn: 1, Source: "a.scss", OrigLine: 2, OrigColumn: 3})
+	g.Add(Mapping{GenLine: 1, GenColumn: 10, Source: "b.scss", OrigLine: 1, OrigColumn: 1})
+	g.Add(Mapping{GenLine: 2, GenColumn: 1, Source: "a.scss", OrigLine: 1, OrigColumn: 1})
+	m := g.Map()
+
+	tests := []struct {
+		from, to ast.Position
+		want     string
+	}{
+		{ast.Position{Line: 1, Column: 2}, ast.Position{Line: 1, Column: 6}, "a.scss 2:4 2:8"},
+		// An end in another source, or before the start, is dropped.
+		{ast.Position{Line: 1, Column: 2}, ast.Position{Line: 1, Column: 11}, "a.scss 2:4 -"},
+		{ast.Position{Line: 1, Column: 2}, ast.Position{Line: 2, Column: 1}, "a.scss 2:4 -"},
+		{ast.Position{Line: 3, Column: 1}, ast.Position{Line: 3, Column: 2}, "unmapped"},
+	}
+	for _, test := range tests {
+		got := "unmapped"
+		if source, span, ok := m.RemapSpan(ast.Span{From: test.from, To: test.to}); ok {
+			got = source + " " + span.From.String() + " " + span.To.String()
+		}
+		if got != test.want {
+			t.Errorf("RemapSpan(%s, %s) = %s, expected %s", test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestRemapOffset(t *testing.T) {
+	g := NewGenerator("a.css")
+	g.Add(Mapping{GenLine: 1, GenColumn: 1, Source: "a.scss", OrigLine: 2, OrigColumn: 1})
+	g.SetSourceContent("a.scss", "a\n😀 b")
+	// The emoji spans two columns, as two UTF-16 code units, and four
+	// bytes.
+	_, pos, ok := g.Map().Remap(ast.Position{Line: 1, Column: 4})
+	if !ok || pos.Line != 2 || pos.Column != 4 || pos.Offset != 7 {
+		t.Errorf("Remap(1:4) = %+v, %v, expected 2:4 at offset 7", pos, ok)
+	}
+}
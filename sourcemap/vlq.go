@@ -0,0 +1,70 @@
+package sourcemap
+
+import (
+	"bytes"
+	"errors"
+)
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+var base64Values [256]int
+
+func init() {
+	for i := range base64Values {
+		base64Values[i] = -1
+	}
+	for i := 0; i < len(base64Chars); i++ {
+		base64Values[base64Chars[i]] = i
+	}
+}
+
+const (
+	vlqShift    = 5
+	vlqBase     = 1 << vlqShift
+	vlqMask     = vlqBase - 1
+	vlqContinue = vlqBase
+)
+
+// writeVLQ appends the base64 VLQ encoding of n to buf.
+func writeVLQ(buf *bytes.Buffer, n int) {
+	// The sign is stored in the least significant bit.
+	v := n << 1
+	if n < 0 {
+		v = (-n << 1) | 1
+	}
+	for {
+		digit := v & vlqMask
+		v >>= vlqShift
+		if v > 0 {
+			digit |= vlqContinue
+		}
+		buf.WriteByte(base64Chars[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
+
+var errBadVLQ = errors.New("sourcemap: invalid VLQ in mappings")
+
+// readVLQ decodes the base64 VLQ at the start of s. It returns the value
+// and the rest of s.
+func readVLQ(s string) (int, string, error) {
+	var v, shift int
+	for i := 0; i < len(s); i++ {
+		digit := base64Values[s[i]]
+		if digit < 0 || shift > 30 {
+			return 0, s, errBadVLQ
+		}
+		v |= (digit & vlqMask) << shift
+		if digit&vlqContinue == 0 {
+			n := v >> 1
+			if v&1 != 0 {
+				n = -n
+			}
+			return n, s[i+1:], nil
+		}
+		shift += vlqShift
+	}
+	return 0, s, errBadVLQ
+}
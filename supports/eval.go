@@ -0,0 +1,162 @@
+package supports
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/scanner"
+)
+
+// Profile describes the features a browser supports.
+type Profile struct {
+	// Properties holds the supported properties. Custom properties are
+	// always supported.
+	Properties map[string]bool
+	// Values holds, for the properties that do not support every value,
+	// the supported keywords, like "grid" for display. Properties missing
+	// from Values accept any keyword.
+	Values map[string]map[string]bool
+	// Functions holds the supported functions of values, like "oklch" or
+	// "color-mix". If Functions is nil, every function is supported.
+	Functions map[string]bool
+	// Selectors holds the supported pseudo-classes and pseudo-elements,
+	// like ":has" or "::backdrop". If Selectors is nil, every selector is
+	// supported.
+	Selectors map[string]bool
+	// FontTechs and FontFormats hold the supported font technologies and
+	// formats, like "color-colrv1" and "woff2".
+	FontTechs   map[string]bool
+	FontFormats map[string]bool
+}
+
+// Eval reports whether the browser described by p supports c.
+func Eval(c Condition, p *Profile) bool {
+	switch c := c.(type) {
+	case *And:
+		for _, c := range c.Conds {
+			if !Eval(c, p) {
+				return false
+			}
+		}
+		return true
+	case *Or:
+		for _, c := range c.Conds {
+			if Eval(c, p) {
+				return true
+			}
+		}
+		return false
+	case *Not:
+		return !Eval(c.Cond, p)
+	case *Declaration:
+		return p.declaration(c)
+	case *Selector:
+		return p.selector(c.Selector)
+	case *FontTech:
+		return p.FontTechs[c.Tech]
+	case *FontFormat:
+		return p.FontFormats[c.Format]
+	}
+	return false
+}
+
+// wideKeywords are the CSS-wide keywords, valid for every property.
+var wideKeywords = map[string]bool{
+	"initial":      true,
+	"inherit":      true,
+	"unset":        true,
+	"revert":       true,
+	"revert-layer": true,
+}
+
+func (p *Profile) declaration(d *Declaration) bool {
+	if strings.HasPrefix(d.Property, "--") {
+		return true
+	}
+	if !p.Properties[d.Property] {
+		return false
+	}
+	values, restricted := p.Values[d.Property]
+	sc := scanner.New(d.Value)
+	for t := sc.Next(); t.Type != scanner.TokenEOF; t = sc.Next() {
+		switch t.Type {
+		case scanner.TokenError:
+			return false
+		case scanner.TokenFunction:
+			name := strings.ToLower(strings.TrimSuffix(t.Value, "("))
+			if p.Functions != nil && !p.Functions[name] {
+				return false
+			}
+		case scanner.TokenIdent:
+			v := strings.ToLower(t.Value)
+			if restricted && !values[v] && !wideKeywords[v] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (p *Profile) selector(sel string) bool {
+	var (
+		toks []*scanner.Token
+		sc   = scanner.New(sel)
+	)
+	for t := sc.Next(); t.Type != scanner.TokenEOF; t = sc.Next() {
+		if t.Type == scanner.TokenError || t.Type == scanner.TokenChar && strings.ContainsAny(t.Value, ";{}") {
+			return false
+		}
+		toks = append(toks, t)
+	}
+	if p.Selectors == nil {
+		return true
+	}
+	for i := 0; i < len(toks); i++ {
+		if !isChar(toks[i], ":") {
+			continue
+		}
+		pseudo := ":"
+		if i+1 < len(toks) && isChar(toks[i+1], ":") {
+			pseudo, i = "::", i+1
+		}
+		if i+1 >= len(toks) {
+			return false
+		}
+		name := toks[i+1]
+		if name.Type != scanner.TokenIdent && name.Type != scanner.TokenFunction {
+			return false
+		}
+		pseudo += strings.ToLower(strings.TrimSuffix(name.Value, "("))
+		if !p.Selectors[pseudo] {
+			return false
+		}
+	}
+	return true
+}
+
+// Prune removes the @supports rules of ss whose condition p does not
+// support, and replaces those it supports by the rules of their block.
+// Rules with an invalid condition are left alone, as are supported rules
+// holding declarations, which cannot be moved to the enclosing block.
+func Prune(ss *ast.Stylesheet, p *Profile) {
+	ast.Apply(ss, nil, func(c *ast.Cursor) bool {
+		at, ok := c.Node().(*ast.AtRule)
+		if !ok || at.Block == nil || !strings.EqualFold(at.AtKeyword, "@supports") || c.Index() < 0 {
+			return true
+		}
+		cond, err := Parse(at.Any)
+		if err != nil {
+			return true
+		}
+		switch {
+		case !Eval(cond, p):
+			c.Delete()
+		case at.Block.DeclList == nil || len(at.Block.DeclList.Declarations) == 0:
+			for _, r := range at.Block.Rules {
+				c.InsertBefore(r)
+			}
+			c.Delete()
+		}
+		return true
+	})
+}
@@ -0,0 +1,247 @@
+package supports
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ttacon/css/scanner"
+)
+
+// Parse parses a supports condition, like the prelude of a @supports
+// rule:
+//
+//	not <supports-in-parens>
+//	| <supports-in-parens> [ [ and <supports-in-parens> ]* | [ or <supports-in-parens> ]* ]
+//
+// Unlike a malformed media query, a malformed condition invalidates the
+// whole @supports rule.
+func Parse(s string) (Condition, error) {
+	var (
+		toks []*scanner.Token
+		sc   = scanner.New(s)
+	)
+	for t := sc.Next(); t.Type != scanner.TokenEOF; t = sc.Next() {
+		if t.Type == scanner.TokenError {
+			return nil, fmt.Errorf("supports: %s in %q", t.Value, s)
+		}
+		if t.Type != scanner.TokenComment {
+			toks = append(toks, t)
+		}
+	}
+	p := &condParser{src: s, toks: toks}
+	p.skip()
+	c, err := p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok() != nil {
+		return nil, p.errorf()
+	}
+	return c, nil
+}
+
+// condParser parses the tokens of a condition, keeping whitespace: the
+// operators must be followed by whitespace.
+type condParser struct {
+	src  string
+	toks []*scanner.Token
+	pos  int
+}
+
+// errorf returns an error about the condition, blaming the current token.
+func (p *condParser) errorf() error {
+	got := "end of condition"
+	if t := p.tok(); t != nil {
+		got = fmt.Sprintf("%q", t.Value)
+	}
+	return fmt.Errorf("supports: invalid condition %q: unexpected %s", strings.TrimSpace(p.src), got)
+}
+
+// tok returns the current token, or nil at the end of the condition.
+func (p *condParser) tok() *scanner.Token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return nil
+}
+
+// skip skips whitespace, and reports whether there was any.
+func (p *condParser) skip() bool {
+	start := p.pos
+	for p.pos < len(p.toks) && p.toks[p.pos].Type == scanner.TokenS {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+// operator consumes the operator op and the whitespace following it.
+func (p *condParser) operator(op string) bool {
+	t := p.tok()
+	if t == nil || t.Type != scanner.TokenIdent || !strings.EqualFold(t.Value, op) {
+		return false
+	}
+	p.pos++
+	if !p.skip() {
+		p.pos--
+		return false
+	}
+	return true
+}
+
+func (p *condParser) parseCondition() (Condition, error) {
+	if p.operator("not") {
+		c, err := p.parseInParens()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Cond: c}, nil
+	}
+	c, err := p.parseInParens()
+	if err != nil {
+		return nil, err
+	}
+	ws := p.skip()
+	var op string
+	switch {
+	case !ws:
+	case p.operator("and"):
+		op = "and"
+	case p.operator("or"):
+		op = "or"
+	}
+	if op == "" {
+		return c, nil
+	}
+	conds := []Condition{c}
+	for {
+		c, err := p.parseInParens()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, c)
+		if !p.skip() || !p.operator(op) {
+			break
+		}
+	}
+	if op == "and" {
+		return &And{Conds: conds}, nil
+	}
+	return &Or{Conds: conds}, nil
+}
+
+// parseInParens parses a parenthesized condition, a supports feature or a
+// general enclosed expression.
+func (p *condParser) parseInParens() (Condition, error) {
+	t := p.tok()
+	if t == nil || !(t.Type == scanner.TokenFunction || t.Type == scanner.TokenChar && t.Value == "(") {
+		return nil, p.errorf()
+	}
+	end := p.closing()
+	if end < 0 {
+		return nil, p.errorf()
+	}
+	var (
+		inner = p.toks[p.pos+1 : end]
+		text  = p.text(p.toks[p.pos : end+1])
+		arg   = strings.TrimSpace(p.text(inner))
+	)
+	p.pos = end + 1
+
+	if t.Type == scanner.TokenFunction {
+		switch strings.ToLower(t.Value) {
+		case "selector(":
+			if arg != "" {
+				return &Selector{Selector: arg}, nil
+			}
+		case "font-tech(":
+			if ident := singleIdent(inner); ident != "" {
+				return &FontTech{Tech: ident}, nil
+			}
+		case "font-format(":
+			if ident := singleIdent(inner); ident != "" {
+				return &FontFormat{Format: ident}, nil
+			}
+		}
+		return &GeneralEnclosed{Text: text}, nil
+	}
+
+	sub := &condParser{src: p.src, toks: inner}
+	sub.skip()
+	if c, err := sub.parseCondition(); err == nil {
+		if sub.skip(); sub.tok() == nil {
+			return c, nil
+		}
+	}
+	if d := parseDeclaration(inner, arg); d != nil {
+		return d, nil
+	}
+	return &GeneralEnclosed{Text: text}, nil
+}
+
+// closing returns the index of the parenthesis closing the current token,
+// or -1.
+func (p *condParser) closing() int {
+	depth := 0
+	for i := p.pos; i < len(p.toks); i++ {
+		t := p.toks[i]
+		switch {
+		case t.Type == scanner.TokenFunction || isChar(t, "("):
+			depth++
+		case isChar(t, ")"):
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// text returns the source text spanned by toks.
+func (p *condParser) text(toks []*scanner.Token) string {
+	if len(toks) == 0 {
+		return ""
+	}
+	last := toks[len(toks)-1]
+	return p.src[toks[0].Offset : last.Offset+len(last.Value)]
+}
+
+// parseDeclaration parses the tokens of a declaration without its
+// parentheses, or returns nil. text is the source of toks.
+func parseDeclaration(toks []*scanner.Token, text string) *Declaration {
+	toks = significant(toks)
+	if len(toks) < 3 || toks[0].Type != scanner.TokenIdent || !isChar(toks[1], ":") {
+		return nil
+	}
+	for _, t := range toks[2:] {
+		if t.Type == scanner.TokenChar && strings.ContainsAny(t.Value, ";{}") {
+			return nil
+		}
+	}
+	value := strings.TrimSpace(text[strings.IndexByte(text, ':')+1:])
+	return &Declaration{Property: strings.ToLower(toks[0].Value), Value: value}
+}
+
+// singleIdent returns the lowercased value of toks if it is a single
+// ident, or "".
+func singleIdent(toks []*scanner.Token) string {
+	toks = significant(toks)
+	if len(toks) != 1 || toks[0].Type != scanner.TokenIdent {
+		return ""
+	}
+	return strings.ToLower(toks[0].Value)
+}
+
+func isChar(t *scanner.Token, c string) bool {
+	return t.Type == scanner.TokenChar && t.Value == c
+}
+
+// significant returns toks without whitespace and comments.
+func significant(toks []*scanner.Token) []*scanner.Token {
+	var sig []*scanner.Token
+	for _, t := range toks {
+		if t.Type != scanner.TokenS && t.Type != scanner.TokenComment {
+			sig = append(sig, t)
+		}
+	}
+	return sig
+}
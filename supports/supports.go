@@ -0,0 +1,97 @@
+// Package supports parses and evaluates the conditions of @supports
+// rules, following CSS Conditional Rules Level 4
+// (https://www.w3.org/TR/css-conditional-4/).
+package supports
+
+import "strings"
+
+// Condition is a supports condition: *And, *Or, *Not, *Declaration,
+// *Selector, *FontTech, *FontFormat or *GeneralEnclosed.
+type Condition interface {
+	String() string
+	condition()
+}
+
+// And is a conjunction of conditions.
+type And struct {
+	Conds []Condition
+}
+
+// Or is a disjunction of conditions.
+type Or struct {
+	Conds []Condition
+}
+
+// Not is the negation of a condition.
+type Not struct {
+	Cond Condition
+}
+
+// Declaration tests support for a declaration, like (display: grid).
+type Declaration struct {
+	// Property is the lowercased property name.
+	Property string
+	// Value is the value as written, with surrounding whitespace removed.
+	Value string
+}
+
+// Selector tests support for a selector, like selector(:has(a)).
+type Selector struct {
+	Selector string
+}
+
+// FontTech tests support for a font technology, like
+// font-tech(color-colrv1).
+type FontTech struct {
+	// Tech is the lowercased technology.
+	Tech string
+}
+
+// FontFormat tests support for a font format, like font-format(woff2).
+type FontFormat struct {
+	// Format is the lowercased format.
+	Format string
+}
+
+// GeneralEnclosed is a parenthesized expression or a function that is
+// not a supports condition, kept for future syntax. It is never
+// supported.
+type GeneralEnclosed struct {
+	Text string
+}
+
+func (*And) condition()             {}
+func (*Or) condition()              {}
+func (*Not) condition()             {}
+func (*Declaration) condition()     {}
+func (*Selector) condition()        {}
+func (*FontTech) condition()        {}
+func (*FontFormat) condition()      {}
+func (*GeneralEnclosed) condition() {}
+
+func (c *And) String() string { return join(c.Conds, " and ") }
+func (c *Or) String() string  { return join(c.Conds, " or ") }
+func (c *Not) String() string { return "not " + inParens(c.Cond) }
+
+func (d *Declaration) String() string     { return "(" + d.Property + ": " + d.Value + ")" }
+func (s *Selector) String() string        { return "selector(" + s.Selector + ")" }
+func (f *FontTech) String() string        { return "font-tech(" + f.Tech + ")" }
+func (f *FontFormat) String() string      { return "font-format(" + f.Format + ")" }
+func (g *GeneralEnclosed) String() string { return g.Text }
+
+func join(conds []Condition, sep string) string {
+	parts := make([]string, len(conds))
+	for i, c := range conds {
+		parts[i] = inParens(c)
+	}
+	return strings.Join(parts, sep)
+}
+
+// inParens returns the text of c as an operand of and, or and not.
+func inParens(c Condition) string {
+	switch c.(type) {
+	case *And, *Or, *Not:
+		return "(" + c.String() + ")"
+	}
+	return c.String()
+}
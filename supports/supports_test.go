@@ -0,0 +1,186 @@
+package supports
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/printer"
+	"github.com/ttacon/css/scanner"
+	"github.com/ttacon/pretty"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Condition
+	}{
+		{"(display: grid)", &Declaration{Property: "display", Value: "grid"}},
+		{"( DISPLAY : Grid !important )", &Declaration{Property: "display", Value: "Grid !important"}},
+		{"(--x: { a })", &GeneralEnclosed{Text: "(--x: { a })"}},
+		{"not (display: grid)", &Not{Cond: &Declaration{Property: "display", Value: "grid"}}},
+		{"(display: grid) and (gap: 1em) and selector(a > b)", &And{Conds: []Condition{
+			&Declaration{Property: "display", Value: "grid"},
+			&Declaration{Property: "gap", Value: "1em"},
+			&Selector{Selector: "a > b"},
+		}}},
+		{"((color: oklch(0 0 0)) or (color: lab(0 0 0)))", &Or{Conds: []Condition{
+			&Declaration{Property: "color", Value: "oklch(0 0 0)"},
+			&Declaration{Property: "color", Value: "lab(0 0 0)"},
+		}}},
+		{"selector(:has(> img)) and (not (font-tech(color-COLRv1)))", &And{Conds: []Condition{
+			&Selector{Selector: ":has(> img)"},
+			&Not{Cond: &FontTech{Tech: "color-colrv1"}},
+		}}},
+		{"font-format(woff2)", &FontFormat{Format: "woff2"}},
+		{"(foo bar) or future(x)", &Or{Conds: []Condition{
+			&GeneralEnclosed{Text: "(foo bar)"},
+			&GeneralEnclosed{Text: "future(x)"},
+		}}},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %s, want %s", test.in, pretty.Sprintf("%# v", got), pretty.Sprintf("%# v", test.want))
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in, err string
+	}{
+		{"", `supports: invalid condition "": unexpected end of condition`},
+		{"display: grid", `supports: invalid condition "display: grid": unexpected "display"`},
+		{"(a: b) and (c: d) or (e: f)", `supports: invalid condition "(a: b) and (c: d) or (e: f)": unexpected "or"`},
+		{"(a: b) and(c: d)", `supports: invalid condition "(a: b) and(c: d)": unexpected "and("`},
+		{"not(a: b) (c: d)", `supports: invalid condition "not(a: b) (c: d)": unexpected "("`},
+		{"(a: b", `supports: invalid condition "(a: b": unexpected "("`},
+	}
+	for _, test := range tests {
+		if _, err := Parse(test.in); err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v, want %s", test.in, err, test.err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"(DISPLAY:grid)", "(display: grid)"},
+		{"not ((a: b) and (not (c: d)))", "not ((a: b) and (not (c: d)))"},
+		{"selector( a  b ) or font-format(WOFF2)", "selector(a  b) or font-format(woff2)"},
+	}
+	for _, test := range tests {
+		c, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if got := c.String(); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+var profile = &Profile{
+	Properties: map[string]bool{"display": true, "color": true, "gap": true, "backdrop-filter": true},
+	Values: map[string]map[string]bool{
+		"display": {"block": true, "flex": true, "grid": true, "none": true},
+	},
+	Functions:   map[string]bool{"rgb": true, "lab": true, "blur": true},
+	Selectors:   map[string]bool{":hover": true, ":is": true, "::before": true},
+	FontTechs:   map[string]bool{"color-colrv1": true},
+	FontFormats: map[string]bool{"woff2": true, "woff": true},
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{"(display: grid)", true},
+		{"(display: inherit)", true},
+		{"(display: subgrid)", false},
+		{"(Display: Flex)", true},
+		{"(container-type: size)", false},
+		{"(--x: anything)", true},
+		{"(backdrop-filter: blur(4px))", true},
+		{"(color: oklch(0.5 0.1 200))", false},
+		{"(color: lab(50 0 0)) and (not (color: oklch(0 0 0)))", true},
+		{"(color: oklch(0 0 0)) or (color: rgb(0 0 0))", true},
+		{"selector(a:hover::before)", true},
+		{"selector(:is(a, b) > c)", true},
+		{"selector(:has(img))", false},
+		{"selector(:is(:has(img)))", false},
+		{"selector(a::backdrop)", false},
+		{"font-tech(color-colrv1)", true},
+		{"font-tech(variations)", false},
+		{"font-format(woff2) and font-format(truetype)", false},
+		{"(foo bar)", false},
+		{"not (foo bar)", true},
+	}
+	for _, test := range tests {
+		c, err := Parse(test.cond)
+		if err != nil {
+			t.Errorf("%q: %v", test.cond, err)
+			continue
+		}
+		if got := Eval(c, profile); got != test.want {
+			t.Errorf("%q: got %v, want %v", test.cond, got, test.want)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	src := `@supports (display: grid) {
+  .a { display: grid; }
+  @supports not (display: grid) { .b { float: left; } }
+}
+@supports (display: subgrid) { .c { display: subgrid; } }
+@supports (a b) c { .d { color: red; } }
+@media screen {
+  @supports selector(:hover) { .e:hover { color: red; } }
+}
+.f {
+  @supports (display: flex) { display: flex; }
+}
+`
+	ss, err := parser.New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Prune(ss, profile)
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, ss); err != nil {
+		t.Fatal(err)
+	}
+	want := `.a {
+  display: grid;
+}
+@supports (a b) c {
+  .d {
+    color: red;
+  }
+}
+@media screen {
+  .e:hover {
+    color: red;
+  }
+}
+.f {
+  @supports (display: flex) {
+    display: flex;
+  }
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
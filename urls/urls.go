@@ -0,0 +1,192 @@
+// Package urls finds and rewrites the URLs a stylesheet references.
+package urls
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/scanner"
+)
+
+// Asset is a URL referenced by a stylesheet.
+type Asset struct {
+	URL string
+	// Node is the *ast.Declaration or the *ast.ImportRule holding the
+	// URL.
+	Node ast.Node
+	// Property is the name of the property or descriptor holding the
+	// URL, or "@import".
+	Property string
+}
+
+// RewriteFunc returns the URL replacing the URL of a.
+type RewriteFunc func(a *Asset) (string, error)
+
+// Assets returns the assets referenced by ss.
+func Assets(ss *ast.Stylesheet) []*Asset {
+	assets, _ := Rewrite(ss, nil)
+	return assets
+}
+
+// Rewrite calls rewrite for every URL referenced by ss, in source order,
+// and replaces the URL with the result. URLs are found in url() tokens,
+// in url() and src() functions, and in @import rules; data: URLs are
+// skipped. Rewrite returns the assets found, with their original URL, and
+// stops at the first error returned by rewrite. A nil rewrite leaves the
+// URLs unchanged.
+func Rewrite(ss *ast.Stylesheet, rewrite RewriteFunc) ([]*Asset, error) {
+	r := &rewriter{rewrite: rewrite}
+	ast.Inspect(ss, func(n ast.Node) bool {
+		if r.err != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.Declaration:
+			r.declaration(n)
+			return false
+		case *ast.ImportRule:
+			n.URL = r.url(n, "@import", n.URL)
+		}
+		return true
+	})
+	return r.assets, r.err
+}
+
+type rewriter struct {
+	rewrite RewriteFunc
+	assets  []*Asset
+	err     error
+}
+
+// url records the asset at u and returns its replacement.
+func (r *rewriter) url(n ast.Node, property, u string) string {
+	if u == "" || hasScheme(u, "data") || r.err != nil {
+		return u
+	}
+	a := &Asset{URL: u, Node: n, Property: property}
+	r.assets = append(r.assets, a)
+	if r.rewrite == nil {
+		return u
+	}
+	v, err := r.rewrite(a)
+	if err != nil {
+		r.err = err
+		return u
+	}
+	return v
+}
+
+func (r *rewriter) declaration(d *ast.Declaration) {
+	if strings.HasPrefix(d.Ident, "--") {
+		for i, c := range d.Components {
+			d.Components[i] = r.custom(d, c)
+		}
+		return
+	}
+	r.components(d, d.Components)
+}
+
+// custom returns the value text of the custom property d, which the
+// parser keeps as written in a single component, with its URLs rewritten
+// and the rest of the text left as it is.
+func (r *rewriter) custom(d *ast.Declaration, text string) string {
+	var (
+		comps []string
+		toks  []*scanner.Token
+		sc    = scanner.New(text)
+	)
+	for t := sc.Next(); t.Type != scanner.TokenEOF && t.Type != scanner.TokenError; t = sc.Next() {
+		if t.Type != scanner.TokenS && t.Type != scanner.TokenComment {
+			comps = append(comps, t.Value)
+			toks = append(toks, t)
+		}
+	}
+	rewritten := append([]string(nil), comps...)
+	r.components(d, rewritten)
+	var (
+		b   strings.Builder
+		end int
+	)
+	for i, t := range toks {
+		if rewritten[i] == comps[i] {
+			continue
+		}
+		b.WriteString(text[end:t.Offset])
+		b.WriteString(rewritten[i])
+		end = t.Offset + len(t.Value)
+	}
+	if end == 0 {
+		return text
+	}
+	b.WriteString(text[end:])
+	return b.String()
+}
+
+// components rewrites the URLs in the components comps of the value of d.
+func (r *rewriter) components(d *ast.Declaration, comps []string) {
+	for i, c := range comps {
+		lower := strings.ToLower(c)
+		switch {
+		case isURI(c):
+			u, _ := Value(c)
+			if v := r.url(d, d.Ident, u); v != u {
+				comps[i] = formatURI(v, c)
+			}
+		case (lower == "url(" || lower == "src(") && i+2 < len(comps) &&
+			isString(comps[i+1]) && comps[i+2] == ")":
+			u, _ := Value(comps[i+1])
+			if v := r.url(d, d.Ident, u); v != u {
+				comps[i+1] = scanner.Quote(v, comps[i+1][0])
+			}
+		}
+	}
+}
+
+// Value returns the URL of a url() token or a string token, and whether
+// tok is one.
+func Value(tok string) (string, bool) {
+	switch {
+	case isString(tok):
+		return scanner.Unquote(tok), true
+	case isURI(tok):
+		v := strings.TrimSpace(tok[len("url(") : len(tok)-1])
+		return scanner.Unquote(v), true
+	}
+	return "", false
+}
+
+// IsRelative reports whether u is a relative URL with a path, such as
+// "img/a.png" or "../a.png".
+func IsRelative(u string) bool {
+	if u == "" || strings.HasPrefix(u, "/") || strings.HasPrefix(u, "#") {
+		return false
+	}
+	// A scheme is only followed by a colon.
+	if i := strings.IndexAny(u, ":/?#"); i >= 0 && u[i] == ':' {
+		return false
+	}
+	return true
+}
+
+// formatURI returns a url() token for u, quoted like the token orig.
+func formatURI(u, orig string) string {
+	if v := strings.TrimSpace(orig[len("url(") : len(orig)-1]); isString(v) {
+		return "url(" + scanner.Quote(u, v[0]) + ")"
+	}
+	if strings.ContainsAny(u, " \t\n\"'()\\") {
+		return "url(" + scanner.Quote(u, '"') + ")"
+	}
+	return "url(" + u + ")"
+}
+
+func hasScheme(u, scheme string) bool {
+	return len(u) > len(scheme) && u[len(scheme)] == ':' && strings.EqualFold(u[:len(scheme)], scheme)
+}
+
+func isURI(s string) bool {
+	return len(s) > len("url()") && strings.EqualFold(s[:len("url(")], "url(") && s[len(s)-1] == ')'
+}
+
+func isString(s string) bool {
+	return len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0]
+}
@@ -0,0 +1,164 @@
+package urls
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/printer"
+	"github.com/ttacon/css/scanner"
+)
+
+const src = `@import "base.css" screen;
+@import url(theme.css);
+@font-face {
+  font-family: x;
+  src: url("fonts/x.woff2") format("woff2"), url(fonts/x.woff) format("woff");
+}
+.a { background: url( 'img/a b.png' ) no-repeat, src("img/b.png"); }
+.b { mask: url(data:image/svg+xml;utf8,x) ; cursor: url(img/c.cur), auto; }
+.c { --bg: url(img/d.png)  no-repeat, /* c */ url("img/e.png"); }
+`
+
+func TestAssets(t *testing.T) {
+	ss, err := parser.New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, a := range Assets(ss) {
+		got = append(got, a.Property+" "+a.URL)
+	}
+	want := []string{
+		"@import base.css",
+		"@import theme.css",
+		"src fonts/x.woff2",
+		"src fonts/x.woff",
+		"background img/a b.png",
+		"background img/b.png",
+		"cursor img/c.cur",
+		"--bg img/d.png",
+		"--bg img/e.png",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	ss, err := parser.NewWithMode(scanner.New(src), parser.Lossless).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets, err := Rewrite(ss, func(a *Asset) (string, error) {
+		if strings.HasSuffix(a.URL, ".css") {
+			return a.URL, nil
+		}
+		if a.URL == "img/b.png" {
+			return `data:image/png;base64,"x"`, nil
+		}
+		return "https://cdn.example.com/" + a.URL + "?v=1", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 9 {
+		t.Errorf("got %d assets, want 9", len(assets))
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, ss); err != nil {
+		t.Fatal(err)
+	}
+	want := `@import "base.css" screen;
+@import url(theme.css);
+@font-face {
+  font-family: x;
+  src: url("https://cdn.example.com/fonts/x.woff2?v=1") format("woff2"), url(https://cdn.example.com/fonts/x.woff?v=1) format("woff");
+}
+.a { background: url('https://cdn.example.com/img/a b.png?v=1') no-repeat, src("data:image/png;base64,\"x\""); }
+.b { mask: url(data:image/svg+xml;utf8,x) ; cursor: url(https://cdn.example.com/img/c.cur?v=1), auto; }
+.c { --bg: url(https://cdn.example.com/img/d.png?v=1)  no-repeat, /* c */ url("https://cdn.example.com/img/e.png?v=1"); }
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRewriteImport(t *testing.T) {
+	ss, err := parser.New(scanner.New(`@import url("a.css") print; @import 'b.css';`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Rewrite(ss, func(a *Asset) (string, error) {
+		return "/css/" + a.URL, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, ss); err != nil {
+		t.Fatal(err)
+	}
+	want := "@import \"/css/a.css\" print;\n@import \"/css/b.css\";\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRewriteError(t *testing.T) {
+	ss, err := parser.New(scanner.New(`.a { background: url(a.png), url(b.png) }`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int
+	_, err = Rewrite(ss, func(a *Asset) (string, error) {
+		calls++
+		return "", fmt.Errorf("%s: %w", a.URL, errors.New("not found"))
+	})
+	if err == nil || err.Error() != "a.png: not found" || calls != 1 {
+		t.Errorf("got error %v after %d calls", err, calls)
+	}
+}
+
+func TestValue(t *testing.T) {
+	var tests = []struct {
+		tok  string
+		want string
+		ok   bool
+	}{
+		{`"a.png"`, "a.png", true},
+		{`'a\'b.png'`, "a'b.png", true},
+		{`url(a.png)`, "a.png", true},
+		{`url( "a\2e png" )`, "a.png", true},
+		{`URL(a\ b.png)`, "a b.png", true},
+		{`a.png`, "", false},
+	}
+	for _, test := range tests {
+		if got, ok := Value(test.tok); got != test.want || ok != test.ok {
+			t.Errorf("Value(%q) = %q, %v, want %q, %v", test.tok, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestIsRelative(t *testing.T) {
+	for u, want := range map[string]bool{
+		"a.png":               true,
+		"../a.png":            true,
+		"a/b:c.png":           true,
+		"/a.png":              false,
+		"//cdn.example.com/a": false,
+		"https://x.com/a.png": false,
+		"data:image/png,x":    false,
+		"#filter":             false,
+		"":                    false,
+	} {
+		if got := IsRelative(u); got != want {
+			t.Errorf("IsRelative(%q) = %v, want %v", u, got, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package vars
+
+import (
+	"strings"
+
+	"github.com/ttacon/css/ast"
+)
+
+// Value returns the components of the value of the custom property name
+// with its var() references substituted, and reports whether it is known
+// statically: all the definitions of name, and of the properties it
+// references, must be global and equal, and none may be part of a cycle.
+// A reference to a property that is never defined takes its fallback.
+func (a *Analysis) Value(name string) ([]string, bool) {
+	return a.value(name, map[string]bool{})
+}
+
+func (a *Analysis) value(name string, seen map[string]bool) ([]string, bool) {
+	defs := a.defs[name]
+	if len(defs) == 0 || seen[name] || a.cyclic[name] {
+		return nil, false
+	}
+	for _, d := range defs {
+		if !d.Global || !equal(d.Value, defs[0].Value) {
+			return nil, false
+		}
+	}
+	seen[name] = true
+	defer delete(seen, name)
+	return a.substitute(defs[0].Value, seen)
+}
+
+// substitute returns comps with their var() references substituted, and
+// reports whether all of them are known statically.
+func (a *Analysis) substitute(comps []string, seen map[string]bool) ([]string, bool) {
+	var out []string
+	for i := 0; i < len(comps); i++ {
+		v, ok := parseVar(comps, i)
+		if !ok {
+			out = append(out, comps[i])
+			continue
+		}
+		var (
+			sub   []string
+			known bool
+		)
+		switch {
+		case len(a.defs[v.name]) > 0:
+			sub, known = a.value(v.name, seen)
+		case v.hasFallback:
+			sub, known = a.substitute(v.fallback, seen)
+		}
+		if !known {
+			return nil, false
+		}
+		out = append(out, sub...)
+		i = v.end
+	}
+	return out, true
+}
+
+// Substitute replaces the var() references of the declarations of
+// standard properties in ss with the values known statically, as Value
+// returns them, and returns the number of declarations changed.
+// Declarations with a reference that is not known are left as they are,
+// as are custom property declarations.
+func (a *Analysis) Substitute(ss *ast.Stylesheet) int {
+	n := 0
+	ast.Inspect(ss, func(node ast.Node) bool {
+		d, ok := node.(*ast.Declaration)
+		if !ok {
+			return true
+		}
+		if isCustom(d.Ident) || !hasVar(d.Components) {
+			return false
+		}
+		if comps, ok := a.substitute(d.Components, map[string]bool{}); ok {
+			d.Components = comps
+			n++
+		}
+		return false
+	})
+	return n
+}
+
+// varRef is a var() function in a list of components.
+type varRef struct {
+	name        string
+	hasFallback bool
+	// fallback holds the components of the fallback value.
+	fallback []string
+	// end is the index of the closing parenthesis.
+	end int
+}
+
+// parseVar parses the var() function starting at comps[i].
+func parseVar(comps []string, i int) (varRef, bool) {
+	if !strings.EqualFold(comps[i], "var(") || i+2 >= len(comps) || !isCustom(comps[i+1]) {
+		return varRef{}, false
+	}
+	end := closing(comps, i)
+	if end < 0 {
+		return varRef{}, false
+	}
+	v := varRef{name: comps[i+1], end: end}
+	switch comps[i+2] {
+	case ",":
+		v.hasFallback, v.fallback = true, comps[i+3:end]
+	case ")":
+	default:
+		return varRef{}, false
+	}
+	return v, true
+}
+
+func hasVar(comps []string) bool {
+	for _, c := range comps {
+		if strings.EqualFold(c, "var(") {
+			return true
+		}
+	}
+	return false
+}
+
+// closing returns the index of the component closing the function or
+// parenthesis opened by comps[i], or -1 if it is not closed.
+func closing(comps []string, i int) int {
+	depth := 0
+	for j := i; j < len(comps); j++ {
+		switch {
+		case strings.HasSuffix(comps[j], "("):
+			depth++
+		case comps[j] == ")":
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,325 @@
+// Package vars analyzes the custom properties of stylesheets, like
+// --accent: #06c, and their var() references.
+//
+// An Analysis holds the dependency graph of the custom properties, where
+// a property depends on those its value references. It finds the cycles
+// of the graph, which make the values of the properties in them invalid
+// at computed-value time, the references to properties that are never
+// defined and the properties that are never used. Properties whose value
+// is known statically can be substituted for their references.
+//
+// Custom properties are inherited and set per element, so the analysis
+// merges the definitions of a property made for different elements:
+// --a: var(--b) in .x and --b: var(--a) in .y make a cycle even if no
+// element matches both rules.
+package vars
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/scanner"
+)
+
+// Definition is a definition of a custom property.
+type Definition struct {
+	// Name is the name of the property, like "--accent". Custom property
+	// names are case-sensitive.
+	Name string
+	// Node is the *ast.Declaration defining the property, or the
+	// *ast.PropertyRule registering it with an initial value.
+	Node ast.Node
+	// Value holds the components of the value.
+	Value []string
+	// Global is set for the definitions applying to every element
+	// unconditionally: the declarations of :root and html rules outside
+	// of conditional rules, like @media, and the initial values of
+	// registered properties.
+	Global bool
+}
+
+// Reference is a var() reference to a custom property.
+type Reference struct {
+	Name string
+	// Decl is the declaration holding the reference.
+	Decl *ast.Declaration
+	// HasFallback is set if the reference has a fallback value, like
+	// var(--accent, blue).
+	HasFallback bool
+}
+
+// Analysis is the analysis of the custom properties of a set of
+// stylesheets.
+type Analysis struct {
+	defs map[string][]*Definition
+	refs []*Reference
+	// deps maps the custom properties to those their values reference.
+	deps   map[string]map[string]bool
+	cycles [][]string
+	cyclic map[string]bool
+}
+
+// Analyze analyzes the custom properties defined and referenced in
+// sheets, which are taken to apply to the same documents.
+func Analyze(sheets ...*ast.Stylesheet) *Analysis {
+	a := &Analysis{
+		defs:   map[string][]*Definition{},
+		deps:   map[string]map[string]bool{},
+		cyclic: map[string]bool{},
+	}
+	for _, ss := range sheets {
+		a.rules(ss.Children, true)
+	}
+	a.findCycles()
+	return a
+}
+
+// rules records the custom properties of list, whose rules are global if
+// global is set and they are :root or html rules.
+func (a *Analysis) rules(list []ast.Rule, global bool) {
+	for _, r := range list {
+		switch r := r.(type) {
+		case *ast.QualifiedRule:
+			if r.Block != nil {
+				a.block(r.Block, global && isRoot(r))
+			}
+		case *ast.LayerRule:
+			if r.Block != nil {
+				a.rules(r.Block.Rules, global)
+			}
+		case *ast.PropertyRule:
+			a.property(r)
+		default:
+			if b := ast.BlockOf(r); b != nil {
+				a.block(b, false)
+			}
+		}
+	}
+}
+
+func (a *Analysis) block(b *ast.Block, global bool) {
+	if b.DeclList != nil {
+		for _, d := range b.DeclList.Declarations {
+			a.declaration(d, global)
+		}
+	}
+	// Rules nested in a style rule apply to other elements.
+	a.rules(b.Rules, false)
+}
+
+func (a *Analysis) declaration(d *ast.Declaration, global bool) {
+	custom := isCustom(d.Ident)
+	comps := d.Components
+	if custom {
+		comps = customComponents(comps)
+		a.define(&Definition{Name: d.Ident, Node: d, Value: comps, Global: global})
+	}
+	for i := range comps {
+		v, ok := parseVar(comps, i)
+		if !ok {
+			continue
+		}
+		a.refs = append(a.refs, &Reference{Name: v.name, Decl: d, HasFallback: v.hasFallback})
+		if custom {
+			a.deps[d.Ident][v.name] = true
+		}
+	}
+}
+
+// property records the initial value of the registered property r.
+func (a *Analysis) property(r *ast.PropertyRule) {
+	if r.Block == nil || r.Block.DeclList == nil || !isCustom(r.Name) {
+		return
+	}
+	for _, d := range r.Block.DeclList.Declarations {
+		if strings.EqualFold(d.Ident, "initial-value") {
+			a.define(&Definition{Name: r.Name, Node: r, Value: d.Components, Global: true})
+		}
+	}
+}
+
+func (a *Analysis) define(d *Definition) {
+	a.defs[d.Name] = append(a.defs[d.Name], d)
+	if a.deps[d.Name] == nil {
+		a.deps[d.Name] = map[string]bool{}
+	}
+}
+
+// Names returns the names of the custom properties defined, sorted.
+func (a *Analysis) Names() []string {
+	var names []string
+	for name := range a.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Definitions returns the definitions of the custom property name, in
+// source order.
+func (a *Analysis) Definitions(name string) []*Definition {
+	return a.defs[name]
+}
+
+// References returns the var() references, in source order.
+func (a *Analysis) References() []*Reference {
+	return a.refs
+}
+
+// Dependencies returns the names of the custom properties referenced by
+// the values of name, fallbacks included, sorted.
+func (a *Analysis) Dependencies(name string) []string {
+	return sorted(a.deps[name])
+}
+
+// Cycles returns the cycles of the dependency graph, as the names of the
+// custom properties in each, sorted. The values of these properties are
+// invalid at computed-value time, like an unset property, where all the
+// definitions of a cycle apply.
+func (a *Analysis) Cycles() [][]string {
+	return a.cycles
+}
+
+// Undefined returns the references to custom properties that are never
+// defined, in source order. Those without fallback make the declaration
+// holding them invalid at computed-value time.
+func (a *Analysis) Undefined() []*Reference {
+	var refs []*Reference
+	for _, r := range a.refs {
+		if len(a.defs[r.Name]) == 0 {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// Unused returns the definitions of the custom properties that are never
+// used, sorted by name: the properties that no declaration of a standard
+// property references, directly or through other custom properties.
+func (a *Analysis) Unused() []*Definition {
+	used := map[string]bool{}
+	var use func(name string)
+	use = func(name string) {
+		if used[name] {
+			return
+		}
+		used[name] = true
+		for dep := range a.deps[name] {
+			use(dep)
+		}
+	}
+	for _, r := range a.refs {
+		if !isCustom(r.Decl.Ident) {
+			use(r.Name)
+		}
+	}
+	var defs []*Definition
+	for _, name := range a.Names() {
+		if !used[name] {
+			defs = append(defs, a.defs[name]...)
+		}
+	}
+	return defs
+}
+
+// findCycles finds the strongly connected components of the dependency
+// graph with Tarjan's algorithm, and keeps those making cycles.
+func (a *Analysis) findCycles() {
+	var (
+		index   = map[string]int{}
+		low     = map[string]int{}
+		onStack = map[string]bool{}
+		stack   []string
+		visit   func(name string)
+	)
+	visit = func(name string) {
+		index[name] = len(index)
+		low[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+		for _, dep := range sorted(a.deps[name]) {
+			if _, ok := index[dep]; !ok {
+				visit(dep)
+				low[name] = minInt(low[name], low[dep])
+			} else if onStack[dep] {
+				low[name] = minInt(low[name], index[dep])
+			}
+		}
+		if low[name] != index[name] {
+			return
+		}
+		var scc []string
+		for {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[n] = false
+			scc = append(scc, n)
+			if n == name {
+				break
+			}
+		}
+		if len(scc) > 1 || a.deps[name][name] {
+			sort.Strings(scc)
+			a.cycles = append(a.cycles, scc)
+			for _, n := range scc {
+				a.cyclic[n] = true
+			}
+		}
+	}
+	for _, name := range a.Names() {
+		if _, ok := index[name]; !ok {
+			visit(name)
+		}
+	}
+	sort.Slice(a.cycles, func(i, j int) bool {
+		return a.cycles[i][0] < a.cycles[j][0]
+	})
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func sorted(set map[string]bool) []string {
+	var names []string
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isCustom(name string) bool {
+	return strings.HasPrefix(name, "--")
+}
+
+// isRoot reports whether r only selects the root element.
+func isRoot(r *ast.QualifiedRule) bool {
+	for _, c := range r.Components {
+		switch strings.ToLower(strings.TrimSpace(c.Name)) {
+		case ":root", "html":
+		default:
+			return false
+		}
+	}
+	return len(r.Components) > 0
+}
+
+// customComponents returns the components of the value of a custom
+// property, which the parser keeps as written in a single component.
+func customComponents(comps []string) []string {
+	var out []string
+	for _, c := range comps {
+		sc := scanner.New(c)
+		for t := sc.Next(); t.Type != scanner.TokenEOF && t.Type != scanner.TokenError; t = sc.Next() {
+			if t.Type != scanner.TokenS && t.Type != scanner.TokenComment {
+				out = append(out, t.Value)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+package vars
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ttacon/css/ast"
+	"github.com/ttacon/css/parser"
+	"github.com/ttacon/css/printer"
+	"github.com/ttacon/css/scanner"
+)
+
+func parse(t *testing.T, src string) *ast.Stylesheet {
+	t.Helper()
+	ss, err := parser.New(scanner.New(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ss
+}
+
+const theme = `
+:root {
+  --blue: #06c;
+  --accent: var(--blue);
+  --gap: 4px;
+  --gap-2: calc(var(--gap) * 2);
+  --unused: 1px;
+  --dead: var(--unused);
+}
+.dark { --fg: white; --accent: var(--fg) }
+.a { --x: var(--y); --y: var(--z, 1px); --z: var(--x) }
+.b { --self: var(--self) }
+@property --size {
+  syntax: "<length>";
+  inherits: false;
+  initial-value: 10px;
+}
+`
+
+const page = `
+.card {
+  color: var(--accent);
+  padding: var(--gap-2) var(--size);
+  margin: var(--missing, 0) var(--x);
+  border-color: var(--nope);
+}
+`
+
+func TestAnalyze(t *testing.T) {
+	a := Analyze(parse(t, theme), parse(t, page))
+
+	wantNames := []string{"--accent", "--blue", "--dead", "--fg", "--gap", "--gap-2", "--self", "--size", "--unused", "--x", "--y", "--z"}
+	if got := a.Names(); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("Names() = %v, expected %v", got, wantNames)
+	}
+	if got, want := a.Dependencies("--accent"), []string{"--blue", "--fg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependencies(--accent) = %v, expected %v", got, want)
+	}
+	wantCycles := [][]string{{"--self"}, {"--x", "--y", "--z"}}
+	if got := a.Cycles(); !reflect.DeepEqual(got, wantCycles) {
+		t.Errorf("Cycles() = %v, expected %v", got, wantCycles)
+	}
+
+	var undefined []string
+	for _, r := range a.Undefined() {
+		undefined = append(undefined, r.Name+" in "+r.Decl.Ident)
+		if r.HasFallback != (r.Name == "--missing") {
+			t.Errorf("%s: HasFallback = %v", r.Name, r.HasFallback)
+		}
+	}
+	if want := []string{"--missing in margin", "--nope in border-color"}; !reflect.DeepEqual(undefined, want) {
+		t.Errorf("Undefined() = %v, expected %v", undefined, want)
+	}
+
+	var unused []string
+	for _, d := range a.Unused() {
+		unused = append(unused, d.Name)
+	}
+	if want := []string{"--dead", "--self", "--unused"}; !reflect.DeepEqual(unused, want) {
+		t.Errorf("Unused() = %v, expected %v", unused, want)
+	}
+
+	if defs := a.Definitions("--size"); len(defs) != 1 || !defs[0].Global {
+		t.Errorf("Definitions(--size) = %v, expected a global definition", defs)
+	} else if _, ok := defs[0].Node.(*ast.PropertyRule); !ok {
+		t.Errorf("definition of --size in %T, expected *ast.PropertyRule", defs[0].Node)
+	}
+	if defs := a.Definitions("--fg"); len(defs) != 1 || defs[0].Global {
+		t.Errorf("Definitions(--fg) = %v, expected a definition in .dark", defs)
+	}
+}
+
+func TestValue(t *testing.T) {
+	a := Analyze(parse(t, theme))
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"--blue", "#06c", true},
+		{"--gap-2", "calc( 4px * 2 )", true},
+		{"--size", "10px", true},
+		// --accent is redefined in .dark.
+		{"--accent", "", false},
+		{"--fg", "", false},
+		{"--x", "", false},
+		{"--undefined", "", false},
+	}
+	for _, test := range tests {
+		v, ok := a.Value(test.name)
+		if got := strings.Join(v, " "); got != test.want || ok != test.ok {
+			t.Errorf("Value(%s) = %q, %v, expected %q, %v", test.name, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	a := Analyze(parse(t, theme))
+	ss := parse(t, page+`.c { --local: var(--blue); background: var(--blue, red) var(--nothing, no-repeat) }`)
+	if n := a.Substitute(ss); n != 2 {
+		t.Errorf("Substitute() = %d, expected 2", n)
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, ss); err != nil {
+		t.Fatal(err)
+	}
+	want := `.card {
+  color: var(--accent);
+  padding: calc(4px * 2) 10px;
+  margin: var(--missing, 0) var(--x);
+  border-color: var(--nope);
+}
+.c {
+  --local: var(--blue);
+  background: #06c no-repeat;
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}